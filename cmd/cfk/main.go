@@ -1,32 +1,70 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"os"
 
 	"github.com/cfk-dev/cfk/internal/config"
 	"github.com/cfk-dev/cfk/internal/core"
+	"github.com/cfk-dev/cfk/internal/kafka/fake"
 	"github.com/cfk-dev/cfk/internal/tui"
 )
 
 func main() {
+	// Subcommands run without the TUI
+	if len(os.Args) > 1 && os.Args[1] == "produce" {
+		os.Exit(runProduce(os.Args[2:]))
+	}
+
+	demo := flag.Bool("demo", false, "run against an in-memory demo cluster instead of the configured clusters")
+	flag.Parse()
+
 	fmt.Println("Welcome to cfk - Console for Kafka!")
 
-	// Load configuration
-	appConfig, err := config.LoadAppConfig()
+	appConfig, app, err := newApp(*demo)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error loading configuration: %v\n", err)
 		os.Exit(1)
 	}
 
-	// Debug code removed
-
-	// Initialize application core
-	app := core.NewApp(appConfig)
-	
 	// Pass the app to the TUI
 	if err := tui.Start(appConfig, app); err != nil {
 		fmt.Fprintf(os.Stderr, "Error running TUI: %v\n", err)
 		os.Exit(1)
 	}
 }
+
+// newApp loads the configuration and initializes the application core, or
+// sets up an in-memory demo cluster
+func newApp(demo bool) (*config.AppConfig, *core.App, error) {
+	if demo {
+		appConfig := demoConfig()
+		app := core.NewApp(appConfig)
+		app.Ephemeral = true
+
+		// Keep one cluster so changes survive reconnecting
+		cluster := fake.NewDemoCluster()
+		app.Connect = func(ctx context.Context, clusterConfig config.KafkaClusterConfig) (core.KafkaAdmin, error) {
+			return cluster, nil
+		}
+		return appConfig, app, nil
+	}
+
+	appConfig, err := config.LoadAppConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	return appConfig, core.NewApp(appConfig), nil
+}
+
+// demoConfig returns a configuration with a single in-memory demo cluster
+func demoConfig() *config.AppConfig {
+	cfg := config.DefaultConfig()
+	cfg.Clusters = []config.KafkaClusterConfig{{
+		Name:      "demo",
+		Bootstrap: []string{"demo:9092"},
+	}}
+	return cfg
+}
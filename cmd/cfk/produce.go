@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/cfk-dev/cfk/internal/core"
+	"github.com/cfk-dev/cfk/internal/kafka"
+)
+
+// progressInterval is how often bulk produce progress is reported
+const progressInterval = time.Second
+
+// runProduce writes the records of a file or of stdin to a topic, reporting
+// progress and failed lines on stderr. It returns the exit code.
+func runProduce(args []string) int {
+	flags := flag.NewFlagSet("produce", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: cfk produce --topic TOPIC [flags] [--file FILE]")
+		fmt.Fprintln(flags.Output(), "\nWrites the records of a JSON Lines, CSV or delimited file, or of stdin, to a topic.")
+		fmt.Fprintln(flags.Output(), "\nFlags:")
+		flags.PrintDefaults()
+	}
+	cluster := flags.String("cluster", "", "name of the configured cluster, optional when there is only one")
+	topic := flags.String("topic", "", "topic to write to")
+	file := flags.String("file", "-", "input file, - for stdin")
+	format := flags.String("format", string(core.BulkJSONL), "input format: jsonl, csv or delimited")
+	delimiter := flags.String("delimiter", `\t`, "field separator of delimited input")
+	serializer := flags.String("serializer", string(core.SerializeString), "value serializer: string, json or avro")
+	acks := flags.String("acks", kafka.AcksAll, "acknowledgements to wait for: all, leader or none")
+	compression := flags.String("compression", "none", "compression codec: none, gzip, snappy, lz4 or zstd")
+	batch := flags.Int("batch", core.DefaultBatchSize, "records per produce request")
+	rate := flags.Float64("rate", 0, "records per second, 0 for no limit")
+	demo := flags.Bool("demo", false, "run against an in-memory demo cluster instead of the configured clusters")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *topic == "" {
+		fmt.Fprintln(os.Stderr, "Error: --topic is required")
+		flags.Usage()
+		return 2
+	}
+
+	appConfig, app, err := newApp(*demo)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading configuration: %v\n", err)
+		return 1
+	}
+	if *cluster == "" {
+		if len(appConfig.Clusters) != 1 {
+			fmt.Fprintf(os.Stderr, "Error: --cluster is required with %d clusters configured\n", len(appConfig.Clusters))
+			return 2
+		}
+		*cluster = appConfig.Clusters[0].Name
+	}
+
+	// Stop at the next batch on interrupt, reporting what was written
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := app.ConnectToCluster(ctx, *cluster); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	defer app.KafkaClient.Close()
+
+	var in io.Reader = os.Stdin
+	if *file != "-" {
+		f, err := os.Open(*file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		defer f.Close()
+		in = f
+	}
+	input, err := core.NewBulkReader(in, core.BulkFormat(*format), *delimiter)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	reported := time.Now()
+	result, err := app.BulkProduce(ctx, input, core.BulkOptions{
+		Topic:      *topic,
+		Serializer: core.Serializer(*serializer),
+		Produce:    kafka.ProduceOptions{Acks: *acks, Compression: *compression},
+		BatchSize:  *batch,
+		Rate:       *rate,
+	}, func(p core.BulkProgress) {
+		if time.Since(reported) >= progressInterval {
+			reported = time.Now()
+			fmt.Fprintf(os.Stderr, "%s\n", progressLine(p))
+		}
+	})
+	if result != nil {
+		for _, e := range result.Errors {
+			fmt.Fprintf(os.Stderr, "%v\n", &e)
+		}
+		if result.Failed > len(result.Errors) {
+			fmt.Fprintf(os.Stderr, "and %d more failed records\n", result.Failed-len(result.Errors))
+		}
+		fmt.Fprintf(os.Stderr, "Done: %s\n", progressLine(result.BulkProgress))
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if result.Failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// progressLine counts the records of a bulk produce so far
+func progressLine(p core.BulkProgress) string {
+	return fmt.Sprintf("read %d, sent %d, failed %d, %.0f records/s, %s",
+		p.Read, p.Sent, p.Failed, p.Rate(), p.Elapsed.Round(time.Millisecond))
+}
@@ -5,14 +5,18 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 
+	"github.com/mitchellh/mapstructure"
 	"github.com/spf13/viper"
 )
 
 // AppConfig holds the application configuration
 type AppConfig struct {
-	Clusters []KafkaClusterConfig `mapstructure:"clusters"`
-	UI       UIConfig             `mapstructure:"ui"`
+	Clusters       []KafkaClusterConfig `mapstructure:"clusters"`
+	UI             UIConfig             `mapstructure:"ui"`
+	TopicTemplates []TopicTemplate      `mapstructure:"topic_templates"`
+	Serdes         []SerdeRule          `mapstructure:"serdes"`
 }
 
 // KafkaClusterConfig holds configuration for a Kafka cluster
@@ -23,7 +27,32 @@ type KafkaClusterConfig struct {
 	Password  string   `mapstructure:"password,omitempty"`
 	SSL       bool     `mapstructure:"ssl"`
 	SASL      bool     `mapstructure:"sasl"`
-	SASLType  string   `mapstructure:"sasl_type,omitempty"` // PLAIN, SCRAM-SHA-256, SCRAM-SHA-512
+	SASLType  string   `mapstructure:"sasl_type,omitempty"` // PLAIN, SCRAM-SHA-256, SCRAM-SHA-512, OAUTHBEARER
+
+	// TLS settings, only used when SSL is enabled
+	SSLCAFile             string `mapstructure:"ssl_ca_file,omitempty"`     // PEM bundle used to verify brokers
+	SSLCertFile           string `mapstructure:"ssl_cert_file,omitempty"`   // client certificate for mTLS
+	SSLKeyFile            string `mapstructure:"ssl_key_file,omitempty"`    // client private key for mTLS
+	SSLServerName         string `mapstructure:"ssl_server_name,omitempty"` // overrides the verified host name
+	SSLInsecureSkipVerify bool   `mapstructure:"ssl_insecure_skip_verify"`
+
+	// OAUTHBEARER settings, only used when SASLType is OAUTHBEARER
+	OAuthProvider     string `mapstructure:"oauth_provider,omitempty"`      // static, oidc, command
+	OAuthToken        string `mapstructure:"oauth_token,omitempty"`         // token for the static provider
+	OAuthTokenURL     string `mapstructure:"oauth_token_url,omitempty"`     // token endpoint for the oidc provider
+	OAuthClientID     string `mapstructure:"oauth_client_id,omitempty"`     // client ID for the oidc provider
+	OAuthClientSecret string `mapstructure:"oauth_client_secret,omitempty"` // client secret for the oidc provider
+	OAuthScopes       string `mapstructure:"oauth_scopes,omitempty"`        // space-separated scopes for the oidc provider
+	OAuthTokenCommand string `mapstructure:"oauth_token_command,omitempty"` // command printing a token for the command provider
+
+	// Schema Registry settings, only used when SchemaRegistryURL is set
+	SchemaRegistryURL                string `mapstructure:"schema_registry_url,omitempty"`
+	SchemaRegistryUsername           string `mapstructure:"schema_registry_username,omitempty"`  // basic auth user
+	SchemaRegistryPassword           string `mapstructure:"schema_registry_password,omitempty"`  // basic auth password
+	SchemaRegistryCAFile             string `mapstructure:"schema_registry_ca_file,omitempty"`   // PEM bundle used to verify the registry
+	SchemaRegistryCertFile           string `mapstructure:"schema_registry_cert_file,omitempty"` // client certificate for mTLS
+	SchemaRegistryKeyFile            string `mapstructure:"schema_registry_key_file,omitempty"`  // client private key for mTLS
+	SchemaRegistryInsecureSkipVerify bool   `mapstructure:"schema_registry_insecure_skip_verify"`
 }
 
 // UIConfig holds UI-related configuration
@@ -31,6 +60,51 @@ type UIConfig struct {
 	Theme            string `mapstructure:"theme"`
 	RefreshInterval  int    `mapstructure:"refresh_interval"`
 	MaxMessagesShown int    `mapstructure:"max_messages_shown"`
+	TailBufferSize   int    `mapstructure:"tail_buffer_size"` // records kept by the live tail
+}
+
+// TopicTemplate holds named settings that prefill the new topic form
+type TopicTemplate struct {
+	Name              string            `mapstructure:"name"`
+	Partitions        int               `mapstructure:"partitions"`
+	ReplicationFactor int               `mapstructure:"replication_factor"`
+	Configs           map[string]string `mapstructure:"configs"`
+}
+
+// SerdeRule picks how the keys and values of the topics matching a pattern
+// are decoded. An empty format is auto-detected.
+type SerdeRule struct {
+	Topic string `mapstructure:"topic"`           // topic name pattern, see glob.Pattern
+	Key   string `mapstructure:"key,omitempty"`   // format of the keys
+	Value string `mapstructure:"value,omitempty"` // format of the values
+}
+
+// DefaultTopicTemplates returns the templates of a new configuration
+func DefaultTopicTemplates() []TopicTemplate {
+	return []TopicTemplate{
+		{
+			Name:              "compacted-changelog",
+			Partitions:        6,
+			ReplicationFactor: 3,
+			Configs: map[string]string{
+				"cleanup.policy":            "compact",
+				"min.cleanable.dirty.ratio": "0.1",
+				"min.insync.replicas":       "2",
+				"segment.ms":                "3600000",
+			},
+		},
+		{
+			Name:              "high-throughput-events",
+			Partitions:        12,
+			ReplicationFactor: 3,
+			Configs: map[string]string{
+				"compression.type":    "lz4",
+				"max.message.bytes":   "2097152",
+				"min.insync.replicas": "2",
+				"retention.ms":        "259200000",
+			},
+		},
+	}
 }
 
 // DefaultConfig returns a default configuration
@@ -41,7 +115,9 @@ func DefaultConfig() *AppConfig {
 			Theme:            "default",
 			RefreshInterval:  5,
 			MaxMessagesShown: 100,
+			TailBufferSize:   1000,
 		},
+		TopicTemplates: DefaultTopicTemplates(),
 	}
 }
 
@@ -83,7 +159,11 @@ func LoadAppConfig() (*AppConfig, error) {
 			return nil, fmt.Errorf("could not read config: %w", err)
 		}
 	} else {
-		// Config file found, unmarshal
+		// Config file found, unmarshal. Templates in the file replace the
+		// defaults rather than being merged into them.
+		if v.IsSet("topic_templates") {
+			config.TopicTemplates = nil
+		}
 		if err := v.Unmarshal(config); err != nil {
 			return nil, fmt.Errorf("could not parse config: %w", err)
 		}
@@ -97,9 +177,20 @@ func SaveAppConfig(config *AppConfig, path string) error {
 	v := viper.New()
 	v.SetConfigFile(path)
 
-	// Set config values
-	v.Set("clusters", config.Clusters)
-	v.Set("ui", config.UI)
+	// Set config values under the names LoadAppConfig reads back. Viper
+	// would marshal structs with their lowercased field names instead.
+	for key, value := range map[string]interface{}{
+		"clusters":        config.Clusters,
+		"ui":              config.UI,
+		"topic_templates": config.TopicTemplates,
+		"serdes":          config.Serdes,
+	} {
+		setting, err := settingOf(value)
+		if err != nil {
+			return fmt.Errorf("could not write config: %w", err)
+		}
+		v.Set(key, setting)
+	}
 
 	// Write config to file
 	if err := v.WriteConfig(); err != nil {
@@ -108,3 +199,24 @@ func SaveAppConfig(config *AppConfig, path string) error {
 
 	return nil
 }
+
+// settingOf converts a struct, or a slice of structs, to maps keyed by the
+// mapstructure names of its fields
+func settingOf(value interface{}) (interface{}, error) {
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Slice {
+		setting := make(map[string]interface{})
+		err := mapstructure.Decode(value, &setting)
+		return setting, err
+	}
+
+	settings := make([]interface{}, rv.Len())
+	for i := range settings {
+		setting, err := settingOf(rv.Index(i).Interface())
+		if err != nil {
+			return nil, err
+		}
+		settings[i] = setting
+	}
+	return settings, nil
+}
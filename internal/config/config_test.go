@@ -0,0 +1,103 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// roundTrip saves a configuration where LoadAppConfig looks for it and loads
+// it back
+func roundTrip(t *testing.T, cfg *AppConfig) *AppConfig {
+	t.Helper()
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	dir := filepath.Join(home, ".cfk")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveAppConfig(cfg, filepath.Join(dir, "config.yaml")); err != nil {
+		t.Fatalf("SaveAppConfig() error = %v", err)
+	}
+	loaded, err := LoadAppConfig()
+	if err != nil {
+		t.Fatalf("LoadAppConfig() error = %v", err)
+	}
+	return loaded
+}
+
+func TestSaveAppConfigRoundTrip(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Clusters = []KafkaClusterConfig{{
+		Name:      "prod",
+		Bootstrap: []string{"kafka-1:9092", "kafka-2:9092"},
+		Username:  "admin",
+		Password:  "secret",
+		SASL:      true,
+		SASLType:  "SCRAM-SHA-512",
+
+		SSL:                   true,
+		SSLCAFile:             "/etc/cfk/ca.pem",
+		SSLCertFile:           "/etc/cfk/client.pem",
+		SSLKeyFile:            "/etc/cfk/client.key",
+		SSLServerName:         "kafka.example.com",
+		SSLInsecureSkipVerify: true,
+	}, {
+		Name:      "cloud",
+		Bootstrap: []string{"broker.cloud.example.com:9093"},
+		SASL:      true,
+		SASLType:  "OAUTHBEARER",
+
+		OAuthProvider:     "oidc",
+		OAuthToken:        "static-token",
+		OAuthTokenURL:     "https://auth.example.com/oauth2/token",
+		OAuthClientID:     "cfk",
+		OAuthClientSecret: "s3cret",
+		OAuthScopes:       "kafka.read kafka.write",
+		OAuthTokenCommand: "print-token --audience kafka",
+
+		SchemaRegistryURL:                "https://registry.example.com",
+		SchemaRegistryUsername:           "registry",
+		SchemaRegistryPassword:           "registry-secret",
+		SchemaRegistryCAFile:             "/etc/cfk/registry-ca.pem",
+		SchemaRegistryCertFile:           "/etc/cfk/registry.pem",
+		SchemaRegistryKeyFile:            "/etc/cfk/registry.key",
+		SchemaRegistryInsecureSkipVerify: true,
+	}}
+	cfg.UI.TailBufferSize = 250
+	cfg.Serdes = []SerdeRule{{Topic: "orders-*", Value: "json"}}
+
+	loaded := roundTrip(t, cfg)
+	if !reflect.DeepEqual(loaded, cfg) {
+		t.Errorf("LoadAppConfig() after SaveAppConfig() = %+v, want %+v", loaded, cfg)
+	}
+}
+
+func TestLoadAppConfigWritesDefaults(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	// The first run writes the defaults, which the next run reads back
+	if _, err := LoadAppConfig(); err != nil {
+		t.Fatalf("LoadAppConfig() error = %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(home, ".cfk", "config.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "replication_factor: 3") {
+		t.Errorf("default config does not use the mapstructure names:\n%s", data)
+	}
+	loaded, err := LoadAppConfig()
+	if err != nil {
+		t.Fatalf("LoadAppConfig() error = %v", err)
+	}
+	want := DefaultConfig()
+	want.Serdes = []SerdeRule{} // written as an empty list
+	if !reflect.DeepEqual(loaded, want) {
+		t.Errorf("LoadAppConfig() of the defaults = %+v, want %+v", loaded, want)
+	}
+}
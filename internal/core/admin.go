@@ -0,0 +1,53 @@
+package core
+
+import (
+	"context"
+	"time"
+
+	"github.com/cfk-dev/cfk/internal/config"
+	"github.com/cfk-dev/cfk/internal/kafka"
+)
+
+// KafkaAdmin is the set of Kafka operations the application uses.
+// It is implemented by kafka.Client and by the in-memory fake.Cluster.
+type KafkaAdmin interface {
+	Close() error
+
+	// Topics
+	ListTopics(ctx context.Context) ([]string, error)
+	GetTopicInfo(ctx context.Context, topicName string) (*kafka.TopicInfo, error)
+	DescribeTopic(ctx context.Context, topicName string) (*kafka.TopicDetails, error)
+	CreateTopicFromSpec(ctx context.Context, spec kafka.TopicSpec) error
+	DeleteTopic(ctx context.Context, topicName string) error
+	UpdateTopicPartitions(ctx context.Context, topicName string, numPartitions int) error
+
+	// Configs
+	AlterTopicConfig(ctx context.Context, topicName string, changes []kafka.ConfigChange) error
+
+	// Consumer groups
+	ListGroups(ctx context.Context) ([]kafka.GroupInfo, error)
+	DescribeGroup(ctx context.Context, groupID string) (*kafka.GroupInfo, error)
+	CommitGroupOffsets(ctx context.Context, groupID string, offsets []kafka.GroupOffset) error
+	DeleteGroups(ctx context.Context, groupIDs []string) (map[string]error, error)
+	DeleteGroupOffsets(ctx context.Context, groupID, topicName string, partitions []int) error
+
+	// Messages
+	ListOffsets(ctx context.Context, topicName string) ([]kafka.PartitionOffsets, error)
+	OffsetsForTime(ctx context.Context, topicName string, t time.Time) (map[int]int64, error)
+	ReadMessages(ctx context.Context, topicName string, partition int, offset int64, limit int) ([]kafka.Message, error)
+	ProduceMessage(ctx context.Context, topicName string, msg kafka.Message) (*kafka.Message, error)
+	ProduceMessages(ctx context.Context, topicName string, messages []kafka.Message, opts kafka.ProduceOptions) ([]kafka.ProduceResult, error)
+	DeleteRecords(ctx context.Context, topicName string, offsets map[int]int64) (map[int]int64, error)
+}
+
+// Connector opens a KafkaAdmin for a cluster configuration
+type Connector func(ctx context.Context, clusterConfig config.KafkaClusterConfig) (KafkaAdmin, error)
+
+// ConnectKafka is the default Connector, connecting a kafka.Client to a real cluster
+func ConnectKafka(ctx context.Context, clusterConfig config.KafkaClusterConfig) (KafkaAdmin, error) {
+	client := kafka.NewClient(clusterConfig)
+	if err := client.Connect(ctx); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
@@ -4,16 +4,29 @@ package core
 import (
 	"context"
 	"fmt"
+	"sync"
 
 	"github.com/cfk-dev/cfk/internal/config"
 	"github.com/cfk-dev/cfk/internal/kafka"
+	"github.com/cfk-dev/cfk/internal/schemaregistry"
 )
 
 // App represents the main application
 type App struct {
 	Config      *config.AppConfig
-	KafkaClient *kafka.Client
+	KafkaClient KafkaAdmin
 	CurrentView string
+	// SchemaRegistry is the registry of the connected cluster, nil if it has none
+	SchemaRegistry *schemaregistry.Client
+
+	// Connect opens the connection to a cluster, ConnectKafka by default
+	Connect Connector
+	// Ephemeral keeps cluster changes in memory instead of saving them (demo mode)
+	Ephemeral bool
+
+	cluster   string // name of the connected cluster
+	importsMu sync.Mutex
+	imports   map[string]importCheckpoint // of imports with dedupe, when ephemeral
 }
 
 // NewApp creates a new application instance
@@ -21,11 +34,12 @@ func NewApp(cfg *config.AppConfig) *App {
 	return &App{
 		Config:      cfg,
 		CurrentView: "clusters",
+		Connect:     ConnectKafka,
 	}
 }
 
 // ConnectToCluster connects to a Kafka cluster
-func (a *App) ConnectToCluster(clusterName string) error {
+func (a *App) ConnectToCluster(ctx context.Context, clusterName string) error {
 	// Find the cluster config
 	var clusterConfig config.KafkaClusterConfig
 	found := false
@@ -42,11 +56,27 @@ func (a *App) ConnectToCluster(clusterName string) error {
 		return fmt.Errorf("cluster %s not found in configuration", clusterName)
 	}
 
+	// Release the pooled connections of the previous cluster
+	if a.KafkaClient != nil {
+		a.KafkaClient.Close()
+	}
+
+	a.KafkaClient = nil
+	a.SchemaRegistry = nil
+
+	registry, err := schemaregistry.NewClient(clusterConfig)
+	if err != nil {
+		return fmt.Errorf("failed to connect to cluster %s: %w", clusterName, err)
+	}
+
 	// Create and connect Kafka client
-	a.KafkaClient = kafka.NewClient(clusterConfig)
-	if err := a.KafkaClient.Connect(); err != nil {
+	client, err := a.Connect(ctx, clusterConfig)
+	if err != nil {
 		return fmt.Errorf("failed to connect to cluster %s: %w", clusterName, err)
 	}
+	a.KafkaClient = client
+	a.SchemaRegistry = registry
+	a.cluster = clusterName
 
 	return nil
 }
@@ -69,6 +99,15 @@ func (a *App) GetTopicInfo(ctx context.Context, topicName string) (*kafka.TopicI
 	return a.KafkaClient.GetTopicInfo(ctx, topicName)
 }
 
+// DescribeTopic gets the partition replicas, offsets and configs of a topic
+func (a *App) DescribeTopic(ctx context.Context, topicName string) (*kafka.TopicDetails, error) {
+	if a.KafkaClient == nil {
+		return nil, fmt.Errorf("not connected to any Kafka cluster")
+	}
+
+	return a.KafkaClient.DescribeTopic(ctx, topicName)
+}
+
 // AddCluster adds a new Kafka cluster configuration
 func (a *App) AddCluster(cluster config.KafkaClusterConfig) error {
 	// Check if cluster with same name already exists
@@ -82,17 +121,7 @@ func (a *App) AddCluster(cluster config.KafkaClusterConfig) error {
 	a.Config.Clusters = append(a.Config.Clusters, cluster)
 
 	// Save the updated configuration
-	homeDir, err := config.GetConfigDir()
-	if err != nil {
-		return fmt.Errorf("failed to get config directory: %w", err)
-	}
-
-	configPath := homeDir + "/config.yaml"
-	if err := config.SaveAppConfig(a.Config, configPath); err != nil {
-		return fmt.Errorf("failed to save configuration: %w", err)
-	}
-
-	return nil
+	return a.saveConfig()
 }
 
 // UpdateCluster updates an existing Kafka cluster configuration
@@ -113,17 +142,7 @@ func (a *App) UpdateCluster(cluster config.KafkaClusterConfig) error {
 	}
 
 	// Save the updated configuration
-	homeDir, err := config.GetConfigDir()
-	if err != nil {
-		return fmt.Errorf("failed to get config directory: %w", err)
-	}
-
-	configPath := homeDir + "/config.yaml"
-	if err := config.SaveAppConfig(a.Config, configPath); err != nil {
-		return fmt.Errorf("failed to save configuration: %w", err)
-	}
-
-	return nil
+	return a.saveConfig()
 }
 
 // RemoveCluster removes a Kafka cluster configuration
@@ -131,7 +150,7 @@ func (a *App) RemoveCluster(clusterName string) error {
 	// Find the cluster to remove
 	found := false
 	var updatedClusters []config.KafkaClusterConfig
-	
+
 	for _, c := range a.Config.Clusters {
 		if c.Name == clusterName {
 			found = true
@@ -148,6 +167,15 @@ func (a *App) RemoveCluster(clusterName string) error {
 	a.Config.Clusters = updatedClusters
 
 	// Save the updated configuration
+	return a.saveConfig()
+}
+
+// saveConfig writes the configuration to the config directory
+func (a *App) saveConfig() error {
+	if a.Ephemeral {
+		return nil
+	}
+
 	homeDir, err := config.GetConfigDir()
 	if err != nil {
 		return fmt.Errorf("failed to get config directory: %w", err)
@@ -167,7 +195,35 @@ func (a *App) CreateTopic(ctx context.Context, topicName string, numPartitions i
 		return fmt.Errorf("not connected to any Kafka cluster")
 	}
 
-	return a.KafkaClient.CreateTopic(ctx, topicName, numPartitions, replicationFactor)
+	return a.CreateTopicFromSpec(ctx, kafka.TopicSpec{
+		Name:              topicName,
+		Partitions:        numPartitions,
+		ReplicationFactor: replicationFactor,
+	})
+}
+
+// CreateTopicFromSpec checks a topic spec and creates the topic. Names that
+// collide with an existing topic by differing only in '.' and '_' are refused.
+func (a *App) CreateTopicFromSpec(ctx context.Context, spec kafka.TopicSpec) error {
+	if a.KafkaClient == nil {
+		return fmt.Errorf("not connected to any Kafka cluster")
+	}
+
+	if err := spec.Validate(); err != nil {
+		return fmt.Errorf("invalid topic %s: %w", spec.Name, err)
+	}
+
+	topics, err := a.KafkaClient.ListTopics(ctx)
+	if err != nil {
+		return err
+	}
+	for _, name := range topics {
+		if kafka.TopicNamesCollide(name, spec.Name) {
+			return fmt.Errorf("topic %s collides with existing topic %s, names may not differ only in '.' and '_'", spec.Name, name)
+		}
+	}
+
+	return a.KafkaClient.CreateTopicFromSpec(ctx, spec)
 }
 
 // DeleteTopic deletes a topic from the connected Kafka cluster
@@ -187,3 +243,116 @@ func (a *App) UpdateTopicPartitions(ctx context.Context, topicName string, numPa
 
 	return a.KafkaClient.UpdateTopicPartitions(ctx, topicName, numPartitions)
 }
+
+// UpdateTopicConfig sets topic-level configs, leaving other configs untouched
+func (a *App) UpdateTopicConfig(ctx context.Context, topicName string, configs map[string]string) error {
+	if a.KafkaClient == nil {
+		return fmt.Errorf("not connected to any Kafka cluster")
+	}
+
+	return a.AlterTopicConfig(ctx, topicName, kafka.SetConfigs(configs))
+}
+
+// AlterTopicConfig sets and removes topic-level configs. New values are
+// type-checked against the known config definitions before anything is sent.
+func (a *App) AlterTopicConfig(ctx context.Context, topicName string, changes []kafka.ConfigChange) error {
+	if a.KafkaClient == nil {
+		return fmt.Errorf("not connected to any Kafka cluster")
+	}
+
+	for _, change := range changes {
+		if change.Delete {
+			continue
+		}
+		if err := kafka.ValidateTopicConfig(change.Name, change.Value); err != nil {
+			return fmt.Errorf("invalid config for topic %s: %w", topicName, err)
+		}
+	}
+
+	return a.KafkaClient.AlterTopicConfig(ctx, topicName, changes)
+}
+
+// ListGroups lists the consumer groups of the connected Kafka cluster
+func (a *App) ListGroups(ctx context.Context) ([]kafka.GroupInfo, error) {
+	if a.KafkaClient == nil {
+		return nil, fmt.Errorf("not connected to any Kafka cluster")
+	}
+
+	return a.KafkaClient.ListGroups(ctx)
+}
+
+// maxDescribeWorkers bounds the groups described at the same time
+const maxDescribeWorkers = 8
+
+// ListGroupsWithOffsets lists the consumer groups with their committed
+// offsets and lag, describing several groups at a time. Groups that fail to
+// be described are listed with the error instead of their offsets.
+func (a *App) ListGroupsWithOffsets(ctx context.Context) ([]kafka.GroupInfo, error) {
+	groups, err := a.ListGroups(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < min(maxDescribeWorkers, len(groups)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				group, err := a.KafkaClient.DescribeGroup(ctx, groups[i].GroupID)
+				if err != nil {
+					groups[i].OffsetsErr = err
+					continue
+				}
+				groups[i].Offsets = group.Offsets
+			}
+		}()
+	}
+	for i := range groups {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+// DescribeGroup gets the members and committed offsets of a consumer group
+func (a *App) DescribeGroup(ctx context.Context, groupID string) (*kafka.GroupInfo, error) {
+	if a.KafkaClient == nil {
+		return nil, fmt.Errorf("not connected to any Kafka cluster")
+	}
+
+	return a.KafkaClient.DescribeGroup(ctx, groupID)
+}
+
+// ListOffsets gets the earliest and latest offsets of every partition of a topic
+func (a *App) ListOffsets(ctx context.Context, topicName string) ([]kafka.PartitionOffsets, error) {
+	if a.KafkaClient == nil {
+		return nil, fmt.Errorf("not connected to any Kafka cluster")
+	}
+
+	return a.KafkaClient.ListOffsets(ctx, topicName)
+}
+
+// ReadMessages reads up to limit messages of a partition starting at offset
+func (a *App) ReadMessages(ctx context.Context, topicName string, partition int, offset int64, limit int) ([]kafka.Message, error) {
+	if a.KafkaClient == nil {
+		return nil, fmt.Errorf("not connected to any Kafka cluster")
+	}
+
+	return a.KafkaClient.ReadMessages(ctx, topicName, partition, offset, limit)
+}
+
+// ProduceMessage writes a message to a topic of the connected Kafka cluster
+func (a *App) ProduceMessage(ctx context.Context, topicName string, msg kafka.Message) (*kafka.Message, error) {
+	if a.KafkaClient == nil {
+		return nil, fmt.Errorf("not connected to any Kafka cluster")
+	}
+
+	return a.KafkaClient.ProduceMessage(ctx, topicName, msg)
+}
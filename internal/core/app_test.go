@@ -0,0 +1,1266 @@
+package core
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cfk-dev/cfk/internal/config"
+	"github.com/cfk-dev/cfk/internal/kafka"
+	"github.com/cfk-dev/cfk/internal/kafka/fake"
+	"github.com/cfk-dev/cfk/internal/schemaregistry"
+	"github.com/cfk-dev/cfk/internal/schemaregistry/registrytest"
+	"github.com/klauspost/compress/zstd"
+)
+
+var (
+	_ KafkaAdmin = (*kafka.Client)(nil)
+	_ KafkaAdmin = (*fake.Cluster)(nil)
+)
+
+// newTestApp returns an app connected to an empty fake cluster
+func newTestApp(t *testing.T) (*App, *fake.Cluster) {
+	t.Helper()
+
+	cluster := fake.NewCluster()
+	app := NewApp(&config.AppConfig{
+		Clusters: []config.KafkaClusterConfig{{Name: "local", Bootstrap: []string{"localhost:9092"}}},
+	})
+	app.Ephemeral = true
+	app.Connect = func(ctx context.Context, clusterConfig config.KafkaClusterConfig) (KafkaAdmin, error) {
+		return cluster, nil
+	}
+
+	if err := app.ConnectToCluster(context.Background(), "local"); err != nil {
+		t.Fatalf("ConnectToCluster() error = %v", err)
+	}
+	return app, cluster
+}
+
+func TestAppRequiresConnection(t *testing.T) {
+	app := NewApp(config.DefaultConfig())
+	if _, err := app.ListTopics(context.Background()); err == nil {
+		t.Errorf("ListTopics() without a connection did not fail")
+	}
+	if err := app.ConnectToCluster(context.Background(), "missing"); err == nil {
+		t.Errorf("ConnectToCluster() to an unknown cluster did not fail")
+	}
+}
+
+func TestAppTopics(t *testing.T) {
+	app, _ := newTestApp(t)
+	ctx := context.Background()
+
+	if err := app.CreateTopic(ctx, "orders", 3, 1); err != nil {
+		t.Fatalf("CreateTopic() error = %v", err)
+	}
+	if err := app.CreateTopic(ctx, "orders", 3, 1); err == nil {
+		t.Errorf("CreateTopic() of an existing topic did not fail")
+	}
+	if err := app.UpdateTopicPartitions(ctx, "orders", 5); err != nil {
+		t.Fatalf("UpdateTopicPartitions() error = %v", err)
+	}
+	if err := app.UpdateTopicPartitions(ctx, "orders", 2); err == nil {
+		t.Errorf("UpdateTopicPartitions() shrinking the topic did not fail")
+	}
+	if err := app.UpdateTopicConfig(ctx, "orders", map[string]string{"retention.ms": "1000"}); err != nil {
+		t.Fatalf("UpdateTopicConfig() error = %v", err)
+	}
+
+	info, err := app.GetTopicInfo(ctx, "orders")
+	if err != nil {
+		t.Fatalf("GetTopicInfo() error = %v", err)
+	}
+	if info.Partitions != 5 || info.Config["retention.ms"] != "1000" {
+		t.Errorf("GetTopicInfo() = %+v, want 5 partitions and retention.ms=1000", info)
+	}
+
+	if err := app.DeleteTopic(ctx, "orders"); err != nil {
+		t.Fatalf("DeleteTopic() error = %v", err)
+	}
+	if topics, _ := app.ListTopics(ctx); len(topics) != 0 {
+		t.Errorf("ListTopics() after delete = %v, want none", topics)
+	}
+}
+
+func TestAppCreateTopicFromSpecRefusesCollidingNames(t *testing.T) {
+	app, _ := newTestApp(t)
+	ctx := context.Background()
+
+	if err := app.CreateTopic(ctx, "orders.eu", 1, 1); err != nil {
+		t.Fatalf("CreateTopic() error = %v", err)
+	}
+	if err := app.CreateTopic(ctx, "orders_eu", 1, 1); err == nil {
+		t.Errorf("CreateTopic() of a name colliding with orders.eu did not fail")
+	}
+	spec := kafka.TopicSpec{Name: "orders-eu", Partitions: 1, ReplicationFactor: 1, Configs: map[string]string{"cleanup.policy": "compact"}}
+	if err := app.CreateTopicFromSpec(ctx, spec); err != nil {
+		t.Fatalf("CreateTopicFromSpec() error = %v", err)
+	}
+	if info, _ := app.GetTopicInfo(ctx, "orders-eu"); info.Config["cleanup.policy"] != "compact" {
+		t.Errorf("GetTopicInfo() configs = %v, want cleanup.policy=compact", info.Config)
+	}
+}
+
+func TestAppAlterTopicConfigChecksValues(t *testing.T) {
+	app, _ := newTestApp(t)
+	ctx := context.Background()
+
+	if err := app.CreateTopic(ctx, "orders", 1, 1); err != nil {
+		t.Fatalf("CreateTopic() error = %v", err)
+	}
+	changes := []kafka.ConfigChange{{Name: "retention.ms", Value: "1000"}, {Name: "cleanup.policy", Value: "archive"}}
+	if err := app.AlterTopicConfig(ctx, "orders", changes); err == nil {
+		t.Fatalf("AlterTopicConfig() with an invalid cleanup.policy did not fail")
+	}
+
+	// Nothing is applied when a change is invalid
+	info, err := app.GetTopicInfo(ctx, "orders")
+	if err != nil {
+		t.Fatalf("GetTopicInfo() error = %v", err)
+	}
+	if _, ok := info.Config["retention.ms"]; ok {
+		t.Errorf("AlterTopicConfig() applied part of an invalid change set: %v", info.Config)
+	}
+}
+
+func TestAppMessagesAndGroupLag(t *testing.T) {
+	app, cluster := newTestApp(t)
+	ctx := context.Background()
+
+	if err := app.CreateTopic(ctx, "events", 2, 1); err != nil {
+		t.Fatalf("CreateTopic() error = %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		msg, err := app.ProduceMessage(ctx, "events", kafka.Message{Partition: 1, Value: []byte{byte(i)}})
+		if err != nil {
+			t.Fatalf("ProduceMessage() error = %v", err)
+		}
+		if msg.Offset != int64(i) {
+			t.Errorf("ProduceMessage() offset = %d, want %d", msg.Offset, i)
+		}
+	}
+
+	messages, err := app.ReadMessages(ctx, "events", 1, 2, 10)
+	if err != nil {
+		t.Fatalf("ReadMessages() error = %v", err)
+	}
+	if len(messages) != 3 || messages[0].Offset != 2 {
+		t.Errorf("ReadMessages() = %+v, want 3 messages starting at offset 2", messages)
+	}
+
+	offsets, err := app.ListOffsets(ctx, "events")
+	if err != nil {
+		t.Fatalf("ListOffsets() error = %v", err)
+	}
+	if offsets[0].Latest != 0 || offsets[1].Latest != 5 {
+		t.Errorf("ListOffsets() = %+v, want latest 0 and 5", offsets)
+	}
+
+	cluster.CommitOffset("billing", "events", 1, 3)
+	group, err := app.DescribeGroup(ctx, "billing")
+	if err != nil {
+		t.Fatalf("DescribeGroup() error = %v", err)
+	}
+	if group.State != fake.GroupStateEmpty || len(group.Offsets) != 1 || group.Offsets[0].Lag != 2 {
+		t.Errorf("DescribeGroup() = %+v, want an Empty group with lag 2", group)
+	}
+
+	groups, err := app.ListGroupsWithOffsets(ctx)
+	if err != nil {
+		t.Fatalf("ListGroupsWithOffsets() error = %v", err)
+	}
+	if len(groups) != 1 || groups[0].TotalLag() != 2 {
+		t.Errorf("ListGroupsWithOffsets() = %+v, want billing with lag 2", groups)
+	}
+
+	// A group that fails to be described is listed with an unknown lag
+	cluster.CommitOffset("shipping", "events", 1, 1)
+	app.KafkaClient = failingDescribeGroup{KafkaAdmin: app.KafkaClient, groupID: "shipping"}
+	groups, err = app.ListGroupsWithOffsets(ctx)
+	if err != nil {
+		t.Fatalf("ListGroupsWithOffsets() with a failing group error = %v", err)
+	}
+	for _, g := range groups {
+		if g.GroupID == "billing" && (g.OffsetsErr != nil || g.TotalLag() != 2) ||
+			g.GroupID == "shipping" && (g.OffsetsErr == nil || len(g.Offsets) != 0) {
+			t.Errorf("ListGroupsWithOffsets() with a failing group = %+v", groups)
+		}
+	}
+	if len(groups) != 2 {
+		t.Errorf("ListGroupsWithOffsets() with a failing group = %+v, want billing and shipping", groups)
+	}
+}
+
+// failingDescribeGroup fails to describe one of the groups
+type failingDescribeGroup struct {
+	KafkaAdmin
+	groupID string
+}
+
+func (f failingDescribeGroup) DescribeGroup(ctx context.Context, groupID string) (*kafka.GroupInfo, error) {
+	if groupID == f.groupID {
+		return nil, fmt.Errorf("coordinator of %s not available", groupID)
+	}
+	return f.KafkaAdmin.DescribeGroup(ctx, groupID)
+}
+
+// sparseOffsetsForTime leaves the partitions without a record at or after the
+// time out of OffsetsForTime instead of reporting them with -1
+type sparseOffsetsForTime struct {
+	KafkaAdmin
+}
+
+func (s sparseOffsetsForTime) OffsetsForTime(ctx context.Context, topicName string, t time.Time) (map[int]int64, error) {
+	offsets, err := s.KafkaAdmin.OffsetsForTime(ctx, topicName, t)
+	for p, offset := range offsets {
+		if offset < 0 {
+			delete(offsets, p)
+		}
+	}
+	return offsets, err
+}
+
+func TestAppOffsetReset(t *testing.T) {
+	app, cluster := newTestApp(t)
+	ctx := context.Background()
+
+	if err := app.CreateTopic(ctx, "events", 2, 1); err != nil {
+		t.Fatalf("CreateTopic() error = %v", err)
+	}
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for p := 0; p < 2; p++ {
+		for i := 0; i < 10; i++ {
+			msg := kafka.Message{Partition: p, Timestamp: start.Add(time.Duration(i) * time.Minute)}
+			if _, err := app.ProduceMessage(ctx, "events", msg); err != nil {
+				t.Fatalf("ProduceMessage() error = %v", err)
+			}
+		}
+	}
+	cluster.CommitOffset("billing", "events", 0, 4)
+	cluster.CommitOffset("billing", "events", 1, 8)
+
+	tests := []struct {
+		name  string
+		reset OffsetReset
+		want  []int64 // new offsets of partitions 0 and 1
+	}{
+		{"earliest", OffsetReset{Strategy: ResetToEarliest}, []int64{0, 0}},
+		{"latest", OffsetReset{Strategy: ResetToLatest}, []int64{10, 10}},
+		{"offset beyond the end", OffsetReset{Strategy: ResetToOffset, Offset: 50}, []int64{10, 10}},
+		{"shift back", OffsetReset{Strategy: ResetShiftBy, Shift: -6}, []int64{0, 2}},
+		{"datetime", OffsetReset{Strategy: ResetToDatetime, Datetime: start.Add(90 * time.Second)}, []int64{2, 2}},
+		{"datetime after the last record", OffsetReset{Strategy: ResetToDatetime, Datetime: start.Add(time.Hour)}, []int64{10, 10}},
+		{"one partition", OffsetReset{Strategy: ResetToEarliest, Topic: "events", Partitions: []int{1}}, []int64{0}},
+		{"file", OffsetReset{Strategy: ResetFromFile, Offsets: []kafka.GroupOffset{{Topic: "events", Partition: 0, CommittedOffset: 7}}}, []int64{7}},
+	}
+	for _, tt := range tests {
+		changes, err := app.PlanOffsetReset(ctx, "billing", tt.reset)
+		if err != nil {
+			t.Errorf("%s: PlanOffsetReset() error = %v", tt.name, err)
+			continue
+		}
+		var got []int64
+		for _, c := range changes {
+			got = append(got, c.New)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: PlanOffsetReset() new offsets = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// Partitions left out of the offsets for a time move to the end too
+	app.KafkaClient = sparseOffsetsForTime{app.KafkaClient}
+	changes, err := app.PlanOffsetReset(ctx, "billing", OffsetReset{Strategy: ResetToDatetime, Datetime: start.Add(time.Hour)})
+	if err != nil || len(changes) != 2 || changes[0].New != 10 || changes[1].New != 10 {
+		t.Errorf("PlanOffsetReset() without offsets for the time = %+v, %v, want the latest offsets", changes, err)
+	}
+
+	if _, err := app.PlanOffsetReset(ctx, "billing", OffsetReset{Strategy: ResetToEarliest, Topic: "events", Partitions: []int{5}}); err == nil {
+		t.Error("PlanOffsetReset() of a missing partition succeeded")
+	}
+
+	changes, err = app.PlanOffsetReset(ctx, "billing", OffsetReset{Strategy: ResetToEarliest})
+	if err != nil {
+		t.Fatalf("PlanOffsetReset() error = %v", err)
+	}
+	cluster.JoinGroup("billing", kafka.GroupMember{MemberID: "consumer-1"})
+	if err := app.ApplyOffsetReset(ctx, "billing", changes); err == nil {
+		t.Error("ApplyOffsetReset() of a Stable group succeeded")
+	}
+	cluster.LeaveGroup("billing")
+	if err := app.ApplyOffsetReset(ctx, "billing", changes); err != nil {
+		t.Fatalf("ApplyOffsetReset() error = %v", err)
+	}
+	group, _ := app.DescribeGroup(ctx, "billing")
+	if group.TotalLag() != 20 {
+		t.Errorf("lag after resetting to earliest = %d, want 20", group.TotalLag())
+	}
+}
+
+func TestAppDeleteGroups(t *testing.T) {
+	app, cluster := newTestApp(t)
+	ctx := context.Background()
+
+	if err := app.CreateTopic(ctx, "events", 1, 1); err != nil {
+		t.Fatalf("CreateTopic() error = %v", err)
+	}
+	if err := app.CreateTopic(ctx, "audit", 1, 1); err != nil {
+		t.Fatalf("CreateTopic() error = %v", err)
+	}
+	for _, id := range []string{"test-1", "test-2", "test-live", "billing"} {
+		cluster.CommitOffset(id, "events", 0, 0)
+	}
+	cluster.CommitOffset("billing", "audit", 0, 0)
+	cluster.JoinGroup("test-live", kafka.GroupMember{MemberID: "consumer-1", Assignments: map[string][]int{"events": {0}}})
+
+	groups, err := app.ListGroups(ctx)
+	if err != nil {
+		t.Fatalf("ListGroups() error = %v", err)
+	}
+	matched, err := MatchEmptyGroups(groups, "test-*")
+	if err != nil {
+		t.Fatalf("MatchEmptyGroups() error = %v", err)
+	}
+	if want := []string{"test-1", "test-2"}; !reflect.DeepEqual(matched, want) {
+		t.Errorf("MatchEmptyGroups(test-*) = %v, want %v", matched, want)
+	}
+	if matched, _ := MatchEmptyGroups(groups, "test-?"); len(matched) != 2 {
+		t.Errorf("MatchEmptyGroups(test-?) = %v, want 2 groups", matched)
+	}
+
+	results, err := app.DeleteGroups(ctx, append(matched, "test-live"))
+	if err != nil {
+		t.Fatalf("DeleteGroups() error = %v", err)
+	}
+	if results["test-1"] != nil || results["test-2"] != nil || results["test-live"] == nil {
+		t.Errorf("DeleteGroups() = %v, want the Empty groups deleted and test-live refused", results)
+	}
+	if groups, _ := app.ListGroups(ctx); len(groups) != 2 {
+		t.Errorf("ListGroups() after DeleteGroups() = %+v, want billing and test-live", groups)
+	}
+
+	if err := app.DeleteGroupOffsets(ctx, "billing", "events"); err != nil {
+		t.Fatalf("DeleteGroupOffsets() error = %v", err)
+	}
+	group, _ := app.DescribeGroup(ctx, "billing")
+	if len(group.Offsets) != 1 || group.Offsets[0].Topic != "audit" {
+		t.Errorf("DescribeGroup() after DeleteGroupOffsets() = %+v, want only the audit offsets", group.Offsets)
+	}
+	if err := app.DeleteGroupOffsets(ctx, "test-live", "events"); err == nil {
+		t.Error("DeleteGroupOffsets() of a topic consumed by a member succeeded")
+	}
+}
+
+func TestAppBrowseMessages(t *testing.T) {
+	app, _ := newTestApp(t)
+	ctx := context.Background()
+
+	// Partition 0 holds even seconds and partition 1 odd ones
+	if err := app.CreateTopic(ctx, "events", 2, 1); err != nil {
+		t.Fatalf("CreateTopic() error = %v", err)
+	}
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 10; i++ {
+		msg := kafka.Message{Partition: i % 2, Timestamp: start.Add(time.Duration(i) * time.Second), Value: []byte(fmt.Sprint(i))}
+		if _, err := app.ProduceMessage(ctx, "events", msg); err != nil {
+			t.Fatalf("ProduceMessage() error = %v", err)
+		}
+	}
+	values := func(page *MessagePage) string {
+		var v []string
+		for _, m := range page.Messages {
+			v = append(v, string(m.Value))
+		}
+		return strings.Join(v, ",")
+	}
+
+	page, err := app.BrowseMessages(ctx, BrowseRequest{Topic: "events", Partition: AllPartitions, From: FromEarliest}, 4)
+	if err != nil {
+		t.Fatalf("BrowseMessages() error = %v", err)
+	}
+	if got := values(page); got != "0,1,2,3" || page.HasPrev() || !page.HasNext() {
+		t.Errorf("first merged page = %s, want 0,1,2,3 with only a next page", got)
+	}
+	next, err := app.NextMessages(ctx, page, 4)
+	if err != nil {
+		t.Fatalf("NextMessages() error = %v", err)
+	}
+	if got := values(next); got != "4,5,6,7" {
+		t.Errorf("next merged page = %s, want 4,5,6,7", got)
+	}
+	prev, err := app.PrevMessages(ctx, next, 4)
+	if err != nil {
+		t.Fatalf("PrevMessages() error = %v", err)
+	}
+	if got := values(prev); got != "0,1,2,3" {
+		t.Errorf("previous merged page = %s, want 0,1,2,3", got)
+	}
+
+	tests := []struct {
+		req  BrowseRequest
+		want string
+	}{
+		{BrowseRequest{Partition: AllPartitions, From: FromLatest, Offset: 3}, "7,8,9"},
+		{BrowseRequest{Partition: 1, From: FromLatest, Offset: 2}, "7,9"},
+		{BrowseRequest{Partition: 0, From: FromOffset, Offset: 3}, "6,8"},
+		{BrowseRequest{Partition: AllPartitions, From: FromTimestamp, Timestamp: start.Add(7500 * time.Millisecond)}, "8,9"},
+	}
+	for _, tt := range tests {
+		tt.req.Topic = "events"
+		page, err := app.BrowseMessages(ctx, tt.req, 4)
+		if err != nil {
+			t.Errorf("BrowseMessages(%+v) error = %v", tt.req, err)
+			continue
+		}
+		if got := values(page); got != tt.want {
+			t.Errorf("BrowseMessages(%+v) = %s, want %s", tt.req, got, tt.want)
+		}
+	}
+
+	if _, err := app.BrowseMessages(ctx, BrowseRequest{Topic: "events", Partition: 7, From: FromEarliest}, 4); err == nil {
+		t.Error("BrowseMessages() of a missing partition succeeded")
+	}
+}
+
+func TestAppTailMessages(t *testing.T) {
+	app, _ := newTestApp(t)
+	ctx := context.Background()
+
+	if err := app.CreateTopic(ctx, "events", 2, 1); err != nil {
+		t.Fatalf("CreateTopic() error = %v", err)
+	}
+	produce := func(partition int, value string) {
+		if _, err := app.ProduceMessage(ctx, "events", kafka.Message{Partition: partition, Value: []byte(value)}); err != nil {
+			t.Fatalf("ProduceMessage() error = %v", err)
+		}
+	}
+	produce(0, "before")
+
+	tail, err := app.TailMessages(ctx, "events")
+	if err != nil {
+		t.Fatalf("TailMessages() error = %v", err)
+	}
+	if messages, err := tail.Poll(ctx, 10); err != nil || len(messages) != 0 {
+		t.Errorf("Poll() before new records = %+v, %v", messages, err)
+	}
+
+	produce(0, "a")
+	produce(1, "b")
+	produce(0, "c")
+	messages, err := tail.Poll(ctx, 2)
+	if err != nil {
+		t.Fatalf("Poll() error = %v", err)
+	}
+	if len(messages) != 3 || string(messages[0].Value) != "a" || string(messages[2].Value) != "c" {
+		t.Errorf("Poll() = %+v, want a, b and c", messages)
+	}
+
+	// Partitions added while tailing are followed from their start
+	if err := app.UpdateTopicPartitions(ctx, "events", 3); err != nil {
+		t.Fatalf("UpdateTopicPartitions() error = %v", err)
+	}
+	produce(2, "d")
+	if messages, err := tail.Poll(ctx, 10); err != nil || len(messages) != 1 || string(messages[0].Value) != "d" {
+		t.Errorf("Poll() after adding a partition = %+v, %v", messages, err)
+	}
+}
+
+func TestMessageRing(t *testing.T) {
+	ring := NewMessageRing(3)
+	for i := 0; i < 5; i++ {
+		ring.Push(kafka.Message{Offset: int64(i)})
+	}
+
+	var offsets []int64
+	for _, m := range ring.Messages() {
+		offsets = append(offsets, m.Offset)
+	}
+	if want := []int64{2, 3, 4}; !reflect.DeepEqual(offsets, want) || ring.Dropped() != 2 || ring.Len() != 3 {
+		t.Errorf("ring = %v with %d dropped, want %v with 2 dropped", offsets, ring.Dropped(), want)
+	}
+}
+
+func TestRateMeter(t *testing.T) {
+	meter := NewRateMeter(2 * time.Second)
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	meter.Add(start, 10)
+	meter.Add(start.Add(time.Second), 30)
+
+	if rate := meter.Rate(start.Add(1500 * time.Millisecond)); rate != 20 {
+		t.Errorf("Rate() = %v, want 20", rate)
+	}
+	if rate := meter.Rate(start.Add(2500 * time.Millisecond)); rate != 15 {
+		t.Errorf("Rate() after the first sample expired = %v, want 15", rate)
+	}
+}
+
+func TestAppSchemaRegistry(t *testing.T) {
+	app, _ := newTestApp(t)
+	ctx := context.Background()
+	if _, err := app.ListSubjects(ctx); err == nil {
+		t.Errorf("ListSubjects() without a registry did not fail")
+	}
+
+	srv := registrytest.NewServer()
+	defer srv.Close()
+	srv.Check = func(subject, level, candidate string) []string {
+		if strings.Contains(candidate, `"int"`) {
+			return []string{"reader field id has type int, writer has string"}
+		}
+		return nil
+	}
+	srv.Register("users-value", "", `{"type":"record","name":"User","fields":[{"name":"id","type":"string"}]}`)
+	srv.Register("users-value", "", `{"type":"record","name":"User","fields":[{"name":"id","type":"string"},{"name":"age","type":"int","default":0}]}`)
+	srv.Register("orders-value", "PROTOBUF", `syntax = "proto3"; message Order { string id = 1; }`)
+
+	app.Config.Clusters[0].SchemaRegistryURL = srv.URL
+	if err := app.ConnectToCluster(ctx, "local"); err != nil {
+		t.Fatalf("ConnectToCluster() error = %v", err)
+	}
+	if subjects, err := app.ListSubjects(ctx); err != nil || !reflect.DeepEqual(subjects, []string{"orders-value", "users-value"}) {
+		t.Fatalf("ListSubjects() = %v, %v", subjects, err)
+	}
+
+	details, err := app.DescribeSubject(ctx, "users-value")
+	if err != nil || len(details.Versions) != 2 || details.Compatibility != "BACKWARD" || !details.Inherited {
+		t.Fatalf("DescribeSubject() = %+v, %v", details, err)
+	}
+	diff := DiffSchemas(details.Versions[0], details.Versions[1])
+	var added []string
+	for _, line := range diff {
+		if line.Op == '+' {
+			added = append(added, strings.TrimSpace(line.Text))
+		} else if line.Op == '-' && strings.TrimSpace(line.Text) != "}" {
+			t.Errorf("unexpected removed line %q", line.Text)
+		}
+	}
+	if !strings.Contains(strings.Join(added, " "), `"name": "age"`) {
+		t.Errorf("added lines = %v, want the age field", added)
+	}
+
+	if err := app.SetSubjectCompatibility(ctx, "users-value", "FULL"); err != nil {
+		t.Fatalf("SetSubjectCompatibility() error = %v", err)
+	}
+	if details, _ := app.DescribeSubject(ctx, "users-value"); details.Compatibility != "FULL" || details.Inherited {
+		t.Errorf("compatibility after setting = %s, inherited %v", details.Compatibility, details.Inherited)
+	}
+
+	result, err := app.TestSchemaCompatibility(ctx, "users-value", "AVRO", `{"type":"record","name":"User","fields":[{"name":"id","type":"int"}]}`)
+	if err != nil || result.Compatible || len(result.Messages) != 1 {
+		t.Errorf("TestSchemaCompatibility(incompatible) = %+v, %v", result, err)
+	}
+	if result, err := app.TestSchemaCompatibility(ctx, "users-value", "AVRO", `{"type":"string"}`); err != nil || !result.Compatible {
+		t.Errorf("TestSchemaCompatibility(compatible) = %+v, %v", result, err)
+	}
+
+	// A soft delete hides the version, a hard delete removes its schema ID
+	if err := app.DeleteSchemaVersion(ctx, "users-value", 1, false); err != nil {
+		t.Fatalf("DeleteSchemaVersion(soft) error = %v", err)
+	}
+	if _, err := app.SchemaRegistry.SchemaByID(ctx, 1); err != nil {
+		t.Errorf("schema of a soft-deleted version: %v", err)
+	}
+	if err := app.DeleteSchemaVersion(ctx, "orders-value", 1, true); err != nil {
+		t.Fatalf("DeleteSchemaVersion(hard) error = %v", err)
+	}
+	if subjects, _ := app.ListSubjects(ctx); !reflect.DeepEqual(subjects, []string{"users-value"}) {
+		t.Errorf("subjects after deleting = %v", subjects)
+	}
+	if details, _ := app.DescribeSubject(ctx, "users-value"); len(details.Versions) != 1 || details.Versions[0].Version != 2 ||
+		!reflect.DeepEqual(details.Deleted, []int{1}) {
+		t.Errorf("versions after deleting = %+v, soft-deleted %v", details.Versions, details.Deleted)
+	}
+	versions, err := app.SchemaRegistry.Versions(ctx, "users-value")
+	if want := []schemaregistry.SubjectVersion{{Version: 1, Deleted: true}, {Version: 2}}; err != nil || !reflect.DeepEqual(versions, want) {
+		t.Errorf("Versions() = %+v, %v, want %+v", versions, err, want)
+	}
+
+	// A soft-deleted version is deleted for good without a second soft delete
+	if err := app.DeleteSchemaVersion(ctx, "users-value", 1, true); err != nil {
+		t.Fatalf("DeleteSchemaVersion(hard) of a soft-deleted version error = %v", err)
+	}
+	if details, _ := app.DescribeSubject(ctx, "users-value"); len(details.Versions) != 1 || len(details.Deleted) != 0 {
+		t.Errorf("versions after deleting for good = %+v, soft-deleted %v", details.Versions, details.Deleted)
+	}
+}
+
+func TestAppProduce(t *testing.T) {
+	app, cluster := newTestApp(t)
+	ctx := context.Background()
+	if err := cluster.CreateTopic(ctx, "orders", 2, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := app.Produce(ctx, ProduceRequest{
+		Topic: "orders", Partition: 1, Key: "o-1", Value: `{"id":"o-1"}`, Serializer: SerializeJSON,
+		Headers: []kafka.Header{{Key: "source", Value: []byte("cli")}},
+	})
+	if err != nil || msg.Partition != 1 || msg.Offset != 0 || string(msg.Key) != "o-1" {
+		t.Fatalf("Produce() = %+v, %v", msg, err)
+	}
+	if _, err := app.Produce(ctx, ProduceRequest{Topic: "orders", Partition: -1, Value: "{oops", Serializer: SerializeJSON}); err == nil {
+		t.Errorf("Produce() of invalid JSON did not fail")
+	}
+	if _, err := app.Produce(ctx, ProduceRequest{Topic: "orders", Partition: -1, Value: "{}", Serializer: SerializeAvro}); err == nil {
+		t.Errorf("Produce() with Avro and no registry did not fail")
+	}
+
+	srv := registrytest.NewServer()
+	defer srv.Close()
+	id := srv.Register("orders-value", "", `{"type":"record","name":"Order","fields":[{"name":"id","type":"string"}]}`)
+	app.Config.Clusters[0].SchemaRegistryURL = srv.URL
+	if err := app.ConnectToCluster(ctx, "local"); err != nil {
+		t.Fatalf("ConnectToCluster() error = %v", err)
+	}
+	msg, err = app.Produce(ctx, ProduceRequest{Topic: "orders", Partition: 0, Value: `{"id":"o-2"}`, Serializer: SerializeAvro})
+	if err != nil || len(msg.Value) < 5 || msg.Value[0] != 0 || int(msg.Value[4]) != id {
+		t.Fatalf("Produce(avro) = %+v, %v", msg, err)
+	}
+	if decoded, err := app.SchemaRegistry.Decode(ctx, msg.Value); err != nil || !strings.Contains(decoded, `"o-2"`) {
+		t.Errorf("Produce(avro) wrote %s, %v", decoded, err)
+	}
+}
+
+func TestAppBulkProduce(t *testing.T) {
+	app, cluster := newTestApp(t)
+	ctx := context.Background()
+	if err := cluster.CreateTopic(ctx, "events", 3, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	produce := func(format BulkFormat, input string, opts BulkOptions) *BulkResult {
+		t.Helper()
+		reader, err := NewBulkReader(strings.NewReader(input), format, "|")
+		if err != nil {
+			t.Fatalf("NewBulkReader(%s) error = %v", format, err)
+		}
+		opts.Topic = "events"
+		var calls int
+		result, err := app.BulkProduce(ctx, reader, opts, func(BulkProgress) { calls++ })
+		if err != nil {
+			t.Fatalf("BulkProduce(%s) error = %v", format, err)
+		}
+		if calls == 0 {
+			t.Errorf("BulkProduce(%s) reported no progress", format)
+		}
+		return result
+	}
+
+	result := produce(BulkJSONL, `{"key":"k1","value":{"n":1},"headers":{"b":"2","a":"1"},"partition":2}
+
+{"value":"plain"}
+{"key":"k3","value":null}
+not json
+{"value":"x","partition":9}
+`, BulkOptions{BatchSize: 2})
+	if result.Read != 5 || result.Sent != 3 || result.Failed != 2 || len(result.Errors) != 2 {
+		t.Fatalf("BulkProduce(jsonl) = %+v", result)
+	}
+	if result.Errors[0].Line != 5 || result.Errors[1].Line != 6 {
+		t.Errorf("BulkProduce(jsonl) errors on lines %d and %d, want 5 and 6", result.Errors[0].Line, result.Errors[1].Line)
+	}
+	written, _ := app.ReadMessages(ctx, "events", 2, 0, 10)
+	if len(written) == 0 || string(written[0].Value) != `{"n":1}` || string(written[0].Headers[0].Key) != "a" {
+		t.Errorf("first JSON Lines record written as %+v", written)
+	}
+
+	result = produce(BulkCSV, "key,value,partition,source\nk1,\"a,b\",0,seed\nk2,c,,\nk3,d,x,\n", BulkOptions{})
+	if result.Sent != 2 || result.Failed != 1 || result.Errors[0].Line != 4 {
+		t.Errorf("BulkProduce(csv) = %+v", result)
+	}
+
+	result = produce(BulkDelimited, "k1|v1\nk2|v2|1|source=seed,run=2\nbroken\n", BulkOptions{Serializer: SerializeJSON})
+	if result.Sent != 0 || result.Failed != 3 {
+		t.Errorf("BulkProduce(delimited, json) = %+v, want every value refused as invalid JSON", result)
+	}
+	result = produce(BulkDelimited, "k1|v1\nk2|v2|1|source=seed,run=2\n", BulkOptions{BatchSize: 1, Rate: 20})
+	if result.Sent != 2 || result.Elapsed < 50*time.Millisecond {
+		t.Errorf("BulkProduce(delimited) at 20 records/s = %+v, want 2 sent over at least 50ms", result)
+	}
+
+	if _, err := NewBulkReader(strings.NewReader("key,partition\n"), BulkCSV, ""); err == nil {
+		t.Errorf("NewBulkReader() of CSV without a value column did not fail")
+	}
+	if headers, err := ParseHeaders("a=1, b = x=y ,"); err != nil || len(headers) != 2 || string(headers[1].Value) != "x=y" {
+		t.Errorf("ParseHeaders() = %+v, %v", headers, err)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	msg := &kafka.Message{
+		Key:     []byte("o-42"),
+		Value:   []byte(`{"id":"o-42","total":150.5,"customer":{"id":7,"tier":"gold"},"items":[{"sku":"a-1","qty":2},{"sku":"b-2","qty":1}]}`),
+		Headers: []kafka.Header{{Key: "source", Value: []byte("web")}, {Key: "trace-id", Value: []byte("abc")}},
+	}
+	tests := []struct {
+		filter string
+		want   bool
+	}{
+		{`key == "o-42"`, true},
+		{`key != o-42`, false},
+		{`value contains "gold"`, true},
+		{`key ~ "^o-[0-9]+$"`, true},
+		{`key matches "^x"`, false},
+		{`header.source = web`, true},
+		{`header["trace-id"] == abc AND header.source != web`, false},
+		{`header.missing exists`, false},
+		{`$.customer.id == 7`, true},
+		{`$.customer.id == "7"`, true},
+		{`$.total > 100 && $.total <= 150.5`, true},
+		{`$.items[*].sku == "b-2"`, true},
+		{`$.items[-1].qty >= 2`, false},
+		{`$..tier == gold`, true},
+		{`$["customer"].tier ~ "^g"`, true},
+		{`$.discount exists OR NOT (key == x OR $.customer.id < 5)`, true},
+		{`"o-42"`, true},
+		{`refund || $.id == 'o-42'`, true},
+	}
+	for _, tt := range tests {
+		f, err := ParseFilter(tt.filter)
+		if err != nil {
+			t.Errorf("ParseFilter(%s) error = %v", tt.filter, err)
+			continue
+		}
+		if got := f.Match(msg, func() (interface{}, bool) { return DecodeJSON(msg.Value) }); got != tt.want {
+			t.Errorf("%s matched %v, want %v", tt.filter, got, tt.want)
+		}
+	}
+
+	// JSON paths never match values that are not JSON
+	f, _ := ParseFilter(`$.id exists`)
+	if f.Match(&kafka.Message{Value: []byte("plain")}, func() (interface{}, bool) { return DecodeJSON([]byte("plain")) }) {
+		t.Errorf("JSON path matched a plain text value")
+	}
+
+	for _, invalid := range []string{"", `key ==`, `status == 1`, `(key == a`, `key ~ "("`, `$.a[1`, `key == "open`, `key == a b`} {
+		if _, err := ParseFilter(invalid); err == nil {
+			t.Errorf("ParseFilter(%s) did not fail", invalid)
+		}
+	}
+}
+
+func TestAppSearchMessages(t *testing.T) {
+	app, _ := newTestApp(t)
+	ctx := context.Background()
+	if err := app.CreateTopic(ctx, "orders", 3, 1); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 30; i++ {
+		value := fmt.Sprintf(`{"n":%d,"status":"%s"}`, i, map[bool]string{true: "refunded", false: "paid"}[i%10 == 0])
+		msg := kafka.Message{Partition: i % 3, Key: []byte(fmt.Sprint("o-", i)), Value: []byte(value), Timestamp: start.Add(time.Duration(i) * time.Second)}
+		if _, err := app.ProduceMessage(ctx, "orders", msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	search := func(req SearchRequest, filter string) (*SearchResult, []string) {
+		t.Helper()
+		req.Topic = "orders"
+		if filter != "" {
+			var err error
+			if req.Filter, err = ParseFilter(filter); err != nil {
+				t.Fatal(err)
+			}
+		}
+		var calls int
+		result, err := app.SearchMessages(ctx, req, func(SearchProgress) { calls++ })
+		if err != nil {
+			t.Fatalf("SearchMessages(%s) error = %v", filter, err)
+		}
+		if calls == 0 {
+			t.Errorf("SearchMessages(%s) reported no progress", filter)
+		}
+		var keys []string
+		for _, m := range result.Messages {
+			keys = append(keys, string(m.Key))
+		}
+		return result, keys
+	}
+
+	result, keys := search(SearchRequest{Partition: AllPartitions, ToOffset: -1}, `$.status == refunded`)
+	if strings.Join(keys, ",") != "o-0,o-10,o-20" || result.Limited || result.Scanned != 30 || result.Total != 30 || result.Done != 3 {
+		t.Errorf("search for refunds = %v, %+v", keys, result.SearchProgress)
+	}
+	result, keys = search(SearchRequest{Partition: AllPartitions, ToOffset: -1, Limit: 2}, `$.n >= 5`)
+	if len(keys) != 2 || !result.Limited || result.Fraction() > 1 {
+		t.Errorf("search limited to 2 = %v, %+v", keys, result.SearchProgress)
+	}
+	_, keys = search(SearchRequest{Partition: 1, FromOffset: 2, ToOffset: 5}, "")
+	if strings.Join(keys, ",") != "o-7,o-10,o-13" {
+		t.Errorf("search of offsets 2-5 of partition 1 = %v", keys)
+	}
+	_, keys = search(SearchRequest{Partition: AllPartitions, ToOffset: -1, FromTime: start.Add(20 * time.Second), ToTime: start.Add(22 * time.Second)}, `key ~ "^o-2"`)
+	if strings.Join(keys, ",") != "o-20,o-21,o-22" {
+		t.Errorf("search of seconds 20-22 = %v", keys)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := app.SearchMessages(cancelled, SearchRequest{Topic: "orders", Partition: AllPartitions, ToOffset: -1}, nil); err == nil {
+		t.Errorf("cancelled search did not fail")
+	}
+}
+
+func TestAppExportMessages(t *testing.T) {
+	app, _ := newTestApp(t)
+	ctx := context.Background()
+	if err := app.CreateTopic(ctx, "orders", 2, 1); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 6; i++ {
+		msg := kafka.Message{Partition: i % 2, Key: []byte(fmt.Sprint("o-", i)), Value: []byte(fmt.Sprintf(`{"n":%d}`, i)),
+			Timestamp: start.Add(time.Duration(i) * time.Second), Headers: []kafka.Header{{Key: "source", Value: []byte("web")}}}
+		if i == 5 {
+			msg.Key, msg.Value = []byte{0xff, 0x00}, nil
+		}
+		if _, err := app.ProduceMessage(ctx, "orders", msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	export := func(req SearchRequest, format ExportFormat, compression ExportCompression) ([]byte, SearchProgress) {
+		t.Helper()
+		req.Topic = "orders"
+		var buf bytes.Buffer
+		out, err := NewExportWriter(&buf, "orders", format, compression)
+		if err != nil {
+			t.Fatal(err)
+		}
+		progress, err := app.ExportMessages(ctx, req, out, nil)
+		if err != nil {
+			t.Fatalf("ExportMessages(%s) error = %v", format, err)
+		}
+		if err := out.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if out.Records() != int64(progress.Matches) || out.Bytes() != int64(buf.Len()) {
+			t.Errorf("export wrote %d records, %d bytes, progress %+v", out.Records(), out.Bytes(), progress)
+		}
+		return buf.Bytes(), progress
+	}
+
+	data, progress := export(SearchRequest{Partition: AllPartitions, ToOffset: -1}, ExportJSONL, ExportUncompressed)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 6 || progress.Matches != 6 || progress.Done != 2 {
+		t.Fatalf("JSON Lines export = %d lines, %+v", len(lines), progress)
+	}
+	want := `{"topic":"orders","partition":0,"offset":0,"timestamp":"2024-05-01T12:00:00Z","key":"o-0","value":"{\"n\":0}","headers":[{"key":"source","value":"web"}]}`
+	if lines[0] != want {
+		t.Errorf("first line = %s, want %s", lines[0], want)
+	}
+	if !strings.Contains(lines[5], `"key":"/wA=","key_encoding":"base64","value":null`) {
+		t.Errorf("binary key and null value = %s", lines[5])
+	}
+
+	data, _ = export(SearchRequest{Partition: AllPartitions, Offsets: map[int]int64{1: 1}, ToOffset: -1}, ExportCSV, ExportGzip)
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(zr).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 || strings.Join(rows[0], ",") != strings.Join(csvExportColumns, ",") ||
+		rows[1][4] != "o-3" || rows[2][5] != "base64" || rows[2][7] != "null" || rows[1][8] != `[{"key":"source","value":"web"}]` {
+		t.Errorf("CSV export from offset 1 of partition 1 = %q", rows)
+	}
+
+	filter, err := ParseFilter(`$.n >= 2`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, progress = export(SearchRequest{Partition: 0, ToOffset: -1, Filter: filter, Limit: 1}, ExportBinary, ExportZstd)
+	zd, err := zstd.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dump, err := io.ReadAll(zd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := "CFKX\x01\x00\x06orders"
+	if progress.Matches != 1 || !strings.HasPrefix(string(dump), header) {
+		t.Fatalf("binary export = %q, %+v", dump, progress)
+	}
+	record := dump[len(header):]
+	if size := binary.BigEndian.Uint32(record); int(size) != len(record)-4 || !bytes.Contains(record, []byte(`{"n":2}`)) {
+		t.Errorf("binary record = %q", record)
+	}
+}
+
+func TestAppCopyMessages(t *testing.T) {
+	app, _ := newTestApp(t)
+	backup := fake.NewCluster()
+	app.Config.Clusters = append(app.Config.Clusters, config.KafkaClusterConfig{Name: "backup"})
+	local := app.KafkaClient
+	app.Connect = func(ctx context.Context, clusterConfig config.KafkaClusterConfig) (KafkaAdmin, error) {
+		if clusterConfig.Name == "backup" {
+			return backup, nil
+		}
+		return local, nil
+	}
+	ctx := context.Background()
+	if err := app.CreateTopic(ctx, "orders", 3, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.CreateTopic(ctx, "orders-replay", 3, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := backup.CreateTopic(ctx, "orders", 2, 1); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 9; i++ {
+		msg := kafka.Message{Partition: i % 3, Key: []byte(fmt.Sprint("o-", i)), Value: []byte(fmt.Sprintf(`{"n":%d}`, i)),
+			Timestamp: start.Add(time.Duration(i) * time.Second),
+			Headers:   []kafka.Header{{Key: "source", Value: []byte("web")}, {Key: "trace", Value: []byte("1")}}}
+		if _, err := app.ProduceMessage(ctx, "orders", msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	filter, err := ParseFilter(`$.n >= 3`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := SearchRequest{Topic: "orders", Partition: AllPartitions, ToOffset: -1, Filter: filter}
+
+	// Within the cluster, keeping partitions and timestamps
+	var calls int
+	progress, err := app.CopyMessages(ctx, CopyRequest{Source: source, TargetTopic: "orders-replay",
+		SetHeaders: []kafka.Header{{Key: "trace", Value: []byte("replay")}}}, func(SearchProgress) { calls++ })
+	if err != nil || progress.Matches != 6 || progress.Scanned != 9 || calls == 0 {
+		t.Fatalf("CopyMessages() = %+v, %d calls, %v", progress, calls, err)
+	}
+	copied, err := app.ReadMessages(ctx, "orders-replay", 1, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(copied) != 2 || string(copied[0].Key) != "o-4" || !copied[0].Timestamp.Equal(start.Add(4*time.Second)) ||
+		fmt.Sprint(copied[0].Headers) != fmt.Sprint([]kafka.Header{{Key: "source", Value: []byte("web")}, {Key: "trace", Value: []byte("replay")}}) {
+		t.Errorf("copied records of partition 1 = %+v", copied)
+	}
+
+	// To another cluster with fewer partitions
+	req := CopyRequest{Source: source, TargetCluster: "backup", TargetTopic: "orders"}
+	if _, err := app.CopyMessages(ctx, req, nil); err == nil || !strings.Contains(err.Error(), "no partition 2") {
+		t.Errorf("copy keeping partition 2 to 2 partitions error = %v", err)
+	}
+	req.Source.Limit = 4
+	req.Partitions, req.Timestamps, req.Headers = PartitionModulo, TimestampNow, HeadersDrop
+	if progress, err = app.CopyMessages(ctx, req, nil); err != nil || progress.Matches != 4 {
+		t.Fatalf("copy to the backup cluster = %+v, %v", progress, err)
+	}
+	offsets, err := backup.ListOffsets(ctx, "orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offsets[0].Latest+offsets[1].Latest != 4 {
+		t.Errorf("backup offsets after the copy = %+v", offsets)
+	}
+	rest, err := backup.ReadMessages(ctx, "orders", 0, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, msg := range rest {
+		if len(msg.Headers) != 0 || msg.Timestamp.Equal(start) || msg.Timestamp.Before(start.Add(9*time.Second)) {
+			t.Errorf("record copied without headers and with the time of the copy = %+v", msg)
+		}
+	}
+}
+
+func TestAppImportMessages(t *testing.T) {
+	app, _ := newTestApp(t)
+	ctx := context.Background()
+	for _, topic := range []string{"orders", "restored", "resumed"} {
+		if err := app.CreateTopic(ctx, topic, 2, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 8; i++ {
+		msg := kafka.Message{Partition: i % 2, Key: []byte(fmt.Sprint("o-", i)), Value: []byte(fmt.Sprintf(`{"n":%d}`, i)),
+			Timestamp: start.Add(time.Duration(i) * time.Second), Headers: []kafka.Header{{Key: "source", Value: []byte{0xff}}}}
+		if i == 7 {
+			msg.Key, msg.Value = nil, nil
+		}
+		if _, err := app.ProduceMessage(ctx, "orders", msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	export := func(format ExportFormat, compression ExportCompression, limit int) *bytes.Buffer {
+		t.Helper()
+		var buf bytes.Buffer
+		out, err := NewExportWriter(&buf, "orders", format, compression)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := SearchRequest{Topic: "orders", Partition: AllPartitions, ToOffset: -1, Limit: limit}
+		if _, err := app.ExportMessages(ctx, req, out, nil); err != nil {
+			t.Fatal(err)
+		}
+		if err := out.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return &buf
+	}
+	read := func(topic string) []kafka.Message {
+		t.Helper()
+		var messages []kafka.Message
+		for p := 0; p < 2; p++ {
+			records, err := app.ReadMessages(ctx, topic, p, 0, 100)
+			if err != nil {
+				t.Fatal(err)
+			}
+			messages = append(messages, records...)
+		}
+		return messages
+	}
+	original := read("orders")
+
+	for _, tt := range []struct {
+		format      ExportFormat
+		compression ExportCompression
+	}{{ExportJSONL, ExportGzip}, {ExportBinary, ExportZstd}} {
+		input, err := NewExportReader(export(tt.format, tt.compression, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if input.Format() != tt.format {
+			t.Errorf("export read as %s, want %s", input.Format(), tt.format)
+		}
+		var messages []kafka.Message
+		for {
+			msg, err := input.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			messages = append(messages, msg)
+		}
+		input.Close()
+		if !reflect.DeepEqual(messages, original) {
+			t.Errorf("%s export read back as %+v, want %+v", tt.format, messages, original)
+		}
+	}
+
+	input, err := NewExportReader(export(ExportBinary, ExportUncompressed, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	progress, err := app.ImportMessages(ctx, input, ImportRequest{Topic: "restored", KeepPartitions: true}, nil)
+	if err != nil || progress.Written != 8 {
+		t.Fatalf("ImportMessages() = %+v, %v", progress, err)
+	}
+	restored := read("restored")
+	for i := range restored {
+		restored[i].Topic = "orders"
+	}
+	if !reflect.DeepEqual(restored, original) {
+		t.Errorf("restored records = %+v, want %+v", restored, original)
+	}
+
+	// A run stopped after 3 records, then resumed, keeping its checkpoint in
+	// the config directory
+	t.Setenv("HOME", t.TempDir())
+	app.Ephemeral = false
+	resume := ImportRequest{Topic: "resumed", KeepPartitions: true, Dedupe: true, ImportID: "orders-1"}
+	input, err = NewExportReader(export(ExportJSONL, ExportUncompressed, 3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if progress, err = app.ImportMessages(ctx, input, resume, nil); err != nil || progress.Written != 3 {
+		t.Fatalf("first import = %+v, %v", progress, err)
+	}
+	input, err = NewExportReader(export(ExportJSONL, ExportUncompressed, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if progress, err = app.ImportMessages(ctx, input, resume, nil); err != nil ||
+		progress.Read != 8 || progress.Skipped != 3 || progress.Written != 5 {
+		t.Fatalf("resumed import = %+v, %v", progress, err)
+	}
+	resumed := read("resumed")
+	for i := range resumed {
+		resumed[i].Topic = "orders"
+	}
+	if !reflect.DeepEqual(resumed, original) {
+		t.Errorf("resumed records = %+v, want %+v", resumed, original)
+	}
+	if path, err := importCheckpointPath(importCheckpointKey("orders-1", "local", "resumed")); err != nil {
+		t.Fatal(err)
+	} else if _, err := os.Stat(path); err != nil {
+		t.Errorf("import checkpoint was not saved: %v", err)
+	}
+
+	// Another export is not mistaken for the one imported
+	input, err = NewExportReader(export(ExportJSONL, ExportUncompressed, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resume.ImportID = "orders-2"
+	if progress, err = app.ImportMessages(ctx, input, resume, nil); err != nil ||
+		progress.Skipped != 0 || progress.Written != 8 {
+		t.Fatalf("import of another export = %+v, %v", progress, err)
+	}
+	resume.ImportID = ""
+	if _, err = app.ImportMessages(ctx, input, resume, nil); err == nil {
+		t.Error("expected an error importing with dedupe without an import ID")
+	}
+
+	// Exports are identified by their contents
+	ids := make(map[string]bool)
+	for _, limit := range []int{0, 0, 3} {
+		id, err := ExportFileID(export(ExportBinary, ExportUncompressed, limit))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids[id] = true
+	}
+	if len(ids) != 2 {
+		t.Errorf("ExportFileID() of two exports, one twice = %v, want 2 IDs", ids)
+	}
+}
+
+func TestExportReaderMalformedBinary(t *testing.T) {
+	header := []byte{'C', 'F', 'K', 'X', binaryVersion, 0, 0}
+	record := make([]byte, 20)                                 // partition, offset and timestamp
+	record = binary.BigEndian.AppendUint32(record, 0xfffffffe) // a key length of -2
+	record = binary.BigEndian.AppendUint32(record, 0)
+	record = binary.BigEndian.AppendUint32(record, 0)
+
+	for name, body := range map[string][]byte{
+		"oversized record": {0xff, 0xff, 0xff, 0xff},
+		"negative length":  append(binary.BigEndian.AppendUint32(nil, uint32(len(record))), record...),
+	} {
+		input, err := NewExportReader(bytes.NewReader(append(header, body...)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if msg, err := input.Next(); err == nil || err == io.EOF {
+			t.Errorf("Next() of a %s = %+v, %v, want a format error", name, msg, err)
+		}
+	}
+}
+
+func TestAppTruncate(t *testing.T) {
+	app, _ := newTestApp(t)
+	ctx := context.Background()
+
+	// Partition 0 holds even seconds and partition 1 odd ones
+	if err := app.CreateTopic(ctx, "events", 3, 1); err != nil {
+		t.Fatalf("CreateTopic() error = %v", err)
+	}
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 10; i++ {
+		msg := kafka.Message{Partition: i % 2, Timestamp: start.Add(time.Duration(i) * time.Second), Value: []byte(fmt.Sprint(i))}
+		if _, err := app.ProduceMessage(ctx, "events", msg); err != nil {
+			t.Fatalf("ProduceMessage() error = %v", err)
+		}
+	}
+
+	plan, err := app.PlanTruncate(ctx, TruncateRequest{Topic: "events", Partition: 1, Offset: 2})
+	if err != nil {
+		t.Fatalf("PlanTruncate(partition) error = %v", err)
+	}
+	if want := []RecordDeletion{{Partition: 1, Earliest: 0, Offset: 2, Latest: 5}}; !reflect.DeepEqual(plan, want) {
+		t.Errorf("PlanTruncate(partition) = %+v, want %+v", plan, want)
+	}
+	if err := app.ApplyTruncate(ctx, "events", plan); err != nil {
+		t.Fatalf("ApplyTruncate() error = %v", err)
+	}
+	if messages, err := app.ReadMessages(ctx, "events", 1, 0, 10); err != nil || len(messages) != 3 || string(messages[0].Value) != "5" {
+		t.Errorf("ReadMessages() after ApplyTruncate() = %+v, %v", messages, err)
+	}
+
+	// At 6.5s partition 0 keeps 8 and partition 1 keeps 7 and 9; the empty
+	// partition 2 has nothing to delete
+	plan, err = app.PlanTruncate(ctx, TruncateRequest{Topic: "events", Partition: AllPartitions, Time: start.Add(6500 * time.Millisecond)})
+	if err != nil {
+		t.Fatalf("PlanTruncate(time) error = %v", err)
+	}
+	want := []RecordDeletion{
+		{Partition: 0, Earliest: 0, Offset: 4, Latest: 5},
+		{Partition: 1, Earliest: 2, Offset: 3, Latest: 5},
+		{Partition: 2, Earliest: 0, Offset: 0, Latest: 0},
+	}
+	if !reflect.DeepEqual(plan, want) {
+		t.Errorf("PlanTruncate(time) = %+v, want %+v", plan, want)
+	}
+	if plan[0].Records() != 4 || plan[2].Records() != 0 {
+		t.Errorf("Records() = %d and %d, want 4 and 0", plan[0].Records(), plan[2].Records())
+	}
+	if err := app.ApplyTruncate(ctx, "events", plan); err != nil {
+		t.Fatalf("ApplyTruncate() error = %v", err)
+	}
+	offsets, err := app.ListOffsets(ctx, "events")
+	if err != nil {
+		t.Fatalf("ListOffsets() error = %v", err)
+	}
+	if offsets[0].Earliest != 4 || offsets[1].Earliest != 3 || offsets[2].Earliest != 0 {
+		t.Errorf("ListOffsets() after ApplyTruncate() = %+v", offsets)
+	}
+
+	// Past the last record every partition is emptied
+	plan, err = app.PlanTruncate(ctx, TruncateRequest{Topic: "events", Partition: AllPartitions, Time: start.Add(time.Hour)})
+	if err != nil || plan[0].Offset != 5 || plan[1].Offset != 5 {
+		t.Errorf("PlanTruncate(after the last record) = %+v, %v", plan, err)
+	}
+
+	if _, err := app.PlanTruncate(ctx, TruncateRequest{Topic: "events", Partition: 0, Offset: 6}); err == nil {
+		t.Error("PlanTruncate() past the end of the partition succeeded")
+	}
+	if _, err := app.PlanTruncate(ctx, TruncateRequest{Topic: "events", Partition: 7}); err == nil {
+		t.Error("PlanTruncate() of a missing partition succeeded")
+	}
+	if err := app.ApplyTruncate(ctx, "events", []RecordDeletion{{Partition: 2}}); err == nil {
+		t.Error("ApplyTruncate() of nothing succeeded")
+	}
+}
+
+func TestParseOffsetsCSV(t *testing.T) {
+	offsets, err := ParseOffsetsCSV(strings.NewReader("# exported\nevents,0,5\nevents, 1, 7\n"))
+	if err != nil {
+		t.Fatalf("ParseOffsetsCSV() error = %v", err)
+	}
+	want := []kafka.GroupOffset{{Topic: "events", Partition: 0, CommittedOffset: 5}, {Topic: "events", Partition: 1, CommittedOffset: 7}}
+	if !reflect.DeepEqual(offsets, want) {
+		t.Errorf("ParseOffsetsCSV() = %+v, want %+v", offsets, want)
+	}
+	if _, err := ParseOffsetsCSV(strings.NewReader("events,x,5\n")); err == nil {
+		t.Error("ParseOffsetsCSV() of an invalid partition succeeded")
+	}
+}
+
+func TestEphemeralAppKeepsClustersInMemory(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	app := NewApp(config.DefaultConfig())
+	app.Ephemeral = true
+	if err := app.AddCluster(config.KafkaClusterConfig{Name: "demo"}); err != nil {
+		t.Fatalf("AddCluster() error = %v", err)
+	}
+	if err := app.AddCluster(config.KafkaClusterConfig{Name: "demo"}); err == nil {
+		t.Errorf("AddCluster() of a duplicate name did not fail")
+	}
+	if err := app.RemoveCluster("demo"); err != nil {
+		t.Fatalf("RemoveCluster() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(home, ".cfk", "config.yaml")); !os.IsNotExist(err) {
+		t.Errorf("ephemeral app saved its configuration")
+	}
+}
@@ -0,0 +1,287 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/cfk-dev/cfk/internal/kafka"
+)
+
+// BrowseFrom is where a message browser starts reading
+type BrowseFrom string
+
+// Browse start positions
+const (
+	FromEarliest  BrowseFrom = "earliest"
+	FromLatest    BrowseFrom = "latest"
+	FromOffset    BrowseFrom = "offset"
+	FromTimestamp BrowseFrom = "timestamp"
+)
+
+// BrowseStarts lists the browse start positions
+var BrowseStarts = []BrowseFrom{FromEarliest, FromLatest, FromOffset, FromTimestamp}
+
+// AllPartitions browses every partition of a topic, merged by timestamp
+const AllPartitions = -1
+
+// BrowseRequest describes where to start browsing a topic
+type BrowseRequest struct {
+	Topic     string
+	Partition int        // a partition, or AllPartitions
+	From      BrowseFrom // where to start
+	Offset    int64      // the offset to start at, or the N of the last N records for FromLatest
+	Timestamp time.Time  // the time to start at
+}
+
+// MessagePage is a page of records. Start and End hold, for every browsed
+// partition, the offset of the first record of the page and the offset after
+// its last record; they are the cursors for the previous and next page.
+type MessagePage struct {
+	Topic     string
+	Partition int
+	Messages  []kafka.Message
+	Start     map[int]int64
+	End       map[int]int64
+	Bounds    map[int]kafka.PartitionOffsets
+}
+
+// HasPrev reports whether there are records before the page
+func (p *MessagePage) HasPrev() bool {
+	for partition, offset := range p.Start {
+		if offset > p.Bounds[partition].Earliest {
+			return true
+		}
+	}
+	return false
+}
+
+// HasNext reports whether there were records after the page when it was read
+func (p *MessagePage) HasNext() bool {
+	for partition, offset := range p.End {
+		if offset < p.Bounds[partition].Latest {
+			return true
+		}
+	}
+	return false
+}
+
+// BrowseMessages reads the first page of records from where a request starts
+func (a *App) BrowseMessages(ctx context.Context, req BrowseRequest, pageSize int) (*MessagePage, error) {
+	if a.KafkaClient == nil {
+		return nil, fmt.Errorf("not connected to any Kafka cluster")
+	}
+	if pageSize <= 0 {
+		return nil, fmt.Errorf("invalid page size %d", pageSize)
+	}
+
+	bounds, err := a.browseBounds(ctx, req.Topic, req.Partition)
+	if err != nil {
+		return nil, err
+	}
+
+	start := make(map[int]int64, len(bounds))
+	switch req.From {
+	case FromEarliest:
+		for p, b := range bounds {
+			start[p] = b.Earliest
+		}
+	case FromLatest:
+		// The last N records are the page before the end of every partition
+		end := make(map[int]int64, len(bounds))
+		for p, b := range bounds {
+			end[p] = b.Latest
+		}
+		if req.Offset <= 0 {
+			return nil, fmt.Errorf("invalid number of latest records %d", req.Offset)
+		}
+		last, err := a.readBefore(ctx, req.Topic, end, bounds, int(req.Offset))
+		if err != nil {
+			return nil, err
+		}
+		start = last.Start
+	case FromOffset:
+		for p, b := range bounds {
+			start[p] = clampOffset(req.Offset, b)
+		}
+	case FromTimestamp:
+		atTime, err := a.KafkaClient.OffsetsForTime(ctx, req.Topic, req.Timestamp)
+		if err != nil {
+			return nil, err
+		}
+		for p, b := range bounds {
+			// Partitions without a record after the time start at the end
+			start[p] = b.Latest
+			if offset, ok := atTime[p]; ok && offset >= 0 {
+				start[p] = clampOffset(offset, b)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown start position %q", req.From)
+	}
+
+	page, err := a.readFrom(ctx, req.Topic, start, bounds, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	page.Partition = req.Partition
+	return page, nil
+}
+
+// NextMessages reads the page after a page. Records produced since the page
+// was read are included.
+func (a *App) NextMessages(ctx context.Context, page *MessagePage, pageSize int) (*MessagePage, error) {
+	if a.KafkaClient == nil {
+		return nil, fmt.Errorf("not connected to any Kafka cluster")
+	}
+
+	bounds, err := a.browseBounds(ctx, page.Topic, page.Partition)
+	if err != nil {
+		return nil, err
+	}
+	next, err := a.readFrom(ctx, page.Topic, page.End, bounds, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	next.Partition = page.Partition
+	return next, nil
+}
+
+// PrevMessages reads the page before a page
+func (a *App) PrevMessages(ctx context.Context, page *MessagePage, pageSize int) (*MessagePage, error) {
+	if a.KafkaClient == nil {
+		return nil, fmt.Errorf("not connected to any Kafka cluster")
+	}
+
+	bounds, err := a.browseBounds(ctx, page.Topic, page.Partition)
+	if err != nil {
+		return nil, err
+	}
+	prev, err := a.readBefore(ctx, page.Topic, page.Start, bounds, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	prev.Partition = page.Partition
+	return prev, nil
+}
+
+// browseBounds gets the offsets of the browsed partitions
+func (a *App) browseBounds(ctx context.Context, topic string, partition int) (map[int]kafka.PartitionOffsets, error) {
+	offsets, err := a.KafkaClient.ListOffsets(ctx, topic)
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := make(map[int]kafka.PartitionOffsets, len(offsets))
+	for _, o := range offsets {
+		if partition == AllPartitions || o.Partition == partition {
+			bounds[o.Partition] = o
+		}
+	}
+	if len(bounds) == 0 {
+		return nil, fmt.Errorf("partition %d of topic %s not found", partition, topic)
+	}
+	return bounds, nil
+}
+
+// readFrom reads the first limit records at or after the start offsets,
+// merged by timestamp
+func (a *App) readFrom(ctx context.Context, topic string, start map[int]int64, bounds map[int]kafka.PartitionOffsets, limit int) (*MessagePage, error) {
+	var messages []kafka.Message
+	for p, offset := range start {
+		if offset >= bounds[p].Latest {
+			continue
+		}
+		read, err := a.KafkaClient.ReadMessages(ctx, topic, p, offset, limit)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, read...)
+	}
+
+	sortMessages(messages)
+	if len(messages) > limit {
+		messages = messages[:limit]
+	}
+
+	page := &MessagePage{Topic: topic, Messages: messages, Start: copyOffsets(start), End: copyOffsets(start), Bounds: bounds}
+	for _, m := range messages {
+		if m.Offset+1 > page.End[m.Partition] {
+			page.End[m.Partition] = m.Offset + 1
+		}
+	}
+	return page, nil
+}
+
+// readBefore reads the last limit records before the end offsets, merged by
+// timestamp
+func (a *App) readBefore(ctx context.Context, topic string, end map[int]int64, bounds map[int]kafka.PartitionOffsets, limit int) (*MessagePage, error) {
+	var messages []kafka.Message
+	for p, offset := range end {
+		from := offset - int64(limit)
+		if from < bounds[p].Earliest {
+			from = bounds[p].Earliest
+		}
+		if from >= offset {
+			continue
+		}
+		read, err := a.KafkaClient.ReadMessages(ctx, topic, p, from, int(offset-from))
+		if err != nil {
+			return nil, err
+		}
+		// Compacted partitions may return records past the end
+		for _, m := range read {
+			if m.Offset < offset {
+				messages = append(messages, m)
+			}
+		}
+	}
+
+	sortMessages(messages)
+	if len(messages) > limit {
+		messages = messages[len(messages)-limit:]
+	}
+
+	page := &MessagePage{Topic: topic, Messages: messages, Start: copyOffsets(end), End: copyOffsets(end), Bounds: bounds}
+	for _, m := range messages {
+		if m.Offset < page.Start[m.Partition] {
+			page.Start[m.Partition] = m.Offset
+		}
+	}
+	return page, nil
+}
+
+// sortMessages orders records by timestamp, then partition and offset
+func sortMessages(messages []kafka.Message) {
+	sort.SliceStable(messages, func(i, j int) bool {
+		a, b := messages[i], messages[j]
+		if !a.Timestamp.Equal(b.Timestamp) {
+			return a.Timestamp.Before(b.Timestamp)
+		}
+		if a.Partition != b.Partition {
+			return a.Partition < b.Partition
+		}
+		return a.Offset < b.Offset
+	})
+}
+
+// clampOffset keeps an offset within the offsets of a partition
+func clampOffset(offset int64, bounds kafka.PartitionOffsets) int64 {
+	if offset < bounds.Earliest {
+		return bounds.Earliest
+	}
+	if offset > bounds.Latest {
+		return bounds.Latest
+	}
+	return offset
+}
+
+// copyOffsets copies per-partition offsets
+func copyOffsets(offsets map[int]int64) map[int]int64 {
+	c := make(map[int]int64, len(offsets))
+	for p, o := range offsets {
+		c[p] = o
+	}
+	return c
+}
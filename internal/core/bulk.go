@@ -0,0 +1,457 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cfk-dev/cfk/internal/kafka"
+)
+
+// BulkFormat is the format of the input of a bulk produce
+type BulkFormat string
+
+// Bulk produce input formats
+const (
+	// BulkJSONL reads one JSON object per line with the fields key, value,
+	// headers (an object of strings) and partition. A key or value that is
+	// not a JSON string is produced as its JSON text; a null value writes a
+	// record without a value.
+	BulkJSONL BulkFormat = "jsonl"
+	// BulkCSV reads CSV with a header row. The key, value and partition
+	// columns fill the record; any other column becomes a header.
+	BulkCSV BulkFormat = "csv"
+	// BulkDelimited reads key<sep>value lines, optionally followed by
+	// <sep>partition and <sep>headers as k=v pairs separated by commas
+	BulkDelimited BulkFormat = "delimited"
+)
+
+// BulkFormats lists the bulk input formats
+var BulkFormats = []BulkFormat{BulkJSONL, BulkCSV, BulkDelimited}
+
+// DefaultBatchSize is the number of records written per batch
+const DefaultBatchSize = 500
+
+// maxBulkErrors bounds the failures kept for the error report
+const maxBulkErrors = 1000
+
+// maxLineSize bounds the length of a line of bulk input
+const maxLineSize = 16 << 20
+
+// BulkRecord is a record read from bulk input. An empty key writes a record
+// without a key.
+type BulkRecord struct {
+	Line      int
+	Partition int // a partition, or -1 to pick one from the key
+	Key       string
+	Value     *string // nil writes a record without a value
+	Headers   []kafka.Header
+}
+
+// BulkError is a record of bulk input that could not be read, serialized or
+// written
+type BulkError struct {
+	Line int
+	Err  error
+}
+
+// Error describes the failure with its line
+func (e *BulkError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+// Unwrap returns the cause of the failure
+func (e *BulkError) Unwrap() error {
+	return e.Err
+}
+
+// BulkReader reads the records of bulk input. Invalid records are returned
+// as a *BulkError, after which reading can go on.
+type BulkReader struct {
+	format    BulkFormat
+	delimiter string
+	lines     *bufio.Scanner
+	csv       *csv.Reader
+	columns   []string // of the CSV header row
+	line      int
+}
+
+// NewBulkReader creates a reader of bulk input. The delimiter separates the
+// fields of delimited input and defaults to a tab, which can also be written
+// as \t.
+func NewBulkReader(r io.Reader, format BulkFormat, delimiter string) (*BulkReader, error) {
+	br := &BulkReader{format: format, delimiter: delimiter}
+	switch format {
+	case BulkJSONL, BulkDelimited:
+		br.lines = bufio.NewScanner(r)
+		br.lines.Buffer(make([]byte, 64<<10), maxLineSize)
+		if br.delimiter == "" || br.delimiter == `\t` {
+			br.delimiter = "\t"
+		}
+	case BulkCSV:
+		br.csv = csv.NewReader(r)
+		br.csv.FieldsPerRecord = -1
+		header, err := br.csv.Read()
+		if err == io.EOF {
+			return nil, fmt.Errorf("empty CSV input, expected a header row")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV header row: %w", err)
+		}
+		br.columns = header
+		hasValue := false
+		for _, column := range header {
+			hasValue = hasValue || column == "value"
+		}
+		if !hasValue {
+			return nil, fmt.Errorf("CSV header row has no value column")
+		}
+	default:
+		return nil, fmt.Errorf("unknown input format %q, use jsonl, csv or delimited", format)
+	}
+	return br, nil
+}
+
+// Next reads the next record, or returns io.EOF at the end of the input
+func (r *BulkReader) Next() (BulkRecord, error) {
+	if r.csv != nil {
+		return r.nextCSV()
+	}
+
+	for r.lines.Scan() {
+		r.line++
+		line := r.lines.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		var rec BulkRecord
+		var err error
+		if r.format == BulkJSONL {
+			rec, err = parseJSONLine(line)
+		} else {
+			rec, err = parseDelimitedLine(line, r.delimiter)
+		}
+		rec.Line = r.line
+		if err != nil {
+			return rec, &BulkError{Line: r.line, Err: err}
+		}
+		return rec, nil
+	}
+	if err := r.lines.Err(); err != nil {
+		return BulkRecord{}, fmt.Errorf("failed to read line %d: %w", r.line+1, err)
+	}
+	return BulkRecord{}, io.EOF
+}
+
+// nextCSV reads the next CSV row
+func (r *BulkReader) nextCSV() (BulkRecord, error) {
+	row, err := r.csv.Read()
+	line, _ := r.csv.FieldPos(0)
+	rec := BulkRecord{Line: line, Partition: -1}
+	if err == io.EOF {
+		return rec, io.EOF
+	}
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return rec, &BulkError{Line: parseErr.Line, Err: parseErr.Err}
+	}
+	if err != nil {
+		return rec, fmt.Errorf("failed to read CSV: %w", err)
+	}
+	if len(row) != len(r.columns) {
+		return rec, &BulkError{Line: line, Err: fmt.Errorf("%d fields, the header row has %d", len(row), len(r.columns))}
+	}
+
+	for i, column := range r.columns {
+		switch column {
+		case "key":
+			rec.Key = row[i]
+		case "value":
+			value := row[i]
+			rec.Value = &value
+		case "partition":
+			if rec.Partition, err = parsePartition(row[i]); err != nil {
+				return rec, &BulkError{Line: line, Err: err}
+			}
+		default:
+			if row[i] != "" {
+				rec.Headers = append(rec.Headers, kafka.Header{Key: column, Value: []byte(row[i])})
+			}
+		}
+	}
+	return rec, nil
+}
+
+// parseJSONLine parses a line of JSON Lines input
+func parseJSONLine(line string) (BulkRecord, error) {
+	var fields struct {
+		Key       json.RawMessage   `json:"key"`
+		Value     json.RawMessage   `json:"value"`
+		Headers   map[string]string `json:"headers"`
+		Partition *int              `json:"partition"`
+	}
+	rec := BulkRecord{Partition: -1}
+	if err := json.Unmarshal([]byte(line), &fields); err != nil {
+		return rec, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	if key := jsonText(fields.Key); key != nil {
+		rec.Key = *key
+	}
+	rec.Value = jsonText(fields.Value)
+	if fields.Partition != nil {
+		if *fields.Partition < 0 {
+			return rec, fmt.Errorf("invalid partition %d", *fields.Partition)
+		}
+		rec.Partition = *fields.Partition
+	}
+
+	names := make([]string, 0, len(fields.Headers))
+	for name := range fields.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		rec.Headers = append(rec.Headers, kafka.Header{Key: name, Value: []byte(fields.Headers[name])})
+	}
+	return rec, nil
+}
+
+// jsonText returns a JSON string unquoted, any other JSON value as its
+// compact text, and nil for null or a missing field
+func jsonText(raw json.RawMessage) *string {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+	var text string
+	if json.Unmarshal(raw, &text) == nil {
+		return &text
+	}
+	var compact bytes.Buffer
+	if json.Compact(&compact, raw) != nil {
+		text = string(raw)
+	} else {
+		text = compact.String()
+	}
+	return &text
+}
+
+// parseDelimitedLine parses a key<sep>value[<sep>partition[<sep>headers]] line
+func parseDelimitedLine(line, delimiter string) (BulkRecord, error) {
+	rec := BulkRecord{Partition: -1}
+	fields := strings.Split(line, delimiter)
+	if len(fields) < 2 {
+		return rec, fmt.Errorf("expected key%svalue", delimiter)
+	}
+	if len(fields) > 4 {
+		return rec, fmt.Errorf("%d fields, expected key, value, partition and headers at most; use CSV or JSON Lines for values containing the delimiter", len(fields))
+	}
+
+	rec.Key = fields[0]
+	rec.Value = &fields[1]
+	var err error
+	if len(fields) > 2 {
+		if rec.Partition, err = parsePartition(fields[2]); err != nil {
+			return rec, err
+		}
+	}
+	if len(fields) > 3 {
+		if rec.Headers, err = ParseHeaders(fields[3]); err != nil {
+			return rec, err
+		}
+	}
+	return rec, nil
+}
+
+// parsePartition parses a partition, empty for one picked from the key
+func parsePartition(s string) (int, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return -1, nil
+	}
+	partition, err := strconv.Atoi(s)
+	if err != nil || partition < 0 {
+		return 0, fmt.Errorf("invalid partition %q", s)
+	}
+	return partition, nil
+}
+
+// ParseHeaders parses record headers written as k=v pairs separated by commas
+func ParseHeaders(s string) ([]kafka.Header, error) {
+	var headers []kafka.Header
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid header %q, expected key=value", strings.TrimSpace(pair))
+		}
+		headers = append(headers, kafka.Header{Key: key, Value: []byte(strings.TrimSpace(value))})
+	}
+	return headers, nil
+}
+
+// BulkOptions control a bulk produce
+type BulkOptions struct {
+	Topic      string
+	Serializer Serializer
+	Produce    kafka.ProduceOptions
+	BatchSize  int     // records written per request, DefaultBatchSize when 0
+	Rate       float64 // records per second, 0 for no limit
+}
+
+// BulkProgress counts the records of a bulk produce so far
+type BulkProgress struct {
+	Read    int // records read, including invalid ones
+	Sent    int // records written
+	Failed  int // records invalid or refused
+	Elapsed time.Duration
+}
+
+// Rate returns the records written per second
+func (p BulkProgress) Rate() float64 {
+	if p.Elapsed <= 0 {
+		return 0
+	}
+	return float64(p.Sent) / p.Elapsed.Seconds()
+}
+
+// BulkResult is the outcome of a bulk produce
+type BulkResult struct {
+	BulkProgress
+	Errors []BulkError // the first failures, in input order
+}
+
+// BulkProduce writes the records of bulk input to a topic in batches, at
+// most at the rate asked for. Invalid and refused records are counted and
+// reported without stopping; progress, if not nil, is called after every
+// batch. On cancellation it returns what was done so far with the error.
+func (a *App) BulkProduce(ctx context.Context, input *BulkReader, opts BulkOptions, progress func(BulkProgress)) (*BulkResult, error) {
+	if a.KafkaClient == nil {
+		return nil, fmt.Errorf("not connected to any Kafka cluster")
+	}
+	if err := opts.Produce.Validate(); err != nil {
+		return nil, err
+	}
+	if opts.Rate < 0 {
+		return nil, fmt.Errorf("invalid rate %g", opts.Rate)
+	}
+	serialize, err := a.valueSerializer(ctx, opts.Topic, opts.Serializer)
+	if err != nil {
+		return nil, err
+	}
+
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	// Send at most about a second of records at a time
+	if opts.Rate > 0 && float64(batchSize) > opts.Rate {
+		batchSize = max(1, int(opts.Rate))
+	}
+
+	start := time.Now()
+	result := &BulkResult{}
+	fail := func(line int, err error) {
+		result.Failed++
+		if len(result.Errors) < maxBulkErrors {
+			result.Errors = append(result.Errors, BulkError{Line: line, Err: err})
+		}
+	}
+	report := func() {
+		result.Elapsed = time.Since(start)
+		if progress != nil {
+			progress(result.BulkProgress)
+		}
+	}
+
+	var batch []kafka.Message
+	var lines []int
+	attempted := 0
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if opts.Rate > 0 {
+			due := start.Add(time.Duration(float64(attempted) / opts.Rate * float64(time.Second)))
+			if wait := time.Until(due); wait > 0 {
+				timer := time.NewTimer(wait)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return ctx.Err()
+				case <-timer.C:
+				}
+			}
+		}
+
+		results, err := a.KafkaClient.ProduceMessages(ctx, opts.Topic, batch, opts.Produce)
+		if err != nil {
+			return err
+		}
+		for i, r := range results {
+			if r.Err != nil {
+				fail(lines[i], r.Err)
+			} else {
+				result.Sent++
+			}
+		}
+		attempted += len(batch)
+		batch, lines = batch[:0], lines[:0]
+		report()
+		return ctx.Err()
+	}
+
+	for {
+		rec, err := input.Next()
+		if err == io.EOF {
+			break
+		}
+		var bulkErr *BulkError
+		if errors.As(err, &bulkErr) {
+			result.Read++
+			fail(bulkErr.Line, bulkErr.Err)
+			continue
+		}
+		if err != nil {
+			report()
+			return result, err
+		}
+		result.Read++
+
+		msg := kafka.Message{Partition: rec.Partition, Headers: rec.Headers}
+		if rec.Key != "" {
+			msg.Key = []byte(rec.Key)
+		}
+		if rec.Value != nil {
+			if msg.Value, err = serialize(*rec.Value); err != nil {
+				fail(rec.Line, fmt.Errorf("invalid value: %w", err))
+				continue
+			}
+		}
+		batch = append(batch, msg)
+		lines = append(lines, rec.Line)
+		if len(batch) >= batchSize {
+			if err := flush(); err != nil {
+				return result, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return result, err
+	}
+	report()
+	return result, nil
+}
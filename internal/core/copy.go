@@ -0,0 +1,198 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cfk-dev/cfk/internal/kafka"
+)
+
+// PartitionMapping picks the target partition of a copied record
+type PartitionMapping string
+
+// Partition mappings
+const (
+	PartitionKeep   PartitionMapping = "keep"   // the partition of the source record
+	PartitionModulo PartitionMapping = "modulo" // the source partition modulo the target partitions
+	PartitionByKey  PartitionMapping = "key"    // picked from the key by the target
+)
+
+// PartitionMappings lists the partition mappings, the default first
+var PartitionMappings = []PartitionMapping{PartitionKeep, PartitionModulo, PartitionByKey}
+
+// TimestampMode sets the timestamps of copied records
+type TimestampMode string
+
+// Timestamp modes
+const (
+	TimestampKeep TimestampMode = "keep" // the time of the source record
+	TimestampNow  TimestampMode = "now"  // the time of the copy
+)
+
+// TimestampModes lists the timestamp modes, the default first
+var TimestampModes = []TimestampMode{TimestampKeep, TimestampNow}
+
+// HeaderMode sets the headers of copied records
+type HeaderMode string
+
+// Header modes
+const (
+	HeadersKeep HeaderMode = "keep" // the headers of the source record
+	HeadersDrop HeaderMode = "drop" // no headers but the ones set by the copy
+)
+
+// HeaderModes lists the header modes, the default first
+var HeaderModes = []HeaderMode{HeadersKeep, HeadersDrop}
+
+// CopyRequest describes the records to copy and where to write them. The
+// source range, filter and limit are those of a search, a limit of 0 copying
+// every match. Empty cluster names stand for the connected cluster.
+type CopyRequest struct {
+	SourceCluster string
+	Source        SearchRequest
+	TargetCluster string
+	TargetTopic   string
+	Partitions    PartitionMapping
+	Timestamps    TimestampMode
+	Headers       HeaderMode
+	SetHeaders    []kafka.Header // added to each record, replacing headers of the same name
+	Produce       kafka.ProduceOptions
+}
+
+// CopyMessages copies the records of a source range that satisfy a filter to
+// a topic, of the same cluster or of another one of the configuration. It
+// copies a partition after another in offset order and stops at the first
+// record it fails to write. Progress, if not nil, is called after each batch,
+// counting the records written as matches. On cancellation it returns the
+// progress so far with the error; the records written are kept.
+func (a *App) CopyMessages(ctx context.Context, req CopyRequest, progress func(SearchProgress)) (SearchProgress, error) {
+	var result SearchProgress
+	if req.TargetTopic == "" {
+		return result, fmt.Errorf("the target topic is required")
+	}
+	if err := req.Produce.Validate(); err != nil {
+		return result, err
+	}
+
+	source, closeSource, err := a.clusterApp(ctx, req.SourceCluster)
+	if err != nil {
+		return result, err
+	}
+	defer closeSource()
+	target, closeTarget, err := a.clusterApp(ctx, req.TargetCluster)
+	if err != nil {
+		return result, err
+	}
+	defer closeTarget()
+
+	offsets, err := target.ListOffsets(ctx, req.TargetTopic)
+	if err != nil {
+		return result, fmt.Errorf("failed to read target topic %s: %w", req.TargetTopic, err)
+	}
+	targetPartitions := len(offsets)
+	if targetPartitions == 0 {
+		return result, fmt.Errorf("target topic %s has no partitions", req.TargetTopic)
+	}
+	if req.Partitions == PartitionKeep || req.Partitions == "" {
+		// Check before writing anything that every source partition has a target
+		last := req.Source.Partition
+		if last == AllPartitions {
+			sourceOffsets, err := source.ListOffsets(ctx, req.Source.Topic)
+			if err != nil {
+				return result, err
+			}
+			last = len(sourceOffsets) - 1
+		}
+		if last >= targetPartitions {
+			return result, fmt.Errorf("target topic %s has no partition %d, map partitions by modulo or key", req.TargetTopic, last)
+		}
+	}
+
+	return source.streamMatches(ctx, req.Source, progress, func(matches []kafka.Message) error {
+		batch := make([]kafka.Message, len(matches))
+		for i, msg := range matches {
+			copied, err := copiedRecord(req, msg, targetPartitions)
+			if err != nil {
+				return err
+			}
+			batch[i] = copied
+		}
+		results, err := target.KafkaClient.ProduceMessages(ctx, req.TargetTopic, batch, req.Produce)
+		if err != nil {
+			return err
+		}
+		for i, r := range results {
+			if r.Err != nil {
+				return fmt.Errorf("failed to copy offset %d of partition %d: %w", matches[i].Offset, matches[i].Partition, r.Err)
+			}
+		}
+		return nil
+	})
+}
+
+// copiedRecord returns the record to write for a source record
+func copiedRecord(req CopyRequest, msg kafka.Message, targetPartitions int) (kafka.Message, error) {
+	copied := kafka.Message{Key: msg.Key, Value: msg.Value, Timestamp: msg.Timestamp}
+	switch req.Partitions {
+	case PartitionKeep, "":
+		if msg.Partition >= targetPartitions {
+			return copied, fmt.Errorf("target topic %s has no partition %d, map partitions by modulo or key", req.TargetTopic, msg.Partition)
+		}
+		copied.Partition = msg.Partition
+	case PartitionModulo:
+		copied.Partition = msg.Partition % targetPartitions
+	case PartitionByKey:
+		copied.Partition = -1
+	default:
+		return copied, fmt.Errorf("unknown partition mapping %q, use keep, modulo or key", req.Partitions)
+	}
+
+	switch req.Timestamps {
+	case TimestampKeep, "":
+	case TimestampNow:
+		copied.Timestamp = time.Time{}
+	default:
+		return copied, fmt.Errorf("unknown timestamp mode %q, use keep or now", req.Timestamps)
+	}
+
+	switch req.Headers {
+	case HeadersKeep, "":
+		for _, h := range msg.Headers {
+			if !hasHeader(req.SetHeaders, h.Key) {
+				copied.Headers = append(copied.Headers, h)
+			}
+		}
+	case HeadersDrop:
+	default:
+		return copied, fmt.Errorf("unknown header mode %q, use keep or drop", req.Headers)
+	}
+	copied.Headers = append(copied.Headers, req.SetHeaders...)
+	return copied, nil
+}
+
+// hasHeader reports whether headers have one of a name
+func hasHeader(headers []kafka.Header, name string) bool {
+	for _, h := range headers {
+		if h.Key == name {
+			return true
+		}
+	}
+	return false
+}
+
+// clusterApp returns an app connected to a cluster of the configuration, and
+// the function releasing its connection. An empty name returns the app itself.
+func (a *App) clusterApp(ctx context.Context, name string) (*App, func(), error) {
+	if name == "" {
+		if a.KafkaClient == nil {
+			return nil, nil, fmt.Errorf("not connected to any Kafka cluster")
+		}
+		return a, func() {}, nil
+	}
+	other := &App{Config: a.Config, Connect: a.Connect, Ephemeral: a.Ephemeral}
+	if err := other.ConnectToCluster(ctx, name); err != nil {
+		return nil, nil, err
+	}
+	return other, func() { other.KafkaClient.Close() }, nil
+}
@@ -0,0 +1,632 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/cfk-dev/cfk/internal/kafka"
+	"github.com/klauspost/compress/zstd"
+)
+
+// ExportFormat is the file format of an export
+type ExportFormat string
+
+// Export file formats
+const (
+	// ExportJSONL writes one JSON object per record with the fields topic,
+	// partition, offset, timestamp, key, value and headers. Keys, values and
+	// header values that are not valid UTF-8 are written in base64 with a
+	// key_encoding, value_encoding or header encoding of "base64"; a missing
+	// key or value is null.
+	ExportJSONL ExportFormat = "jsonl"
+	// ExportCSV writes a header row and then the columns topic, partition,
+	// offset, timestamp, key, key_encoding, value, value_encoding and headers,
+	// the headers as a JSON array like in JSON Lines. An encoding is empty for
+	// text, "base64", or "null" for a missing key or value.
+	ExportCSV ExportFormat = "csv"
+	// ExportBinary writes a length-prefixed dump keeping every byte:
+	//
+	//	file   = "CFKX" version:uint8 topic:string record*
+	//	record = size:uint32 partition:int32 offset:int64 timestamp:int64
+	//	         key:bytes value:bytes headers:uint32 (key:string value:bytes)*
+	//	string = length:uint16 data
+	//	bytes  = length:int32 data, a length of -1 for null
+	//
+	// Integers are big-endian, timestamps in Unix milliseconds and the size
+	// of a record counts the bytes after it.
+	ExportBinary ExportFormat = "binary"
+)
+
+// ExportFormats lists the export file formats
+var ExportFormats = []ExportFormat{ExportJSONL, ExportCSV, ExportBinary}
+
+// ExportCompression is the compression of an export file
+type ExportCompression string
+
+// Export file compressions
+const (
+	ExportUncompressed ExportCompression = "none"
+	ExportGzip         ExportCompression = "gzip"
+	ExportZstd         ExportCompression = "zstd"
+)
+
+// ExportCompressions lists the export file compressions
+var ExportCompressions = []ExportCompression{ExportUncompressed, ExportGzip, ExportZstd}
+
+// binaryMagic starts a binary export, followed by binaryVersion
+const (
+	binaryMagic   = "CFKX"
+	binaryVersion = 1
+)
+
+// encodingBase64 and encodingNull mark the bytes of an export that are not
+// plain text
+const (
+	encodingBase64 = "base64"
+	encodingNull   = "null"
+)
+
+// csvExportColumns is the header row of a CSV export
+var csvExportColumns = []string{"topic", "partition", "offset", "timestamp", "key", "key_encoding", "value", "value_encoding", "headers"}
+
+// ExportFileName returns the default name of an export of a topic
+func ExportFileName(topic string, format ExportFormat, compression ExportCompression) string {
+	name := topic + "." + string(format)
+	if format == ExportBinary {
+		name = topic + ".bin"
+	}
+	switch compression {
+	case ExportGzip:
+		name += ".gz"
+	case ExportZstd:
+		name += ".zst"
+	}
+	return name
+}
+
+// exportRecord is a record of a JSON Lines export
+type exportRecord struct {
+	Topic         string         `json:"topic"`
+	Partition     int            `json:"partition"`
+	Offset        int64          `json:"offset"`
+	Timestamp     time.Time      `json:"timestamp"`
+	Key           *string        `json:"key"`
+	KeyEncoding   string         `json:"key_encoding,omitempty"`
+	Value         *string        `json:"value"`
+	ValueEncoding string         `json:"value_encoding,omitempty"`
+	Headers       []exportHeader `json:"headers,omitempty"`
+}
+
+// exportHeader is a record header of a JSON Lines or CSV export
+type exportHeader struct {
+	Key      string  `json:"key"`
+	Value    *string `json:"value"`
+	Encoding string  `json:"encoding,omitempty"`
+}
+
+// ExportWriter streams records to an export file, compressing them if asked
+// to. Close must be called to flush the end of the file.
+type ExportWriter struct {
+	format     ExportFormat
+	topic      string
+	out        *countingWriter
+	compressor io.WriteCloser // nil without compression
+	buf        *bufio.Writer
+	csv        *csv.Writer
+	started    bool // whether the file header was written
+	records    int64
+}
+
+// NewExportWriter creates a writer of an export of a topic to w
+func NewExportWriter(w io.Writer, topic string, format ExportFormat, compression ExportCompression) (*ExportWriter, error) {
+	switch format {
+	case ExportJSONL, ExportCSV, ExportBinary:
+	default:
+		return nil, fmt.Errorf("unknown export format %q, use jsonl, csv or binary", format)
+	}
+
+	ew := &ExportWriter{format: format, topic: topic, out: &countingWriter{w: w}}
+	var dst io.Writer = ew.out
+	switch compression {
+	case ExportUncompressed, "":
+	case ExportGzip:
+		ew.compressor = gzip.NewWriter(ew.out)
+		dst = ew.compressor
+	case ExportZstd:
+		enc, err := zstd.NewWriter(ew.out)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create zstd encoder: %w", err)
+		}
+		ew.compressor = enc
+		dst = enc
+	default:
+		return nil, fmt.Errorf("unknown export compression %q, use none, gzip or zstd", compression)
+	}
+	ew.buf = bufio.NewWriterSize(dst, 64<<10)
+	if format == ExportCSV {
+		ew.csv = csv.NewWriter(ew.buf)
+	}
+	return ew, nil
+}
+
+// Records returns the number of records written
+func (e *ExportWriter) Records() int64 {
+	return e.records
+}
+
+// Bytes returns the number of bytes written to the file so far, which lags
+// behind the records until Close
+func (e *ExportWriter) Bytes() int64 {
+	return e.out.n
+}
+
+// Write adds a record to the export
+func (e *ExportWriter) Write(msg *kafka.Message) error {
+	if err := e.start(); err != nil {
+		return err
+	}
+	var err error
+	switch e.format {
+	case ExportJSONL:
+		err = e.writeJSON(msg)
+	case ExportCSV:
+		err = e.writeCSV(msg)
+	case ExportBinary:
+		err = e.writeBinary(msg)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write offset %d of partition %d: %w", msg.Offset, msg.Partition, err)
+	}
+	e.records++
+	return nil
+}
+
+// Close writes the end of the export. It does not close the underlying
+// writer.
+func (e *ExportWriter) Close() error {
+	if err := e.start(); err != nil {
+		return err
+	}
+	if e.csv != nil {
+		e.csv.Flush()
+		if err := e.csv.Error(); err != nil {
+			return err
+		}
+	}
+	if err := e.buf.Flush(); err != nil {
+		return err
+	}
+	if e.compressor != nil {
+		return e.compressor.Close()
+	}
+	return nil
+}
+
+// start writes the header of the file before the first record
+func (e *ExportWriter) start() error {
+	if e.started {
+		return nil
+	}
+	e.started = true
+	switch e.format {
+	case ExportCSV:
+		return e.csv.Write(csvExportColumns)
+	case ExportBinary:
+		if len(e.topic) > math.MaxUint16 {
+			return fmt.Errorf("topic name too long")
+		}
+		e.buf.WriteString(binaryMagic)
+		e.buf.WriteByte(binaryVersion)
+		binary.Write(e.buf, binary.BigEndian, uint16(len(e.topic)))
+		_, err := e.buf.WriteString(e.topic)
+		return err
+	}
+	return nil
+}
+
+// writeJSON writes a record as a line of JSON
+func (e *ExportWriter) writeJSON(msg *kafka.Message) error {
+	rec := exportRecord{
+		Topic:     msg.Topic,
+		Partition: msg.Partition,
+		Offset:    msg.Offset,
+		Timestamp: msg.Timestamp.UTC(),
+		Headers:   exportHeaders(msg.Headers),
+	}
+	rec.Key, rec.KeyEncoding = exportBytes(msg.Key)
+	rec.Value, rec.ValueEncoding = exportBytes(msg.Value)
+
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	e.buf.Write(line)
+	return e.buf.WriteByte('\n')
+}
+
+// writeCSV writes a record as a CSV row
+func (e *ExportWriter) writeCSV(msg *kafka.Message) error {
+	key, keyEncoding := csvBytes(msg.Key)
+	value, valueEncoding := csvBytes(msg.Value)
+	headers := ""
+	if len(msg.Headers) > 0 {
+		data, err := json.Marshal(exportHeaders(msg.Headers))
+		if err != nil {
+			return err
+		}
+		headers = string(data)
+	}
+	return e.csv.Write([]string{
+		msg.Topic,
+		strconv.Itoa(msg.Partition),
+		strconv.FormatInt(msg.Offset, 10),
+		msg.Timestamp.UTC().Format(time.RFC3339Nano),
+		key, keyEncoding,
+		value, valueEncoding,
+		headers,
+	})
+}
+
+// writeBinary writes a record of a binary dump
+func (e *ExportWriter) writeBinary(msg *kafka.Message) error {
+	size := 4 + 8 + 8 + 4 + len(msg.Key) + 4 + len(msg.Value) + 4
+	for _, h := range msg.Headers {
+		if len(h.Key) > math.MaxUint16 {
+			return fmt.Errorf("header name too long")
+		}
+		size += 2 + len(h.Key) + 4 + len(h.Value)
+	}
+
+	record := make([]byte, 0, 4+size)
+	record = binary.BigEndian.AppendUint32(record, uint32(size))
+	record = binary.BigEndian.AppendUint32(record, uint32(int32(msg.Partition)))
+	record = binary.BigEndian.AppendUint64(record, uint64(msg.Offset))
+	record = binary.BigEndian.AppendUint64(record, uint64(msg.Timestamp.UnixMilli()))
+	record = appendBinaryBytes(record, msg.Key)
+	record = appendBinaryBytes(record, msg.Value)
+	record = binary.BigEndian.AppendUint32(record, uint32(len(msg.Headers)))
+	for _, h := range msg.Headers {
+		record = binary.BigEndian.AppendUint16(record, uint16(len(h.Key)))
+		record = append(record, h.Key...)
+		record = appendBinaryBytes(record, h.Value)
+	}
+	_, err := e.buf.Write(record)
+	return err
+}
+
+// appendBinaryBytes appends length-prefixed bytes, with a length of -1 for nil
+func appendBinaryBytes(b, data []byte) []byte {
+	if data == nil {
+		return binary.BigEndian.AppendUint32(b, uint32(0xffffffff))
+	}
+	b = binary.BigEndian.AppendUint32(b, uint32(len(data)))
+	return append(b, data...)
+}
+
+// exportBytes returns the text of a key or value with its encoding, empty
+// for text or base64 for other bytes, or nil for nil bytes
+func exportBytes(data []byte) (*string, string) {
+	if data == nil {
+		return nil, ""
+	}
+	if utf8.Valid(data) {
+		text := string(data)
+		return &text, ""
+	}
+	text := base64.StdEncoding.EncodeToString(data)
+	return &text, encodingBase64
+}
+
+// exportHeaders converts record headers for JSON Lines and CSV exports
+func exportHeaders(headers []kafka.Header) []exportHeader {
+	if len(headers) == 0 {
+		return nil
+	}
+	result := make([]exportHeader, len(headers))
+	for i, h := range headers {
+		result[i] = exportHeader{Key: h.Key}
+		result[i].Value, result[i].Encoding = exportBytes(h.Value)
+	}
+	return result
+}
+
+// csvBytes returns the text of a key or value with its encoding for a CSV
+// export, which marks nil bytes as null
+func csvBytes(data []byte) (string, string) {
+	text, encoding := exportBytes(data)
+	if text == nil {
+		return "", encodingNull
+	}
+	return *text, encoding
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// ExportMessages streams the records of a request's range that satisfy its
+// filter to an export, a partition after another in offset order, so that
+// exports do not need to fit in memory. A limit of 0 exports every match.
+// Progress, if not nil, is called after each batch. On cancellation it
+// returns the progress so far with the error; the records written are kept.
+func (a *App) ExportMessages(ctx context.Context, req SearchRequest, out *ExportWriter, progress func(SearchProgress)) (SearchProgress, error) {
+	return a.streamMatches(ctx, req, progress, func(matches []kafka.Message) error {
+		for i := range matches {
+			if err := out.Write(&matches[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ExportPage writes records already read, such as a browser page or search
+// results, to an export
+func ExportPage(out *ExportWriter, messages []kafka.Message) error {
+	for i := range messages {
+		if err := out.Write(&messages[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Compressed stream magic numbers
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// ExportReader reads back the records of a JSON Lines or binary export,
+// compressed or not, which it detects from the first bytes
+type ExportReader struct {
+	format     ExportFormat
+	topic      string // of a binary export
+	in         *bufio.Reader
+	lines      *bufio.Scanner
+	decompress io.Closer // nil without compression
+	record     int       // records read
+}
+
+// NewExportReader creates a reader of an export
+func NewExportReader(r io.Reader) (*ExportReader, error) {
+	er := &ExportReader{in: bufio.NewReaderSize(r, 64<<10)}
+	head, _ := er.in.Peek(len(zstdMagic))
+	switch {
+	case bytes.HasPrefix(head, gzipMagic):
+		zr, err := gzip.NewReader(er.in)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip stream: %w", err)
+		}
+		er.decompress = zr
+		er.in = bufio.NewReaderSize(zr, 64<<10)
+	case bytes.HasPrefix(head, zstdMagic):
+		zr, err := zstd.NewReader(er.in)
+		if err != nil {
+			return nil, fmt.Errorf("invalid zstd stream: %w", err)
+		}
+		er.decompress = zr.IOReadCloser()
+		er.in = bufio.NewReaderSize(zr, 64<<10)
+	}
+
+	head, _ = er.in.Peek(len(binaryMagic))
+	if string(head) != binaryMagic {
+		er.format = ExportJSONL
+		er.lines = bufio.NewScanner(er.in)
+		er.lines.Buffer(make([]byte, 64<<10), maxLineSize)
+		return er, nil
+	}
+
+	er.format = ExportBinary
+	header := make([]byte, len(binaryMagic)+3)
+	if _, err := io.ReadFull(er.in, header); err != nil {
+		return nil, fmt.Errorf("truncated binary export header: %w", err)
+	}
+	if version := header[len(binaryMagic)]; version != binaryVersion {
+		return nil, fmt.Errorf("unsupported binary export version %d", version)
+	}
+	topic := make([]byte, binary.BigEndian.Uint16(header[len(binaryMagic)+1:]))
+	if _, err := io.ReadFull(er.in, topic); err != nil {
+		return nil, fmt.Errorf("truncated binary export header: %w", err)
+	}
+	er.topic = string(topic)
+	return er, nil
+}
+
+// Format returns the format of the export
+func (r *ExportReader) Format() ExportFormat {
+	return r.format
+}
+
+// Topic returns the topic of a binary export, empty for JSON Lines where
+// each record names its topic
+func (r *ExportReader) Topic() string {
+	return r.topic
+}
+
+// Next reads the next record, or returns io.EOF at the end of the export
+func (r *ExportReader) Next() (kafka.Message, error) {
+	var msg kafka.Message
+	var err error
+	if r.format == ExportBinary {
+		msg, err = r.nextBinary()
+	} else {
+		msg, err = r.nextJSON()
+	}
+	if err != nil && err != io.EOF {
+		return msg, fmt.Errorf("record %d: %w", r.record+1, err)
+	}
+	if err == nil {
+		r.record++
+	}
+	return msg, err
+}
+
+// Close releases the decompressor. It does not close the underlying reader.
+func (r *ExportReader) Close() error {
+	if r.decompress != nil {
+		return r.decompress.Close()
+	}
+	return nil
+}
+
+// nextJSON reads a record of a JSON Lines export
+func (r *ExportReader) nextJSON() (kafka.Message, error) {
+	for r.lines.Scan() {
+		line := r.lines.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var rec exportRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return kafka.Message{}, fmt.Errorf("invalid JSON: %w", err)
+		}
+		msg := kafka.Message{Topic: rec.Topic, Partition: rec.Partition, Offset: rec.Offset, Timestamp: rec.Timestamp}
+		var err error
+		if msg.Key, err = importBytes(rec.Key, rec.KeyEncoding); err != nil {
+			return msg, fmt.Errorf("invalid key: %w", err)
+		}
+		if msg.Value, err = importBytes(rec.Value, rec.ValueEncoding); err != nil {
+			return msg, fmt.Errorf("invalid value: %w", err)
+		}
+		for _, h := range rec.Headers {
+			value, err := importBytes(h.Value, h.Encoding)
+			if err != nil {
+				return msg, fmt.Errorf("invalid header %s: %w", h.Key, err)
+			}
+			msg.Headers = append(msg.Headers, kafka.Header{Key: h.Key, Value: value})
+		}
+		return msg, nil
+	}
+	if err := r.lines.Err(); err != nil {
+		return kafka.Message{}, err
+	}
+	return kafka.Message{}, io.EOF
+}
+
+// importBytes decodes the text of a key or value with its encoding
+func importBytes(text *string, encoding string) ([]byte, error) {
+	if text == nil {
+		return nil, nil
+	}
+	switch encoding {
+	case "":
+		return []byte(*text), nil
+	case encodingBase64:
+		return base64.StdEncoding.DecodeString(*text)
+	}
+	return nil, fmt.Errorf("unknown encoding %q", encoding)
+}
+
+// nextBinary reads a record of a binary export
+func (r *ExportReader) nextBinary() (kafka.Message, error) {
+	msg := kafka.Message{Topic: r.topic}
+	var size [4]byte
+	if _, err := io.ReadFull(r.in, size[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return msg, fmt.Errorf("truncated record size")
+		}
+		return msg, err
+	}
+	// Records are bounded like the lines of a JSON Lines export
+	n := binary.BigEndian.Uint32(size[:])
+	if n > maxLineSize {
+		return msg, fmt.Errorf("record size %d exceeds %d bytes", n, maxLineSize)
+	}
+	record := make([]byte, n)
+	if _, err := io.ReadFull(r.in, record); err != nil {
+		return msg, fmt.Errorf("truncated record")
+	}
+
+	d := binaryDecoder{data: record}
+	msg.Partition = int(int32(d.uint32()))
+	msg.Offset = int64(d.uint64())
+	msg.Timestamp = time.UnixMilli(int64(d.uint64())).UTC()
+	msg.Key = d.bytes()
+	msg.Value = d.bytes()
+	for n := d.uint32(); n > 0 && d.err == nil; n-- {
+		key := string(d.next(int(d.uint16())))
+		msg.Headers = append(msg.Headers, kafka.Header{Key: key, Value: d.bytes()})
+	}
+	if d.err == nil && len(d.data) > 0 {
+		d.err = fmt.Errorf("%d bytes after the record", len(d.data))
+	}
+	return msg, d.err
+}
+
+// binaryDecoder reads the fields of a binary export record, keeping the
+// first error
+type binaryDecoder struct {
+	data []byte
+	err  error
+}
+
+// next returns the next n bytes of the record
+func (d *binaryDecoder) next(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n > len(d.data) {
+		d.err = fmt.Errorf("record too short")
+		return nil
+	}
+	b := d.data[:n:n]
+	d.data = d.data[n:]
+	return b
+}
+
+func (d *binaryDecoder) uint16() uint16 {
+	if b := d.next(2); b != nil {
+		return binary.BigEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (d *binaryDecoder) uint32() uint32 {
+	if b := d.next(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (d *binaryDecoder) uint64() uint64 {
+	if b := d.next(8); b != nil {
+		return binary.BigEndian.Uint64(b)
+	}
+	return 0
+}
+
+// bytes returns length-prefixed bytes, nil for a length of -1
+func (d *binaryDecoder) bytes() []byte {
+	n := int32(d.uint32())
+	if n < -1 && d.err == nil {
+		d.err = fmt.Errorf("invalid length %d", n)
+	}
+	if n < 0 || d.err != nil {
+		return nil
+	}
+	if b := d.next(int(n)); b != nil {
+		return b
+	}
+	return nil
+}
@@ -0,0 +1,663 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/cfk-dev/cfk/internal/kafka"
+)
+
+// Filter is a parsed record filter. Its expression combines predicates with
+// AND, OR, NOT and parentheses:
+//
+//	key == "o-1"                   key equality, also !=
+//	value contains "refund"        substring of the value
+//	key ~ "^o-[0-9]+$"             regular expression, also matches
+//	header.source == "web"         header equality, also contains and ~
+//	$.customer.id == 42            field of the value decoded as JSON
+//	$.items[*].price > 100         comparisons <, <=, > and >= are numeric
+//	$.discount exists              whether a field or header is present
+//	"o-1"                          key or value contains the text
+//
+// JSON paths select fields with .name, ["name"], [index], [*] and ..name for
+// a field at any depth. A predicate holds if any header or selected field
+// satisfies it.
+type Filter struct {
+	text     string
+	root     filterNode
+	jsonPath bool // whether values need to be decoded as JSON
+}
+
+// ParseFilter parses a filter expression
+func ParseFilter(text string) (*Filter, error) {
+	tokens, err := lexFilter(text)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty filter")
+	}
+	p := &filterParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q at position %d", p.tokens[p.pos].text, p.tokens[p.pos].pos+1)
+	}
+	return &Filter{text: text, root: root, jsonPath: p.jsonPath}, nil
+}
+
+// String returns the expression of the filter
+func (f *Filter) String() string {
+	return f.text
+}
+
+// Match reports whether a record satisfies the filter. The value is decoded
+// as JSON by decode, called at most once and only by JSON path predicates;
+// it returns false for values that are not JSON.
+func (f *Filter) Match(msg *kafka.Message, decode func() (interface{}, bool)) bool {
+	r := &filterRecord{msg: msg, decode: decode}
+	if !f.jsonPath {
+		r.decoded = true
+	}
+	return f.root.match(r)
+}
+
+// filterRecord is a record being matched, with its value decoded on demand
+type filterRecord struct {
+	msg     *kafka.Message
+	decode  func() (interface{}, bool)
+	decoded bool
+	doc     interface{}
+	isJSON  bool
+}
+
+// json returns the value decoded as JSON
+func (r *filterRecord) json() (interface{}, bool) {
+	if !r.decoded {
+		r.decoded = true
+		if r.decode != nil {
+			r.doc, r.isJSON = r.decode()
+		}
+	}
+	return r.doc, r.isJSON
+}
+
+// DecodeJSON decodes a JSON document for filters, keeping numbers exact
+func DecodeJSON(data []byte) (interface{}, bool) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil || dec.More() {
+		return nil, false
+	}
+	return doc, true
+}
+
+// filterNode is a node of a parsed filter expression
+type filterNode interface {
+	match(r *filterRecord) bool
+}
+
+type andNode struct{ left, right filterNode }
+
+func (n andNode) match(r *filterRecord) bool { return n.left.match(r) && n.right.match(r) }
+
+type orNode struct{ left, right filterNode }
+
+func (n orNode) match(r *filterRecord) bool { return n.left.match(r) || n.right.match(r) }
+
+type notNode struct{ node filterNode }
+
+func (n notNode) match(r *filterRecord) bool { return !n.node.match(r) }
+
+// filterField selects the values a predicate applies to
+type filterField struct {
+	name   string // key, value, header or any
+	header string
+	path   []pathSegment // of a JSON path
+}
+
+// values returns the selected values of a record
+func (f filterField) values(r *filterRecord) []interface{} {
+	switch f.name {
+	case "key", "value":
+		data := r.msg.Key
+		if f.name == "value" {
+			data = r.msg.Value
+		}
+		if data == nil {
+			return nil
+		}
+		return []interface{}{string(data)}
+	case "header":
+		var values []interface{}
+		for _, h := range r.msg.Headers {
+			if h.Key == f.header {
+				values = append(values, string(h.Value))
+			}
+		}
+		return values
+	case "any":
+		var values []interface{}
+		for _, data := range [][]byte{r.msg.Key, r.msg.Value} {
+			if data != nil {
+				values = append(values, string(data))
+			}
+		}
+		return values
+	}
+	doc, ok := r.json()
+	if !ok {
+		return nil
+	}
+	return evalPath(f.path, doc)
+}
+
+// predicateNode compares the values of a field with an operand
+type predicateNode struct {
+	field   filterField
+	op      string // ==, !=, <, <=, >, >=, contains, ~ or exists
+	operand string
+	number  float64
+	numeric bool // whether the operand is a number
+	re      *regexp.Regexp
+}
+
+func (n predicateNode) match(r *filterRecord) bool {
+	values := n.field.values(r)
+	if n.op == "exists" {
+		return len(values) > 0
+	}
+	for _, v := range values {
+		if n.matchValue(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchValue compares one value with the operand
+func (n predicateNode) matchValue(v interface{}) bool {
+	text := filterText(v)
+	switch n.op {
+	case "contains":
+		return strings.Contains(text, n.operand)
+	case "~":
+		return n.re.MatchString(text)
+	case "==", "!=":
+		equal := text == n.operand
+		if num, ok := v.(json.Number); ok && n.numeric {
+			f, err := num.Float64()
+			equal = err == nil && f == n.number
+		}
+		return equal == (n.op == "==")
+	}
+
+	// Order numbers numerically and anything else as text
+	cmp := strings.Compare(text, n.operand)
+	if f, err := strconv.ParseFloat(text, 64); err == nil && n.numeric {
+		switch {
+		case f < n.number:
+			cmp = -1
+		case f > n.number:
+			cmp = 1
+		default:
+			cmp = 0
+		}
+	}
+	switch n.op {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	default:
+		return cmp >= 0
+	}
+}
+
+// filterText renders a value for comparisons: strings as is and anything
+// else as compact JSON
+func filterText(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+// pathSegment is a step of a JSON path
+type pathSegment struct {
+	name      string // field name, or "" for an index
+	index     int
+	wildcard  bool
+	recursive bool // search the field at any depth
+}
+
+// parsePath parses a JSON path starting with $
+func parsePath(path string) ([]pathSegment, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("JSON path %q must start with $", path)
+	}
+	var segments []pathSegment
+	rest := path[1:]
+	for rest != "" {
+		var seg pathSegment
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			seg.recursive = true
+			rest = rest[2:]
+			seg.name, rest = pathName(rest)
+		case rest[0] == '.':
+			seg.name, rest = pathName(rest[1:])
+		case rest[0] == '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed [ in JSON path %q", path)
+			}
+			inner := strings.TrimSpace(rest[1:end])
+			rest = rest[end+1:]
+			switch {
+			case inner == "*":
+				seg.wildcard = true
+			case strings.HasPrefix(inner, `"`) || strings.HasPrefix(inner, "'"):
+				if len(inner) < 2 || inner[len(inner)-1] != inner[0] {
+					return nil, fmt.Errorf("invalid field %s in JSON path %q", inner, path)
+				}
+				seg.name = inner[1 : len(inner)-1]
+			default:
+				i, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("invalid index %q in JSON path %q", inner, path)
+				}
+				seg.index = i
+			}
+			segments = append(segments, seg)
+			continue
+		default:
+			return nil, fmt.Errorf("invalid JSON path %q", path)
+		}
+		if seg.name == "" {
+			return nil, fmt.Errorf("missing field name in JSON path %q", path)
+		}
+		if seg.name == "*" {
+			seg.name, seg.wildcard = "", true
+		}
+		segments = append(segments, seg)
+	}
+	return segments, nil
+}
+
+// pathName splits the field name at the start of the rest of a path
+func pathName(rest string) (string, string) {
+	end := strings.IndexAny(rest, ".[")
+	if end < 0 {
+		return rest, ""
+	}
+	return rest[:end], rest[end:]
+}
+
+// evalPath returns the values a JSON path selects in a document
+func evalPath(path []pathSegment, doc interface{}) []interface{} {
+	values := []interface{}{doc}
+	for _, seg := range path {
+		var next []interface{}
+		for _, v := range values {
+			candidates := []interface{}{v}
+			if seg.recursive {
+				candidates = descendants(v, nil)
+			}
+			for _, c := range candidates {
+				next = append(next, selectChildren(seg, c)...)
+			}
+		}
+		values = next
+	}
+	return values
+}
+
+// selectChildren applies one path segment to a value
+func selectChildren(seg pathSegment, v interface{}) []interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if seg.wildcard {
+			children := make([]interface{}, 0, len(v))
+			for _, child := range v {
+				children = append(children, child)
+			}
+			return children
+		}
+		if child, ok := v[seg.name]; ok && seg.name != "" {
+			return []interface{}{child}
+		}
+	case []interface{}:
+		if seg.wildcard {
+			return v
+		}
+		if seg.name == "" {
+			i := seg.index
+			if i < 0 {
+				i += len(v)
+			}
+			if i >= 0 && i < len(v) {
+				return []interface{}{v[i]}
+			}
+		}
+	}
+	return nil
+}
+
+// descendants appends a value and every value nested in it
+func descendants(v interface{}, all []interface{}) []interface{} {
+	all = append(all, v)
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for _, child := range v {
+			all = descendants(child, all)
+		}
+	case []interface{}:
+		for _, child := range v {
+			all = descendants(child, all)
+		}
+	}
+	return all
+}
+
+// filterToken is a token of a filter expression
+type filterToken struct {
+	text   string
+	quoted bool // a string literal
+	op     bool // an operator or parenthesis
+	pos    int
+}
+
+// filterOperators are the symbolic operators, longest first
+var filterOperators = []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "=", "~", "!", "(", ")"}
+
+// lexFilter splits a filter expression into tokens
+func lexFilter(text string) ([]filterToken, error) {
+	var tokens []filterToken
+	i := 0
+	for i < len(text) {
+		c := text[i]
+		if unicode.IsSpace(rune(c)) {
+			i++
+			continue
+		}
+		if c == '"' || c == '\'' {
+			end, err := quoteEnd(text, i)
+			if err != nil {
+				return nil, err
+			}
+			value, err := unquote(text[i:end])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string at position %d: %w", i+1, err)
+			}
+			tokens = append(tokens, filterToken{text: value, quoted: true, pos: i})
+			i = end
+			continue
+		}
+		if op := operatorAt(text, i); op != "" {
+			tokens = append(tokens, filterToken{text: op, op: true, pos: i})
+			i += len(op)
+			continue
+		}
+
+		// A word runs to the next space or operator, except inside the
+		// brackets of a JSON path or header name
+		start, depth := i, 0
+		for i < len(text) {
+			c := text[i]
+			if depth == 0 && (unicode.IsSpace(rune(c)) || operatorAt(text, i) != "") {
+				break
+			}
+			switch c {
+			case '[':
+				depth++
+			case ']':
+				depth--
+			case '"', '\'':
+				if depth > 0 {
+					end, err := quoteEnd(text, i)
+					if err != nil {
+						return nil, err
+					}
+					i = end
+					continue
+				}
+			}
+			i++
+		}
+		tokens = append(tokens, filterToken{text: text[start:i], pos: start})
+	}
+	return tokens, nil
+}
+
+// operatorAt returns the operator starting at a position, if any
+func operatorAt(text string, i int) string {
+	for _, op := range filterOperators {
+		if strings.HasPrefix(text[i:], op) {
+			return op
+		}
+	}
+	return ""
+}
+
+// quoteEnd returns the position after the string literal starting at i
+func quoteEnd(text string, i int) (int, error) {
+	quote := text[i]
+	for j := i + 1; j < len(text); j++ {
+		switch text[j] {
+		case '\\':
+			j++
+		case quote:
+			return j + 1, nil
+		}
+	}
+	return 0, fmt.Errorf("unterminated string at position %d", i+1)
+}
+
+// unquote decodes a string literal in double or single quotes
+func unquote(literal string) (string, error) {
+	if literal[0] == '\'' {
+		literal = `"` + strings.ReplaceAll(strings.ReplaceAll(literal[1:len(literal)-1], `\'`, `'`), `"`, `\"`) + `"`
+	}
+	return strconv.Unquote(literal)
+}
+
+// filterParser parses filter tokens by recursive descent
+type filterParser struct {
+	tokens   []filterToken
+	pos      int
+	jsonPath bool
+}
+
+// peek returns the next token, or nil at the end
+func (p *filterParser) peek() *filterToken {
+	if p.pos < len(p.tokens) {
+		return &p.tokens[p.pos]
+	}
+	return nil
+}
+
+// keyword reports whether the next token is one of a few keywords or
+// operators, consuming it if so
+func (p *filterParser) keyword(words ...string) bool {
+	t := p.peek()
+	if t == nil || t.quoted {
+		return false
+	}
+	for _, w := range words {
+		if strings.EqualFold(t.text, w) {
+			p.pos++
+			return true
+		}
+	}
+	return false
+}
+
+func (p *filterParser) parseOr() (filterNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or", "||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (filterNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("and", "&&") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseUnary() (filterNode, error) {
+	if p.keyword("not", "!") {
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{node}, nil
+	}
+	if p.keyword("(") {
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.keyword(")") {
+			return nil, fmt.Errorf("missing )")
+		}
+		return node, nil
+	}
+	return p.parsePredicate()
+}
+
+// parsePredicate parses a field, an operator and an operand, or a lone text
+func (p *filterParser) parsePredicate() (filterNode, error) {
+	t := p.peek()
+	if t == nil {
+		return nil, fmt.Errorf("unexpected end of filter")
+	}
+	if t.op {
+		return nil, fmt.Errorf("unexpected %q at position %d", t.text, t.pos+1)
+	}
+	p.pos++
+
+	field, ok, err := p.parseField(t)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		if next := p.peek(); next != nil && !t.quoted && isComparison(next) {
+			return nil, fmt.Errorf("unknown field %q, use key, value, header.<name> or a JSON path starting with $", t.text)
+		}
+		// A lone text searches the key and the value
+		return predicateNode{field: filterField{name: "any"}, op: "contains", operand: t.text}, nil
+	}
+
+	opToken := p.peek()
+	if opToken == nil {
+		return nil, fmt.Errorf("missing operator after %s", t.text)
+	}
+	op := strings.ToLower(opToken.text)
+	switch {
+	case opToken.quoted:
+		return nil, fmt.Errorf("missing operator after %s", t.text)
+	case op == "=":
+		op = "=="
+	case op == "matches":
+		op = "~"
+	case op == "exists":
+		p.pos++
+		return predicateNode{field: field, op: op}, nil
+	}
+	switch op {
+	case "==", "!=", "<", "<=", ">", ">=", "~", "contains":
+	default:
+		return nil, fmt.Errorf("unknown operator %q after %s", opToken.text, t.text)
+	}
+	p.pos++
+
+	operand := p.peek()
+	if operand == nil || operand.op {
+		return nil, fmt.Errorf("missing value after %s %s", t.text, opToken.text)
+	}
+	p.pos++
+	n := predicateNode{field: field, op: op, operand: operand.text}
+	if f, err := strconv.ParseFloat(operand.text, 64); err == nil {
+		n.number, n.numeric = f, true
+	}
+	if op == "~" {
+		if n.re, err = regexp.Compile(operand.text); err != nil {
+			return nil, fmt.Errorf("invalid regular expression %q: %w", operand.text, err)
+		}
+	}
+	return n, nil
+}
+
+// isComparison reports whether a token is the operator of a predicate
+func isComparison(t *filterToken) bool {
+	if t.quoted {
+		return false
+	}
+	switch strings.ToLower(t.text) {
+	case "==", "!=", "<", "<=", ">", ">=", "=", "~", "contains", "matches", "exists":
+		return true
+	}
+	return false
+}
+
+// parseField parses the field of a predicate, reporting false for a word
+// that names no field
+func (p *filterParser) parseField(t *filterToken) (filterField, bool, error) {
+	if t.quoted {
+		return filterField{}, false, nil
+	}
+	word := t.text
+	switch {
+	case strings.EqualFold(word, "key"), strings.EqualFold(word, "value"):
+		return filterField{name: strings.ToLower(word)}, true, nil
+	case strings.HasPrefix(word, "$"):
+		path, err := parsePath(word)
+		if err != nil {
+			return filterField{}, false, err
+		}
+		p.jsonPath = true
+		return filterField{name: "json", path: path}, true, nil
+	case strings.HasPrefix(word, "header.") && len(word) > len("header."):
+		return filterField{name: "header", header: word[len("header."):]}, true, nil
+	case strings.HasPrefix(word, "header[") && strings.HasSuffix(word, "]"):
+		name, err := unquote(word[len("header[") : len(word)-1])
+		if err != nil {
+			return filterField{}, false, fmt.Errorf("invalid header name in %s", word)
+		}
+		return filterField{name: "header", header: name}, true, nil
+	}
+	return filterField{}, false, nil
+}
@@ -0,0 +1,50 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/cfk-dev/cfk/internal/glob"
+	"github.com/cfk-dev/cfk/internal/kafka"
+)
+
+// DeleteGroups deletes consumer groups with their committed offsets. The
+// result maps every group to the error deleting it, nil if it was deleted.
+func (a *App) DeleteGroups(ctx context.Context, groupIDs []string) (map[string]error, error) {
+	if a.KafkaClient == nil {
+		return nil, fmt.Errorf("not connected to any Kafka cluster")
+	}
+
+	return a.KafkaClient.DeleteGroups(ctx, groupIDs)
+}
+
+// DeleteGroupOffsets removes the committed offsets of a group for a topic,
+// leaving the group and its offsets on other topics in place
+func (a *App) DeleteGroupOffsets(ctx context.Context, groupID, topicName string) error {
+	if a.KafkaClient == nil {
+		return fmt.Errorf("not connected to any Kafka cluster")
+	}
+
+	return a.KafkaClient.DeleteGroupOffsets(ctx, groupID, topicName, nil)
+}
+
+// MatchEmptyGroups returns the IDs of the Empty groups matching a glob
+// pattern, with the syntax of the topic patterns of serde rules
+func MatchEmptyGroups(groups []kafka.GroupInfo, pattern string) ([]string, error) {
+	if strings.TrimSpace(pattern) == "" {
+		return nil, fmt.Errorf("empty group pattern")
+	}
+	p, err := glob.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	var matched []string
+	for _, g := range groups {
+		if g.State == "Empty" && p.Match(g.GroupID) {
+			matched = append(matched, g.GroupID)
+		}
+	}
+	return matched, nil
+}
@@ -0,0 +1,276 @@
+package core
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/cfk-dev/cfk/internal/config"
+	"github.com/cfk-dev/cfk/internal/kafka"
+)
+
+// ImportRequest describes where to write the records of an export. An empty
+// cluster name stands for the connected cluster.
+type ImportRequest struct {
+	TargetCluster  string
+	Topic          string
+	KeepPartitions bool // write records to their original partition instead of one picked from the key
+	// Dedupe skips the records that a previous run with dedupe of the same
+	// ImportID already wrote to the topic, going by the checkpoints the runs
+	// keep in the config directory. Records are written unchanged.
+	Dedupe    bool
+	ImportID  string // identifies the export, see ExportFileID
+	Produce   kafka.ProduceOptions
+	BatchSize int // records per produce request, DefaultBatchSize when 0
+}
+
+// ImportProgress counts the records of an import so far
+type ImportProgress struct {
+	Read    int64
+	Written int64
+	Skipped int64 // already written by a previous run
+	Elapsed time.Duration
+}
+
+// ImportMessages writes the records of an export to a topic, of the same
+// cluster or of another one of the configuration, keeping their keys,
+// values, headers and timestamps. Records without a key are spread over the
+// partitions in turn unless partitions are kept. It stops at the first record
+// it fails to write. Progress, if not nil, is called after each batch. On
+// cancellation it returns the progress so far with the error.
+func (a *App) ImportMessages(ctx context.Context, input *ExportReader, req ImportRequest, progress func(ImportProgress)) (ImportProgress, error) {
+	var result ImportProgress
+	if req.Topic == "" {
+		return result, fmt.Errorf("the target topic is required")
+	}
+	if req.Dedupe && req.ImportID == "" {
+		return result, fmt.Errorf("an import ID is required for dedupe")
+	}
+	if err := req.Produce.Validate(); err != nil {
+		return result, err
+	}
+	target, closeTarget, err := a.clusterApp(ctx, req.TargetCluster)
+	if err != nil {
+		return result, err
+	}
+	defer closeTarget()
+
+	offsets, err := target.ListOffsets(ctx, req.Topic)
+	if err != nil {
+		return result, fmt.Errorf("failed to read target topic %s: %w", req.Topic, err)
+	}
+	partitions := len(offsets)
+	if partitions == 0 {
+		return result, fmt.Errorf("target topic %s has no partitions", req.Topic)
+	}
+	// The last record a previous run wrote to each partition
+	written := make(importCheckpoint)
+	var checkpoint string
+	if req.Dedupe {
+		checkpoint = importCheckpointKey(req.ImportID, target.cluster, req.Topic)
+		if written, err = a.loadImportCheckpoint(checkpoint); err != nil {
+			return result, err
+		}
+	}
+	batchSize := req.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+
+	start := time.Now()
+	report := func() {
+		result.Elapsed = time.Since(start)
+		if progress != nil {
+			progress(result)
+		}
+	}
+	var batch []kafka.Message
+	var seqs []int64 // of the records of the batch
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		results, err := target.KafkaClient.ProduceMessages(ctx, req.Topic, batch, req.Produce)
+		if err != nil {
+			return err
+		}
+		var failed error
+		broken := make(map[int]bool) // partitions a record failed to go to
+		for i, r := range results {
+			p := batch[i].Partition
+			if r.Err != nil {
+				if failed == nil {
+					failed = fmt.Errorf("failed to import record %d: %w", seqs[i], r.Err)
+				}
+				broken[p] = true
+				continue
+			}
+			result.Written++
+			if !broken[p] {
+				written[p] = seqs[i]
+			}
+		}
+		if req.Dedupe {
+			if err := a.saveImportCheckpoint(checkpoint, written); err != nil {
+				return err
+			}
+		}
+		if failed != nil {
+			return failed
+		}
+		batch, seqs = batch[:0], seqs[:0]
+		report()
+		return ctx.Err()
+	}
+
+	for {
+		msg, err := input.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			report()
+			return result, err
+		}
+		result.Read++
+
+		seq := result.Read
+		imported := kafka.Message{Key: msg.Key, Value: msg.Value, Timestamp: msg.Timestamp, Headers: msg.Headers}
+		switch {
+		case req.KeepPartitions:
+			if msg.Partition < 0 || msg.Partition >= partitions {
+				report()
+				return result, fmt.Errorf("record %d: target topic %s has no partition %d", seq, req.Topic, msg.Partition)
+			}
+			imported.Partition = msg.Partition
+		case msg.Key == nil:
+			// Known partitions let a resumed run tell where records went
+			imported.Partition = int(seq % int64(partitions))
+		default:
+			imported.Partition = kafka.PartitionForKey(msg.Key, partitions)
+		}
+		if req.Dedupe {
+			if last, ok := written[imported.Partition]; ok && seq <= last {
+				result.Skipped++
+				continue
+			}
+		}
+
+		batch = append(batch, imported)
+		seqs = append(seqs, seq)
+		if len(batch) >= batchSize {
+			if err := flush(); err != nil {
+				report()
+				return result, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		report()
+		return result, err
+	}
+	report()
+	return result, nil
+}
+
+// importCheckpoint holds the number, in the order of the export, of the last
+// record an import with dedupe wrote to each partition of its topic
+type importCheckpoint map[int]int64
+
+// importCheckpointKey names the checkpoint of the import of an export to a
+// topic of a cluster
+func importCheckpointKey(importID, cluster, topic string) string {
+	sum := sha256.Sum256([]byte(importID + "\x00" + cluster + "\x00" + topic))
+	return hex.EncodeToString(sum[:16])
+}
+
+// importCheckpointPath returns the file of a checkpoint in the config directory
+func importCheckpointPath(key string) (string, error) {
+	dir, err := config.GetConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get config directory: %w", err)
+	}
+	return filepath.Join(dir, "imports", key+".json"), nil
+}
+
+// loadImportCheckpoint reads the checkpoint of previous runs of an import,
+// empty if there were none
+func (a *App) loadImportCheckpoint(key string) (importCheckpoint, error) {
+	checkpoint := make(importCheckpoint)
+	if a.Ephemeral {
+		a.importsMu.Lock()
+		defer a.importsMu.Unlock()
+		for p, seq := range a.imports[key] {
+			checkpoint[p] = seq
+		}
+		return checkpoint, nil
+	}
+
+	path, err := importCheckpointPath(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return checkpoint, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read import checkpoint: %w", err)
+	}
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return nil, fmt.Errorf("failed to read import checkpoint %s: %w", path, err)
+	}
+	return checkpoint, nil
+}
+
+// saveImportCheckpoint records the progress of an import, in memory when
+// ephemeral
+func (a *App) saveImportCheckpoint(key string, checkpoint importCheckpoint) error {
+	if a.Ephemeral {
+		a.importsMu.Lock()
+		defer a.importsMu.Unlock()
+		if a.imports == nil {
+			a.imports = make(map[string]importCheckpoint)
+		}
+		saved := make(importCheckpoint, len(checkpoint))
+		for p, seq := range checkpoint {
+			saved[p] = seq
+		}
+		a.imports[key] = saved
+		return nil
+	}
+
+	path, err := importCheckpointPath(key)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to save import checkpoint: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to save import checkpoint: %w", err)
+	}
+	return nil
+}
+
+// ExportFileID identifies an export for an import with dedupe by a hash of
+// its contents
+func ExportFileID(r io.Reader) (string, error) {
+	hash := sha256.New()
+	if _, err := io.Copy(hash, r); err != nil {
+		return "", fmt.Errorf("failed to read export: %w", err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)[:16]), nil
+}
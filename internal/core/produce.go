@@ -0,0 +1,87 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/cfk-dev/cfk/internal/kafka"
+)
+
+// Serializer turns the text of a produced value into bytes
+type Serializer string
+
+// Value serializers
+const (
+	SerializeString Serializer = "string" // the text as is
+	SerializeJSON   Serializer = "json"   // the text, once validated as JSON
+	SerializeAvro   Serializer = "avro"   // JSON text encoded with the latest schema of the <topic>-value subject
+)
+
+// Serializers lists the value serializers, the default first
+var Serializers = []Serializer{SerializeString, SerializeJSON, SerializeAvro}
+
+// ProduceRequest is a record to write, with its key and value as text. An
+// empty key writes a record without a key.
+type ProduceRequest struct {
+	Topic      string
+	Partition  int // a partition, or -1 to pick one from the key
+	Key        string
+	Value      string
+	Headers    []kafka.Header
+	Serializer Serializer
+	Options    kafka.ProduceOptions
+}
+
+// Produce serializes the value of a record and writes it, returning the
+// record with the partition and offset the broker assigned
+func (a *App) Produce(ctx context.Context, req ProduceRequest) (*kafka.Message, error) {
+	if a.KafkaClient == nil {
+		return nil, fmt.Errorf("not connected to any Kafka cluster")
+	}
+
+	serialize, err := a.valueSerializer(ctx, req.Topic, req.Serializer)
+	if err != nil {
+		return nil, err
+	}
+	value, err := serialize(req.Value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value: %w", err)
+	}
+	msg := kafka.Message{Partition: req.Partition, Value: value, Headers: req.Headers}
+	if req.Key != "" {
+		msg.Key = []byte(req.Key)
+	}
+
+	results, err := a.KafkaClient.ProduceMessages(ctx, req.Topic, []kafka.Message{msg}, req.Options)
+	if err != nil {
+		return nil, err
+	}
+	if results[0].Err != nil {
+		return nil, results[0].Err
+	}
+	return &results[0].Message, nil
+}
+
+// valueSerializer returns the function serializing the values of a topic
+func (a *App) valueSerializer(ctx context.Context, topic string, serializer Serializer) (func(string) ([]byte, error), error) {
+	switch serializer {
+	case "", SerializeString:
+		return func(text string) ([]byte, error) { return []byte(text), nil }, nil
+	case SerializeJSON:
+		return func(text string) ([]byte, error) {
+			var v interface{}
+			if err := json.Unmarshal([]byte(text), &v); err != nil {
+				return nil, fmt.Errorf("not valid JSON: %w", err)
+			}
+			return []byte(text), nil
+		}, nil
+	case SerializeAvro:
+		registry, err := a.schemaRegistry()
+		if err != nil {
+			return nil, err
+		}
+		return registry.AvroSerializer(ctx, topic+"-value")
+	}
+	return nil, fmt.Errorf("unknown serializer %q", serializer)
+}
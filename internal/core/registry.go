@@ -0,0 +1,190 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/cfk-dev/cfk/internal/schemaregistry"
+)
+
+// SubjectDetails holds the live versions and the compatibility level of a
+// Schema Registry subject
+type SubjectDetails struct {
+	Subject       string
+	Compatibility string
+	Inherited     bool                     // the subject has no level of its own, the global level applies
+	Versions      []*schemaregistry.Schema // oldest first
+	Deleted       []int                    // soft-deleted versions, which can still be deleted for good
+}
+
+// DiffLine is a line of a line-by-line diff
+type DiffLine struct {
+	Op   byte // ' ' for a common line, '-' for a removed line, '+' for an added line
+	Text string
+}
+
+// schemaRegistry returns the registry of the connected cluster
+func (a *App) schemaRegistry() (*schemaregistry.Client, error) {
+	if a.KafkaClient == nil {
+		return nil, fmt.Errorf("not connected to any Kafka cluster")
+	}
+	if a.SchemaRegistry == nil {
+		return nil, fmt.Errorf("no schema registry configured for this cluster")
+	}
+	return a.SchemaRegistry, nil
+}
+
+// ListSubjects lists the subjects of the registry
+func (a *App) ListSubjects(ctx context.Context) ([]string, error) {
+	registry, err := a.schemaRegistry()
+	if err != nil {
+		return nil, err
+	}
+	return registry.Subjects(ctx)
+}
+
+// DescribeSubject gets the schemas of the live versions and the
+// compatibility level of a subject
+func (a *App) DescribeSubject(ctx context.Context, subject string) (*SubjectDetails, error) {
+	registry, err := a.schemaRegistry()
+	if err != nil {
+		return nil, err
+	}
+
+	versions, err := registry.Versions(ctx, subject)
+	if err != nil {
+		return nil, err
+	}
+	details := &SubjectDetails{Subject: subject}
+	for _, v := range versions {
+		if v.Deleted {
+			details.Deleted = append(details.Deleted, v.Version)
+			continue
+		}
+		schema, err := registry.SubjectVersion(ctx, subject, v.Version)
+		if err != nil {
+			return nil, err
+		}
+		details.Versions = append(details.Versions, schema)
+	}
+
+	details.Compatibility, details.Inherited, err = registry.Compatibility(ctx, subject)
+	if err != nil {
+		return nil, err
+	}
+	return details, nil
+}
+
+// SetSubjectCompatibility sets the compatibility level of a subject
+func (a *App) SetSubjectCompatibility(ctx context.Context, subject, level string) error {
+	registry, err := a.schemaRegistry()
+	if err != nil {
+		return err
+	}
+	return registry.SetCompatibility(ctx, subject, level)
+}
+
+// TestSchemaCompatibility tests whether a candidate schema could be
+// registered as the next version of a subject
+func (a *App) TestSchemaCompatibility(ctx context.Context, subject, schemaType, text string) (*schemaregistry.CompatibilityResult, error) {
+	registry, err := a.schemaRegistry()
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(text) == "" {
+		return nil, fmt.Errorf("empty schema")
+	}
+	return registry.TestCompatibility(ctx, subject, schemaregistry.Schema{Type: schemaType, Schema: text})
+}
+
+// DeleteSchemaVersion soft-deletes a live version of a subject and, when
+// permanent, then deletes it for good, which frees its schema ID. A version
+// that is already soft-deleted is deleted for good straight away.
+func (a *App) DeleteSchemaVersion(ctx context.Context, subject string, version int, permanent bool) error {
+	registry, err := a.schemaRegistry()
+	if err != nil {
+		return err
+	}
+	versions, err := registry.Versions(ctx, subject)
+	if err != nil {
+		return err
+	}
+	softDeleted := false
+	for _, v := range versions {
+		if v.Version == version {
+			softDeleted = v.Deleted
+		}
+	}
+	if !softDeleted {
+		if err := registry.DeleteVersion(ctx, subject, version, false); err != nil {
+			return err
+		}
+	}
+	if permanent {
+		return registry.DeleteVersion(ctx, subject, version, true)
+	}
+	return nil
+}
+
+// FormatSchema returns the text of a schema for display: Avro and JSON
+// schemas pretty-printed, Protobuf schemas as registered
+func FormatSchema(schema *schemaregistry.Schema) string {
+	if schema.SchemaType() == schemaregistry.TypeProtobuf {
+		return strings.TrimRight(schema.Schema, "\n")
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, []byte(schema.Schema), "", "  "); err != nil {
+		return schema.Schema
+	}
+	return out.String()
+}
+
+// DiffSchemas compares the display text of two schemas line by line
+func DiffSchemas(old, new *schemaregistry.Schema) []DiffLine {
+	return diffLines(strings.Split(FormatSchema(old), "\n"), strings.Split(FormatSchema(new), "\n"))
+}
+
+// diffLines computes a line diff from the longest common subsequence
+func diffLines(a, b []string) []DiffLine {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var diff []DiffLine
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			diff = append(diff, DiffLine{Op: ' ', Text: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			diff = append(diff, DiffLine{Op: '-', Text: a[i]})
+			i++
+		default:
+			diff = append(diff, DiffLine{Op: '+', Text: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		diff = append(diff, DiffLine{Op: '-', Text: a[i]})
+	}
+	for ; j < len(b); j++ {
+		diff = append(diff, DiffLine{Op: '+', Text: b[j]})
+	}
+	return diff
+}
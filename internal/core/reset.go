@@ -0,0 +1,267 @@
+package core
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cfk-dev/cfk/internal/kafka"
+)
+
+// ResetStrategy is how an offset reset picks the new offsets, named after
+// the options of kafka-consumer-groups.sh
+type ResetStrategy string
+
+// Offset reset strategies
+const (
+	ResetToEarliest ResetStrategy = "to-earliest"
+	ResetToLatest   ResetStrategy = "to-latest"
+	ResetToOffset   ResetStrategy = "to-offset"
+	ResetToDatetime ResetStrategy = "to-datetime"
+	ResetShiftBy    ResetStrategy = "shift-by"
+	ResetFromFile   ResetStrategy = "from-file"
+)
+
+// ResetStrategies lists the offset reset strategies
+var ResetStrategies = []ResetStrategy{
+	ResetToEarliest, ResetToLatest, ResetToOffset, ResetToDatetime, ResetShiftBy, ResetFromFile,
+}
+
+// OffsetReset describes an offset reset of a consumer group. Without a topic
+// it covers every partition the group has committed offsets for.
+type OffsetReset struct {
+	Strategy   ResetStrategy
+	Topic      string              // only reset this topic, if set
+	Partitions []int               // only reset these partitions of Topic, if set
+	Offset     int64               // target of to-offset
+	Shift      int64               // offset delta of shift-by, negative to rewind
+	Datetime   time.Time           // target of to-datetime
+	Offsets    []kafka.GroupOffset // targets of from-file
+}
+
+// OffsetChange is a planned change of a committed offset
+type OffsetChange struct {
+	Topic     string
+	Partition int
+	Current   int64 // -1 if the group has not committed an offset
+	New       int64
+}
+
+// PlanOffsetReset works out the new offsets of a reset without applying it.
+// New offsets are kept within the earliest and latest offset of each partition.
+func (a *App) PlanOffsetReset(ctx context.Context, groupID string, reset OffsetReset) ([]OffsetChange, error) {
+	if a.KafkaClient == nil {
+		return nil, fmt.Errorf("not connected to any Kafka cluster")
+	}
+
+	group, err := a.KafkaClient.DescribeGroup(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	current := make(map[string]map[int]int64)
+	for _, o := range group.Offsets {
+		if current[o.Topic] == nil {
+			current[o.Topic] = make(map[int]int64)
+		}
+		current[o.Topic][o.Partition] = o.CommittedOffset
+	}
+
+	// The partitions to reset, by topic
+	targets := make(map[string][]int)
+	switch {
+	case reset.Strategy == ResetFromFile:
+		if len(reset.Offsets) == 0 {
+			return nil, fmt.Errorf("no offsets to reset to")
+		}
+		for _, o := range reset.Offsets {
+			targets[o.Topic] = append(targets[o.Topic], o.Partition)
+		}
+	case reset.Topic != "":
+		targets[reset.Topic] = reset.Partitions
+	default:
+		for topic, partitions := range current {
+			for p := range partitions {
+				targets[topic] = append(targets[topic], p)
+			}
+		}
+		if len(targets) == 0 {
+			return nil, fmt.Errorf("group %s has no committed offsets, choose a topic to reset", groupID)
+		}
+	}
+
+	var changes []OffsetChange
+	for topic, partitions := range targets {
+		topicChanges, err := a.planTopicReset(ctx, topic, partitions, current[topic], reset)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, topicChanges...)
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Topic != changes[j].Topic {
+			return changes[i].Topic < changes[j].Topic
+		}
+		return changes[i].Partition < changes[j].Partition
+	})
+
+	return changes, nil
+}
+
+// planTopicReset works out the new offsets of some partitions of a topic,
+// or of all of them if none are given
+func (a *App) planTopicReset(ctx context.Context, topic string, partitions []int, current map[int]int64, reset OffsetReset) ([]OffsetChange, error) {
+	offsets, err := a.KafkaClient.ListOffsets(ctx, topic)
+	if err != nil {
+		return nil, err
+	}
+	bounds := make(map[int]kafka.PartitionOffsets, len(offsets))
+	for _, o := range offsets {
+		bounds[o.Partition] = o
+	}
+	if len(partitions) == 0 {
+		for _, o := range offsets {
+			partitions = append(partitions, o.Partition)
+		}
+	}
+
+	var atTime map[int]int64
+	if reset.Strategy == ResetToDatetime {
+		if atTime, err = a.KafkaClient.OffsetsForTime(ctx, topic, reset.Datetime); err != nil {
+			return nil, err
+		}
+	}
+
+	fromFile := make(map[int]int64)
+	for _, o := range reset.Offsets {
+		if o.Topic == topic {
+			fromFile[o.Partition] = o.CommittedOffset
+		}
+	}
+
+	changes := make([]OffsetChange, 0, len(partitions))
+	for _, p := range partitions {
+		limits, ok := bounds[p]
+		if !ok {
+			return nil, fmt.Errorf("partition %d of topic %s not found", p, topic)
+		}
+
+		change := OffsetChange{Topic: topic, Partition: p, Current: -1}
+		if committed, ok := current[p]; ok {
+			change.Current = committed
+		}
+
+		switch reset.Strategy {
+		case ResetToEarliest:
+			change.New = limits.Earliest
+		case ResetToLatest:
+			change.New = limits.Latest
+		case ResetToOffset:
+			change.New = reset.Offset
+		case ResetToDatetime:
+			// Partitions without a record after the time move to the end
+			offset, ok := atTime[p]
+			if !ok || offset < 0 {
+				offset = limits.Latest
+			}
+			change.New = offset
+		case ResetShiftBy:
+			if change.Current < 0 {
+				return nil, fmt.Errorf("no committed offset to shift for %s/%d", topic, p)
+			}
+			change.New = change.Current + reset.Shift
+		case ResetFromFile:
+			change.New = fromFile[p]
+		default:
+			return nil, fmt.Errorf("unknown reset strategy %q", reset.Strategy)
+		}
+
+		if change.New < limits.Earliest {
+			change.New = limits.Earliest
+		}
+		if change.New > limits.Latest {
+			change.New = limits.Latest
+		}
+		changes = append(changes, change)
+	}
+
+	return changes, nil
+}
+
+// ApplyOffsetReset commits planned offsets for a group. It refuses unless the
+// group is Empty, because active members would overwrite the new offsets.
+func (a *App) ApplyOffsetReset(ctx context.Context, groupID string, changes []OffsetChange) error {
+	if a.KafkaClient == nil {
+		return fmt.Errorf("not connected to any Kafka cluster")
+	}
+
+	group, err := a.KafkaClient.DescribeGroup(ctx, groupID)
+	if err != nil {
+		return err
+	}
+	if group.State != "Empty" {
+		return fmt.Errorf("group %s is %s, offsets can only be reset while it is Empty", groupID, group.State)
+	}
+
+	offsets := make([]kafka.GroupOffset, len(changes))
+	for i, c := range changes {
+		offsets[i] = kafka.GroupOffset{Topic: c.Topic, Partition: c.Partition, CommittedOffset: c.New}
+	}
+	return a.KafkaClient.CommitGroupOffsets(ctx, groupID, offsets)
+}
+
+// ReadOffsetsFile reads offsets to reset to from a CSV file with
+// topic,partition,offset lines, the format kafka-consumer-groups.sh exports
+func ReadOffsetsFile(path string) ([]kafka.GroupOffset, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open offsets file: %w", err)
+	}
+	defer f.Close()
+
+	offsets, err := ParseOffsetsCSV(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read offsets file %s: %w", path, err)
+	}
+	return offsets, nil
+}
+
+// ParseOffsetsCSV parses topic,partition,offset lines
+func ParseOffsetsCSV(r io.Reader) ([]kafka.GroupOffset, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 3
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+
+	var offsets []kafka.GroupOffset
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		partition, err := strconv.Atoi(strings.TrimSpace(record[1]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid partition %q", record[1])
+		}
+		offset, err := strconv.ParseInt(strings.TrimSpace(record[2]), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid offset %q", record[2])
+		}
+		offsets = append(offsets, kafka.GroupOffset{
+			Topic:           strings.TrimSpace(record[0]),
+			Partition:       partition,
+			CommittedOffset: offset,
+		})
+	}
+
+	return offsets, nil
+}
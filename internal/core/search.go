@@ -0,0 +1,352 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cfk-dev/cfk/internal/kafka"
+)
+
+// Search limits
+const (
+	DefaultSearchLimit = 100 // matches returned when no limit is asked for
+	searchBatchSize    = 500 // records read per partition and fetch
+	maxSearchWorkers   = 8   // partitions scanned at the same time
+)
+
+// SearchRequest describes the records to scan and the filter they must
+// satisfy. The range applies to every scanned partition; time bounds take
+// precedence over offset bounds when set.
+type SearchRequest struct {
+	Topic      string
+	Partition  int           // a partition, or AllPartitions
+	FromOffset int64         // first offset to scan, clamped to each partition
+	Offsets    map[int]int64 // if set, the first offset of each partition in place of FromOffset
+	ToOffset   int64         // offset to stop before, or -1 for the end of each partition
+	FromTime   time.Time     // if set, the first record time to scan
+	ToTime     time.Time     // if set, the last record time to scan
+	Filter     *Filter       // nil matches every record
+	Limit      int           // matches to stop after, DefaultSearchLimit for searches and none for exports when 0
+}
+
+// SearchProgress counts the records of a search so far
+type SearchProgress struct {
+	Scanned    int64 // offsets of the range passed over
+	Total      int64 // offsets in the range
+	Matches    int
+	Partitions int // partitions in the range
+	Done       int // partitions scanned to the end of the range
+	Elapsed    time.Duration
+}
+
+// Fraction returns the scanned share of the range, from 0 to 1
+func (p SearchProgress) Fraction() float64 {
+	if p.Total <= 0 {
+		return 1
+	}
+	return float64(p.Scanned) / float64(p.Total)
+}
+
+// SearchResult is the outcome of a search
+type SearchResult struct {
+	SearchProgress
+	Messages []kafka.Message // matches ordered by timestamp
+	Limited  bool            // whether the search stopped at the limit
+}
+
+// searchRange is the offsets of a partition to scan
+type searchRange struct {
+	partition int
+	start     int64
+	end       int64
+}
+
+// SearchMessages scans the range of a request in parallel per partition and
+// returns the records satisfying its filter, stopping once the limit is
+// reached. Progress, if not nil, is called as partitions are read, never
+// concurrently. On cancellation it returns the matches so far with the error.
+func (a *App) SearchMessages(ctx context.Context, req SearchRequest, progress func(SearchProgress)) (*SearchResult, error) {
+	if a.KafkaClient == nil {
+		return nil, fmt.Errorf("not connected to any Kafka cluster")
+	}
+	limit := req.Limit
+	if limit <= 0 {
+		limit = DefaultSearchLimit
+	}
+	if !req.FromTime.IsZero() && !req.ToTime.IsZero() && req.ToTime.Before(req.FromTime) {
+		return nil, fmt.Errorf("the end of the time range is before its start")
+	}
+
+	ranges, err := a.searchRanges(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	result := &SearchResult{}
+	result.Partitions = len(ranges)
+	for _, r := range ranges {
+		result.Total += r.end - r.start
+	}
+
+	// Workers stop when the limit is reached or the caller cancels
+	scanCtx, stop := context.WithCancel(ctx)
+	defer stop()
+
+	var mu sync.Mutex
+	var firstErr error
+	record := func(scanned int64, matches []kafka.Message, done bool) error {
+		mu.Lock()
+		defer mu.Unlock()
+		result.Scanned += scanned
+		result.Messages = append(result.Messages, matches...)
+		result.Matches = len(result.Messages)
+		if done {
+			result.Done++
+		}
+		if result.Matches >= limit {
+			result.Limited = true
+			stop()
+		}
+		result.Elapsed = time.Since(start)
+		if progress != nil {
+			progress(result.SearchProgress)
+		}
+		return nil
+	}
+
+	decode := a.searchDecoder(ctx)
+	work := make(chan searchRange)
+	var wg sync.WaitGroup
+	for i := 0; i < min(maxSearchWorkers, len(ranges)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := range work {
+				if err := a.scanPartition(scanCtx, req, r, decode, record); err != nil && scanCtx.Err() == nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					mu.Unlock()
+					stop()
+				}
+			}
+		}()
+	}
+	for _, r := range ranges {
+		if scanCtx.Err() != nil {
+			break
+		}
+		work <- r
+	}
+	close(work)
+	wg.Wait()
+
+	sortMessages(result.Messages)
+	if len(result.Messages) > limit {
+		result.Messages = result.Messages[:limit]
+	}
+	result.Matches = len(result.Messages)
+	result.Elapsed = time.Since(start)
+	if firstErr != nil {
+		return result, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// errStreamLimit stops a stream of matches that reached its limit
+var errStreamLimit = errors.New("limit reached")
+
+// streamMatches passes the records of a request's range that satisfy its
+// filter to write in batches, a partition after another in offset order, up
+// to the limit of the request or every match when it is 0. Progress counts
+// the records written as matches.
+func (a *App) streamMatches(ctx context.Context, req SearchRequest, progress func(SearchProgress),
+	write func(matches []kafka.Message) error) (SearchProgress, error) {
+	var result SearchProgress
+	if a.KafkaClient == nil {
+		return result, fmt.Errorf("not connected to any Kafka cluster")
+	}
+	if !req.FromTime.IsZero() && !req.ToTime.IsZero() && req.ToTime.Before(req.FromTime) {
+		return result, fmt.Errorf("the end of the time range is before its start")
+	}
+
+	ranges, err := a.searchRanges(ctx, req)
+	if err != nil {
+		return result, err
+	}
+
+	start := time.Now()
+	result.Partitions = len(ranges)
+	for _, r := range ranges {
+		result.Total += r.end - r.start
+	}
+	record := func(scanned int64, matches []kafka.Message, done bool) error {
+		limited := false
+		if req.Limit > 0 && result.Matches+len(matches) >= req.Limit {
+			matches = matches[:req.Limit-result.Matches]
+			limited = true
+		}
+		if len(matches) > 0 {
+			if err := write(matches); err != nil {
+				return err
+			}
+		}
+		result.Scanned += scanned
+		result.Matches += len(matches)
+		if done {
+			result.Done++
+		}
+		result.Elapsed = time.Since(start)
+		if progress != nil {
+			progress(result)
+		}
+		if limited {
+			return errStreamLimit
+		}
+		return nil
+	}
+
+	decode := a.searchDecoder(ctx)
+	for _, r := range ranges {
+		err := a.scanPartition(ctx, req, r, decode, record)
+		if errors.Is(err, errStreamLimit) {
+			break
+		}
+		if err != nil {
+			result.Elapsed = time.Since(start)
+			return result, err
+		}
+	}
+	result.Elapsed = time.Since(start)
+	return result, nil
+}
+
+// searchRanges works out the offsets of each partition to scan
+func (a *App) searchRanges(ctx context.Context, req SearchRequest) ([]searchRange, error) {
+	bounds, err := a.browseBounds(ctx, req.Topic, req.Partition)
+	if err != nil {
+		return nil, err
+	}
+
+	var fromTime, toTime map[int]int64
+	if !req.FromTime.IsZero() {
+		if fromTime, err = a.KafkaClient.OffsetsForTime(ctx, req.Topic, req.FromTime); err != nil {
+			return nil, err
+		}
+	}
+	if !req.ToTime.IsZero() {
+		// The first offset after the end of the range
+		if toTime, err = a.KafkaClient.OffsetsForTime(ctx, req.Topic, req.ToTime.Add(time.Millisecond)); err != nil {
+			return nil, err
+		}
+	}
+
+	var ranges []searchRange
+	for p, b := range bounds {
+		r := searchRange{partition: p, start: clampOffset(req.FromOffset, b), end: b.Latest}
+		if req.Offsets != nil {
+			// Partitions without a start have nothing to scan
+			r.start = b.Latest
+			if offset, ok := req.Offsets[p]; ok {
+				r.start = clampOffset(offset, b)
+			}
+		}
+		if req.ToOffset >= 0 {
+			r.end = clampOffset(req.ToOffset, b)
+		}
+		if fromTime != nil {
+			// Partitions without a record after the time have nothing to scan
+			r.start = b.Latest
+			if offset, ok := fromTime[p]; ok && offset >= 0 {
+				r.start = clampOffset(offset, b)
+			}
+		}
+		if toTime != nil {
+			if offset, ok := toTime[p]; ok && offset >= 0 {
+				r.end = clampOffset(offset, b)
+			}
+		}
+		if r.end < r.start {
+			r.end = r.start
+		}
+		ranges = append(ranges, r)
+	}
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].partition < ranges[j].partition })
+	return ranges, nil
+}
+
+// scanPartition reads the range of a partition in batches, recording the
+// matches of each batch. It stops at the first error of record.
+func (a *App) scanPartition(ctx context.Context, req SearchRequest, r searchRange, decode func([]byte) (interface{}, bool),
+	record func(scanned int64, matches []kafka.Message, done bool) error) error {
+	offset := r.start
+	for offset < r.end {
+		messages, err := a.KafkaClient.ReadMessages(ctx, req.Topic, r.partition, offset, int(min(searchBatchSize, r.end-offset)))
+		if err != nil {
+			return err
+		}
+
+		var matches []kafka.Message
+		next := offset
+		for i := range messages {
+			msg := &messages[i]
+			if msg.Offset >= r.end {
+				break
+			}
+			next = msg.Offset + 1
+			if !req.FromTime.IsZero() && msg.Timestamp.Before(req.FromTime) ||
+				!req.ToTime.IsZero() && msg.Timestamp.After(req.ToTime) {
+				continue
+			}
+			if req.Filter == nil || req.Filter.Match(msg, func() (interface{}, bool) { return decode(msg.Value) }) {
+				matches = append(matches, *msg)
+			}
+		}
+		// Nothing left before the end, e.g. compacted away
+		if next == offset {
+			next = r.end
+		}
+
+		if err := record(next-offset, matches, next >= r.end); err != nil {
+			return err
+		}
+		offset = next
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+	if r.start == r.end {
+		return record(0, nil, true)
+	}
+	return nil
+}
+
+// searchDecoder returns the function decoding values as JSON for filters:
+// JSON documents as they are, and records in the Schema Registry wire format
+// with the registry of the cluster
+func (a *App) searchDecoder(ctx context.Context) func([]byte) (interface{}, bool) {
+	registry := a.SchemaRegistry
+	return func(data []byte) (interface{}, bool) {
+		if json.Valid(data) {
+			return DecodeJSON(data)
+		}
+		if registry == nil || len(data) < 5 || data[0] != 0 {
+			return nil, false
+		}
+		text, err := registry.Decode(ctx, data)
+		if err != nil {
+			return nil, false
+		}
+		return DecodeJSON([]byte(text))
+	}
+}
@@ -0,0 +1,177 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cfk-dev/cfk/internal/kafka"
+)
+
+// Tail follows the records appended to every partition of a topic, like a
+// consumer starting at the end, but without joining a consumer group. Its
+// cursors only advance as records are polled, so a paused tail picks up where
+// it stopped. A Tail is not safe for concurrent polls.
+type Tail struct {
+	app     *App
+	topic   string
+	cursors map[int]int64
+}
+
+// TailMessages starts following a topic from the end of each partition
+func (a *App) TailMessages(ctx context.Context, topic string) (*Tail, error) {
+	if a.KafkaClient == nil {
+		return nil, fmt.Errorf("not connected to any Kafka cluster")
+	}
+
+	offsets, err := a.KafkaClient.ListOffsets(ctx, topic)
+	if err != nil {
+		return nil, err
+	}
+	t := &Tail{app: a, topic: topic, cursors: make(map[int]int64, len(offsets))}
+	for _, o := range offsets {
+		t.cursors[o.Partition] = o.Latest
+	}
+	return t, nil
+}
+
+// Topic returns the followed topic
+func (t *Tail) Topic() string {
+	return t.topic
+}
+
+// Poll reads up to limit new records per partition, merged by timestamp.
+// Partitions added since the tail started are followed from their start.
+func (t *Tail) Poll(ctx context.Context, limit int) ([]kafka.Message, error) {
+	if t.app.KafkaClient == nil {
+		return nil, fmt.Errorf("not connected to any Kafka cluster")
+	}
+
+	offsets, err := t.app.KafkaClient.ListOffsets(ctx, t.topic)
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []kafka.Message
+	for _, o := range offsets {
+		cursor, ok := t.cursors[o.Partition]
+		// Skip records deleted by retention since the last poll
+		if !ok || cursor < o.Earliest {
+			cursor = o.Earliest
+		}
+		if cursor >= o.Latest {
+			t.cursors[o.Partition] = cursor
+			continue
+		}
+
+		read, err := t.app.KafkaClient.ReadMessages(ctx, t.topic, o.Partition, cursor, limit)
+		if err != nil {
+			return nil, err
+		}
+		if len(read) > 0 {
+			cursor = read[len(read)-1].Offset + 1
+		}
+		t.cursors[o.Partition] = cursor
+		messages = append(messages, read...)
+	}
+
+	sortMessages(messages)
+	return messages, nil
+}
+
+// MessageRing keeps the most recent records up to a capacity, dropping the
+// oldest ones first
+type MessageRing struct {
+	buf     []kafka.Message
+	start   int // index of the oldest record
+	size    int
+	dropped int64
+}
+
+// NewMessageRing creates a ring holding up to capacity records
+func NewMessageRing(capacity int) *MessageRing {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &MessageRing{buf: make([]kafka.Message, capacity)}
+}
+
+// Push appends records, dropping the oldest ones once the ring is full
+func (r *MessageRing) Push(messages ...kafka.Message) {
+	for _, m := range messages {
+		if r.size < len(r.buf) {
+			r.buf[(r.start+r.size)%len(r.buf)] = m
+			r.size++
+			continue
+		}
+		r.buf[r.start] = m
+		r.start = (r.start + 1) % len(r.buf)
+		r.dropped++
+	}
+}
+
+// Messages returns the records from oldest to newest
+func (r *MessageRing) Messages() []kafka.Message {
+	messages := make([]kafka.Message, r.size)
+	for i := range messages {
+		messages[i] = r.buf[(r.start+i)%len(r.buf)]
+	}
+	return messages
+}
+
+// Len returns the number of records held
+func (r *MessageRing) Len() int {
+	return r.size
+}
+
+// Cap returns the capacity of the ring
+func (r *MessageRing) Cap() int {
+	return len(r.buf)
+}
+
+// Dropped returns the number of records dropped to make room
+func (r *MessageRing) Dropped() int64 {
+	return r.dropped
+}
+
+// RateMeter measures a rate of events per second over a sliding window
+type RateMeter struct {
+	window  time.Duration
+	samples []rateSample
+}
+
+// rateSample is a number of events counted at a time
+type rateSample struct {
+	at    time.Time
+	count int
+}
+
+// NewRateMeter creates a meter averaging over a window
+func NewRateMeter(window time.Duration) *RateMeter {
+	return &RateMeter{window: window}
+}
+
+// Add counts events at a time
+func (r *RateMeter) Add(at time.Time, count int) {
+	r.samples = append(r.samples, rateSample{at: at, count: count})
+	r.expire(at)
+}
+
+// Rate returns the events per second over the window ending at now
+func (r *RateMeter) Rate(now time.Time) float64 {
+	r.expire(now)
+	total := 0
+	for _, s := range r.samples {
+		total += s.count
+	}
+	return float64(total) / r.window.Seconds()
+}
+
+// expire drops the samples that fell out of the window
+func (r *RateMeter) expire(now time.Time) {
+	i := 0
+	for i < len(r.samples) && now.Sub(r.samples[i].at) >= r.window {
+		i++
+	}
+	r.samples = r.samples[i:]
+}
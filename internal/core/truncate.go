@@ -0,0 +1,108 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// TruncateRequest describes the records to delete from the start of a topic:
+// those of a partition before an offset, or those of every partition before
+// a time
+type TruncateRequest struct {
+	Topic     string
+	Partition int       // a partition, or AllPartitions to truncate at Time
+	Offset    int64     // the first offset of Partition to keep
+	Time      time.Time // the time of the first records to keep
+}
+
+// RecordDeletion is the planned deletion of the first records of a partition
+type RecordDeletion struct {
+	Partition int
+	Earliest  int64 // the first offset before the deletion
+	Offset    int64 // the first offset kept
+	Latest    int64
+}
+
+// Records returns the number of records the deletion removes
+func (d RecordDeletion) Records() int64 {
+	return d.Offset - d.Earliest
+}
+
+// DeleteRecords deletes the records of partitions of a topic before an offset
+// and returns the new earliest offset of each partition
+func (a *App) DeleteRecords(ctx context.Context, topicName string, offsets map[int]int64) (map[int]int64, error) {
+	if a.KafkaClient == nil {
+		return nil, fmt.Errorf("not connected to any Kafka cluster")
+	}
+
+	return a.KafkaClient.DeleteRecords(ctx, topicName, offsets)
+}
+
+// PlanTruncate works out how many records a truncation deletes from each
+// partition without deleting them. Partitions without a record at or after
+// the time of a truncation of every partition are emptied.
+func (a *App) PlanTruncate(ctx context.Context, req TruncateRequest) ([]RecordDeletion, error) {
+	if a.KafkaClient == nil {
+		return nil, fmt.Errorf("not connected to any Kafka cluster")
+	}
+
+	offsets, err := a.KafkaClient.ListOffsets(ctx, req.Topic)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Partition != AllPartitions {
+		for _, o := range offsets {
+			if o.Partition != req.Partition {
+				continue
+			}
+			if req.Offset > o.Latest {
+				return nil, fmt.Errorf("offset %d is past the end of partition %d, at %d", req.Offset, o.Partition, o.Latest)
+			}
+			return []RecordDeletion{{
+				Partition: o.Partition,
+				Earliest:  o.Earliest,
+				Offset:    max(req.Offset, o.Earliest),
+				Latest:    o.Latest,
+			}}, nil
+		}
+		return nil, fmt.Errorf("partition %d of topic %s not found", req.Partition, req.Topic)
+	}
+
+	atTime, err := a.KafkaClient.OffsetsForTime(ctx, req.Topic, req.Time)
+	if err != nil {
+		return nil, err
+	}
+	plan := make([]RecordDeletion, len(offsets))
+	for i, o := range offsets {
+		offset, ok := atTime[o.Partition]
+		if !ok || offset < 0 {
+			offset = o.Latest
+		}
+		plan[i] = RecordDeletion{
+			Partition: o.Partition,
+			Earliest:  o.Earliest,
+			Offset:    min(max(offset, o.Earliest), o.Latest),
+			Latest:    o.Latest,
+		}
+	}
+	return plan, nil
+}
+
+// ApplyTruncate deletes the records of a planned truncation. Partitions the
+// plan deletes nothing from are left alone.
+func (a *App) ApplyTruncate(ctx context.Context, topic string, plan []RecordDeletion) error {
+	offsets := make(map[int]int64)
+	for _, d := range plan {
+		if d.Records() > 0 {
+			offsets[d.Partition] = d.Offset
+		}
+	}
+	if len(offsets) == 0 {
+		return fmt.Errorf("no records to delete")
+	}
+
+	_, err := a.DeleteRecords(ctx, topic, offsets)
+	return err
+}
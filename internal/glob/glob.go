@@ -0,0 +1,119 @@
+// Package glob matches topic names and consumer group IDs against the
+// shell-like patterns of the configuration and the TUI
+package glob
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Pattern is a compiled glob pattern, in which * matches any run of
+// characters, ? a single one, [abc], [a-z] and [^a-z] a character of a class,
+// and \ escapes the character after it. Unlike path.Match, * and ? also
+// match /, which group IDs may contain.
+type Pattern struct {
+	re *regexp.Regexp
+}
+
+// Compile checks the syntax of a pattern and compiles it
+func Compile(pattern string) (*Pattern, error) {
+	var expr strings.Builder
+	expr.WriteString(`(?s)^`)
+	for i := 0; i < len(pattern); {
+		r, n := utf8.DecodeRuneInString(pattern[i:])
+		i += n
+		switch r {
+		case '*':
+			expr.WriteString(`.*`)
+		case '?':
+			expr.WriteString(`.`)
+		case '[':
+			n, err := writeClass(&expr, pattern[i:])
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+			}
+			i += n
+		case '\\':
+			if i == len(pattern) {
+				return nil, fmt.Errorf("invalid pattern %q: trailing backslash", pattern)
+			}
+			r, n = utf8.DecodeRuneInString(pattern[i:])
+			i += n
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	expr.WriteString(`$`)
+
+	re, err := regexp.Compile(expr.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	return &Pattern{re: re}, nil
+}
+
+// Match reports whether a name matches the whole pattern
+func (p *Pattern) Match(name string) bool {
+	return p.re.MatchString(name)
+}
+
+// Match reports whether a name matches a pattern. Invalid patterns match
+// nothing.
+func Match(pattern, name string) bool {
+	p, err := Compile(pattern)
+	return err == nil && p.Match(name)
+}
+
+// writeClass writes the regular expression of the character class after a
+// [ and returns the length of the class up to its ]. A ] right after the [
+// or the ^ stands for itself.
+func writeClass(expr *strings.Builder, class string) (int, error) {
+	expr.WriteString(`[`)
+	i := 0
+	if strings.HasPrefix(class, "^") {
+		expr.WriteString(`^`)
+		i++
+	}
+	for items := 0; ; items++ {
+		if i == len(class) {
+			return 0, fmt.Errorf("unterminated character class")
+		}
+		if class[i] == ']' && items > 0 {
+			expr.WriteString(`]`)
+			return i + 1, nil
+		}
+
+		lo, n, err := classChar(class[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n
+		hi := lo
+		if strings.HasPrefix(class[i:], "-") && i+1 < len(class) && class[i+1] != ']' {
+			if hi, n, err = classChar(class[i+1:]); err != nil {
+				return 0, err
+			}
+			i += 1 + n
+			if hi < lo {
+				return 0, fmt.Errorf("invalid range %c-%c", lo, hi)
+			}
+		}
+		fmt.Fprintf(expr, `\x{%x}-\x{%x}`, lo, hi)
+	}
+}
+
+// classChar reads a character of a class, escaped or not
+func classChar(class string) (rune, int, error) {
+	r, n := utf8.DecodeRuneInString(class)
+	if r != '\\' {
+		return r, n, nil
+	}
+	if n == len(class) {
+		return 0, 0, fmt.Errorf("unterminated character class")
+	}
+	r, m := utf8.DecodeRuneInString(class[n:])
+	return r, n + m, nil
+}
@@ -0,0 +1,46 @@
+package glob
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"orders", "orders", true},
+		{"orders", "orders-1", false},
+		{"orders-*", "orders-eu", true},
+		{"orders-*", "orders-", true},
+		{"*", "team/orders", true},
+		{"team/*", "team/orders/v2", true},
+		{"events-?", "events-1", true},
+		{"events-?", "events-12", false},
+		{"events-?", "events-é", true},
+		{"events-[0-9]", "events-7", true},
+		{"events-[0-9]", "events-x", false},
+		{"events-[^0-9]", "events-x", true},
+		{"events-[^0-9]", "events-7", false},
+		{"[]a]", "]", true},
+		{"[a-]", "-", true},
+		{`orders\*`, "orders*", true},
+		{`orders\*`, "orders-1", false},
+		{`[\]]`, "]", true},
+		{"a.b", "axb", false},
+	}
+	for _, tt := range tests {
+		if got := Match(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCompileInvalid(t *testing.T) {
+	for _, pattern := range []string{"[", "[a", "[^", "orders\\", "[z-a]", `[\`} {
+		if _, err := Compile(pattern); err == nil {
+			t.Errorf("Compile(%q) succeeded, want an error", pattern)
+		}
+		if Match(pattern, pattern) {
+			t.Errorf("invalid pattern %q matched", pattern)
+		}
+	}
+}
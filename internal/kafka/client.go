@@ -3,17 +3,25 @@ package kafka
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/cfk-dev/cfk/internal/config"
 	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/protocol/createtopics"
+	"github.com/segmentio/kafka-go/protocol/metadata"
 )
 
-// Client represents a Kafka client
+// Client represents a Kafka client.
+// It is built on the kafka-go admin client, whose transport keeps one pooled
+// connection per broker, tries every bootstrap server and routes leader- and
+// controller-targeted requests with the same TLS and SASL settings.
 type Client struct {
-	Config config.KafkaClusterConfig
-	Conn   *kafka.Conn
+	Config    config.KafkaClusterConfig
+	admin     *kafka.Client
+	transport *kafka.Transport
 }
 
 // TopicInfo holds information about a Kafka topic
@@ -21,7 +29,44 @@ type TopicInfo struct {
 	Name              string
 	Partitions        int
 	ReplicationFactor int
-	Config            map[string]string
+	Config            map[string]string // non-default topic configs
+}
+
+// TopicDetails holds the partition layout, offsets and configs of a topic
+type TopicDetails struct {
+	Name              string
+	ReplicationFactor int
+	Partitions        []PartitionDetails
+	Configs           []ConfigEntry // all configs in name order
+}
+
+// PartitionDetails holds the replica state and offsets of a partition
+type PartitionDetails struct {
+	Partition int
+	Leader    int // -1 if the partition has no leader
+	Replicas  []int
+	ISR       []int
+	Offline   []int // replicas on brokers that are down
+	Earliest  int64 // -1 if unknown because the partition has no leader
+	Latest    int64
+}
+
+// Messages returns the number of records between the earliest and latest offset
+func (p PartitionDetails) Messages() int64 {
+	if p.Earliest < 0 {
+		return 0
+	}
+	return p.Latest - p.Earliest
+}
+
+// Leaderless reports whether the partition has no leader
+func (p PartitionDetails) Leaderless() bool {
+	return p.Leader < 0
+}
+
+// UnderReplicated reports whether some replicas are not in sync
+func (p PartitionDetails) UnderReplicated() bool {
+	return len(p.ISR) < len(p.Replicas)
 }
 
 // NewClient creates a new Kafka client
@@ -31,187 +76,334 @@ func NewClient(clusterConfig config.KafkaClusterConfig) *Client {
 	}
 }
 
-// Connect establishes a connection to the Kafka cluster
-func (c *Client) Connect() error {
-	// Use the first bootstrap server for initial connection
+// Connect establishes a connection to the Kafka cluster.
+// The context bounds the initial metadata request.
+func (c *Client) Connect(ctx context.Context) error {
 	if len(c.Config.Bootstrap) == 0 {
 		return fmt.Errorf("no bootstrap servers configured")
 	}
 
-	// Set up dialer with authentication if needed
-	dialer := &kafka.Dialer{
-		Timeout: 10 * time.Second,
+	// Set up transport with authentication if needed
+	transport := &kafka.Transport{
+		ClientID:    "cfk",
+		DialTimeout: 10 * time.Second,
 	}
 
-	// Configure SASL if enabled
-	if c.Config.SASL {
-		// For SASL PLAIN authentication, we need to set up the mechanism
-		// The kafka-go package requires a specific format for SASL
-		dialer.DualStack = true
-		dialer.TLS = nil // Disable TLS for now, can be enabled with proper config
+	// Configure TLS if enabled
+	tlsConfig, err := NewTLSConfig(c.Config)
+	if err != nil {
+		return fmt.Errorf("invalid TLS configuration: %w", err)
 	}
+	transport.TLS = tlsConfig
 
-	// Connect to the broker
-	conn, err := dialer.Dial("tcp", c.Config.Bootstrap[0])
+	// Configure SASL if enabled
+	mechanism, err := NewSASLMechanism(c.Config)
 	if err != nil {
+		return fmt.Errorf("invalid SASL configuration: %w", err)
+	}
+	transport.SASL = mechanism
+
+	admin := &kafka.Client{
+		Addr:      kafka.TCP(c.Config.Bootstrap...),
+		Transport: transport,
+		Timeout:   10 * time.Second,
+	}
+
+	// Make sure at least one bootstrap server answers
+	if _, err := admin.Metadata(ctx, &kafka.MetadataRequest{}); err != nil {
+		transport.CloseIdleConnections()
 		return fmt.Errorf("failed to connect to Kafka: %w", err)
 	}
 
-	c.Conn = conn
+	c.admin = admin
+	c.transport = transport
 	return nil
 }
 
-// Close closes the Kafka connection
+// Close closes the Kafka connections
 func (c *Client) Close() error {
-	if c.Conn != nil {
-		return c.Conn.Close()
+	if c.transport != nil {
+		c.transport.CloseIdleConnections()
 	}
 	return nil
 }
 
 // ListTopics lists all topics in the Kafka cluster
 func (c *Client) ListTopics(ctx context.Context) ([]string, error) {
-	if c.Conn == nil {
+	if c.admin == nil {
 		return nil, fmt.Errorf("not connected to Kafka")
 	}
 
 	// Get metadata for all topics
-	partitions, err := c.Conn.ReadPartitions()
+	metadata, err := c.admin.Metadata(ctx, &kafka.MetadataRequest{})
 	if err != nil {
-		return nil, fmt.Errorf("failed to read partitions: %w", err)
+		return nil, fmt.Errorf("failed to read metadata: %w", err)
 	}
 
-	// Extract unique topic names
-	topicMap := make(map[string]bool)
-	for _, p := range partitions {
-		topicMap[p.Topic] = true
-	}
-
-	// Convert map to slice
-	topics := make([]string, 0, len(topicMap))
-	for topic := range topicMap {
-		topics = append(topics, topic)
+	topics := make([]string, 0, len(metadata.Topics))
+	for _, topic := range metadata.Topics {
+		if topic.Error == nil {
+			topics = append(topics, topic.Name)
+		}
 	}
+	sort.Strings(topics)
 
 	return topics, nil
 }
 
 // GetTopicInfo gets detailed information about a specific topic
 func (c *Client) GetTopicInfo(ctx context.Context, topicName string) (*TopicInfo, error) {
-	if c.Conn == nil {
+	if c.admin == nil {
 		return nil, fmt.Errorf("not connected to Kafka")
 	}
 
-	// Get metadata for all topics
-	partitions, err := c.Conn.ReadPartitions(topicName)
+	// Get metadata for the topic
+	topic, err := c.topicMetadata(ctx, topicName)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read partitions for topic %s: %w", topicName, err)
-	}
-
-	if len(partitions) == 0 {
-		return nil, fmt.Errorf("topic %s not found", topicName)
+		return nil, err
 	}
 
 	// Create topic info
 	topicInfo := &TopicInfo{
 		Name:       topicName,
-		Partitions: len(partitions),
+		Partitions: len(topic.Partitions),
 		Config:     make(map[string]string),
 	}
 
-	// For now, we don't have a way to get replication factor and config
-	// This would require admin client functionality
+	// The replication factor is the size of the largest replica set
+	for _, p := range topic.Partitions {
+		if len(p.Replicas) > topicInfo.ReplicationFactor {
+			topicInfo.ReplicationFactor = len(p.Replicas)
+		}
+	}
+
+	entries, err := c.describeTopicConfig(ctx, topicName)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.ConfigSource == ConfigSourceDynamicTopic {
+			topicInfo.Config[entry.ConfigName] = entry.ConfigValue
+		}
+	}
 
 	return topicInfo, nil
 }
 
-// CreateTopic creates a new topic in the Kafka cluster
-func (c *Client) CreateTopic(ctx context.Context, topicName string, numPartitions int, replicationFactor int) error {
-	if c.Conn == nil {
-		return fmt.Errorf("not connected to Kafka")
+// DescribeTopic gets the leader, replicas, in-sync replicas and offsets of
+// every partition of a topic, and all of its configs
+func (c *Client) DescribeTopic(ctx context.Context, topicName string) (*TopicDetails, error) {
+	if c.admin == nil {
+		return nil, fmt.Errorf("not connected to Kafka")
 	}
 
-	// Get the controller broker
-	controllerBroker, err := c.Conn.Controller()
+	// The kafka-go metadata call drops offline replicas and reports a
+	// missing leader as broker 0, so read the protocol response instead
+	msg, err := c.transport.RoundTrip(ctx, c.admin.Addr, &metadata.Request{TopicNames: []string{topicName}})
 	if err != nil {
-		return fmt.Errorf("failed to get controller: %w", err)
+		return nil, fmt.Errorf("failed to read metadata for topic %s: %w", topicName, err)
+	}
+	var topic *metadata.ResponseTopic
+	resp := msg.(*metadata.Response)
+	for i, t := range resp.Topics {
+		if t.Name == topicName && t.ErrorCode == 0 && len(t.Partitions) > 0 {
+			topic = &resp.Topics[i]
+		}
+	}
+	if topic == nil {
+		return nil, fmt.Errorf("topic %s not found", topicName)
 	}
 
-	// Create a controller connection for admin operations
-	controller, err := kafka.Dial("tcp", fmt.Sprintf("%s:%d", controllerBroker.Host, controllerBroker.Port))
+	details := &TopicDetails{Name: topicName}
+	var led []int
+	for _, p := range topic.Partitions {
+		partition := PartitionDetails{
+			Partition: int(p.PartitionIndex),
+			Leader:    int(p.LeaderID),
+			Replicas:  brokerIDs(p.ReplicaNodes),
+			ISR:       brokerIDs(p.IsrNodes),
+			Offline:   brokerIDs(p.OfflineReplicas),
+			Earliest:  -1,
+			Latest:    -1,
+		}
+		if len(partition.Replicas) > details.ReplicationFactor {
+			details.ReplicationFactor = len(partition.Replicas)
+		}
+		if !partition.Leaderless() {
+			led = append(led, partition.Partition)
+		}
+		details.Partitions = append(details.Partitions, partition)
+	}
+	sort.Slice(details.Partitions, func(i, j int) bool {
+		return details.Partitions[i].Partition < details.Partitions[j].Partition
+	})
+
+	// Only partition leaders can answer offset requests
+	offsets, err := c.listOffsets(ctx, topicName, led)
 	if err != nil {
-		return fmt.Errorf("failed to connect to controller: %w", err)
+		return nil, err
+	}
+	for _, o := range offsets {
+		details.Partitions[o.Partition].Earliest = o.Earliest
+		details.Partitions[o.Partition].Latest = o.Latest
 	}
-	defer controller.Close()
 
-	// Create the topic
-	err = controller.CreateTopics(kafka.TopicConfig{
-		Topic:             topicName,
-		NumPartitions:     numPartitions,
-		ReplicationFactor: replicationFactor,
-	})
+	entries, err := c.describeTopicConfig(ctx, topicName)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		details.Configs = append(details.Configs, ConfigEntry{
+			Name:      entry.ConfigName,
+			Value:     entry.ConfigValue,
+			Source:    entry.ConfigSource,
+			ReadOnly:  entry.ReadOnly,
+			Sensitive: entry.IsSensitive,
+		})
+	}
 
+	return details, nil
+}
+
+// brokerIDs converts protocol broker IDs
+func brokerIDs(nodes []int32) []int {
+	ids := make([]int, len(nodes))
+	for i, id := range nodes {
+		ids[i] = int(id)
+	}
+	return ids
+}
+
+// topicMetadata reads the metadata of a single topic
+func (c *Client) topicMetadata(ctx context.Context, topicName string) (*kafka.Topic, error) {
+	metadata, err := c.admin.Metadata(ctx, &kafka.MetadataRequest{Topics: []string{topicName}})
 	if err != nil {
-		return fmt.Errorf("failed to create topic %s: %w", topicName, err)
+		return nil, fmt.Errorf("failed to read metadata for topic %s: %w", topicName, err)
 	}
 
-	return nil
+	for i := range metadata.Topics {
+		topic := &metadata.Topics[i]
+		if topic.Name != topicName {
+			continue
+		}
+		if errors.Is(topic.Error, kafka.UnknownTopicOrPartition) || (topic.Error == nil && len(topic.Partitions) == 0) {
+			return nil, fmt.Errorf("topic %s not found", topicName)
+		}
+		if topic.Error != nil {
+			return nil, fmt.Errorf("failed to read metadata for topic %s: %w", topicName, topic.Error)
+		}
+		return topic, nil
+	}
+
+	return nil, fmt.Errorf("topic %s not found", topicName)
 }
 
-// DeleteTopic deletes a topic from the Kafka cluster
-func (c *Client) DeleteTopic(ctx context.Context, topicName string) error {
-	if c.Conn == nil {
+// CreateTopic creates a new topic in the Kafka cluster
+func (c *Client) CreateTopic(ctx context.Context, topicName string, numPartitions int, replicationFactor int) error {
+	return c.CreateTopicFromSpec(ctx, TopicSpec{
+		Name:              topicName,
+		Partitions:        numPartitions,
+		ReplicationFactor: replicationFactor,
+	})
+}
+
+// CreateTopicFromSpec creates a topic with the layout and configs of a spec.
+// A replica assignment places every partition on the given brokers.
+func (c *Client) CreateTopicFromSpec(ctx context.Context, spec TopicSpec) error {
+	if c.admin == nil {
 		return fmt.Errorf("not connected to Kafka")
 	}
 
-	// Get the controller broker
-	controllerBroker, err := c.Conn.Controller()
-	if err != nil {
-		return fmt.Errorf("failed to get controller: %w", err)
+	topic := kafka.TopicConfig{
+		Topic:             spec.Name,
+		NumPartitions:     spec.Partitions,
+		ReplicationFactor: spec.ReplicationFactor,
+	}
+	if len(spec.ReplicaAssignment) > 0 {
+		// The brokers take the layout from the assignment alone
+		topic.NumPartitions, topic.ReplicationFactor = -1, -1
+		for p, replicas := range spec.ReplicaAssignment {
+			topic.ReplicaAssignments = append(topic.ReplicaAssignments, kafka.ReplicaAssignment{Partition: p, Replicas: replicas})
+		}
+	}
+	for _, config := range SetConfigs(spec.Configs) {
+		topic.ConfigEntries = append(topic.ConfigEntries, kafka.ConfigEntry{ConfigName: config.Name, ConfigValue: config.Value})
+	}
+
+	// Create the topic
+	resp, err := c.admin.CreateTopics(ctx, &kafka.CreateTopicsRequest{
+		Topics: []kafka.TopicConfig{topic},
+	})
+	if err == nil {
+		err = resp.Errors[spec.Name]
 	}
 
-	// Create a controller connection for admin operations
-	controller, err := kafka.Dial("tcp", fmt.Sprintf("%s:%d", controllerBroker.Host, controllerBroker.Port))
 	if err != nil {
-		return fmt.Errorf("failed to connect to controller: %w", err)
+		return fmt.Errorf("failed to create topic %s: %w", spec.Name, err)
+	}
+
+	return nil
+}
+
+// DeleteTopic deletes a topic from the Kafka cluster
+func (c *Client) DeleteTopic(ctx context.Context, topicName string) error {
+	if c.admin == nil {
+		return fmt.Errorf("not connected to Kafka")
 	}
-	defer controller.Close()
 
 	// Delete the topic
-	err = controller.DeleteTopics(topicName)
+	resp, err := c.admin.DeleteTopics(ctx, &kafka.DeleteTopicsRequest{
+		Topics: []string{topicName},
+	})
+	if err == nil {
+		err = resp.Errors[topicName]
+	}
 	if err != nil {
 		return fmt.Errorf("failed to delete topic %s: %w", topicName, err)
 	}
 
-	return nil
+	return c.refreshMetadata(ctx)
 }
 
 // UpdateTopicPartitions updates the number of partitions for a topic
 func (c *Client) UpdateTopicPartitions(ctx context.Context, topicName string, numPartitions int) error {
-	if c.Conn == nil {
+	if c.admin == nil {
 		return fmt.Errorf("not connected to Kafka")
 	}
 
-	// First, get the current topic info
-	topicInfo, err := c.GetTopicInfo(ctx, topicName)
+	// First, get the current partition count
+	topic, err := c.topicMetadata(ctx, topicName)
 	if err != nil {
 		return fmt.Errorf("failed to get topic info: %w", err)
 	}
 
 	// Only proceed if we're increasing partitions
-	if numPartitions <= topicInfo.Partitions {
-		return fmt.Errorf("new partition count must be greater than current count (%d)", topicInfo.Partitions)
+	if numPartitions <= len(topic.Partitions) {
+		return fmt.Errorf("new partition count must be greater than current count (%d)", len(topic.Partitions))
 	}
 
-	// Unfortunately, the kafka-go library doesn't provide a direct way to update partitions
-	// We would need to use the Kafka AdminClient API or a different library
+	resp, err := c.admin.CreatePartitions(ctx, &kafka.CreatePartitionsRequest{
+		Topics: []kafka.TopicPartitionsConfig{{
+			Name:  topicName,
+			Count: int32(numPartitions),
+		}},
+	})
+	if err == nil {
+		err = resp.Errors[topicName]
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update partitions for topic %s: %w", topicName, err)
+	}
 
-	// We can't directly use the kafka-go library to update partitions
-	// as it doesn't provide this functionality in the current version
+	return c.refreshMetadata(ctx)
+}
 
-	// Use the kafka-go command line tool to update partitions
-	// This is a workaround since the library doesn't directly support altering partitions
-	// In a production environment, you would use the Kafka AdminClient API
-	return fmt.Errorf("updating partitions is supported but requires Kafka AdminClient API. " +
-		"Please use kafka-topics.sh --alter --topic %s --partitions %d", topicName, numPartitions)
+// refreshMetadata makes the transport reload its cached cluster metadata,
+// which it otherwise only does every few seconds and after creating topics.
+// An empty CreateTopics request is the one way to trigger that reload.
+func (c *Client) refreshMetadata(ctx context.Context) error {
+	if _, err := c.transport.RoundTrip(ctx, c.admin.Addr, &createtopics.Request{}); err != nil {
+		return fmt.Errorf("failed to refresh metadata: %w", err)
+	}
+	return nil
 }
@@ -0,0 +1,679 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/cfk-dev/cfk/internal/config"
+	"github.com/cfk-dev/cfk/internal/kafka/kafkatest"
+	"github.com/segmentio/kafka-go"
+)
+
+// newTestServer starts an in-process broker that is closed with the test
+func newTestServer(t *testing.T, cfg kafkatest.Config) *kafkatest.Server {
+	t.Helper()
+
+	srv, err := kafkatest.NewServer(cfg)
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// newTestClient connects a client to the first node of a test server
+func newTestClient(t *testing.T, srv *kafkatest.Server, clusterConfig config.KafkaClusterConfig) *Client {
+	t.Helper()
+
+	clusterConfig.Name = "test"
+	clusterConfig.Bootstrap = srv.Addrs[:1]
+	client := NewClient(clusterConfig)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Connect(ctx); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func testContext(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func TestClientBootstrapAndReconnect(t *testing.T) {
+	srv := newTestServer(t, kafkatest.Config{Nodes: 2})
+
+	// A bootstrap server that is down is skipped
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	down := l.Addr().String()
+	l.Close()
+	client := NewClient(config.KafkaClusterConfig{Name: "test", Bootstrap: []string{down, srv.Addrs[1]}})
+	ctx := testContext(t)
+	if err := client.Connect(ctx); err != nil {
+		t.Fatalf("Connect() with a bootstrap server down error = %v", err)
+	}
+	defer client.Close()
+	if err := client.CreateTopic(ctx, "orders", 2, 2); err != nil {
+		t.Fatalf("CreateTopic() error = %v", err)
+	}
+
+	// Calls after the brokers restarted connect again
+	srv.CloseConnections()
+	topics, err := client.ListTopics(ctx)
+	if err != nil || !reflect.DeepEqual(topics, []string{"orders"}) {
+		t.Errorf("ListTopics() after a restart = %v, %v", topics, err)
+	}
+	if _, err := client.ProduceMessage(ctx, "orders", Message{Partition: 1, Value: []byte("order")}); err != nil {
+		t.Errorf("ProduceMessage() to a partition leader after a restart error = %v", err)
+	}
+}
+
+func TestClientTopics(t *testing.T) {
+	srv := newTestServer(t, kafkatest.Config{Nodes: 3})
+	client := newTestClient(t, srv, config.KafkaClusterConfig{})
+	ctx := testContext(t)
+
+	if err := client.CreateTopic(ctx, "orders", 3, 2); err != nil {
+		t.Fatalf("CreateTopic() error = %v", err)
+	}
+	if err := client.CreateTopic(ctx, "audit", 1, 1); err != nil {
+		t.Fatalf("CreateTopic() error = %v", err)
+	}
+	if err := client.CreateTopic(ctx, "orders", 1, 1); !errors.Is(err, kafka.TopicAlreadyExists) {
+		t.Errorf("CreateTopic(existing) error = %v, want TopicAlreadyExists", err)
+	}
+	if err := client.CreateTopic(ctx, "too-replicated", 1, 4); err == nil {
+		t.Error("CreateTopic() with more replicas than brokers succeeded")
+	}
+
+	topics, err := client.ListTopics(ctx)
+	if err != nil {
+		t.Fatalf("ListTopics() error = %v", err)
+	}
+	if want := []string{"audit", "orders"}; !reflect.DeepEqual(topics, want) {
+		t.Errorf("ListTopics() = %v, want %v", topics, want)
+	}
+
+	if err := client.UpdateTopicConfig(ctx, "orders", map[string]string{"retention.ms": "3600000"}); err != nil {
+		t.Fatalf("UpdateTopicConfig() error = %v", err)
+	}
+	if err := client.UpdateTopicConfig(ctx, "orders", map[string]string{"retention.ms": "soon"}); err == nil {
+		t.Error("UpdateTopicConfig() with an invalid value succeeded")
+	}
+
+	info, err := client.GetTopicInfo(ctx, "orders")
+	if err != nil {
+		t.Fatalf("GetTopicInfo() error = %v", err)
+	}
+	want := &TopicInfo{
+		Name:              "orders",
+		Partitions:        3,
+		ReplicationFactor: 2,
+		Config:            map[string]string{"retention.ms": "3600000"},
+	}
+	if !reflect.DeepEqual(info, want) {
+		t.Errorf("GetTopicInfo() = %+v, want %+v", info, want)
+	}
+
+	if err := client.UpdateTopicPartitions(ctx, "orders", 2); err == nil {
+		t.Error("UpdateTopicPartitions() shrinking the topic succeeded")
+	}
+	if err := client.UpdateTopicPartitions(ctx, "orders", 5); err != nil {
+		t.Fatalf("UpdateTopicPartitions() error = %v", err)
+	}
+	if info, err := client.GetTopicInfo(ctx, "orders"); err != nil || info.Partitions != 5 {
+		t.Errorf("GetTopicInfo() after adding partitions = %+v, %v; want 5 partitions", info, err)
+	}
+
+	if err := client.DeleteTopic(ctx, "audit"); err != nil {
+		t.Fatalf("DeleteTopic() error = %v", err)
+	}
+	if topics, _ := client.ListTopics(ctx); !reflect.DeepEqual(topics, []string{"orders"}) {
+		t.Errorf("ListTopics() after delete = %v", topics)
+	}
+	if _, err := client.GetTopicInfo(ctx, "audit"); err == nil {
+		t.Error("GetTopicInfo() of a deleted topic succeeded")
+	}
+}
+
+func TestClientDescribeTopic(t *testing.T) {
+	srv := newTestServer(t, kafkatest.Config{Nodes: 3})
+	client := newTestClient(t, srv, config.KafkaClusterConfig{})
+	ctx := testContext(t)
+
+	// Replicas are spread round-robin: orders has [0 1] [1 2] [2 0]
+	// and single has [0] [1] [2]
+	if err := client.CreateTopic(ctx, "orders", 3, 2); err != nil {
+		t.Fatalf("CreateTopic() error = %v", err)
+	}
+	if err := client.CreateTopic(ctx, "single", 3, 1); err != nil {
+		t.Fatalf("CreateTopic() error = %v", err)
+	}
+	if err := client.UpdateTopicConfig(ctx, "orders", map[string]string{"cleanup.policy": "compact"}); err != nil {
+		t.Fatalf("UpdateTopicConfig() error = %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := client.ProduceMessage(ctx, "orders", Message{Partition: 0, Value: []byte("order")}); err != nil {
+			t.Fatalf("ProduceMessage() error = %v", err)
+		}
+	}
+
+	// Creating a topic makes the client reload the metadata
+	srv.StopNode(1)
+	if err := client.CreateTopic(ctx, "refresh", 1, 1); err != nil {
+		t.Fatalf("CreateTopic() error = %v", err)
+	}
+
+	details, err := client.DescribeTopic(ctx, "orders")
+	if err != nil {
+		t.Fatalf("DescribeTopic() error = %v", err)
+	}
+	want := []PartitionDetails{
+		{Partition: 0, Leader: 0, Replicas: []int{0, 1}, ISR: []int{0}, Offline: []int{1}, Earliest: 0, Latest: 2},
+		{Partition: 1, Leader: 2, Replicas: []int{1, 2}, ISR: []int{2}, Offline: []int{1}, Earliest: 0, Latest: 0},
+		{Partition: 2, Leader: 2, Replicas: []int{2, 0}, ISR: []int{2, 0}, Offline: []int{}, Earliest: 0, Latest: 0},
+	}
+	if !reflect.DeepEqual(details.Partitions, want) {
+		t.Errorf("DescribeTopic() partitions = %+v, want %+v", details.Partitions, want)
+	}
+	if details.ReplicationFactor != 2 || !details.Partitions[0].UnderReplicated() || details.Partitions[2].UnderReplicated() {
+		t.Errorf("DescribeTopic() = %+v", details)
+	}
+
+	sources := make(map[string]int8)
+	for _, entry := range details.Configs {
+		sources[entry.Name] = entry.Source
+	}
+	if sources["cleanup.policy"] != ConfigSourceDynamicTopic || sources["retention.ms"] != ConfigSourceDefault {
+		t.Errorf("DescribeTopic() config sources = %v", sources)
+	}
+
+	details, err = client.DescribeTopic(ctx, "single")
+	if err != nil {
+		t.Fatalf("DescribeTopic() error = %v", err)
+	}
+	if p := details.Partitions[1]; !p.Leaderless() || p.Earliest != -1 || p.Messages() != 0 {
+		t.Errorf("DescribeTopic() partition on the stopped node = %+v, want leaderless", p)
+	}
+}
+
+func TestClientCreateTopicFromSpec(t *testing.T) {
+	srv := newTestServer(t, kafkatest.Config{Nodes: 3})
+	client := newTestClient(t, srv, config.KafkaClusterConfig{})
+	ctx := testContext(t)
+
+	spec := TopicSpec{
+		Name:              "changelog",
+		ReplicaAssignment: [][]int{{2, 0}, {0, 1}},
+		Configs:           map[string]string{"cleanup.policy": "compact", "min.insync.replicas": "2"},
+	}
+	if err := client.CreateTopicFromSpec(ctx, spec); err != nil {
+		t.Fatalf("CreateTopicFromSpec() error = %v", err)
+	}
+
+	details, err := client.DescribeTopic(ctx, "changelog")
+	if err != nil {
+		t.Fatalf("DescribeTopic() error = %v", err)
+	}
+	if len(details.Partitions) != 2 || !reflect.DeepEqual(details.Partitions[0].Replicas, []int{2, 0}) || details.Partitions[0].Leader != 2 {
+		t.Errorf("DescribeTopic() partitions = %+v, want the assigned replicas", details.Partitions)
+	}
+	info, err := client.GetTopicInfo(ctx, "changelog")
+	if err != nil {
+		t.Fatalf("GetTopicInfo() error = %v", err)
+	}
+	if !reflect.DeepEqual(info.Config, spec.Configs) {
+		t.Errorf("GetTopicInfo() configs = %v, want %v", info.Config, spec.Configs)
+	}
+
+	if err := client.CreateTopicFromSpec(ctx, TopicSpec{Name: "orders", ReplicaAssignment: [][]int{{0, 5}}}); err == nil {
+		t.Errorf("CreateTopicFromSpec() on an unknown broker did not fail")
+	}
+}
+
+func TestClientAlterTopicConfig(t *testing.T) {
+	srv := newTestServer(t, kafkatest.Config{Nodes: 1})
+	client := newTestClient(t, srv, config.KafkaClusterConfig{})
+	ctx := testContext(t)
+
+	if err := client.CreateTopic(ctx, "orders", 1, 1); err != nil {
+		t.Fatalf("CreateTopic() error = %v", err)
+	}
+	if err := client.UpdateTopicConfig(ctx, "orders", map[string]string{"cleanup.policy": "compact", "retention.ms": "1000"}); err != nil {
+		t.Fatalf("UpdateTopicConfig() error = %v", err)
+	}
+
+	// Changes are incremental: untouched overrides are kept
+	changes := []ConfigChange{{Name: "cleanup.policy", Delete: true}, {Name: "max.message.bytes", Value: "2097152"}}
+	if err := client.AlterTopicConfig(ctx, "orders", changes); err != nil {
+		t.Fatalf("AlterTopicConfig() error = %v", err)
+	}
+
+	details, err := client.DescribeTopic(ctx, "orders")
+	if err != nil {
+		t.Fatalf("DescribeTopic() error = %v", err)
+	}
+	configs := make(map[string]ConfigEntry)
+	for _, entry := range details.Configs {
+		configs[entry.Name] = entry
+	}
+	if c := configs["cleanup.policy"]; c.Value != "delete" || !c.IsDefault() {
+		t.Errorf("cleanup.policy = %+v, want the default", c)
+	}
+	if c := configs["retention.ms"]; c.Value != "1000" || c.Source != ConfigSourceDynamicTopic {
+		t.Errorf("retention.ms = %+v, want the override kept", c)
+	}
+	if c := configs["max.message.bytes"]; c.Value != "2097152" {
+		t.Errorf("max.message.bytes = %+v, want 2097152", c)
+	}
+
+	if err := client.AlterTopicConfig(ctx, "orders", []ConfigChange{{Name: "retention.ms", Value: "soon"}}); err == nil {
+		t.Errorf("AlterTopicConfig() with an invalid value did not fail")
+	}
+}
+
+func TestClientMessages(t *testing.T) {
+	srv := newTestServer(t, kafkatest.Config{Nodes: 2})
+	client := newTestClient(t, srv, config.KafkaClusterConfig{})
+	ctx := testContext(t)
+
+	if err := client.CreateTopic(ctx, "events", 2, 1); err != nil {
+		t.Fatalf("CreateTopic() error = %v", err)
+	}
+
+	ts := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i, value := range []string{"a", "b", "c"} {
+		msg, err := client.ProduceMessage(ctx, "events", Message{
+			Partition: 1,
+			Timestamp: ts.Add(time.Duration(i) * time.Minute),
+			Key:       []byte("key-" + value),
+			Value:     []byte(value),
+			Headers:   []Header{{Key: "source", Value: []byte("test")}},
+		})
+		if err != nil {
+			t.Fatalf("ProduceMessage() error = %v", err)
+		}
+		if msg.Offset != int64(i) {
+			t.Errorf("ProduceMessage() offset = %d, want %d", msg.Offset, i)
+		}
+	}
+
+	keyed, err := client.ProduceMessage(ctx, "events", Message{Partition: -1, Key: []byte("user-1"), Value: []byte("x")})
+	if err != nil {
+		t.Fatalf("ProduceMessage() error = %v", err)
+	}
+	if want := PartitionForKey([]byte("user-1"), 2); keyed.Partition != want {
+		t.Errorf("ProduceMessage() partition = %d, want %d", keyed.Partition, want)
+	}
+
+	offsets, err := client.ListOffsets(ctx, "events")
+	if err != nil {
+		t.Fatalf("ListOffsets() error = %v", err)
+	}
+	latest := map[int]int64{0: 0, 1: 3}
+	latest[keyed.Partition]++
+	for _, p := range offsets {
+		if p.Earliest != 0 || p.Latest != latest[p.Partition] {
+			t.Errorf("ListOffsets() partition %d = %+v, want latest %d", p.Partition, p, latest[p.Partition])
+		}
+	}
+
+	messages, err := client.ReadMessages(ctx, "events", 1, 1, 10)
+	if err != nil {
+		t.Fatalf("ReadMessages() error = %v", err)
+	}
+	if len(messages) < 2 {
+		t.Fatalf("ReadMessages() = %+v, want at least 2 messages", messages)
+	}
+	got := messages[0]
+	if got.Offset != 1 || string(got.Key) != "key-b" || string(got.Value) != "b" || !got.Timestamp.Equal(ts.Add(time.Minute)) {
+		t.Errorf("ReadMessages()[0] = %+v", got)
+	}
+	if want := []Header{{Key: "source", Value: []byte("test")}}; !reflect.DeepEqual(got.Headers, want) {
+		t.Errorf("ReadMessages()[0].Headers = %+v, want %+v", got.Headers, want)
+	}
+
+	if messages, err := client.ReadMessages(ctx, "events", 0, 0, 10); err != nil || len(messages) != int(latest[0]) {
+		t.Errorf("ReadMessages(partition 0) = %+v, %v", messages, err)
+	}
+
+	atTime, err := client.OffsetsForTime(ctx, "events", ts.Add(30*time.Second))
+	if err != nil {
+		t.Fatalf("OffsetsForTime() error = %v", err)
+	}
+	if atTime[1] != 1 {
+		t.Errorf("OffsetsForTime() = %v, want offset 1 in partition 1", atTime)
+	}
+	if atTime, err := client.OffsetsForTime(ctx, "events", time.Now().Add(time.Hour)); err != nil || atTime[0] != -1 || atTime[1] != -1 {
+		t.Errorf("OffsetsForTime(future) = %v, %v, want -1 everywhere", atTime, err)
+	}
+}
+
+func TestClientReadMessagesAcrossCompactedGaps(t *testing.T) {
+	srv := newTestServer(t, kafkatest.Config{})
+	client := newTestClient(t, srv, config.KafkaClusterConfig{})
+	ctx := testContext(t)
+
+	if err := client.CreateTopic(ctx, "events", 1, 1); err != nil {
+		t.Fatalf("CreateTopic() error = %v", err)
+	}
+	for i := 0; i < 12; i++ {
+		if _, err := client.ProduceMessage(ctx, "events", Message{Value: []byte(strconv.Itoa(i))}); err != nil {
+			t.Fatalf("ProduceMessage() error = %v", err)
+		}
+	}
+	// Gaps of several empty batches, one of them at the end of the log
+	srv.Compact("events", 0, 2, 7)
+	srv.Compact("events", 0, 9, 12)
+
+	offsetsOf := func(messages []Message) []int64 {
+		var offsets []int64
+		for _, msg := range messages {
+			offsets = append(offsets, msg.Offset)
+		}
+		return offsets
+	}
+	tests := []struct {
+		offset int64
+		limit  int
+		want   []int64
+	}{
+		{0, 10, []int64{0, 1, 7, 8}},
+		{3, 10, []int64{7, 8}},
+		{0, 3, []int64{0, 1, 7}},
+		{10, 10, nil},
+	}
+	for _, tt := range tests {
+		messages, err := client.ReadMessages(ctx, "events", 0, tt.offset, tt.limit)
+		if got := offsetsOf(messages); err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ReadMessages(offset %d, limit %d) = %v, %v, want %v", tt.offset, tt.limit, got, err, tt.want)
+		}
+	}
+}
+
+func TestClientProduceMessages(t *testing.T) {
+	srv := newTestServer(t, kafkatest.Config{Nodes: 2})
+	client := newTestClient(t, srv, config.KafkaClusterConfig{})
+	ctx := testContext(t)
+
+	if err := client.CreateTopic(ctx, "events", 2, 1); err != nil {
+		t.Fatalf("CreateTopic() error = %v", err)
+	}
+
+	// A compressed batch spread over both partitions, with one record refused
+	messages := []Message{
+		{Partition: 0, Value: []byte("a")},
+		{Partition: 1, Value: []byte("b")},
+		{Partition: 0, Value: []byte("c")},
+		{Partition: 7, Value: []byte("lost")},
+	}
+	results, err := client.ProduceMessages(ctx, "events", messages, ProduceOptions{Acks: AcksLeader, Compression: "zstd"})
+	if err != nil {
+		t.Fatalf("ProduceMessages() error = %v", err)
+	}
+	for i, want := range []int64{0, 0, 1} {
+		if results[i].Err != nil || results[i].Message.Offset != want {
+			t.Errorf("ProduceMessages()[%d] = %+v, want offset %d", i, results[i], want)
+		}
+	}
+	if results[3].Err == nil {
+		t.Errorf("ProduceMessages() to a missing partition did not fail")
+	}
+	if read, err := client.ReadMessages(ctx, "events", 0, 0, 10); err != nil || len(read) != 2 || string(read[1].Value) != "c" {
+		t.Errorf("ReadMessages() after a compressed batch = %+v, %v", read, err)
+	}
+
+	// Without acks the offsets are unknown
+	results, err = client.ProduceMessages(ctx, "events", []Message{{Partition: 1, Value: []byte("d")}}, ProduceOptions{Acks: AcksNone})
+	if err != nil || results[0].Err != nil || results[0].Message.Offset != -1 {
+		t.Errorf("ProduceMessages(acks none) = %+v, %v", results, err)
+	}
+
+	if _, err := client.ProduceMessages(ctx, "events", messages, ProduceOptions{Compression: "brotli"}); err == nil {
+		t.Errorf("ProduceMessages() with an unknown codec did not fail")
+	}
+}
+
+func TestClientDeleteRecords(t *testing.T) {
+	srv := newTestServer(t, kafkatest.Config{Nodes: 2})
+	client := newTestClient(t, srv, config.KafkaClusterConfig{})
+	ctx := testContext(t)
+
+	if err := client.CreateTopic(ctx, "events", 2, 1); err != nil {
+		t.Fatalf("CreateTopic() error = %v", err)
+	}
+	var messages []Message
+	for i := 0; i < 4; i++ {
+		messages = append(messages, Message{Partition: i % 2, Value: []byte{byte('a' + i)}})
+	}
+	if _, err := client.ProduceMessages(ctx, "events", messages, ProduceOptions{}); err != nil {
+		t.Fatalf("ProduceMessages() error = %v", err)
+	}
+
+	// Partitions are routed to their leaders, on both nodes
+	earliest, err := client.DeleteRecords(ctx, "events", map[int]int64{0: 1, 1: -1})
+	if err != nil {
+		t.Fatalf("DeleteRecords() error = %v", err)
+	}
+	if want := map[int]int64{0: 1, 1: 2}; !reflect.DeepEqual(earliest, want) {
+		t.Errorf("DeleteRecords() = %v, want %v", earliest, want)
+	}
+	offsets, err := client.ListOffsets(ctx, "events")
+	if err != nil {
+		t.Fatalf("ListOffsets() error = %v", err)
+	}
+	if want := []PartitionOffsets{{0, 1, 2}, {1, 2, 2}}; !reflect.DeepEqual(offsets, want) {
+		t.Errorf("ListOffsets() after DeleteRecords() = %+v, want %+v", offsets, want)
+	}
+	if read, err := client.ReadMessages(ctx, "events", 0, 1, 10); err != nil || len(read) != 1 || string(read[0].Value) != "c" {
+		t.Errorf("ReadMessages() after DeleteRecords() = %+v, %v", read, err)
+	}
+	if _, err := client.ReadMessages(ctx, "events", 0, 0, 10); !errors.Is(err, kafka.OffsetOutOfRange) {
+		t.Errorf("ReadMessages() of a deleted record error = %v, want OffsetOutOfRange", err)
+	}
+
+	if _, err := client.DeleteRecords(ctx, "events", map[int]int64{0: 5}); !errors.Is(err, kafka.OffsetOutOfRange) {
+		t.Errorf("DeleteRecords() past the end error = %v, want OffsetOutOfRange", err)
+	}
+
+	expired, cancel := context.WithDeadline(ctx, time.Now().Add(-time.Second))
+	defer cancel()
+	if _, err := client.DeleteRecords(expired, "events", map[int]int64{0: 2}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("DeleteRecords() past the deadline error = %v, want DeadlineExceeded", err)
+	}
+}
+
+func TestClientGroups(t *testing.T) {
+	srv := newTestServer(t, kafkatest.Config{})
+	client := newTestClient(t, srv, config.KafkaClusterConfig{})
+	ctx := testContext(t)
+
+	if err := client.CreateTopic(ctx, "orders", 2, 1); err != nil {
+		t.Fatalf("CreateTopic() error = %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := client.ProduceMessage(ctx, "orders", Message{Value: []byte("order")}); err != nil {
+			t.Fatalf("ProduceMessage() error = %v", err)
+		}
+	}
+
+	// Join as a consumer, assign both partitions and commit partition 0
+	consumer := &kafka.Client{Addr: kafka.TCP(srv.Addrs...)}
+	join, err := consumer.JoinGroup(ctx, &kafka.JoinGroupRequest{
+		GroupID:          "billing",
+		SessionTimeout:   10 * time.Second,
+		RebalanceTimeout: 10 * time.Second,
+		ProtocolType:     "consumer",
+		Protocols: []kafka.GroupProtocol{{
+			Name:     "range",
+			Metadata: kafka.GroupProtocolSubscription{Topics: []string{"orders"}},
+		}},
+	})
+	if err == nil {
+		err = join.Error
+	}
+	if err != nil {
+		t.Fatalf("JoinGroup() error = %v", err)
+	}
+	sync, err := consumer.SyncGroup(ctx, &kafka.SyncGroupRequest{
+		GroupID:      "billing",
+		GenerationID: join.GenerationID,
+		MemberID:     join.MemberID,
+		ProtocolType: "consumer",
+		ProtocolName: join.ProtocolName,
+		Assignments: []kafka.SyncGroupRequestAssignment{{
+			MemberID:   join.MemberID,
+			Assignment: kafka.GroupProtocolAssignment{AssignedPartitions: map[string][]int{"orders": {0, 1}}},
+		}},
+	})
+	if err == nil {
+		err = sync.Error
+	}
+	if err != nil {
+		t.Fatalf("SyncGroup() error = %v", err)
+	}
+	if _, err := consumer.OffsetCommit(ctx, &kafka.OffsetCommitRequest{
+		GroupID:      "billing",
+		GenerationID: join.GenerationID,
+		MemberID:     join.MemberID,
+		Topics:       map[string][]kafka.OffsetCommit{"orders": {{Partition: 0, Offset: 0}}},
+	}); err != nil {
+		t.Fatalf("OffsetCommit() error = %v", err)
+	}
+
+	groups, err := client.ListGroups(ctx)
+	if err != nil {
+		t.Fatalf("ListGroups() error = %v", err)
+	}
+	if len(groups) != 1 || groups[0].GroupID != "billing" || groups[0].State != "Stable" || groups[0].Protocol != "range" || len(groups[0].Members) != 1 {
+		t.Fatalf("ListGroups() = %+v", groups)
+	}
+
+	group, err := client.DescribeGroup(ctx, "billing")
+	if err != nil {
+		t.Fatalf("DescribeGroup() error = %v", err)
+	}
+	if want := map[string][]int{"orders": {0, 1}}; !reflect.DeepEqual(group.Members[0].Assignments, want) {
+		t.Errorf("DescribeGroup() assignments = %v, want %v", group.Members[0].Assignments, want)
+	}
+
+	offsets, _ := client.ListOffsets(ctx, "orders")
+	want := []GroupOffset{{Topic: "orders", Partition: 0, CommittedOffset: 0, LogEndOffset: offsets[0].Latest, Lag: offsets[0].Latest}}
+	if !reflect.DeepEqual(group.Offsets, want) {
+		t.Errorf("DescribeGroup() offsets = %+v, want %+v", group.Offsets, want)
+	}
+
+	// Offsets of a group with members can only be committed by its members
+	if err := client.CommitGroupOffsets(ctx, "billing", []GroupOffset{{Topic: "orders", Partition: 1, CommittedOffset: 1}}); err == nil {
+		t.Error("CommitGroupOffsets() of a group with members succeeded")
+	}
+	if err := client.CommitGroupOffsets(ctx, "audit", []GroupOffset{{Topic: "orders", Partition: 1, CommittedOffset: 1}}); err != nil {
+		t.Fatalf("CommitGroupOffsets() error = %v", err)
+	}
+	if audit, err := client.DescribeGroup(ctx, "audit"); err != nil || len(audit.Offsets) != 1 || audit.Offsets[0].CommittedOffset != 1 {
+		t.Errorf("DescribeGroup() after CommitGroupOffsets() = %+v, %v", audit, err)
+	}
+
+	// Offsets of a topic can only be removed while no member consumes it
+	if err := client.DeleteGroupOffsets(ctx, "billing", "orders", nil); !errors.Is(err, kafka.GroupSubscribedToTopic) {
+		t.Errorf("DeleteGroupOffsets() of a subscribed topic error = %v, want %v", err, kafka.GroupSubscribedToTopic)
+	}
+	if err := client.DeleteGroupOffsets(ctx, "audit", "orders", nil); err != nil {
+		t.Fatalf("DeleteGroupOffsets() error = %v", err)
+	}
+	if audit, err := client.DescribeGroup(ctx, "audit"); err != nil || len(audit.Offsets) != 0 {
+		t.Errorf("DescribeGroup() after DeleteGroupOffsets() = %+v, %v", audit, err)
+	}
+
+	results, err := client.DeleteGroups(ctx, []string{"billing", "audit"})
+	if err != nil {
+		t.Fatalf("DeleteGroups() error = %v", err)
+	}
+	if !errors.Is(results["billing"], kafka.NonEmptyGroup) || results["audit"] != nil {
+		t.Errorf("DeleteGroups() = %v, want billing refused and audit deleted", results)
+	}
+
+	if _, err := client.DescribeGroup(ctx, "missing"); err == nil {
+		t.Error("DescribeGroup() of an unknown group succeeded")
+	}
+}
+
+func TestClientSASL(t *testing.T) {
+	users := map[string]string{"alice": "alice-secret"}
+	tests := []struct {
+		name    string
+		server  kafkatest.Config
+		cluster config.KafkaClusterConfig
+		wantErr bool
+	}{
+		{
+			name:    "plain",
+			server:  kafkatest.Config{SASLMechanism: "PLAIN", Users: users},
+			cluster: config.KafkaClusterConfig{SASL: true, SASLType: "PLAIN", Username: "alice", Password: "alice-secret"},
+		},
+		{
+			name:    "plain wrong password",
+			server:  kafkatest.Config{SASLMechanism: "PLAIN", Users: users},
+			cluster: config.KafkaClusterConfig{SASL: true, SASLType: "PLAIN", Username: "alice", Password: "guess"},
+			wantErr: true,
+		},
+		{
+			name:    "scram-sha-256",
+			server:  kafkatest.Config{SASLMechanism: "SCRAM-SHA-256", Users: users},
+			cluster: config.KafkaClusterConfig{SASL: true, SASLType: "SCRAM-SHA-256", Username: "alice", Password: "alice-secret"},
+		},
+		{
+			name:    "scram-sha-512 wrong password",
+			server:  kafkatest.Config{SASLMechanism: "SCRAM-SHA-512", Users: users},
+			cluster: config.KafkaClusterConfig{SASL: true, SASLType: "SCRAM-SHA-512", Username: "alice", Password: "guess"},
+			wantErr: true,
+		},
+		{
+			name:    "oauthbearer",
+			server:  kafkatest.Config{SASLMechanism: "OAUTHBEARER", Tokens: []string{"token-1"}},
+			cluster: config.KafkaClusterConfig{SASL: true, SASLType: "OAUTHBEARER", OAuthToken: "token-1"},
+		},
+		{
+			name:    "mechanism mismatch",
+			server:  kafkatest.Config{SASLMechanism: "SCRAM-SHA-512", Users: users},
+			cluster: config.KafkaClusterConfig{SASL: true, SASLType: "PLAIN", Username: "alice", Password: "alice-secret"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newTestServer(t, tt.server)
+
+			tt.cluster.Bootstrap = srv.Addrs
+			client := NewClient(tt.cluster)
+			defer client.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			err := client.Connect(ctx)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Connect() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if _, err := client.ListTopics(ctx); err != nil {
+				t.Errorf("ListTopics() error = %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package kafka
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ConfigType is the value type of a config
+type ConfigType string
+
+// Config value types, as named by Kafka
+const (
+	ConfigTypeBoolean ConfigType = "boolean"
+	ConfigTypeInt     ConfigType = "int"
+	ConfigTypeLong    ConfigType = "long"
+	ConfigTypeDouble  ConfigType = "double"
+	ConfigTypeString  ConfigType = "string"
+	ConfigTypeList    ConfigType = "list"
+)
+
+// ConfigDef describes a topic config and the values it accepts
+type ConfigDef struct {
+	Name        string
+	Type        ConfigType
+	Default     string
+	ValidValues []string // allowed strings or list items, anything if empty
+	Min         string   // lower bound of numeric values, if any
+	Max         string   // upper bound of numeric values, if any
+	Doc         string
+}
+
+// TopicConfigDefs lists the topic configs of Apache Kafka 3.x in name order
+var TopicConfigDefs = []ConfigDef{
+	{Name: "cleanup.policy", Type: ConfigTypeList, Default: "delete", ValidValues: []string{"compact", "delete"},
+		Doc: "Delete old segments, compact them to the latest value per key, or both"},
+	{Name: "compression.type", Type: ConfigTypeString, Default: "producer",
+		ValidValues: []string{"uncompressed", "zstd", "lz4", "snappy", "gzip", "producer"},
+		Doc:         "Compression of stored batches; producer keeps the producer's codec"},
+	{Name: "delete.retention.ms", Type: ConfigTypeLong, Default: "86400000", Min: "0",
+		Doc: "How long delete tombstones are kept on compacted topics"},
+	{Name: "file.delete.delay.ms", Type: ConfigTypeLong, Default: "60000", Min: "0",
+		Doc: "Time to wait before deleting a file from the filesystem"},
+	{Name: "flush.messages", Type: ConfigTypeLong, Default: "9223372036854775807", Min: "1",
+		Doc: "Number of messages after which data is fsynced to the log"},
+	{Name: "flush.ms", Type: ConfigTypeLong, Default: "9223372036854775807", Min: "0",
+		Doc: "Time after which data is fsynced to the log"},
+	{Name: "follower.replication.throttled.replicas", Type: ConfigTypeList, Default: "",
+		Doc: "Replicas (partition:broker) throttled on the follower side, or *"},
+	{Name: "index.interval.bytes", Type: ConfigTypeInt, Default: "4096", Min: "0",
+		Doc: "How often an entry is added to the offset index"},
+	{Name: "leader.replication.throttled.replicas", Type: ConfigTypeList, Default: "",
+		Doc: "Replicas (partition:broker) throttled on the leader side, or *"},
+	{Name: "local.retention.bytes", Type: ConfigTypeLong, Default: "-2", Min: "-2",
+		Doc: "Bytes kept locally with tiered storage; -2 uses retention.bytes"},
+	{Name: "local.retention.ms", Type: ConfigTypeLong, Default: "-2", Min: "-2",
+		Doc: "Time segments are kept locally with tiered storage; -2 uses retention.ms"},
+	{Name: "max.compaction.lag.ms", Type: ConfigTypeLong, Default: "9223372036854775807", Min: "1",
+		Doc: "Maximum time a message stays uncompacted"},
+	{Name: "max.message.bytes", Type: ConfigTypeInt, Default: "1048588", Min: "0",
+		Doc: "Largest record batch size allowed"},
+	{Name: "message.downconversion.enable", Type: ConfigTypeBoolean, Default: "true",
+		Doc: "Whether messages are down-converted for old consumers"},
+	{Name: "message.timestamp.difference.max.ms", Type: ConfigTypeLong, Default: "9223372036854775807", Min: "0",
+		Doc: "Maximum difference between the broker time and a message's CreateTime"},
+	{Name: "message.timestamp.type", Type: ConfigTypeString, Default: "CreateTime",
+		ValidValues: []string{"CreateTime", "LogAppendTime"},
+		Doc:         "Whether record timestamps are set by producers or the broker"},
+	{Name: "min.cleanable.dirty.ratio", Type: ConfigTypeDouble, Default: "0.5", Min: "0", Max: "1",
+		Doc: "Dirty log ratio above which the log is compacted"},
+	{Name: "min.compaction.lag.ms", Type: ConfigTypeLong, Default: "0", Min: "0",
+		Doc: "Minimum time a message stays uncompacted"},
+	{Name: "min.insync.replicas", Type: ConfigTypeInt, Default: "1", Min: "1",
+		Doc: "Minimum in-sync replicas for writes with acks=all"},
+	{Name: "preallocate", Type: ConfigTypeBoolean, Default: "false",
+		Doc: "Whether to preallocate files for new segments"},
+	{Name: "remote.storage.enable", Type: ConfigTypeBoolean, Default: "false",
+		Doc: "Whether tiered storage is enabled for the topic"},
+	{Name: "retention.bytes", Type: ConfigTypeLong, Default: "-1",
+		Doc: "Maximum size of a partition before old segments are deleted; -1 for no limit"},
+	{Name: "retention.ms", Type: ConfigTypeLong, Default: "604800000", Min: "-1",
+		Doc: "Maximum time a segment is kept before deletion; -1 for no limit"},
+	{Name: "segment.bytes", Type: ConfigTypeInt, Default: "1073741824", Min: "14",
+		Doc: "Size of a log segment file"},
+	{Name: "segment.index.bytes", Type: ConfigTypeInt, Default: "10485760", Min: "4",
+		Doc: "Size of the offset index of a segment"},
+	{Name: "segment.jitter.ms", Type: ConfigTypeLong, Default: "0", Min: "0",
+		Doc: "Maximum random jitter subtracted from segment.ms"},
+	{Name: "segment.ms", Type: ConfigTypeLong, Default: "604800000", Min: "1",
+		Doc: "Time after which a segment is rolled even if it is not full"},
+	{Name: "unclean.leader.election.enable", Type: ConfigTypeBoolean, Default: "false",
+		Doc: "Whether out-of-sync replicas may become leader, at the risk of data loss"},
+}
+
+// TopicConfigDef looks up the definition of a topic config
+func TopicConfigDef(name string) (ConfigDef, bool) {
+	for _, def := range TopicConfigDefs {
+		if def.Name == name {
+			return def, true
+		}
+	}
+	return ConfigDef{}, false
+}
+
+// ValidateTopicConfig checks a value against the definition of a topic config.
+// Configs without a known definition are left to the broker to check.
+func ValidateTopicConfig(name, value string) error {
+	def, ok := TopicConfigDef(name)
+	if !ok {
+		return nil
+	}
+	return def.Validate(value)
+}
+
+// Validate checks that a value has the config's type and is allowed
+func (d ConfigDef) Validate(value string) error {
+	value = strings.TrimSpace(value)
+
+	switch d.Type {
+	case ConfigTypeBoolean:
+		if !strings.EqualFold(value, "true") && !strings.EqualFold(value, "false") {
+			return fmt.Errorf("%s must be true or false", d.Name)
+		}
+	case ConfigTypeInt, ConfigTypeLong:
+		bits := 64
+		if d.Type == ConfigTypeInt {
+			bits = 32
+		}
+		n, err := strconv.ParseInt(value, 10, bits)
+		if err != nil {
+			return fmt.Errorf("%s must be a whole number (%s)", d.Name, d.Type)
+		}
+		return d.checkRange(float64(n))
+	case ConfigTypeDouble:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("%s must be a number", d.Name)
+		}
+		return d.checkRange(f)
+	case ConfigTypeString:
+		if len(d.ValidValues) > 0 && !contains(d.ValidValues, value) {
+			return fmt.Errorf("%s must be one of %s", d.Name, strings.Join(d.ValidValues, ", "))
+		}
+	case ConfigTypeList:
+		if len(d.ValidValues) == 0 {
+			return nil
+		}
+		if value == "" {
+			return fmt.Errorf("%s needs at least one of %s", d.Name, strings.Join(d.ValidValues, ", "))
+		}
+		for _, item := range strings.Split(value, ",") {
+			if !contains(d.ValidValues, strings.TrimSpace(item)) {
+				return fmt.Errorf("%s items must be one of %s", d.Name, strings.Join(d.ValidValues, ", "))
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkRange checks a numeric value against the bounds of the config
+func (d ConfigDef) checkRange(v float64) error {
+	if d.Min != "" {
+		if min, _ := strconv.ParseFloat(d.Min, 64); v < min {
+			return fmt.Errorf("%s must be at least %s", d.Name, d.Min)
+		}
+	}
+	if d.Max != "" {
+		if max, _ := strconv.ParseFloat(d.Max, 64); v > max {
+			return fmt.Errorf("%s must be at most %s", d.Name, d.Max)
+		}
+	}
+	return nil
+}
+
+// contains reports whether a slice holds a string
+func contains(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,36 @@
+package kafka
+
+import "testing"
+
+func TestValidateTopicConfig(t *testing.T) {
+	tests := []struct {
+		name, value string
+		valid       bool
+	}{
+		{"retention.ms", "3600000", true},
+		{"retention.ms", "-1", true},
+		{"retention.ms", "-2", false},
+		{"retention.ms", "1h", false},
+		{"max.message.bytes", "2147483648", false},
+		{"min.insync.replicas", "0", false},
+		{"min.insync.replicas", "2", true},
+		{"cleanup.policy", "compact,delete", true},
+		{"cleanup.policy", "compact, delete", true},
+		{"cleanup.policy", "archive", false},
+		{"cleanup.policy", "", false},
+		{"compression.type", "zstd", true},
+		{"compression.type", "brotli", false},
+		{"min.cleanable.dirty.ratio", "0.25", true},
+		{"min.cleanable.dirty.ratio", "1.5", false},
+		{"preallocate", "TRUE", true},
+		{"preallocate", "yes", false},
+		{"custom.setting", "anything", true},
+	}
+
+	for _, tt := range tests {
+		err := ValidateTopicConfig(tt.name, tt.value)
+		if (err == nil) != tt.valid {
+			t.Errorf("ValidateTopicConfig(%q, %q) error = %v, want valid %v", tt.name, tt.value, err, tt.valid)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Config sources reported by DescribeConfigs
+const (
+	ConfigSourceUnknown              int8 = 0
+	ConfigSourceDynamicTopic         int8 = 1
+	ConfigSourceDynamicBroker        int8 = 2
+	ConfigSourceDynamicDefaultBroker int8 = 3
+	ConfigSourceStaticBroker         int8 = 4
+	ConfigSourceDefault              int8 = 5
+	ConfigSourceDynamicBrokerLogger  int8 = 6
+)
+
+// ConfigEntry is a topic config with the origin of its value
+type ConfigEntry struct {
+	Name      string
+	Value     string
+	Source    int8 // one of the ConfigSource constants
+	ReadOnly  bool
+	Sensitive bool
+}
+
+// IsDefault reports whether the value is Kafka's built-in default
+func (e ConfigEntry) IsDefault() bool {
+	return e.Source == ConfigSourceDefault
+}
+
+// ConfigSourceName describes a config source as default, dynamic or static
+func ConfigSourceName(source int8) string {
+	switch source {
+	case ConfigSourceDynamicTopic:
+		return "dynamic"
+	case ConfigSourceDynamicBroker:
+		return "dynamic broker"
+	case ConfigSourceDynamicDefaultBroker:
+		return "dynamic broker default"
+	case ConfigSourceStaticBroker:
+		return "static"
+	case ConfigSourceDefault:
+		return "default"
+	case ConfigSourceDynamicBrokerLogger:
+		return "dynamic broker logger"
+	}
+	return "unknown"
+}
+
+// describeTopicConfig reads all config entries of a topic
+func (c *Client) describeTopicConfig(ctx context.Context, topicName string) ([]kafka.DescribeConfigResponseConfigEntry, error) {
+	resp, err := c.admin.DescribeConfigs(ctx, &kafka.DescribeConfigsRequest{
+		Resources: []kafka.DescribeConfigRequestResource{{
+			ResourceType: kafka.ResourceTypeTopic,
+			ResourceName: topicName,
+		}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe configs for topic %s: %w", topicName, err)
+	}
+
+	for _, resource := range resp.Resources {
+		if resource.ResourceName != topicName {
+			continue
+		}
+		if resource.Error != nil {
+			return nil, fmt.Errorf("failed to describe configs for topic %s: %w", topicName, resource.Error)
+		}
+
+		entries := resource.ConfigEntries
+		sort.Slice(entries, func(i, j int) bool { return entries[i].ConfigName < entries[j].ConfigName })
+		return entries, nil
+	}
+
+	return nil, fmt.Errorf("no configs returned for topic %s", topicName)
+}
+
+// ConfigChange sets a topic config, or removes its override when Delete is set
+type ConfigChange struct {
+	Name   string
+	Value  string
+	Delete bool
+}
+
+// UpdateTopicConfig sets topic-level configs with an incremental alter,
+// leaving configs that are not mentioned untouched
+func (c *Client) UpdateTopicConfig(ctx context.Context, topicName string, configs map[string]string) error {
+	return c.AlterTopicConfig(ctx, topicName, SetConfigs(configs))
+}
+
+// SetConfigs turns config values into changes setting them, in name order
+func SetConfigs(configs map[string]string) []ConfigChange {
+	changes := make([]ConfigChange, 0, len(configs))
+	for name, value := range configs {
+		changes = append(changes, ConfigChange{Name: name, Value: value})
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Name < changes[j].Name })
+	return changes
+}
+
+// AlterTopicConfig sets and removes topic-level configs with an incremental
+// alter. Removed configs fall back to the broker or Kafka default.
+func (c *Client) AlterTopicConfig(ctx context.Context, topicName string, changes []ConfigChange) error {
+	if c.admin == nil {
+		return fmt.Errorf("not connected to Kafka")
+	}
+
+	resource := kafka.IncrementalAlterConfigsRequestResource{
+		ResourceType: kafka.ResourceTypeTopic,
+		ResourceName: topicName,
+	}
+	for _, change := range changes {
+		op := kafka.ConfigOperationSet
+		if change.Delete {
+			op = kafka.ConfigOperationDelete
+		}
+		resource.Configs = append(resource.Configs, kafka.IncrementalAlterConfigsRequestConfig{
+			Name:            change.Name,
+			Value:           change.Value,
+			ConfigOperation: op,
+		})
+	}
+
+	resp, err := c.admin.IncrementalAlterConfigs(ctx, &kafka.IncrementalAlterConfigsRequest{
+		Resources: []kafka.IncrementalAlterConfigsRequestResource{resource},
+	})
+	if err == nil {
+		for _, r := range resp.Resources {
+			if r.Error != nil {
+				err = r.Error
+				break
+			}
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update configs for topic %s: %w", topicName, err)
+	}
+
+	return nil
+}
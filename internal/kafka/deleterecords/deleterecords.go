@@ -0,0 +1,115 @@
+// Package deleterecords implements the DeleteRecords API of the Kafka
+// protocol, which kafka-go knows the key of but has no messages for.
+//
+// Importing the package registers the messages, so that kafka-go transports
+// can send them and protocol.ReadRequest can decode them.
+package deleterecords
+
+import (
+	"fmt"
+
+	"github.com/segmentio/kafka-go/protocol"
+)
+
+func init() {
+	protocol.Register(&Request{}, &Response{})
+}
+
+// Request deletes the records before an offset of partitions. Brokers only
+// accept partitions they lead, so the request is split by partition.
+type Request struct {
+	Topics    []RequestTopic `kafka:"min=v0,max=v1"`
+	TimeoutMs int32          `kafka:"min=v0,max=v1"`
+}
+
+// RequestTopic lists the partitions of a topic to delete records from
+type RequestTopic struct {
+	Name       string             `kafka:"min=v0,max=v1"`
+	Partitions []RequestPartition `kafka:"min=v0,max=v1"`
+}
+
+// RequestPartition deletes the records of a partition before an offset, or
+// every record with an offset of -1
+type RequestPartition struct {
+	PartitionIndex int32 `kafka:"min=v0,max=v1"`
+	Offset         int64 `kafka:"min=v0,max=v1"`
+}
+
+// ApiKey returns the DeleteRecords API key
+func (r *Request) ApiKey() protocol.ApiKey { return protocol.DeleteRecords }
+
+// Broker routes a request of a single partition, as returned by Split, to the
+// leader of the partition
+func (r *Request) Broker(cluster protocol.Cluster) (protocol.Broker, error) {
+	topic := r.Topics[0].Name
+	partition := r.Topics[0].Partitions[0].PartitionIndex
+
+	for _, p := range cluster.Topics[topic].Partitions {
+		if p.ID == partition {
+			return cluster.Brokers[p.Leader], nil
+		}
+	}
+	return protocol.Broker{ID: -1}, fmt.Errorf("partition %d of topic %s not found", partition, topic)
+}
+
+// Split makes a request of every partition
+func (r *Request) Split(cluster protocol.Cluster) ([]protocol.Message, protocol.Merger, error) {
+	var messages []protocol.Message
+	for _, t := range r.Topics {
+		for _, p := range t.Partitions {
+			messages = append(messages, &Request{
+				Topics:    []RequestTopic{{Name: t.Name, Partitions: []RequestPartition{p}}},
+				TimeoutMs: r.TimeoutMs,
+			})
+		}
+	}
+	return messages, new(Response), nil
+}
+
+// Response holds the new low watermark, the first offset kept, of every
+// partition
+type Response struct {
+	ThrottleTimeMs int32           `kafka:"min=v0,max=v1"`
+	Topics         []ResponseTopic `kafka:"min=v0,max=v1"`
+}
+
+// ResponseTopic holds the results of the partitions of a topic
+type ResponseTopic struct {
+	Name       string              `kafka:"min=v0,max=v1"`
+	Partitions []ResponsePartition `kafka:"min=v0,max=v1"`
+}
+
+// ResponsePartition is the result of a partition
+type ResponsePartition struct {
+	PartitionIndex int32 `kafka:"min=v0,max=v1"`
+	LowWatermark   int64 `kafka:"min=v0,max=v1"`
+	ErrorCode      int16 `kafka:"min=v0,max=v1"`
+}
+
+// ApiKey returns the DeleteRecords API key
+func (r *Response) ApiKey() protocol.ApiKey { return protocol.DeleteRecords }
+
+// Merge combines the responses of the requests of Split. It fails if any of
+// the requests did, since records may then be left in some partitions.
+func (r *Response) Merge(requests []protocol.Message, results []interface{}) (protocol.Message, error) {
+	index := make(map[string]int)
+	for _, res := range results {
+		m, err := protocol.Result(res)
+		if err != nil {
+			return nil, err
+		}
+		response := m.(*Response)
+		r.ThrottleTimeMs = max(r.ThrottleTimeMs, response.ThrottleTimeMs)
+
+		for _, t := range response.Topics {
+			i, ok := index[t.Name]
+			if !ok {
+				i = len(r.Topics)
+				index[t.Name] = i
+				r.Topics = append(r.Topics, ResponseTopic{Name: t.Name})
+			}
+			r.Topics[i].Partitions = append(r.Topics[i].Partitions, t.Partitions...)
+		}
+	}
+	return r, nil
+}
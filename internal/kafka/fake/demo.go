@@ -0,0 +1,90 @@
+package fake
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cfk-dev/cfk/internal/kafka"
+)
+
+// NewDemoCluster creates a cluster with sample topics, messages and consumer
+// groups, used by the --demo mode
+func NewDemoCluster() *Cluster {
+	c := NewCluster()
+	ctx := context.Background()
+
+	c.CreateTopic(ctx, "orders", 6, 3)
+	c.UpdateTopicConfig(ctx, "orders", map[string]string{"retention.ms": "604800000"})
+	c.CreateTopic(ctx, "payments", 3, 3)
+	c.UpdateTopicConfig(ctx, "payments", map[string]string{"min.insync.replicas": "2"})
+	c.CreateTopic(ctx, "customer-profiles", 3, 3)
+	c.UpdateTopicConfig(ctx, "customer-profiles", map[string]string{"cleanup.policy": "compact"})
+	c.CreateTopic(ctx, "audit-log", 1, 1)
+
+	start := time.Now().Add(-2 * time.Hour)
+	statuses := []string{"created", "paid", "shipped", "delivered"}
+	for i := 0; i < 120; i++ {
+		ts := start.Add(time.Duration(i) * time.Minute)
+		orderID := fmt.Sprintf("order-%04d", 1000+i)
+		customerID := fmt.Sprintf("customer-%02d", i%17)
+
+		c.ProduceMessage(ctx, "orders", kafka.Message{
+			Partition: -1,
+			Timestamp: ts,
+			Key:       []byte(orderID),
+			Value: []byte(fmt.Sprintf(`{"order_id":%q,"customer_id":%q,"status":%q,"amount":%d.%02d}`,
+				orderID, customerID, statuses[i%len(statuses)], 10+i%90, i%100)),
+			Headers: []kafka.Header{{Key: "source", Value: []byte("web")}},
+		})
+		if i%3 == 0 {
+			c.ProduceMessage(ctx, "payments", kafka.Message{
+				Partition: -1,
+				Timestamp: ts.Add(10 * time.Second),
+				Key:       []byte(orderID),
+				Value:     []byte(fmt.Sprintf(`{"order_id":%q,"method":"card","approved":%t}`, orderID, i%9 != 0)),
+			})
+		}
+		if i < 17 {
+			c.ProduceMessage(ctx, "customer-profiles", kafka.Message{
+				Partition: -1,
+				Timestamp: ts,
+				Key:       []byte(customerID),
+				Value:     []byte(fmt.Sprintf(`{"customer_id":%q,"name":"Customer %d","tier":"gold"}`, customerID, i)),
+			})
+		}
+		if i%10 == 0 {
+			c.ProduceMessage(ctx, "audit-log", kafka.Message{
+				Partition: 0,
+				Timestamp: ts,
+				Value:     []byte(fmt.Sprintf("user admin viewed %s", orderID)),
+			})
+		}
+	}
+
+	// A running consumer group that is a few records behind
+	c.JoinGroup("order-service", kafka.GroupMember{
+		MemberID:    "order-service-1-5f1c",
+		ClientID:    "order-service-1",
+		Host:        "/10.0.1.15",
+		Assignments: map[string][]int{"orders": {0, 1, 2}},
+	})
+	c.JoinGroup("order-service", kafka.GroupMember{
+		MemberID:    "order-service-2-9a7e",
+		ClientID:    "order-service-2",
+		Host:        "/10.0.1.16",
+		Assignments: map[string][]int{"orders": {3, 4, 5}},
+	})
+	offsets, _ := c.ListOffsets(ctx, "orders")
+	for _, p := range offsets {
+		c.CommitOffset("order-service", "orders", p.Partition, p.Latest-int64(p.Partition%3))
+	}
+
+	// A stopped group that can have its offsets reset
+	offsets, _ = c.ListOffsets(ctx, "payments")
+	for _, p := range offsets {
+		c.CommitOffset("payments-reconciler", "payments", p.Partition, p.Latest/2)
+	}
+
+	return c
+}
@@ -0,0 +1,304 @@
+// Package fake provides an in-memory Kafka cluster for tests and the demo mode
+package fake
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cfk-dev/cfk/internal/kafka"
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+// Brokers is the number of brokers of a fake cluster, which limits the
+// replication factor of its topics
+const Brokers = 3
+
+// Cluster is an in-memory stand-in for a Kafka cluster.
+// It implements the same operations as kafka.Client and is safe for concurrent use.
+type Cluster struct {
+	mu     sync.Mutex
+	topics map[string]*topic
+	groups map[string]*group
+	down   map[int]bool // stopped brokers
+	now    func() time.Time
+}
+
+// topic is an in-memory topic
+type topic struct {
+	replicationFactor int
+	partitions        []*partitionLog
+	configs           map[string]string
+}
+
+// partitionLog holds the replicas and records of a partition. Records
+// before startOffset have been deleted.
+type partitionLog struct {
+	replicas    []int
+	startOffset int64
+	records     []kafka.Message
+}
+
+// endOffset returns the offset of the next record
+func (p *partitionLog) endOffset() int64 {
+	return p.startOffset + int64(len(p.records))
+}
+
+// group is an in-memory consumer group
+type group struct {
+	state    string
+	protocol string
+	members  []kafka.GroupMember
+	offsets  map[string]map[int]int64 // topic -> partition -> committed offset
+}
+
+// NewCluster creates an empty cluster
+func NewCluster() *Cluster {
+	return &Cluster{
+		topics: make(map[string]*topic),
+		groups: make(map[string]*group),
+		down:   make(map[int]bool),
+		now:    time.Now,
+	}
+}
+
+// Close implements core.KafkaAdmin; the fake holds no connections
+func (c *Cluster) Close() error {
+	return nil
+}
+
+// ListTopics lists all topics in name order
+func (c *Cluster) ListTopics(ctx context.Context) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	topics := make([]string, 0, len(c.topics))
+	for name := range c.topics {
+		topics = append(topics, name)
+	}
+	sort.Strings(topics)
+
+	return topics, nil
+}
+
+// GetTopicInfo gets the partitions, replication factor and configs of a topic
+func (c *Cluster) GetTopicInfo(ctx context.Context, topicName string) (*kafka.TopicInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t, err := c.topic(topicName)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &kafka.TopicInfo{
+		Name:              topicName,
+		Partitions:        len(t.partitions),
+		ReplicationFactor: t.replicationFactor,
+		Config:            make(map[string]string, len(t.configs)),
+	}
+	for k, v := range t.configs {
+		info.Config[k] = v
+	}
+
+	return info, nil
+}
+
+// DescribeTopic gets the replica state and offsets of every partition of a
+// topic, and its configs. Overrides are reported as dynamic topic configs.
+func (c *Cluster) DescribeTopic(ctx context.Context, topicName string) (*kafka.TopicDetails, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t, err := c.topic(topicName)
+	if err != nil {
+		return nil, err
+	}
+
+	details := &kafka.TopicDetails{Name: topicName, ReplicationFactor: t.replicationFactor}
+	for i, p := range t.partitions {
+		partition := kafka.PartitionDetails{
+			Partition: i,
+			Leader:    -1,
+			Replicas:  append([]int(nil), p.replicas...),
+			ISR:       []int{},
+			Offline:   []int{},
+			Earliest:  -1,
+			Latest:    -1,
+		}
+		for _, id := range p.replicas {
+			if c.down[id] {
+				partition.Offline = append(partition.Offline, id)
+				continue
+			}
+			if partition.Leader < 0 {
+				partition.Leader = id
+			}
+			partition.ISR = append(partition.ISR, id)
+		}
+		if !partition.Leaderless() {
+			partition.Earliest = p.startOffset
+			partition.Latest = p.endOffset()
+		}
+		details.Partitions = append(details.Partitions, partition)
+	}
+
+	configs := make(map[string]kafka.ConfigEntry)
+	for _, def := range kafka.TopicConfigDefs {
+		configs[def.Name] = kafka.ConfigEntry{Name: def.Name, Value: def.Default, Source: kafka.ConfigSourceDefault}
+	}
+	for name, value := range t.configs {
+		configs[name] = kafka.ConfigEntry{Name: name, Value: value, Source: kafka.ConfigSourceDynamicTopic}
+	}
+	for _, entry := range configs {
+		details.Configs = append(details.Configs, entry)
+	}
+	sort.Slice(details.Configs, func(i, j int) bool { return details.Configs[i].Name < details.Configs[j].Name })
+
+	return details, nil
+}
+
+// StopBroker marks a broker as down. Its replicas drop out of the in-sync
+// replicas, and partitions without another live replica lose their leader.
+func (c *Cluster) StopBroker(id int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.down[id] = true
+}
+
+// topic looks up a topic; the caller holds the lock
+func (c *Cluster) topic(topicName string) (*topic, error) {
+	t, ok := c.topics[topicName]
+	if !ok {
+		return nil, fmt.Errorf("topic %s not found", topicName)
+	}
+	return t, nil
+}
+
+// CreateTopic creates a topic
+func (c *Cluster) CreateTopic(ctx context.Context, topicName string, numPartitions int, replicationFactor int) error {
+	return c.CreateTopicFromSpec(ctx, kafka.TopicSpec{
+		Name:              topicName,
+		Partitions:        numPartitions,
+		ReplicationFactor: replicationFactor,
+	})
+}
+
+// CreateTopicFromSpec creates a topic with the layout and configs of a spec
+func (c *Cluster) CreateTopicFromSpec(ctx context.Context, spec kafka.TopicSpec) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.topics[spec.Name]; ok {
+		return fmt.Errorf("failed to create topic %s: %w", spec.Name, kafkago.TopicAlreadyExists)
+	}
+	for name := range c.topics {
+		if kafka.TopicNamesCollide(name, spec.Name) {
+			return fmt.Errorf("failed to create topic %s: %w", spec.Name, kafkago.InvalidTopic)
+		}
+	}
+	if err := spec.Validate(); err != nil {
+		return fmt.Errorf("failed to create topic %s: %w", spec.Name, err)
+	}
+
+	t := &topic{
+		replicationFactor: spec.ReplicationFactor,
+		configs:           make(map[string]string),
+	}
+	for name, value := range spec.Configs {
+		t.configs[name] = value
+	}
+
+	if len(spec.ReplicaAssignment) > 0 {
+		t.replicationFactor = len(spec.ReplicaAssignment[0])
+		for _, replicas := range spec.ReplicaAssignment {
+			for _, id := range replicas {
+				if id >= Brokers {
+					return fmt.Errorf("failed to create topic %s: %w", spec.Name, kafkago.InvalidReplicaAssignment)
+				}
+			}
+			t.partitions = append(t.partitions, &partitionLog{replicas: append([]int(nil), replicas...)})
+		}
+	} else {
+		if spec.ReplicationFactor > Brokers {
+			return fmt.Errorf("failed to create topic %s: %w", spec.Name, kafkago.InvalidReplicationFactor)
+		}
+		t.addPartitions(spec.Partitions)
+	}
+	c.topics[spec.Name] = t
+
+	return nil
+}
+
+// DeleteTopic deletes a topic and the committed offsets on it
+func (c *Cluster) DeleteTopic(ctx context.Context, topicName string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, err := c.topic(topicName); err != nil {
+		return fmt.Errorf("failed to delete topic %s: %w", topicName, err)
+	}
+	delete(c.topics, topicName)
+	for _, g := range c.groups {
+		delete(g.offsets, topicName)
+	}
+
+	return nil
+}
+
+// UpdateTopicPartitions adds partitions to a topic
+func (c *Cluster) UpdateTopicPartitions(ctx context.Context, topicName string, numPartitions int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t, err := c.topic(topicName)
+	if err != nil {
+		return fmt.Errorf("failed to get topic info: %w", err)
+	}
+	if numPartitions <= len(t.partitions) {
+		return fmt.Errorf("new partition count must be greater than current count (%d)", len(t.partitions))
+	}
+
+	t.addPartitions(numPartitions)
+
+	return nil
+}
+
+// addPartitions grows a topic to n partitions, spreading their replicas
+// over the brokers
+func (t *topic) addPartitions(n int) {
+	for i := len(t.partitions); i < n; i++ {
+		p := &partitionLog{}
+		for r := 0; r < t.replicationFactor; r++ {
+			p.replicas = append(p.replicas, (i+r)%Brokers)
+		}
+		t.partitions = append(t.partitions, p)
+	}
+}
+
+// UpdateTopicConfig sets topic-level configs, leaving other configs untouched
+func (c *Cluster) UpdateTopicConfig(ctx context.Context, topicName string, configs map[string]string) error {
+	return c.AlterTopicConfig(ctx, topicName, kafka.SetConfigs(configs))
+}
+
+// AlterTopicConfig sets and removes topic-level configs
+func (c *Cluster) AlterTopicConfig(ctx context.Context, topicName string, changes []kafka.ConfigChange) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t, err := c.topic(topicName)
+	if err != nil {
+		return fmt.Errorf("failed to update config of topic %s: %w", topicName, err)
+	}
+	for _, change := range changes {
+		if change.Delete {
+			delete(t.configs, change.Name)
+		} else {
+			t.configs[change.Name] = change.Value
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,221 @@
+package fake
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/cfk-dev/cfk/internal/kafka"
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+// Consumer group states
+const (
+	GroupStateEmpty  = "Empty"
+	GroupStateStable = "Stable"
+)
+
+// ListGroups lists all consumer groups in ID order
+func (c *Cluster) ListGroups(ctx context.Context) ([]kafka.GroupInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	groups := make([]kafka.GroupInfo, 0, len(c.groups))
+	for id, g := range c.groups {
+		groups = append(groups, g.info(id))
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].GroupID < groups[j].GroupID })
+
+	return groups, nil
+}
+
+// DescribeGroup gets the members and committed offsets of a consumer group
+func (c *Cluster) DescribeGroup(ctx context.Context, groupID string) (*kafka.GroupInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	g, ok := c.groups[groupID]
+	if !ok {
+		return nil, fmt.Errorf("group %s not found", groupID)
+	}
+
+	info := g.info(groupID)
+	for topicName, partitions := range g.offsets {
+		for partition, committed := range partitions {
+			offset := kafka.GroupOffset{
+				Topic:           topicName,
+				Partition:       partition,
+				CommittedOffset: committed,
+			}
+			if t, ok := c.topics[topicName]; ok && partition < len(t.partitions) {
+				offset.LogEndOffset = t.partitions[partition].endOffset()
+			}
+			if offset.LogEndOffset > committed {
+				offset.Lag = offset.LogEndOffset - committed
+			}
+			info.Offsets = append(info.Offsets, offset)
+		}
+	}
+	sort.Slice(info.Offsets, func(i, j int) bool {
+		if info.Offsets[i].Topic != info.Offsets[j].Topic {
+			return info.Offsets[i].Topic < info.Offsets[j].Topic
+		}
+		return info.Offsets[i].Partition < info.Offsets[j].Partition
+	})
+
+	return &info, nil
+}
+
+// info returns the group summary without offsets
+func (g *group) info(groupID string) kafka.GroupInfo {
+	info := kafka.GroupInfo{
+		GroupID:      groupID,
+		State:        g.state,
+		ProtocolType: "consumer",
+		Protocol:     g.protocol,
+	}
+	for _, m := range g.members {
+		member := m
+		member.Assignments = make(map[string][]int, len(m.Assignments))
+		for topicName, partitions := range m.Assignments {
+			member.Assignments[topicName] = append([]int(nil), partitions...)
+		}
+		info.Members = append(info.Members, member)
+	}
+	return info
+}
+
+// group returns a consumer group, creating it in the Empty state if needed.
+// The caller holds the lock.
+func (c *Cluster) group(groupID string) *group {
+	g, ok := c.groups[groupID]
+	if !ok {
+		g = &group{
+			state:   GroupStateEmpty,
+			offsets: make(map[string]map[int]int64),
+		}
+		c.groups[groupID] = g
+	}
+	return g
+}
+
+// CommitOffset commits an offset for a group, creating the group if needed
+func (c *Cluster) CommitOffset(groupID, topicName string, partition int, offset int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	g := c.group(groupID)
+	if g.offsets[topicName] == nil {
+		g.offsets[topicName] = make(map[int]int64)
+	}
+	g.offsets[topicName][partition] = offset
+}
+
+// CommitGroupOffsets commits offsets for a group without joining it, which
+// is refused while the group has members
+func (c *Cluster) CommitGroupOffsets(ctx context.Context, groupID string, offsets []kafka.GroupOffset) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if g, ok := c.groups[groupID]; ok && len(g.members) > 0 {
+		return fmt.Errorf("failed to commit offsets of group %s: %w", groupID, kafkago.UnknownMemberId)
+	}
+	for _, o := range offsets {
+		if _, err := c.partition(o.Topic, o.Partition); err != nil {
+			return fmt.Errorf("failed to commit offsets of group %s: %w", groupID, err)
+		}
+	}
+
+	g := c.group(groupID)
+	for _, o := range offsets {
+		if g.offsets[o.Topic] == nil {
+			g.offsets[o.Topic] = make(map[int]int64)
+		}
+		g.offsets[o.Topic][o.Partition] = o.CommittedOffset
+	}
+
+	return nil
+}
+
+// DeleteGroups deletes groups without members, reporting an error for each
+// group that could not be deleted
+func (c *Cluster) DeleteGroups(ctx context.Context, groupIDs []string) (map[string]error, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	results := make(map[string]error, len(groupIDs))
+	for _, groupID := range groupIDs {
+		g, ok := c.groups[groupID]
+		switch {
+		case !ok:
+			results[groupID] = fmt.Errorf("failed to delete group %s: %w", groupID, kafkago.GroupIdNotFound)
+		case len(g.members) > 0:
+			results[groupID] = fmt.Errorf("failed to delete group %s: %w", groupID, kafkago.NonEmptyGroup)
+		default:
+			delete(c.groups, groupID)
+			results[groupID] = nil
+		}
+	}
+
+	return results, nil
+}
+
+// DeleteGroupOffsets removes the committed offsets of a group for partitions
+// of a topic, or all of them if none are given. It is refused while members
+// are assigned partitions of the topic.
+func (c *Cluster) DeleteGroupOffsets(ctx context.Context, groupID, topicName string, partitions []int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	g, ok := c.groups[groupID]
+	if !ok {
+		return fmt.Errorf("failed to delete offsets of group %s: %w", groupID, kafkago.GroupIdNotFound)
+	}
+	for _, m := range g.members {
+		if len(m.Assignments[topicName]) > 0 {
+			return fmt.Errorf("failed to delete offsets of group %s for topic %s: %w", groupID, topicName, kafkago.GroupSubscribedToTopic)
+		}
+	}
+	for _, p := range partitions {
+		if _, err := c.partition(topicName, p); err != nil {
+			return fmt.Errorf("failed to delete offsets of group %s: %w", groupID, err)
+		}
+	}
+
+	if len(partitions) == 0 {
+		delete(g.offsets, topicName)
+		return nil
+	}
+	for _, p := range partitions {
+		delete(g.offsets[topicName], p)
+	}
+	if len(g.offsets[topicName]) == 0 {
+		delete(g.offsets, topicName)
+	}
+
+	return nil
+}
+
+// JoinGroup adds a member to a group, which makes the group Stable
+func (c *Cluster) JoinGroup(groupID string, member kafka.GroupMember) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	g := c.group(groupID)
+	if g.protocol == "" {
+		g.protocol = "range"
+	}
+	g.members = append(g.members, member)
+	g.state = GroupStateStable
+}
+
+// LeaveGroup removes all members of a group, which makes the group Empty
+func (c *Cluster) LeaveGroup(groupID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if g, ok := c.groups[groupID]; ok {
+		g.members = nil
+		g.state = GroupStateEmpty
+	}
+}
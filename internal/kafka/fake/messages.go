@@ -0,0 +1,167 @@
+package fake
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cfk-dev/cfk/internal/kafka"
+)
+
+// ListOffsets gets the earliest and latest offsets of every partition of a topic
+func (c *Cluster) ListOffsets(ctx context.Context, topicName string) ([]kafka.PartitionOffsets, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t, err := c.topic(topicName)
+	if err != nil {
+		return nil, err
+	}
+
+	offsets := make([]kafka.PartitionOffsets, len(t.partitions))
+	for i, p := range t.partitions {
+		offsets[i] = kafka.PartitionOffsets{
+			Partition: i,
+			Earliest:  p.startOffset,
+			Latest:    p.endOffset(),
+		}
+	}
+
+	return offsets, nil
+}
+
+// OffsetsForTime finds the first offset at or after t in every partition of
+// a topic, or -1 if there is none
+func (c *Cluster) OffsetsForTime(ctx context.Context, topicName string, t time.Time) (map[int]int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	tp, err := c.topic(topicName)
+	if err != nil {
+		return nil, err
+	}
+
+	offsets := make(map[int]int64, len(tp.partitions))
+	for i, p := range tp.partitions {
+		offsets[i] = -1
+		for _, r := range p.records {
+			if !r.Timestamp.Before(t) {
+				offsets[i] = r.Offset
+				break
+			}
+		}
+	}
+
+	return offsets, nil
+}
+
+// ReadMessages reads up to limit messages of a partition starting at offset
+func (c *Cluster) ReadMessages(ctx context.Context, topicName string, partition int, offset int64, limit int) ([]kafka.Message, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p, err := c.partition(topicName, partition)
+	if err != nil {
+		return nil, err
+	}
+	if offset < p.startOffset {
+		offset = p.startOffset
+	}
+
+	var messages []kafka.Message
+	for i := offset - p.startOffset; i < int64(len(p.records)) && len(messages) < limit; i++ {
+		messages = append(messages, p.records[i])
+	}
+
+	return messages, nil
+}
+
+// ProduceMessage appends a message to a partition.
+// A negative partition picks one from the key like the real client.
+func (c *Cluster) ProduceMessage(ctx context.Context, topicName string, msg kafka.Message) (*kafka.Message, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t, err := c.topic(topicName)
+	if err != nil {
+		return nil, err
+	}
+	if msg.Partition < 0 {
+		msg.Partition = kafka.PartitionForKey(msg.Key, len(t.partitions))
+	}
+
+	p, err := c.partition(topicName, msg.Partition)
+	if err != nil {
+		return nil, err
+	}
+
+	msg.Topic = topicName
+	msg.Offset = p.endOffset()
+	if msg.Timestamp.IsZero() {
+		msg.Timestamp = c.now()
+	}
+	p.records = append(p.records, msg)
+
+	return &msg, nil
+}
+
+// ProduceMessages appends messages to their partitions like the real client.
+// Compression is ignored; without acks the offsets are -1.
+func (c *Cluster) ProduceMessages(ctx context.Context, topicName string, messages []kafka.Message, opts kafka.ProduceOptions) ([]kafka.ProduceResult, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	results := make([]kafka.ProduceResult, len(messages))
+	for i, msg := range messages {
+		produced, err := c.ProduceMessage(ctx, topicName, msg)
+		if err != nil {
+			results[i] = kafka.ProduceResult{Message: msg, Err: err}
+			continue
+		}
+		if opts.Acks == kafka.AcksNone {
+			produced.Offset = -1
+		}
+		results[i] = kafka.ProduceResult{Message: *produced}
+	}
+	return results, nil
+}
+
+// DeleteRecords deletes the records of partitions before an offset, or
+// every record with an offset of -1, and returns their new earliest offsets
+func (c *Cluster) DeleteRecords(ctx context.Context, topicName string, offsets map[int]int64) (map[int]int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	earliest := make(map[int]int64, len(offsets))
+	for partition, offset := range offsets {
+		p, err := c.partition(topicName, partition)
+		if err != nil {
+			return nil, err
+		}
+		if offset == -1 {
+			offset = p.endOffset()
+		}
+		if offset < 0 || offset > p.endOffset() {
+			return nil, fmt.Errorf("offset %d is out of range of %s/%d", offset, topicName, partition)
+		}
+		if offset > p.startOffset {
+			p.records = p.records[offset-p.startOffset:]
+			p.startOffset = offset
+		}
+		earliest[partition] = p.startOffset
+	}
+	return earliest, nil
+}
+
+// partition looks up a partition; the caller holds the lock
+func (c *Cluster) partition(topicName string, partition int) (*partitionLog, error) {
+	t, err := c.topic(topicName)
+	if err != nil {
+		return nil, err
+	}
+	if partition < 0 || partition >= len(t.partitions) {
+		return nil, fmt.Errorf("partition %d of topic %s not found", partition, topicName)
+	}
+	return t.partitions[partition], nil
+}
@@ -0,0 +1,370 @@
+package kafka
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"sort"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/protocol/describegroups"
+)
+
+// GroupInfo holds information about a consumer group
+type GroupInfo struct {
+	GroupID      string
+	State        string // Empty, Stable, PreparingRebalance, CompletingRebalance, Dead
+	ProtocolType string // "consumer" for Kafka consumers
+	Protocol     string // partition assignor, e.g. range
+	Members      []GroupMember
+	Offsets      []GroupOffset // only filled in by DescribeGroup
+	// OffsetsErr is why the offsets of a listed group could not be read, in
+	// which case its lag is unknown
+	OffsetsErr error
+}
+
+// GroupMember is a member of a consumer group with its assigned partitions
+type GroupMember struct {
+	MemberID    string
+	ClientID    string
+	Host        string
+	Assignments map[string][]int // topic -> partitions
+}
+
+// GroupOffset is the committed offset of a group for one partition
+type GroupOffset struct {
+	Topic           string
+	Partition       int
+	CommittedOffset int64
+	LogEndOffset    int64
+	Lag             int64
+}
+
+// TotalLag returns the summed lag of all committed offsets of the group
+func (g GroupInfo) TotalLag() int64 {
+	var lag int64
+	for _, o := range g.Offsets {
+		lag += o.Lag
+	}
+	return lag
+}
+
+// Owner returns the member a partition is assigned to, if any
+func (g GroupInfo) Owner(topic string, partition int) (GroupMember, bool) {
+	for _, m := range g.Members {
+		for _, p := range m.Assignments[topic] {
+			if p == partition {
+				return m, true
+			}
+		}
+	}
+	return GroupMember{}, false
+}
+
+// ListGroups lists all consumer groups in the cluster with their members
+func (c *Client) ListGroups(ctx context.Context) ([]GroupInfo, error) {
+	if c.admin == nil {
+		return nil, fmt.Errorf("not connected to Kafka")
+	}
+
+	resp, err := c.admin.ListGroups(ctx, &kafka.ListGroupsRequest{})
+	if err == nil {
+		err = resp.Error
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list groups: %w", err)
+	}
+	if len(resp.Groups) == 0 {
+		return nil, nil
+	}
+
+	groupIDs := make([]string, len(resp.Groups))
+	for i, group := range resp.Groups {
+		groupIDs[i] = group.GroupID
+	}
+
+	groups, err := c.describeGroups(ctx, groupIDs)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].GroupID < groups[j].GroupID })
+
+	return groups, nil
+}
+
+// DescribeGroup gets the members and committed offsets of a consumer group
+func (c *Client) DescribeGroup(ctx context.Context, groupID string) (*GroupInfo, error) {
+	if c.admin == nil {
+		return nil, fmt.Errorf("not connected to Kafka")
+	}
+
+	groups, err := c.describeGroups(ctx, []string{groupID})
+	if err != nil {
+		return nil, err
+	}
+	group := &groups[0]
+
+	// Committed offsets for every partition the group knows about
+	offsets, err := c.admin.OffsetFetch(ctx, &kafka.OffsetFetchRequest{GroupID: groupID})
+	if err == nil {
+		err = offsets.Error
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch offsets of group %s: %w", groupID, err)
+	}
+
+	request := make(map[string][]kafka.OffsetRequest)
+	for topic, partitions := range offsets.Topics {
+		for _, p := range partitions {
+			if p.Error == nil && p.CommittedOffset >= 0 {
+				request[topic] = append(request[topic], kafka.LastOffsetOf(p.Partition))
+			}
+		}
+	}
+
+	logEnd := make(map[string]map[int]int64)
+	if len(request) > 0 {
+		resp, err := c.admin.ListOffsets(ctx, &kafka.ListOffsetsRequest{Topics: request})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list offsets of group %s: %w", groupID, err)
+		}
+		for topic, partitions := range resp.Topics {
+			logEnd[topic] = make(map[int]int64, len(partitions))
+			for _, p := range partitions {
+				if p.Error == nil {
+					logEnd[topic][p.Partition] = p.LastOffset
+				}
+			}
+		}
+	}
+
+	for topic, partitions := range offsets.Topics {
+		for _, p := range partitions {
+			if p.Error != nil || p.CommittedOffset < 0 {
+				continue
+			}
+			offset := GroupOffset{
+				Topic:           topic,
+				Partition:       p.Partition,
+				CommittedOffset: p.CommittedOffset,
+				LogEndOffset:    logEnd[topic][p.Partition],
+			}
+			if offset.LogEndOffset > offset.CommittedOffset {
+				offset.Lag = offset.LogEndOffset - offset.CommittedOffset
+			}
+			group.Offsets = append(group.Offsets, offset)
+		}
+	}
+	sortGroupOffsets(group.Offsets)
+
+	return group, nil
+}
+
+// CommitGroupOffsets commits offsets for a group without joining it, the way
+// offset reset tools do. Brokers only accept this while the group has no members.
+func (c *Client) CommitGroupOffsets(ctx context.Context, groupID string, offsets []GroupOffset) error {
+	if c.admin == nil {
+		return fmt.Errorf("not connected to Kafka")
+	}
+
+	topics := make(map[string][]kafka.OffsetCommit)
+	for _, o := range offsets {
+		topics[o.Topic] = append(topics[o.Topic], kafka.OffsetCommit{Partition: o.Partition, Offset: o.CommittedOffset})
+	}
+
+	resp, err := c.admin.OffsetCommit(ctx, &kafka.OffsetCommitRequest{
+		GroupID:      groupID,
+		GenerationID: -1,
+		Topics:       topics,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to commit offsets of group %s: %w", groupID, err)
+	}
+	for topic, partitions := range resp.Topics {
+		for _, p := range partitions {
+			if p.Error != nil {
+				return fmt.Errorf("failed to commit offset of group %s for %s/%d: %w", groupID, topic, p.Partition, p.Error)
+			}
+		}
+	}
+
+	return nil
+}
+
+// DeleteGroups deletes consumer groups and their committed offsets. Each group
+// is deleted on its own coordinator; the result maps every group to its error,
+// nil if it was deleted. Brokers refuse to delete groups that have members.
+func (c *Client) DeleteGroups(ctx context.Context, groupIDs []string) (map[string]error, error) {
+	if c.admin == nil {
+		return nil, fmt.Errorf("not connected to Kafka")
+	}
+
+	results := make(map[string]error, len(groupIDs))
+	for _, groupID := range groupIDs {
+		resp, err := c.admin.DeleteGroups(ctx, &kafka.DeleteGroupsRequest{GroupIDs: []string{groupID}})
+		if err == nil {
+			err = resp.Errors[groupID]
+		}
+		if err != nil {
+			err = fmt.Errorf("failed to delete group %s: %w", groupID, err)
+			// Stop at a cancelled operation rather than failing every group
+			if ctx.Err() != nil {
+				return nil, err
+			}
+		}
+		results[groupID] = err
+	}
+
+	return results, nil
+}
+
+// DeleteGroupOffsets removes the committed offsets of a group for the given
+// partitions of a topic, or all of its partitions if none are given. Brokers
+// refuse while members of the group are subscribed to the topic.
+func (c *Client) DeleteGroupOffsets(ctx context.Context, groupID, topicName string, partitions []int) error {
+	if c.admin == nil {
+		return fmt.Errorf("not connected to Kafka")
+	}
+
+	if len(partitions) == 0 {
+		topic, err := c.topicMetadata(ctx, topicName)
+		if err != nil {
+			return err
+		}
+		for _, p := range topic.Partitions {
+			partitions = append(partitions, p.ID)
+		}
+	}
+
+	resp, err := c.admin.OffsetDelete(ctx, &kafka.OffsetDeleteRequest{
+		GroupID: groupID,
+		Topics:  map[string][]int{topicName: partitions},
+	})
+	if err == nil {
+		err = resp.Error
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete offsets of group %s for topic %s: %w", groupID, topicName, err)
+	}
+	for _, p := range resp.Topics[topicName] {
+		if p.Error != nil {
+			return fmt.Errorf("failed to delete offset of group %s for %s/%d: %w", groupID, topicName, p.Partition, p.Error)
+		}
+	}
+
+	return nil
+}
+
+// describeGroups describes groups at the protocol level, which unlike the
+// kafka-go admin call keeps the protocol type and assignor of each group
+func (c *Client) describeGroups(ctx context.Context, groupIDs []string) ([]GroupInfo, error) {
+	msg, err := c.transport.RoundTrip(ctx, c.admin.Addr, &describegroups.Request{Groups: groupIDs})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe groups: %w", err)
+	}
+	resp := msg.(*describegroups.Response)
+
+	groups := make([]GroupInfo, 0, len(resp.Groups))
+	for _, g := range resp.Groups {
+		if g.ErrorCode != 0 {
+			return nil, fmt.Errorf("failed to describe group %s: %w", g.GroupID, kafka.Error(g.ErrorCode))
+		}
+		// Brokers describe groups they do not know as Dead
+		if g.GroupState == "Dead" {
+			continue
+		}
+
+		group := GroupInfo{
+			GroupID:      g.GroupID,
+			State:        g.GroupState,
+			ProtocolType: g.ProtocolType,
+			Protocol:     g.ProtocolData,
+		}
+		for _, m := range g.Members {
+			member := GroupMember{
+				MemberID: m.MemberID,
+				ClientID: m.ClientID,
+				Host:     m.ClientHost,
+			}
+			// Only the consumer protocol has a known assignment format
+			if g.ProtocolType == "consumer" {
+				if member.Assignments, err = decodeAssignment(m.MemberAssignment); err != nil {
+					return nil, fmt.Errorf("failed to describe member %s of group %s: %w", m.MemberID, g.GroupID, err)
+				}
+			}
+			group.Members = append(group.Members, member)
+		}
+		groups = append(groups, group)
+	}
+
+	if len(groups) == 0 {
+		return nil, fmt.Errorf("group %s not found", groupIDs[0])
+	}
+	return groups, nil
+}
+
+// decodeAssignment decodes a consumer protocol member assignment:
+// version int16, [topic string, [partition int32]], user data bytes.
+// Members without an assignment yet have an empty one.
+func decodeAssignment(b []byte) (map[string][]int, error) {
+	assignments := make(map[string][]int)
+	if len(b) == 0 {
+		return assignments, nil
+	}
+	errMalformed := fmt.Errorf("malformed member assignment")
+	if len(b) < 6 {
+		return nil, errMalformed
+	}
+	b = b[2:] // version
+
+	// readCount reads an array length, which cannot exceed the items of size
+	// bytes left
+	readCount := func(size int) (int, bool) {
+		if len(b) < 4 {
+			return 0, false
+		}
+		v := int(int32(binary.BigEndian.Uint32(b)))
+		b = b[4:]
+		return v, v >= 0 && v <= len(b)/size
+	}
+
+	topics, ok := readCount(6)
+	if !ok {
+		return nil, errMalformed
+	}
+	for i := 0; i < topics; i++ {
+		if len(b) < 2 {
+			return nil, errMalformed
+		}
+		n := int(binary.BigEndian.Uint16(b))
+		if len(b) < 2+n {
+			return nil, errMalformed
+		}
+		topic := string(b[2 : 2+n])
+		b = b[2+n:]
+
+		count, ok := readCount(4)
+		if !ok {
+			return nil, errMalformed
+		}
+		partitions := make([]int, count)
+		for j := range partitions {
+			partitions[j] = int(int32(binary.BigEndian.Uint32(b)))
+			b = b[4:]
+		}
+		sort.Ints(partitions)
+		assignments[topic] = partitions
+	}
+
+	return assignments, nil
+}
+
+// sortGroupOffsets orders offsets by topic then partition
+func sortGroupOffsets(offsets []GroupOffset) {
+	sort.Slice(offsets, func(i, j int) bool {
+		if offsets[i].Topic != offsets[j].Topic {
+			return offsets[i].Topic < offsets[j].Topic
+		}
+		return offsets[i].Partition < offsets[j].Partition
+	})
+}
@@ -0,0 +1,35 @@
+package kafka
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDecodeAssignment(t *testing.T) {
+	// Version 1, topic "a" with partitions 2 and 0, then user data
+	valid := []byte{0, 1, 0, 0, 0, 1, 0, 1, 'a', 0, 0, 0, 2, 0, 0, 0, 2, 0, 0, 0, 0, 0xca, 0xfe}
+	got, err := decodeAssignment(valid)
+	if want := map[string][]int{"a": {0, 2}}; err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("decodeAssignment() = %v, %v, want %v", got, err, want)
+	}
+
+	if got, err := decodeAssignment(nil); err != nil || len(got) != 0 {
+		t.Errorf("decodeAssignment(empty) = %v, %v, want no assignments", got, err)
+	}
+
+	malformed := map[string][]byte{
+		"short":                {0, 0, 0},
+		"negative topic count": {0, 0, 0xff, 0xff, 0xff, 0xff},
+		"topic count past end": {0, 0, 0x7f, 0xff, 0xff, 0xff, 0, 1, 'a'},
+		"truncated topic":      {0, 0, 0, 0, 0, 1, 0, 5, 'a', 'b', 'c', 'd'},
+		"negative partitions":  {0, 0, 0, 0, 0, 1, 0, 1, 'a', 0xff, 0xff, 0xff, 0xff},
+		"partitions past end":  {0, 0, 0, 0, 0, 1, 0, 1, 'a', 0x7f, 0xff, 0xff, 0xff, 0, 0, 0, 0},
+		"truncated partition":  {0, 0, 0, 0, 0, 1, 0, 1, 'a', 0, 0, 0, 2, 0, 0, 0, 1, 0, 0},
+		"missing partitions":   {0, 0, 0, 0, 0, 1, 0, 1, 'a'},
+	}
+	for name, b := range malformed {
+		if got, err := decodeAssignment(b); err == nil {
+			t.Errorf("decodeAssignment(%s) = %v, want a malformed assignment error", name, got)
+		}
+	}
+}
@@ -0,0 +1,450 @@
+package kafkatest
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+
+	"github.com/segmentio/kafka-go/protocol/deletegroups"
+	"github.com/segmentio/kafka-go/protocol/describegroups"
+	"github.com/segmentio/kafka-go/protocol/findcoordinator"
+	"github.com/segmentio/kafka-go/protocol/heartbeat"
+	"github.com/segmentio/kafka-go/protocol/joingroup"
+	"github.com/segmentio/kafka-go/protocol/leavegroup"
+	"github.com/segmentio/kafka-go/protocol/listgroups"
+	"github.com/segmentio/kafka-go/protocol/offsetcommit"
+	"github.com/segmentio/kafka-go/protocol/offsetdelete"
+	"github.com/segmentio/kafka-go/protocol/offsetfetch"
+	"github.com/segmentio/kafka-go/protocol/syncgroup"
+)
+
+// Consumer group states
+const (
+	groupEmpty               = "Empty"
+	groupStable              = "Stable"
+	groupCompletingRebalance = "CompletingRebalance"
+	groupDead                = "Dead"
+)
+
+// group is a consumer group. Rebalances are simplified: the generation
+// moves on whenever membership changes, the first member is the leader and
+// there is no waiting for members to rejoin.
+type group struct {
+	state        string
+	protocolType string
+	protocol     string
+	generation   int32
+	members      []*member // in join order, the first is the leader
+	offsets      map[string]map[int32]int64
+}
+
+// member is a consumer group member
+type member struct {
+	id         string
+	clientID   string
+	host       string
+	protocols  []joingroup.RequestProtocol
+	assignment []byte
+}
+
+// member looks up a member of the group
+func (g *group) member(id string) *member {
+	for _, m := range g.members {
+		if m.id == id {
+			return m
+		}
+	}
+	return nil
+}
+
+// metadata returns the member's metadata for the selected protocol
+func (m *member) metadata(protocol string) []byte {
+	for _, p := range m.protocols {
+		if p.Name == protocol {
+			return p.Metadata
+		}
+	}
+	return nil
+}
+
+// group returns a group, creating it Empty if needed; the caller holds the lock
+func (s *Server) group(groupID string) *group {
+	g, ok := s.groups[groupID]
+	if !ok {
+		g = &group{state: groupEmpty, offsets: make(map[string]map[int32]int64)}
+		s.groups[groupID] = g
+	}
+	return g
+}
+
+// findCoordinator makes node 0 the coordinator of every group
+func (s *Server) findCoordinator(req *findcoordinator.Request) *findcoordinator.Response {
+	broker := s.broker(0)
+	return &findcoordinator.Response{
+		NodeID: broker.NodeID,
+		Host:   broker.Host,
+		Port:   broker.Port,
+	}
+}
+
+// joinGroup adds or refreshes a member and starts a new generation
+func (s *Server) joinGroup(sess *session, req *joingroup.Request) *joingroup.Response {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	g := s.group(req.GroupID)
+	resp := &joingroup.Response{GenerationID: -1}
+	if len(g.members) > 0 && g.protocolType != req.ProtocolType {
+		resp.ErrorCode = errInconsistentProtocol
+		return resp
+	}
+
+	m := g.member(req.MemberID)
+	switch {
+	case m == nil && req.MemberID != "":
+		resp.ErrorCode = errUnknownMemberID
+		return resp
+	case m == nil:
+		s.members++
+		m = &member{
+			id:       fmt.Sprintf("%s-%d", sess.clientID, s.members),
+			clientID: sess.clientID,
+			host:     sess.host,
+		}
+		g.members = append(g.members, m)
+		g.generation++
+	case g.state == groupStable:
+		// A member rejoining a stable group asks for a rebalance
+		g.generation++
+	}
+	m.protocols = req.Protocols
+
+	g.state = groupCompletingRebalance
+	g.protocolType = req.ProtocolType
+	leader := g.members[0]
+	if len(leader.protocols) > 0 {
+		g.protocol = leader.protocols[0].Name
+	}
+
+	resp.GenerationID = g.generation
+	resp.ProtocolType = g.protocolType
+	resp.ProtocolName = g.protocol
+	resp.LeaderID = leader.id
+	resp.MemberID = m.id
+	if m == leader {
+		for _, gm := range g.members {
+			resp.Members = append(resp.Members, joingroup.ResponseMember{
+				MemberID: gm.id,
+				Metadata: gm.metadata(g.protocol),
+			})
+		}
+	}
+
+	return resp
+}
+
+// checkMember validates a member and generation; the caller holds the lock
+func (s *Server) checkMember(groupID, memberID string, generation int32) (*group, *member, int16) {
+	g, ok := s.groups[groupID]
+	if !ok {
+		return nil, nil, errUnknownMemberID
+	}
+	m := g.member(memberID)
+	if m == nil {
+		return g, nil, errUnknownMemberID
+	}
+	if generation != g.generation {
+		return g, m, errIllegalGeneration
+	}
+	return g, m, 0
+}
+
+// syncGroup stores the leader's assignments and hands members theirs
+func (s *Server) syncGroup(req *syncgroup.Request) *syncgroup.Response {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resp := &syncgroup.Response{}
+	g, m, code := s.checkMember(req.GroupID, req.MemberID, req.GenerationID)
+	if code != 0 {
+		resp.ErrorCode = code
+		return resp
+	}
+
+	if m == g.members[0] {
+		for _, a := range req.Assignments {
+			if gm := g.member(a.MemberID); gm != nil {
+				gm.assignment = a.Assignment
+			}
+		}
+		g.state = groupStable
+	}
+	if g.state != groupStable {
+		resp.ErrorCode = errRebalanceInProgress
+		return resp
+	}
+
+	resp.ProtocolType = g.protocolType
+	resp.ProtocolName = g.protocol
+	resp.Assignments = m.assignment
+	return resp
+}
+
+// heartbeat tells members whether they must rejoin
+func (s *Server) heartbeat(req *heartbeat.Request) *heartbeat.Response {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	g, _, code := s.checkMember(req.GroupID, req.MemberID, req.GenerationID)
+	if code == errIllegalGeneration || (code == 0 && g.state != groupStable) {
+		code = errRebalanceInProgress
+	}
+	return &heartbeat.Response{ErrorCode: code}
+}
+
+// leaveGroup removes members, emptying the group when the last one leaves
+func (s *Server) leaveGroup(req *leavegroup.Request) *leavegroup.Response {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resp := &leavegroup.Response{}
+	ids := []string{req.MemberID}
+	if len(req.Members) > 0 {
+		ids = ids[:0]
+		for _, m := range req.Members {
+			ids = append(ids, m.MemberID)
+		}
+	}
+
+	g, ok := s.groups[req.GroupID]
+	for _, id := range ids {
+		result := leavegroup.ResponseMember{MemberID: id}
+		if !ok || g.member(id) == nil {
+			result.ErrorCode = errUnknownMemberID
+			resp.ErrorCode = errUnknownMemberID
+		} else {
+			for i, m := range g.members {
+				if m.id == id {
+					g.members = append(g.members[:i], g.members[i+1:]...)
+					break
+				}
+			}
+			g.generation++
+			g.state = groupCompletingRebalance
+			if len(g.members) == 0 {
+				g.state = groupEmpty
+			}
+		}
+		resp.Members = append(resp.Members, result)
+	}
+
+	return resp
+}
+
+// offsetCommit stores committed offsets. Groups without members accept
+// commits from anyone, like the standalone commits of admin tools.
+func (s *Server) offsetCommit(req *offsetcommit.Request) *offsetcommit.Response {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	g := s.group(req.GroupID)
+	var code int16
+	if len(g.members) > 0 {
+		_, _, code = s.checkMember(req.GroupID, req.MemberID, req.GenerationID)
+	}
+
+	resp := &offsetcommit.Response{}
+	for _, rt := range req.Topics {
+		result := offsetcommit.ResponseTopic{Name: rt.Name}
+		for _, rp := range rt.Partitions {
+			pr := offsetcommit.ResponsePartition{PartitionIndex: rp.PartitionIndex, ErrorCode: code}
+			if code == 0 {
+				if s.partition(rt.Name, rp.PartitionIndex) == nil {
+					pr.ErrorCode = errUnknownTopicOrPartition
+				} else {
+					if g.offsets[rt.Name] == nil {
+						g.offsets[rt.Name] = make(map[int32]int64)
+					}
+					g.offsets[rt.Name][rp.PartitionIndex] = rp.CommittedOffset
+				}
+			}
+			result.Partitions = append(result.Partitions, pr)
+		}
+		resp.Topics = append(resp.Topics, result)
+	}
+
+	return resp
+}
+
+// offsetFetch returns committed offsets, all of them if no topics are given
+func (s *Server) offsetFetch(req *offsetfetch.Request) *offsetfetch.Response {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resp := &offsetfetch.Response{}
+	g, ok := s.groups[req.GroupID]
+	if !ok {
+		g = &group{}
+	}
+
+	requested := req.Topics
+	if requested == nil {
+		for name, partitions := range g.offsets {
+			rt := offsetfetch.RequestTopic{Name: name}
+			for p := range partitions {
+				rt.PartitionIndexes = append(rt.PartitionIndexes, p)
+			}
+			sort.Slice(rt.PartitionIndexes, func(i, j int) bool { return rt.PartitionIndexes[i] < rt.PartitionIndexes[j] })
+			requested = append(requested, rt)
+		}
+		sort.Slice(requested, func(i, j int) bool { return requested[i].Name < requested[j].Name })
+	}
+
+	for _, rt := range requested {
+		result := offsetfetch.ResponseTopic{Name: rt.Name}
+		for _, p := range rt.PartitionIndexes {
+			offset, ok := g.offsets[rt.Name][p]
+			if !ok {
+				offset = -1
+			}
+			result.Partitions = append(result.Partitions, offsetfetch.ResponsePartition{
+				PartitionIndex:      p,
+				CommittedOffset:     offset,
+				ComittedLeaderEpoch: -1,
+			})
+		}
+		resp.Topics = append(resp.Topics, result)
+	}
+
+	return resp
+}
+
+// listGroups lists all groups
+func (s *Server) listGroups() *listgroups.Response {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resp := &listgroups.Response{}
+	for id, g := range s.groups {
+		resp.Groups = append(resp.Groups, listgroups.ResponseGroup{GroupID: id, ProtocolType: g.protocolType})
+	}
+	sort.Slice(resp.Groups, func(i, j int) bool { return resp.Groups[i].GroupID < resp.Groups[j].GroupID })
+
+	return resp
+}
+
+// describeGroups describes groups; unknown groups are reported as Dead
+func (s *Server) describeGroups(req *describegroups.Request) *describegroups.Response {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resp := &describegroups.Response{}
+	for _, id := range req.Groups {
+		result := describegroups.ResponseGroup{GroupID: id, GroupState: groupDead}
+		if g, ok := s.groups[id]; ok {
+			result.GroupState = g.state
+			result.ProtocolType = g.protocolType
+			result.ProtocolData = g.protocol
+			for _, m := range g.members {
+				result.Members = append(result.Members, describegroups.ResponseGroupMember{
+					MemberID:         m.id,
+					ClientID:         m.clientID,
+					ClientHost:       m.host,
+					MemberMetadata:   m.metadata(g.protocol),
+					MemberAssignment: m.assignment,
+				})
+			}
+		}
+		resp.Groups = append(resp.Groups, result)
+	}
+
+	return resp
+}
+
+// deleteGroups deletes empty groups
+func (s *Server) deleteGroups(req *deletegroups.Request) *deletegroups.Response {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resp := &deletegroups.Response{}
+	for _, id := range req.GroupIDs {
+		result := deletegroups.ResponseGroup{GroupID: id}
+		g, ok := s.groups[id]
+		switch {
+		case !ok:
+			result.ErrorCode = errGroupIDNotFound
+		case len(g.members) > 0:
+			result.ErrorCode = errNonEmptyGroup
+		default:
+			delete(s.groups, id)
+		}
+		resp.Responses = append(resp.Responses, result)
+	}
+
+	return resp
+}
+
+// offsetDelete removes committed offsets of topics the group does not consume
+func (s *Server) offsetDelete(req *offsetdelete.Request) *offsetdelete.Response {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resp := &offsetdelete.Response{}
+	g, ok := s.groups[req.GroupID]
+	if !ok {
+		resp.ErrorCode = errGroupIDNotFound
+		return resp
+	}
+
+	subscribed := make(map[string]bool)
+	if g.protocolType == "consumer" {
+		for _, m := range g.members {
+			for _, topicName := range subscription(m.metadata(g.protocol)) {
+				subscribed[topicName] = true
+			}
+		}
+	}
+
+	for _, rt := range req.Topics {
+		result := offsetdelete.ResponseTopic{Name: rt.Name}
+		for _, rp := range rt.Partitions {
+			pr := offsetdelete.ResponsePartition{PartitionIndex: rp.PartitionIndex}
+			switch {
+			case s.partition(rt.Name, rp.PartitionIndex) == nil:
+				pr.ErrorCode = errUnknownTopicOrPartition
+			case subscribed[rt.Name]:
+				pr.ErrorCode = errGroupSubscribedToTopic
+			default:
+				delete(g.offsets[rt.Name], rp.PartitionIndex)
+				if len(g.offsets[rt.Name]) == 0 {
+					delete(g.offsets, rt.Name)
+				}
+			}
+			result.Partitions = append(result.Partitions, pr)
+		}
+		resp.Topics = append(resp.Topics, result)
+	}
+
+	return resp
+}
+
+// subscription decodes the topics of consumer protocol member metadata:
+// version int16, [topic string], user data bytes
+func subscription(b []byte) []string {
+	if len(b) < 6 {
+		return nil
+	}
+	n := int(int32(binary.BigEndian.Uint32(b[2:])))
+	b = b[6:]
+
+	var topics []string
+	for i := 0; i < n && len(b) >= 2; i++ {
+		size := int(binary.BigEndian.Uint16(b))
+		if len(b) < 2+size {
+			break
+		}
+		topics = append(topics, string(b[2:2+size]))
+		b = b[2+size:]
+	}
+	return topics
+}
@@ -0,0 +1,347 @@
+package kafkatest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"time"
+
+	"github.com/cfk-dev/cfk/internal/kafka/deleterecords"
+	"github.com/segmentio/kafka-go/protocol"
+	"github.com/segmentio/kafka-go/protocol/fetch"
+	"github.com/segmentio/kafka-go/protocol/listoffsets"
+	"github.com/segmentio/kafka-go/protocol/produce"
+)
+
+// Special ListOffsets timestamps
+const (
+	latestTimestamp   = -1
+	earliestTimestamp = -2
+)
+
+// produce appends records to partition logs
+func (s *Server) produce(req *produce.Request) *produce.Response {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resp := &produce.Response{}
+	for _, rt := range req.Topics {
+		result := produce.ResponseTopic{Topic: rt.Topic}
+		for _, rp := range rt.Partitions {
+			pr := produce.ResponsePartition{
+				Partition:     rp.Partition,
+				BaseOffset:    -1,
+				LogAppendTime: -1,
+			}
+
+			p := s.partition(rt.Topic, rp.Partition)
+			if p == nil {
+				pr.ErrorCode = errUnknownTopicOrPartition
+				result.Partitions = append(result.Partitions, pr)
+				continue
+			}
+
+			records, err := readRecords(rp.RecordSet.Records)
+			if err != nil {
+				pr.ErrorCode = errInvalidRequest
+				result.Partitions = append(result.Partitions, pr)
+				continue
+			}
+
+			pr.BaseOffset = p.endOffset()
+			for _, r := range records {
+				r.offset = p.endOffset()
+				p.records = append(p.records, r)
+			}
+			pr.LogStartOffset = p.startOffset
+			result.Partitions = append(result.Partitions, pr)
+		}
+		resp.Topics = append(resp.Topics, result)
+	}
+
+	return resp
+}
+
+// partition looks up a partition; the caller holds the lock
+func (s *Server) partition(topicName string, index int32) *partition {
+	t, ok := s.topics[topicName]
+	if !ok || index < 0 || int(index) >= len(t.partitions) {
+		return nil
+	}
+	return t.partitions[index]
+}
+
+// readRecords copies the records of a produced record set
+func readRecords(records protocol.RecordReader) ([]record, error) {
+	if records == nil {
+		return nil, nil
+	}
+
+	var result []record
+	for {
+		r, err := records.ReadRecord()
+		if errors.Is(err, io.EOF) {
+			return result, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		rec := record{time: r.Time}
+		if rec.time.IsZero() {
+			rec.time = time.Now()
+		}
+		for _, h := range r.Headers {
+			rec.headers = append(rec.headers, protocol.Header{Key: h.Key, Value: append([]byte(nil), h.Value...)})
+		}
+		if r.Key != nil {
+			if rec.key, err = protocol.ReadAll(r.Key); err != nil {
+				return nil, err
+			}
+		}
+		if r.Value != nil {
+			if rec.value, err = protocol.ReadAll(r.Value); err != nil {
+				return nil, err
+			}
+		}
+		result = append(result, rec)
+	}
+}
+
+// listOffsets resolves the earliest, latest or timestamp offsets of partitions
+func (s *Server) listOffsets(req *listoffsets.Request) *listoffsets.Response {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resp := &listoffsets.Response{}
+	for _, rt := range req.Topics {
+		result := listoffsets.ResponseTopic{Topic: rt.Topic}
+		for _, rp := range rt.Partitions {
+			pr := listoffsets.ResponsePartition{Partition: rp.Partition, Timestamp: -1, Offset: -1}
+
+			p := s.partition(rt.Topic, rp.Partition)
+			switch {
+			case p == nil:
+				pr.ErrorCode = errUnknownTopicOrPartition
+			case rp.Timestamp == latestTimestamp:
+				pr.Offset = p.endOffset()
+			case rp.Timestamp == earliestTimestamp:
+				pr.Offset = p.startOffset
+			default:
+				// The first record at or after the timestamp
+				for _, r := range p.records {
+					if !r.compacted && r.time.UnixMilli() >= rp.Timestamp {
+						pr.Offset = r.offset
+						pr.Timestamp = r.time.UnixMilli()
+						break
+					}
+				}
+			}
+			result.Partitions = append(result.Partitions, pr)
+		}
+		resp.Topics = append(resp.Topics, result)
+	}
+
+	return resp
+}
+
+// deleteRecords deletes the records before an offset of partitions, moving
+// their start offset
+func (s *Server) deleteRecords(req *deleterecords.Request) *deleterecords.Response {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resp := &deleterecords.Response{}
+	for _, rt := range req.Topics {
+		result := deleterecords.ResponseTopic{Name: rt.Name}
+		for _, rp := range rt.Partitions {
+			pr := deleterecords.ResponsePartition{PartitionIndex: rp.PartitionIndex, LowWatermark: -1}
+
+			p := s.partition(rt.Name, rp.PartitionIndex)
+			offset := rp.Offset
+			if p != nil && offset == -1 {
+				offset = p.endOffset()
+			}
+			switch {
+			case p == nil:
+				pr.ErrorCode = errUnknownTopicOrPartition
+			case offset < 0 || offset > p.endOffset():
+				pr.ErrorCode = errOffsetOutOfRange
+			default:
+				if offset > p.startOffset {
+					p.records = p.records[offset-p.startOffset:]
+					p.startOffset = offset
+				}
+				pr.LowWatermark = p.startOffset
+			}
+			result.Partitions = append(result.Partitions, pr)
+		}
+		resp.Topics = append(resp.Topics, result)
+	}
+
+	return resp
+}
+
+// fetchPartition is the result of fetching one partition
+type fetchPartition struct {
+	partition     int32
+	errorCode     int16
+	highWatermark int64
+	startOffset   int64
+	records       []byte // encoded record batch, nil if empty
+}
+
+// fetch reads records and returns the encoded response. Fetch responses are
+// encoded by hand because kafka-go always writes record batches with a base
+// offset of zero, which is only correct for produce requests.
+func (s *Server) fetch(version int16, correlationID int32, req *fetch.Request) []byte {
+	s.mu.Lock()
+	topics := make(map[string][]fetchPartition, len(req.Topics))
+	for _, rt := range req.Topics {
+		for _, rp := range rt.Partitions {
+			result := fetchPartition{partition: rp.Partition, highWatermark: -1, startOffset: -1}
+
+			p := s.partition(rt.Topic, rp.Partition)
+			switch {
+			case p == nil:
+				result.errorCode = errUnknownTopicOrPartition
+			case rp.FetchOffset < p.startOffset || rp.FetchOffset > p.endOffset():
+				result.errorCode = errOffsetOutOfRange
+				result.highWatermark = p.endOffset()
+				result.startOffset = p.startOffset
+			default:
+				result.highWatermark = p.endOffset()
+				result.startOffset = p.startOffset
+				result.records = encodeSegment(p.records[rp.FetchOffset-p.startOffset:], int(rp.PartitionMaxBytes))
+			}
+			topics[rt.Topic] = append(topics[rt.Topic], result)
+		}
+	}
+	s.mu.Unlock()
+
+	return encodeFetchResponse(version, correlationID, req.Topics, topics)
+}
+
+// encodeSegment encodes the records of the log segment that starts with the
+// first one: the empty batches of a gap left by compaction, or the records up
+// to the next gap
+func encodeSegment(records []record, maxBytes int) []byte {
+	if len(records) == 0 || !records[0].compacted {
+		for i, r := range records {
+			if r.compacted {
+				return encodeBatch(records[:i], maxBytes)
+			}
+		}
+		return encodeBatch(records, maxBytes)
+	}
+
+	var b []byte
+	for _, r := range records {
+		if !r.compacted || (maxBytes > 0 && len(b) >= maxBytes) {
+			break
+		}
+		b = append(b, encodeEmptyBatch(r.offset, r.time)...)
+	}
+	return b
+}
+
+// encodeEmptyBatch encodes the header of a v2 record batch whose records were
+// all removed by compaction
+func encodeEmptyBatch(offset int64, t time.Time) []byte {
+	b := binary.BigEndian.AppendUint64(nil, uint64(offset))
+	b = binary.BigEndian.AppendUint32(b, 49) // size of the rest of the batch
+	b = binary.BigEndian.AppendUint32(b, 0)  // partition leader epoch
+	b = append(b, 2)                         // magic
+	crcAt := len(b)
+	b = binary.BigEndian.AppendUint32(b, 0) // CRC placeholder
+	b = binary.BigEndian.AppendUint16(b, 0) // attributes
+	b = binary.BigEndian.AppendUint32(b, 0) // last offset delta
+	b = binary.BigEndian.AppendUint64(b, uint64(t.UnixMilli()))
+	b = binary.BigEndian.AppendUint64(b, uint64(t.UnixMilli()))
+	b = binary.BigEndian.AppendUint64(b, ^uint64(0)) // producer ID -1
+	b = binary.BigEndian.AppendUint16(b, ^uint16(0)) // producer epoch -1
+	b = binary.BigEndian.AppendUint32(b, ^uint32(0)) // base sequence -1
+	b = binary.BigEndian.AppendUint32(b, 0)          // no records
+	binary.BigEndian.PutUint32(b[crcAt:], crc32.Checksum(b[crcAt+4:], crc32.MakeTable(crc32.Castagnoli)))
+	return b
+}
+
+// encodeBatch encodes records as a single v2 record batch of about maxBytes.
+// At least one record is included so large records can always be fetched.
+func encodeBatch(records []record, maxBytes int) []byte {
+	if len(records) == 0 {
+		return nil
+	}
+
+	var batch []protocol.Record
+	size := 0
+	for _, r := range records {
+		size += len(r.key) + len(r.value) + 32
+		if len(batch) > 0 && maxBytes > 0 && size > maxBytes {
+			break
+		}
+
+		pr := protocol.Record{Offset: r.offset, Time: r.time, Headers: r.headers}
+		if r.key != nil {
+			pr.Key = protocol.NewBytes(r.key)
+		}
+		if r.value != nil {
+			pr.Value = protocol.NewBytes(r.value)
+		}
+		batch = append(batch, pr)
+	}
+
+	var buf bytes.Buffer
+	rs := protocol.RecordSet{Version: 2, Records: protocol.NewRecordReader(batch...)}
+	if _, err := rs.WriteTo(&buf); err != nil {
+		return nil
+	}
+
+	// The output is the int32 size followed by the batch, which starts with
+	// its int64 base offset. The CRC does not cover the base offset.
+	b := buf.Bytes()
+	binary.BigEndian.PutUint64(b[4:12], uint64(records[0].offset))
+	return b[4:]
+}
+
+// encodeFetchResponse writes a fetch response for versions 4 to 11
+func encodeFetchResponse(version int16, correlationID int32, order []fetch.RequestTopic, topics map[string][]fetchPartition) []byte {
+	var b []byte
+	b = binary.BigEndian.AppendUint32(b, 0) // size placeholder
+	b = binary.BigEndian.AppendUint32(b, uint32(correlationID))
+
+	b = binary.BigEndian.AppendUint32(b, 0) // throttle time
+	if version >= 7 {
+		b = binary.BigEndian.AppendUint16(b, 0) // error code
+		b = binary.BigEndian.AppendUint32(b, 0) // no fetch session
+	}
+
+	b = binary.BigEndian.AppendUint32(b, uint32(len(order)))
+	for _, rt := range order {
+		b = binary.BigEndian.AppendUint16(b, uint16(len(rt.Topic)))
+		b = append(b, rt.Topic...)
+
+		partitions := topics[rt.Topic]
+		b = binary.BigEndian.AppendUint32(b, uint32(len(partitions)))
+		for _, p := range partitions {
+			b = binary.BigEndian.AppendUint32(b, uint32(p.partition))
+			b = binary.BigEndian.AppendUint16(b, uint16(p.errorCode))
+			b = binary.BigEndian.AppendUint64(b, uint64(p.highWatermark))
+			b = binary.BigEndian.AppendUint64(b, uint64(p.highWatermark)) // last stable offset
+			if version >= 5 {
+				b = binary.BigEndian.AppendUint64(b, uint64(p.startOffset))
+			}
+			b = binary.BigEndian.AppendUint32(b, 0) // no aborted transactions
+			if version >= 11 {
+				b = binary.BigEndian.AppendUint32(b, uint32(0xffffffff)) // preferred read replica -1
+			}
+			b = binary.BigEndian.AppendUint32(b, uint32(len(p.records)))
+			b = append(b, p.records...)
+		}
+	}
+
+	binary.BigEndian.PutUint32(b, uint32(len(b)-4))
+	return b
+}
@@ -0,0 +1,128 @@
+package kafkatest
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/segmentio/kafka-go/protocol/saslauthenticate"
+	"github.com/segmentio/kafka-go/protocol/saslhandshake"
+	"github.com/xdg-go/scram"
+)
+
+// saslConversation checks the client messages of a SASL exchange.
+// It returns the server challenge and whether authentication completed.
+type saslConversation func(msg []byte) ([]byte, bool, error)
+
+// saslHandshake accepts the configured mechanism only
+func (sess *session) saslHandshake(req *saslhandshake.Request) *saslhandshake.Response {
+	mechanism := sess.server.config.SASLMechanism
+	resp := &saslhandshake.Response{Mechanisms: []string{mechanism}}
+
+	conv, err := sess.server.newConversation(req.Mechanism)
+	if mechanism == "" || !strings.EqualFold(req.Mechanism, mechanism) || err != nil {
+		resp.ErrorCode = errUnsupportedSASL
+		return resp
+	}
+
+	sess.mechanism = req.Mechanism
+	sess.sasl = conv
+	return resp
+}
+
+// saslAuthenticate runs one step of the SASL exchange
+func (sess *session) saslAuthenticate(req *saslauthenticate.Request) *saslauthenticate.Response {
+	resp := &saslauthenticate.Response{}
+	if sess.sasl == nil {
+		resp.ErrorCode = errSASLAuthFailed
+		resp.ErrorMessage = "SASL handshake is required before authentication"
+		return resp
+	}
+
+	challenge, done, err := sess.sasl(req.AuthBytes)
+	if err != nil {
+		sess.sasl = nil
+		resp.ErrorCode = errSASLAuthFailed
+		resp.ErrorMessage = fmt.Sprintf("authentication failed during authentication due to invalid credentials with SASL mechanism %s: %v", sess.mechanism, err)
+		return resp
+	}
+
+	resp.AuthBytes = challenge
+	if done {
+		sess.authenticated = true
+		sess.sasl = nil
+	}
+	return resp
+}
+
+// newConversation starts a server side SASL exchange
+func (s *Server) newConversation(mechanism string) (saslConversation, error) {
+	switch strings.ToUpper(mechanism) {
+	case "PLAIN":
+		return s.plain, nil
+	case "OAUTHBEARER":
+		return s.oauthBearer, nil
+	case "SCRAM-SHA-256":
+		return s.scram(scram.SHA256)
+	case "SCRAM-SHA-512":
+		return s.scram(scram.SHA512)
+	}
+	return nil, fmt.Errorf("unsupported mechanism %s", mechanism)
+}
+
+// plain checks "authzid \0 user \0 password" against the configured users
+func (s *Server) plain(msg []byte) ([]byte, bool, error) {
+	parts := bytes.Split(msg, []byte{0})
+	if len(parts) != 3 {
+		return nil, false, fmt.Errorf("malformed PLAIN message")
+	}
+
+	password, ok := s.config.Users[string(parts[1])]
+	if !ok || password != string(parts[2]) {
+		return nil, false, fmt.Errorf("invalid username or password")
+	}
+	return nil, true, nil
+}
+
+// oauthBearer checks the bearer token of the initial client response (RFC 7628)
+func (s *Server) oauthBearer(msg []byte) ([]byte, bool, error) {
+	for _, kv := range strings.Split(string(msg), "\x01") {
+		token, ok := strings.CutPrefix(kv, "auth=Bearer ")
+		if !ok {
+			continue
+		}
+		for _, valid := range s.config.Tokens {
+			if token == valid {
+				return nil, true, nil
+			}
+		}
+	}
+	return nil, false, fmt.Errorf("invalid bearer token")
+}
+
+// scram starts a SCRAM exchange with credentials derived from the configured users
+func (s *Server) scram(hash scram.HashGeneratorFcn) (saslConversation, error) {
+	server, err := hash.NewServer(func(username string) (scram.StoredCredentials, error) {
+		password, ok := s.config.Users[username]
+		if !ok {
+			return scram.StoredCredentials{}, fmt.Errorf("unknown user %s", username)
+		}
+		client, err := hash.NewClient(username, password, "")
+		if err != nil {
+			return scram.StoredCredentials{}, err
+		}
+		return client.GetStoredCredentials(scram.KeyFactors{Salt: "kafkatest-salt", Iters: 4096}), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	conv := server.NewConversation()
+	return func(msg []byte) ([]byte, bool, error) {
+		challenge, err := conv.Step(string(msg))
+		if err != nil {
+			return nil, false, err
+		}
+		return []byte(challenge), conv.Done() && conv.Valid(), nil
+	}, nil
+}
@@ -0,0 +1,405 @@
+// Package kafkatest provides an in-process Kafka broker for integration tests.
+//
+// The server speaks enough of the Kafka wire protocol for kafka-go: topic
+// administration, produce, fetch, offsets, record deletion, consumer groups
+// and SASL. Records are kept in memory, and every node of a multi-node server
+// shares the same state, so tests can exercise the real client code paths
+// without Docker.
+package kafkatest
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cfk-dev/cfk/internal/kafka/deleterecords"
+	"github.com/segmentio/kafka-go/protocol"
+	"github.com/segmentio/kafka-go/protocol/apiversions"
+	"github.com/segmentio/kafka-go/protocol/createpartitions"
+	"github.com/segmentio/kafka-go/protocol/createtopics"
+	"github.com/segmentio/kafka-go/protocol/deletegroups"
+	"github.com/segmentio/kafka-go/protocol/deletetopics"
+	"github.com/segmentio/kafka-go/protocol/describeconfigs"
+	"github.com/segmentio/kafka-go/protocol/describegroups"
+	"github.com/segmentio/kafka-go/protocol/fetch"
+	"github.com/segmentio/kafka-go/protocol/findcoordinator"
+	"github.com/segmentio/kafka-go/protocol/heartbeat"
+	"github.com/segmentio/kafka-go/protocol/incrementalalterconfigs"
+	"github.com/segmentio/kafka-go/protocol/joingroup"
+	"github.com/segmentio/kafka-go/protocol/leavegroup"
+	"github.com/segmentio/kafka-go/protocol/listgroups"
+	"github.com/segmentio/kafka-go/protocol/listoffsets"
+	"github.com/segmentio/kafka-go/protocol/metadata"
+	"github.com/segmentio/kafka-go/protocol/offsetcommit"
+	"github.com/segmentio/kafka-go/protocol/offsetdelete"
+	"github.com/segmentio/kafka-go/protocol/offsetfetch"
+	"github.com/segmentio/kafka-go/protocol/produce"
+	"github.com/segmentio/kafka-go/protocol/saslauthenticate"
+	"github.com/segmentio/kafka-go/protocol/saslhandshake"
+	"github.com/segmentio/kafka-go/protocol/syncgroup"
+)
+
+// Config configures a test server
+type Config struct {
+	// Nodes is the number of broker nodes, 1 if zero. Each node listens on
+	// its own port and reports itself as the leader of some partitions.
+	Nodes int
+
+	// SASLMechanism requires clients to authenticate with PLAIN,
+	// SCRAM-SHA-256, SCRAM-SHA-512 or OAUTHBEARER. Empty disables SASL.
+	SASLMechanism string
+	// Users maps user names to passwords for PLAIN and SCRAM
+	Users map[string]string
+	// Tokens lists the bearer tokens accepted for OAUTHBEARER
+	Tokens []string
+}
+
+// Server is an in-process Kafka cluster
+type Server struct {
+	// Addrs holds the host:port of every node; node i listens on Addrs[i]
+	Addrs []string
+
+	config    Config
+	listeners []net.Listener
+	wg        sync.WaitGroup
+
+	mu      sync.Mutex
+	closed  bool
+	done    chan struct{} // closed with the server
+	latency time.Duration // of every response
+	conns   map[net.Conn]struct{}
+	topics  map[string]*topic
+	groups  map[string]*group
+	members int            // member ID sequence
+	stopped map[int32]bool // nodes reported as down
+}
+
+// supportedAPIs lists the APIs the server implements
+var supportedAPIs = []protocol.ApiKey{
+	protocol.Produce,
+	protocol.Fetch,
+	protocol.ListOffsets,
+	protocol.Metadata,
+	protocol.OffsetCommit,
+	protocol.OffsetFetch,
+	protocol.FindCoordinator,
+	protocol.JoinGroup,
+	protocol.Heartbeat,
+	protocol.LeaveGroup,
+	protocol.SyncGroup,
+	protocol.DescribeGroups,
+	protocol.ListGroups,
+	protocol.SaslHandshake,
+	protocol.ApiVersions,
+	protocol.CreateTopics,
+	protocol.DeleteTopics,
+	protocol.DeleteRecords,
+	protocol.DescribeConfigs,
+	protocol.SaslAuthenticate,
+	protocol.CreatePartitions,
+	protocol.DeleteGroups,
+	protocol.IncrementalAlterConfigs,
+	protocol.OffsetDelete,
+}
+
+// NewServer starts a server listening on loopback ports
+func NewServer(cfg Config) (*Server, error) {
+	nodes := cfg.Nodes
+	if nodes <= 0 {
+		nodes = 1
+	}
+
+	s := &Server{
+		config:  cfg,
+		done:    make(chan struct{}),
+		conns:   make(map[net.Conn]struct{}),
+		topics:  make(map[string]*topic),
+		groups:  make(map[string]*group),
+		stopped: make(map[int32]bool),
+	}
+
+	for i := 0; i < nodes; i++ {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			s.Close()
+			return nil, fmt.Errorf("failed to listen: %w", err)
+		}
+		s.listeners = append(s.listeners, l)
+		s.Addrs = append(s.Addrs, l.Addr().String())
+	}
+
+	for i, l := range s.listeners {
+		s.wg.Add(1)
+		go s.serve(int32(i), l)
+	}
+
+	return s, nil
+}
+
+// Close stops the server and closes all client connections
+func (s *Server) Close() {
+	s.mu.Lock()
+	if !s.closed {
+		close(s.done)
+	}
+	s.closed = true
+	for _, l := range s.listeners {
+		l.Close()
+	}
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+}
+
+// CloseConnections closes the client connections, as a restart of the
+// brokers would. The server keeps its state and accepts new connections.
+func (s *Server) CloseConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+}
+
+// SetLatency delays the responses to the requests read from now on, to test
+// timeouts and cancellation
+func (s *Server) SetLatency(latency time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latency = latency
+}
+
+// delay waits for the latency of a response, reporting false if the server
+// closed meanwhile
+func (s *Server) delay() bool {
+	s.mu.Lock()
+	latency := s.latency
+	s.mu.Unlock()
+	if latency <= 0 {
+		return true
+	}
+
+	select {
+	case <-time.After(latency):
+		return true
+	case <-s.done:
+		return false
+	}
+}
+
+// Compact removes the records of a partition from an offset up to another,
+// as log compaction does. Every record is taken to have been produced in a
+// batch of its own, whose empty header stays in the log. The gap is a log
+// segment of its own: a fetch in it only returns its empty batches.
+func (s *Server) Compact(topicName string, partition int32, from, to int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := s.partition(topicName, partition)
+	if p == nil {
+		return
+	}
+	for i := range p.records {
+		if r := &p.records[i]; r.offset >= from && r.offset < to {
+			r.compacted = true
+		}
+	}
+}
+
+// StopNode reports a node as down in metadata. It drops out of the broker
+// list and the in-sync replicas, and partitions it led move to the next
+// in-sync replica or become leaderless. The node keeps serving requests.
+func (s *Server) StopNode(nodeID int32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stopped[nodeID] = true
+}
+
+// serve accepts connections for one node
+func (s *Server) serve(nodeID int32, l net.Listener) {
+	defer s.wg.Done()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return
+		}
+		s.conns[conn] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+
+		go s.handleConn(nodeID, conn)
+	}
+}
+
+// session is the state of one client connection
+type session struct {
+	server        *Server
+	nodeID        int32
+	host          string
+	clientID      string // of the current request
+	mechanism     string // negotiated by the SASL handshake
+	sasl          saslConversation
+	authenticated bool
+}
+
+// handleConn reads requests from a connection and writes the responses.
+// Like a real broker, it drops connections on malformed requests and on
+// requests sent before SASL authentication completed.
+func (s *Server) handleConn(nodeID int32, conn net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		conn.Close()
+	}()
+
+	host, _, _ := net.SplitHostPort(conn.RemoteAddr().String())
+	sess := &session{
+		server:        s,
+		nodeID:        nodeID,
+		host:          "/" + host,
+		authenticated: s.config.SASLMechanism == "",
+	}
+	r := bufio.NewReader(conn)
+
+	for {
+		version, correlationID, clientID, req, err := protocol.ReadRequest(r)
+		if err != nil {
+			return
+		}
+		sess.clientID = clientID
+
+		switch req.(type) {
+		case *apiversions.Request, *saslhandshake.Request, *saslauthenticate.Request:
+		default:
+			if !sess.authenticated {
+				return
+			}
+		}
+
+		if !s.delay() {
+			return
+		}
+
+		// Fetch responses are encoded by hand, see fetchResponse
+		if fetchReq, ok := req.(*fetch.Request); ok {
+			if _, err := conn.Write(s.fetch(version, correlationID, fetchReq)); err != nil {
+				return
+			}
+			continue
+		}
+
+		resp, err := sess.handle(version, req)
+		if err != nil {
+			return
+		}
+		if resp == nil {
+			continue // produce with acks=0 has no response
+		}
+		if err := protocol.WriteResponse(conn, version, correlationID, resp); err != nil {
+			return
+		}
+	}
+}
+
+// handle dispatches a request to its handler
+func (sess *session) handle(version int16, req protocol.Message) (protocol.Message, error) {
+	s := sess.server
+
+	switch r := req.(type) {
+	case *apiversions.Request:
+		return s.apiVersions(), nil
+	case *saslhandshake.Request:
+		return sess.saslHandshake(r), nil
+	case *saslauthenticate.Request:
+		return sess.saslAuthenticate(r), nil
+	case *metadata.Request:
+		return s.metadata(r), nil
+	case *createtopics.Request:
+		return s.createTopics(r), nil
+	case *deletetopics.Request:
+		return s.deleteTopics(r), nil
+	case *createpartitions.Request:
+		return s.createPartitions(r), nil
+	case *describeconfigs.Request:
+		return s.describeConfigs(version, r), nil
+	case *incrementalalterconfigs.Request:
+		return s.incrementalAlterConfigs(r), nil
+	case *produce.Request:
+		resp := s.produce(r)
+		if r.Acks == 0 {
+			return nil, nil
+		}
+		return resp, nil
+	case *listoffsets.Request:
+		return s.listOffsets(r), nil
+	case *findcoordinator.Request:
+		return s.findCoordinator(r), nil
+	case *joingroup.Request:
+		return s.joinGroup(sess, r), nil
+	case *syncgroup.Request:
+		return s.syncGroup(r), nil
+	case *heartbeat.Request:
+		return s.heartbeat(r), nil
+	case *leavegroup.Request:
+		return s.leaveGroup(r), nil
+	case *offsetcommit.Request:
+		return s.offsetCommit(r), nil
+	case *offsetfetch.Request:
+		return s.offsetFetch(r), nil
+	case *listgroups.Request:
+		return s.listGroups(), nil
+	case *describegroups.Request:
+		return s.describeGroups(r), nil
+	case *deletegroups.Request:
+		return s.deleteGroups(r), nil
+	case *offsetdelete.Request:
+		return s.offsetDelete(r), nil
+	case *deleterecords.Request:
+		return s.deleteRecords(r), nil
+	}
+
+	return nil, fmt.Errorf("unsupported request %s", req.ApiKey())
+}
+
+// apiVersions advertises the versions kafka-go knows for every supported API
+func (s *Server) apiVersions() *apiversions.Response {
+	resp := &apiversions.Response{}
+	for _, key := range supportedAPIs {
+		minVersion := key.MinVersion()
+		if key == protocol.Fetch {
+			minVersion = 4 // see fetchResponse
+		}
+		resp.ApiKeys = append(resp.ApiKeys, apiversions.ApiKeyResponse{
+			ApiKey:     int16(key),
+			MinVersion: minVersion,
+			MaxVersion: key.MaxVersion(),
+		})
+	}
+	return resp
+}
+
+// broker returns the metadata entry of a node
+func (s *Server) broker(nodeID int32) metadata.ResponseBroker {
+	host, portStr, _ := net.SplitHostPort(s.Addrs[nodeID])
+	port, _ := strconv.Atoi(portStr)
+	return metadata.ResponseBroker{
+		NodeID: nodeID,
+		Host:   host,
+		Port:   int32(port),
+	}
+}
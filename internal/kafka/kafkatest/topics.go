@@ -0,0 +1,529 @@
+package kafkatest
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go/protocol"
+	"github.com/segmentio/kafka-go/protocol/createpartitions"
+	"github.com/segmentio/kafka-go/protocol/createtopics"
+	"github.com/segmentio/kafka-go/protocol/deletetopics"
+	"github.com/segmentio/kafka-go/protocol/describeconfigs"
+	"github.com/segmentio/kafka-go/protocol/incrementalalterconfigs"
+	"github.com/segmentio/kafka-go/protocol/metadata"
+)
+
+// Kafka error codes returned by the server
+const (
+	errOffsetOutOfRange        int16 = 1
+	errUnknownTopicOrPartition int16 = 3
+	errInvalidTopic            int16 = 17
+	errIllegalGeneration       int16 = 22
+	errInconsistentProtocol    int16 = 23
+	errUnknownMemberID         int16 = 25
+	errRebalanceInProgress     int16 = 27
+	errUnsupportedSASL         int16 = 33
+	errTopicAlreadyExists      int16 = 36
+	errInvalidPartitions       int16 = 37
+	errInvalidReplication      int16 = 38
+	errInvalidReplicaAssign    int16 = 39
+	errInvalidConfig           int16 = 40
+	errInvalidRequest          int16 = 42
+	errSASLAuthFailed          int16 = 58
+	errNonEmptyGroup           int16 = 68
+	errGroupIDNotFound         int16 = 69
+	errGroupSubscribedToTopic  int16 = 86
+)
+
+// Config sources reported by DescribeConfigs
+const (
+	configSourceDynamicTopic int8 = 1
+	configSourceDefault      int8 = 5
+)
+
+// Config operations of IncrementalAlterConfigs
+const (
+	configOpSet      int8 = 0
+	configOpDelete   int8 = 1
+	configOpAppend   int8 = 2
+	configOpSubtract int8 = 3
+)
+
+// resourceTypeTopic is the DescribeConfigs resource type of topics
+const resourceTypeTopic int8 = 2
+
+// defaultTopicConfigs are the broker defaults of the topic configs the server knows
+var defaultTopicConfigs = map[string]string{
+	"cleanup.policy":                          "delete",
+	"compression.type":                        "producer",
+	"delete.retention.ms":                     "86400000",
+	"file.delete.delay.ms":                    "60000",
+	"flush.messages":                          "9223372036854775807",
+	"flush.ms":                                "9223372036854775807",
+	"index.interval.bytes":                    "4096",
+	"max.compaction.lag.ms":                   "9223372036854775807",
+	"max.message.bytes":                       "1048588",
+	"message.timestamp.type":                  "CreateTime",
+	"min.cleanable.dirty.ratio":               "0.5",
+	"min.compaction.lag.ms":                   "0",
+	"min.insync.replicas":                     "1",
+	"preallocate":                             "false",
+	"retention.bytes":                         "-1",
+	"retention.ms":                            "604800000",
+	"segment.bytes":                           "1073741824",
+	"segment.ms":                              "604800000",
+	"unclean.leader.election.enable":          "false",
+	"message.downconversion.enable":           "true",
+	"segment.index.bytes":                     "10485760",
+	"segment.jitter.ms":                       "0",
+	"follower.replication.throttled.replicas": "",
+	"leader.replication.throttled.replicas":   "",
+}
+
+// checkConfig validates a config value against the type of its default,
+// returning an error message for unknown configs and malformed values
+func checkConfig(name, value string) string {
+	defaultValue, ok := defaultTopicConfigs[name]
+	if !ok {
+		return fmt.Sprintf("unknown topic config name: %s", name)
+	}
+
+	var err error
+	if _, intErr := strconv.ParseInt(defaultValue, 10, 64); intErr == nil {
+		_, err = strconv.ParseInt(value, 10, 64)
+	} else if _, boolErr := strconv.ParseBool(defaultValue); boolErr == nil {
+		_, err = strconv.ParseBool(value)
+	} else if _, floatErr := strconv.ParseFloat(defaultValue, 64); floatErr == nil {
+		_, err = strconv.ParseFloat(value, 64)
+	}
+	if err != nil {
+		return fmt.Sprintf("invalid value %s for configuration %s", value, name)
+	}
+	return ""
+}
+
+// validTopicName matches the characters Kafka allows in topic names
+var validTopicName = regexp.MustCompile(`^[a-zA-Z0-9._-]{1,249}$`)
+
+// topic is an in-memory topic
+type topic struct {
+	partitions []*partition
+	configs    map[string]string // overrides of defaultTopicConfigs
+}
+
+// partition is an in-memory partition log. Records before startOffset
+// have been deleted.
+type partition struct {
+	replicas    []int32
+	startOffset int64
+	records     []record
+}
+
+// endOffset returns the offset of the next record
+func (p *partition) endOffset() int64 {
+	return p.startOffset + int64(len(p.records))
+}
+
+// record is a stored record
+type record struct {
+	offset    int64
+	time      time.Time
+	key       []byte
+	value     []byte
+	headers   []protocol.Header
+	compacted bool // removed by Compact, leaving its batch empty
+}
+
+// nodes returns the number of broker nodes
+func (s *Server) nodes() int32 {
+	return int32(len(s.Addrs))
+}
+
+// metadata describes the brokers and the requested topics, all topics if none
+func (s *Server) metadata(req *metadata.Request) *metadata.Response {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resp := &metadata.Response{
+		ClusterID:    "kafkatest",
+		ControllerID: 0,
+	}
+	for i := int32(0); i < s.nodes(); i++ {
+		if !s.stopped[i] {
+			resp.Brokers = append(resp.Brokers, s.broker(i))
+		}
+	}
+
+	names := req.TopicNames
+	if names == nil {
+		for name := range s.topics {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+
+	for _, name := range names {
+		t, ok := s.topics[name]
+		if !ok {
+			resp.Topics = append(resp.Topics, metadata.ResponseTopic{Name: name, ErrorCode: errUnknownTopicOrPartition})
+			continue
+		}
+
+		rt := metadata.ResponseTopic{Name: name, IsInternal: strings.HasPrefix(name, "__")}
+		for i, p := range t.partitions {
+			rp := metadata.ResponsePartition{
+				PartitionIndex:  int32(i),
+				LeaderID:        -1,
+				ReplicaNodes:    p.replicas,
+				IsrNodes:        []int32{},
+				OfflineReplicas: []int32{},
+			}
+			for _, id := range p.replicas {
+				if s.stopped[id] {
+					rp.OfflineReplicas = append(rp.OfflineReplicas, id)
+					continue
+				}
+				if rp.LeaderID < 0 {
+					rp.LeaderID = id
+				}
+				rp.IsrNodes = append(rp.IsrNodes, id)
+			}
+			rt.Partitions = append(rt.Partitions, rp)
+		}
+		resp.Topics = append(resp.Topics, rt)
+	}
+
+	return resp
+}
+
+// createTopics creates topics with either a partition count and replication
+// factor or a manual replica assignment
+func (s *Server) createTopics(req *createtopics.Request) *createtopics.Response {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resp := &createtopics.Response{}
+	for _, rt := range req.Topics {
+		result := createtopics.ResponseTopic{Name: rt.Name}
+
+		t, code, msg := s.newTopic(rt)
+		if code != 0 {
+			result.ErrorCode = code
+			result.ErrorMessage = msg
+			result.NumPartitions = -1
+			result.ReplicationFactor = -1
+		} else {
+			if !req.ValidateOnly {
+				s.topics[rt.Name] = t
+			}
+			result.NumPartitions = int32(len(t.partitions))
+			result.ReplicationFactor = int16(len(t.partitions[0].replicas))
+			for _, entry := range t.describe(nil) {
+				result.Configs = append(result.Configs, createtopics.ResponseTopicConfig{
+					Name:         entry.ConfigName,
+					Value:        entry.ConfigValue,
+					ConfigSource: entry.ConfigSource,
+				})
+			}
+		}
+		resp.Topics = append(resp.Topics, result)
+	}
+
+	return resp
+}
+
+// newTopic validates a topic creation request; the caller holds the lock
+func (s *Server) newTopic(rt createtopics.RequestTopic) (*topic, int16, string) {
+	if !validTopicName.MatchString(rt.Name) || rt.Name == "." || rt.Name == ".." {
+		return nil, errInvalidTopic, fmt.Sprintf("topic name %q is illegal", rt.Name)
+	}
+	if _, ok := s.topics[rt.Name]; ok {
+		return nil, errTopicAlreadyExists, fmt.Sprintf("topic '%s' already exists", rt.Name)
+	}
+	for name := range s.topics {
+		if strings.ReplaceAll(name, ".", "_") == strings.ReplaceAll(rt.Name, ".", "_") {
+			return nil, errInvalidTopic, fmt.Sprintf("topic '%s' collides with existing topic: %s", rt.Name, name)
+		}
+	}
+
+	t := &topic{configs: make(map[string]string)}
+	for _, c := range rt.Configs {
+		if msg := checkConfig(c.Name, c.Value); msg != "" {
+			return nil, errInvalidConfig, msg
+		}
+		t.configs[c.Name] = c.Value
+	}
+
+	if len(rt.Assignments) > 0 {
+		if rt.NumPartitions != -1 || rt.ReplicationFactor != -1 {
+			return nil, errInvalidRequest, "both partitions and replica assignment were set"
+		}
+		assignments := append([]createtopics.RequestAssignment(nil), rt.Assignments...)
+		sort.Slice(assignments, func(i, j int) bool { return assignments[i].PartitionIndex < assignments[j].PartitionIndex })
+		for i, a := range assignments {
+			if a.PartitionIndex != int32(i) {
+				return nil, errInvalidReplicaAssign, "partitions must be numbered from 0 without gaps"
+			}
+			if msg := s.checkReplicas(a.BrokerIDs, len(assignments[0].BrokerIDs)); msg != "" {
+				return nil, errInvalidReplicaAssign, msg
+			}
+			t.partitions = append(t.partitions, &partition{replicas: a.BrokerIDs})
+		}
+		return t, 0, ""
+	}
+
+	numPartitions, replicationFactor := int(rt.NumPartitions), int(rt.ReplicationFactor)
+	if numPartitions == -1 {
+		numPartitions = 1
+	}
+	if replicationFactor == -1 {
+		replicationFactor = 1
+	}
+	if numPartitions <= 0 {
+		return nil, errInvalidPartitions, "number of partitions must be larger than 0"
+	}
+	if replicationFactor <= 0 || replicationFactor > int(s.nodes()) {
+		return nil, errInvalidReplication, fmt.Sprintf("replication factor: %d larger than available brokers: %d", replicationFactor, s.nodes())
+	}
+	for i := 0; i < numPartitions; i++ {
+		t.partitions = append(t.partitions, &partition{replicas: s.assignReplicas(i, replicationFactor)})
+	}
+
+	return t, 0, ""
+}
+
+// assignReplicas spreads the replicas of a partition over the nodes
+func (s *Server) assignReplicas(partition, replicationFactor int) []int32 {
+	replicas := make([]int32, replicationFactor)
+	for i := range replicas {
+		replicas[i] = int32((partition + i) % int(s.nodes()))
+	}
+	return replicas
+}
+
+// checkReplicas validates a manual replica assignment
+func (s *Server) checkReplicas(replicas []int32, replicationFactor int) string {
+	if len(replicas) == 0 || len(replicas) != replicationFactor {
+		return "all partitions must have the same number of replicas"
+	}
+	seen := make(map[int32]bool)
+	for _, id := range replicas {
+		if id < 0 || id >= s.nodes() {
+			return fmt.Sprintf("unknown broker %d in replica assignment", id)
+		}
+		if seen[id] {
+			return fmt.Sprintf("duplicate broker %d in replica assignment", id)
+		}
+		seen[id] = true
+	}
+	return ""
+}
+
+// deleteTopics deletes topics and the offsets committed on them
+func (s *Server) deleteTopics(req *deletetopics.Request) *deletetopics.Response {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resp := &deletetopics.Response{}
+	for _, name := range req.TopicNames {
+		result := deletetopics.ResponseTopic{Name: name}
+		if _, ok := s.topics[name]; !ok {
+			result.ErrorCode = errUnknownTopicOrPartition
+		} else {
+			delete(s.topics, name)
+			for _, g := range s.groups {
+				delete(g.offsets, name)
+			}
+		}
+		resp.Responses = append(resp.Responses, result)
+	}
+
+	return resp
+}
+
+// createPartitions adds partitions to topics
+func (s *Server) createPartitions(req *createpartitions.Request) *createpartitions.Response {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resp := &createpartitions.Response{}
+	for _, rt := range req.Topics {
+		result := createpartitions.ResponseResult{Name: rt.Name}
+		t, ok := s.topics[rt.Name]
+		switch {
+		case !ok:
+			result.ErrorCode = errUnknownTopicOrPartition
+		case int(rt.Count) <= len(t.partitions):
+			result.ErrorCode = errInvalidPartitions
+			result.ErrorMessage = fmt.Sprintf("topic currently has %d partitions, which is higher than the requested %d", len(t.partitions), rt.Count)
+		case rt.Assignments != nil && len(rt.Assignments) != int(rt.Count)-len(t.partitions):
+			result.ErrorCode = errInvalidReplicaAssign
+			result.ErrorMessage = "replica assignment does not match the number of new partitions"
+		default:
+			replicationFactor := len(t.partitions[0].replicas)
+			var added []*partition
+			for i := len(t.partitions); i < int(rt.Count); i++ {
+				replicas := s.assignReplicas(i, replicationFactor)
+				if rt.Assignments != nil {
+					replicas = rt.Assignments[i-len(t.partitions)].BrokerIDs
+					if msg := s.checkReplicas(replicas, replicationFactor); msg != "" {
+						result.ErrorCode = errInvalidReplicaAssign
+						result.ErrorMessage = msg
+						break
+					}
+				}
+				added = append(added, &partition{replicas: replicas})
+			}
+			if result.ErrorCode == 0 && !req.ValidateOnly {
+				t.partitions = append(t.partitions, added...)
+			}
+		}
+		resp.Results = append(resp.Results, result)
+	}
+
+	return resp
+}
+
+// describe lists the configs of a topic, all of them if names is nil
+func (t *topic) describe(names []string) []describeconfigs.ResponseConfigEntry {
+	if names == nil {
+		for name := range defaultTopicConfigs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+
+	var entries []describeconfigs.ResponseConfigEntry
+	for _, name := range names {
+		defaultValue, known := defaultTopicConfigs[name]
+		if !known {
+			continue
+		}
+
+		entry := describeconfigs.ResponseConfigEntry{
+			ConfigName:   name,
+			ConfigValue:  defaultValue,
+			IsDefault:    true,
+			ConfigSource: configSourceDefault,
+		}
+		if value, ok := t.configs[name]; ok {
+			entry.ConfigValue = value
+			entry.IsDefault = false
+			entry.ConfigSource = configSourceDynamicTopic
+			entry.ConfigSynonyms = append(entry.ConfigSynonyms, describeconfigs.ResponseConfigSynonym{
+				ConfigName:   name,
+				ConfigValue:  value,
+				ConfigSource: configSourceDynamicTopic,
+			})
+		}
+		entry.ConfigSynonyms = append(entry.ConfigSynonyms, describeconfigs.ResponseConfigSynonym{
+			ConfigName:   name,
+			ConfigValue:  defaultValue,
+			ConfigSource: configSourceDefault,
+		})
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
+
+// describeConfigs describes topic configs. Other resources have no configs.
+func (s *Server) describeConfigs(version int16, req *describeconfigs.Request) *describeconfigs.Response {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resp := &describeconfigs.Response{}
+	for _, r := range req.Resources {
+		result := describeconfigs.ResponseResource{
+			ResourceType: r.ResourceType,
+			ResourceName: r.ResourceName,
+		}
+		if r.ResourceType == resourceTypeTopic {
+			if t, ok := s.topics[r.ResourceName]; ok {
+				result.ConfigEntries = t.describe(r.ConfigNames)
+				if !req.IncludeSynonyms {
+					for i := range result.ConfigEntries {
+						result.ConfigEntries[i].ConfigSynonyms = nil
+					}
+				}
+			} else {
+				result.ErrorCode = errUnknownTopicOrPartition
+				result.ErrorMessage = fmt.Sprintf("topic %s does not exist", r.ResourceName)
+			}
+		}
+		resp.Resources = append(resp.Resources, result)
+	}
+
+	return resp
+}
+
+// incrementalAlterConfigs sets, deletes, appends to or subtracts from topic configs
+func (s *Server) incrementalAlterConfigs(req *incrementalalterconfigs.Request) *incrementalalterconfigs.Response {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resp := &incrementalalterconfigs.Response{}
+	for _, r := range req.Resources {
+		result := incrementalalterconfigs.ResponseAlterResponse{
+			ResourceType: r.ResourceType,
+			ResourceName: r.ResourceName,
+		}
+		t, ok := s.topics[r.ResourceName]
+		if r.ResourceType != resourceTypeTopic || !ok {
+			result.ErrorCode = errUnknownTopicOrPartition
+			resp.Responses = append(resp.Responses, result)
+			continue
+		}
+
+		configs := make(map[string]string, len(t.configs))
+		for k, v := range t.configs {
+			configs[k] = v
+		}
+		for _, c := range r.Configs {
+			defaultValue, known := defaultTopicConfigs[c.Name]
+			if !known {
+				result.ErrorCode = errInvalidConfig
+				result.ErrorMessage = fmt.Sprintf("unknown topic config name: %s", c.Name)
+				break
+			}
+
+			current, ok := configs[c.Name]
+			if !ok {
+				current = defaultValue
+			}
+			switch c.ConfigOperation {
+			case configOpSet:
+				if msg := checkConfig(c.Name, c.Value); msg != "" {
+					result.ErrorCode = errInvalidConfig
+					result.ErrorMessage = msg
+					break
+				}
+				configs[c.Name] = c.Value
+			case configOpDelete:
+				delete(configs, c.Name)
+			case configOpAppend:
+				configs[c.Name] = strings.Trim(current+","+c.Value, ",")
+			case configOpSubtract:
+				var kept []string
+				for _, v := range strings.Split(current, ",") {
+					if v != c.Value && v != "" {
+						kept = append(kept, v)
+					}
+				}
+				configs[c.Name] = strings.Join(kept, ",")
+			default:
+				result.ErrorCode = errInvalidRequest
+			}
+		}
+		if result.ErrorCode == 0 && !req.ValidateOnly {
+			t.configs = configs
+		}
+		resp.Responses = append(resp.Responses, result)
+	}
+
+	return resp
+}
@@ -0,0 +1,331 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/cfk-dev/cfk/internal/kafka/deleterecords"
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/protocol"
+)
+
+// fetchMaxBytes bounds the size of a single fetch response
+const fetchMaxBytes = 1 << 20
+
+// Message is a record read from or written to a topic partition
+type Message struct {
+	Topic     string
+	Partition int
+	Offset    int64
+	Timestamp time.Time
+	Key       []byte
+	Value     []byte
+	Headers   []Header
+}
+
+// Header is a record header
+type Header struct {
+	Key   string
+	Value []byte
+}
+
+// PartitionOffsets holds the earliest and latest offsets of a partition.
+// Latest is the log end offset, i.e. the offset of the next record.
+type PartitionOffsets struct {
+	Partition int
+	Earliest  int64
+	Latest    int64
+}
+
+// PartitionForKey picks a partition the way the default Java producer does:
+// murmur2 hash of the key, or a random partition when there is no key
+func PartitionForKey(key []byte, numPartitions int) int {
+	partitions := make([]int, numPartitions)
+	for i := range partitions {
+		partitions[i] = i
+	}
+	return kafka.Murmur2Balancer{}.Balance(kafka.Message{Key: key}, partitions...)
+}
+
+// ListOffsets gets the earliest and latest offsets of every partition of a topic
+func (c *Client) ListOffsets(ctx context.Context, topicName string) ([]PartitionOffsets, error) {
+	if c.admin == nil {
+		return nil, fmt.Errorf("not connected to Kafka")
+	}
+
+	topic, err := c.topicMetadata(ctx, topicName)
+	if err != nil {
+		return nil, err
+	}
+
+	partitions := make([]int, len(topic.Partitions))
+	for i, p := range topic.Partitions {
+		partitions[i] = p.ID
+	}
+	return c.listOffsets(ctx, topicName, partitions)
+}
+
+// OffsetsForTime finds, for every partition of a topic, the offset of the
+// first record with a timestamp at or after t. Partitions without such a
+// record map to -1.
+func (c *Client) OffsetsForTime(ctx context.Context, topicName string, t time.Time) (map[int]int64, error) {
+	if c.admin == nil {
+		return nil, fmt.Errorf("not connected to Kafka")
+	}
+
+	topic, err := c.topicMetadata(ctx, topicName)
+	if err != nil {
+		return nil, err
+	}
+
+	requests := make([]kafka.OffsetRequest, len(topic.Partitions))
+	for i, p := range topic.Partitions {
+		requests[i] = kafka.TimeOffsetOf(p.ID, t)
+	}
+	resp, err := c.admin.ListOffsets(ctx, &kafka.ListOffsetsRequest{
+		Topics: map[string][]kafka.OffsetRequest{topicName: requests},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list offsets for topic %s: %w", topicName, err)
+	}
+
+	offsets := make(map[int]int64, len(topic.Partitions))
+	for _, p := range resp.Topics[topicName] {
+		if p.Error != nil {
+			return nil, fmt.Errorf("failed to list offsets for %s/%d: %w", topicName, p.Partition, p.Error)
+		}
+		// A found record is keyed by its offset; no record comes back as offset -1
+		offsets[p.Partition] = -1
+		for offset := range p.Offsets {
+			offsets[p.Partition] = offset
+		}
+	}
+
+	return offsets, nil
+}
+
+// listOffsets gets the earliest and latest offsets of some partitions of a
+// topic, in the order given
+func (c *Client) listOffsets(ctx context.Context, topicName string, partitions []int) ([]PartitionOffsets, error) {
+	requests := make([]kafka.OffsetRequest, 0, 2*len(partitions))
+	index := make(map[int]int, len(partitions))
+	offsets := make([]PartitionOffsets, len(partitions))
+	for i, p := range partitions {
+		requests = append(requests, kafka.FirstOffsetOf(p), kafka.LastOffsetOf(p))
+		index[p] = i
+		offsets[i].Partition = p
+	}
+	if len(requests) == 0 {
+		return offsets, nil
+	}
+
+	resp, err := c.admin.ListOffsets(ctx, &kafka.ListOffsetsRequest{
+		Topics: map[string][]kafka.OffsetRequest{topicName: requests},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list offsets for topic %s: %w", topicName, err)
+	}
+
+	for _, p := range resp.Topics[topicName] {
+		if p.Error != nil {
+			return nil, fmt.Errorf("failed to list offsets for %s/%d: %w", topicName, p.Partition, p.Error)
+		}
+		i, ok := index[p.Partition]
+		if !ok {
+			continue
+		}
+		// The first and last offset requests are merged into one entry
+		if p.FirstOffset >= 0 {
+			offsets[i].Earliest = p.FirstOffset
+		}
+		if p.LastOffset >= 0 {
+			offsets[i].Latest = p.LastOffset
+		}
+	}
+
+	return offsets, nil
+}
+
+// deleteRecordsTimeout is how long brokers wait for replicas to delete
+// records when the context has no deadline
+const deleteRecordsTimeout = 30 * time.Second
+
+// DeleteRecords deletes the records of partitions of a topic before an
+// offset, like kafka-delete-records.sh; an offset of -1 deletes every record.
+// It returns the new earliest offset of every partition.
+func (c *Client) DeleteRecords(ctx context.Context, topicName string, offsets map[int]int64) (map[int]int64, error) {
+	if c.admin == nil {
+		return nil, fmt.Errorf("not connected to Kafka")
+	}
+
+	partitions := make([]deleterecords.RequestPartition, 0, len(offsets))
+	for p, offset := range offsets {
+		partitions = append(partitions, deleterecords.RequestPartition{PartitionIndex: int32(p), Offset: offset})
+	}
+	sort.Slice(partitions, func(i, j int) bool { return partitions[i].PartitionIndex < partitions[j].PartitionIndex })
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to delete records of topic %s: %w", topicName, err)
+	}
+	timeout := deleteRecordsTimeout
+	if deadline, ok := ctx.Deadline(); ok {
+		// Brokers do not wait for the replicas with a timeout of 0
+		timeout = max(time.Until(deadline), time.Millisecond)
+	}
+
+	msg, err := c.transport.RoundTrip(ctx, c.admin.Addr, &deleterecords.Request{
+		Topics:    []deleterecords.RequestTopic{{Name: topicName, Partitions: partitions}},
+		TimeoutMs: int32(timeout.Milliseconds()),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete records of topic %s: %w", topicName, err)
+	}
+
+	earliest := make(map[int]int64, len(offsets))
+	for _, t := range msg.(*deleterecords.Response).Topics {
+		for _, p := range t.Partitions {
+			if p.ErrorCode != 0 {
+				return nil, fmt.Errorf("failed to delete records of %s/%d: %w", topicName, p.PartitionIndex, kafka.Error(p.ErrorCode))
+			}
+			earliest[int(p.PartitionIndex)] = p.LowWatermark
+		}
+	}
+	return earliest, nil
+}
+
+// ReadMessages reads up to limit messages of a partition starting at offset.
+// It stops early at the end of the partition.
+func (c *Client) ReadMessages(ctx context.Context, topicName string, partition int, offset int64, limit int) ([]Message, error) {
+	if c.admin == nil {
+		return nil, fmt.Errorf("not connected to Kafka")
+	}
+
+	var messages []Message
+	for len(messages) < limit {
+		resp, err := c.admin.Fetch(ctx, &kafka.FetchRequest{
+			Topic:     topicName,
+			Partition: partition,
+			Offset:    offset,
+			MinBytes:  1,
+			MaxBytes:  fetchMaxBytes,
+			MaxWait:   100 * time.Millisecond,
+		})
+		if err == nil {
+			err = resp.Error
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch %s/%d at offset %d: %w", topicName, partition, offset, err)
+		}
+
+		read := 0
+		last := int64(-1) // offset of the last record fetched
+		for len(messages) < limit {
+			record, err := resp.Records.ReadRecord()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to read records of %s/%d: %w", topicName, partition, err)
+			}
+			last = max(last, record.Offset)
+			// Fetches return whole batches, which may start before the requested offset
+			if record.Offset < offset {
+				continue
+			}
+
+			msg, err := readRecord(topicName, partition, record)
+			if err != nil {
+				return nil, err
+			}
+			messages = append(messages, msg)
+			offset = record.Offset + 1
+			read++
+		}
+
+		// Batches without a record left at the offset, after compaction or
+		// of transaction markers, are skipped at once
+		if read == 0 {
+			next, ok := skipBatches(resp, offset, last)
+			if !ok {
+				break
+			}
+			offset = next
+		}
+		if offset >= resp.HighWatermark {
+			break
+		}
+	}
+
+	return messages, nil
+}
+
+// skipBatches returns the offset to fetch from after a response without a
+// record at or after the offset: past the records and batches it holds, even
+// empty or of transaction markers, and past the start of the log. It reports
+// false if the response holds nothing to skip.
+func skipBatches(resp *kafka.FetchResponse, offset, last int64) (int64, bool) {
+	next := max(last+1, resp.LogStartOffset)
+	batches := 0
+	if stream, ok := resp.Records.(*protocol.RecordStream); ok {
+		// A batch ends before the next one starts
+		batches = len(stream.Records)
+		for _, batch := range stream.Records {
+			switch b := batch.(type) {
+			case *protocol.RecordBatch:
+				next = max(next, b.BaseOffset)
+			case *protocol.ControlBatch:
+				next = max(next, b.BaseOffset+1)
+			}
+		}
+	}
+	// The end of the last batch is not decoded, so a fetch at its start
+	// moves on by one
+	if last >= 0 || batches > 0 {
+		next = max(next, offset+1)
+	}
+	return next, next > offset
+}
+
+// readRecord copies a fetched record into a Message
+func readRecord(topicName string, partition int, record *kafka.Record) (Message, error) {
+	msg := Message{
+		Topic:     topicName,
+		Partition: partition,
+		Offset:    record.Offset,
+		Timestamp: record.Time,
+	}
+
+	var err error
+	if record.Key != nil {
+		if msg.Key, err = io.ReadAll(record.Key); err != nil {
+			return msg, fmt.Errorf("failed to read key at offset %d: %w", record.Offset, err)
+		}
+	}
+	if record.Value != nil {
+		if msg.Value, err = io.ReadAll(record.Value); err != nil {
+			return msg, fmt.Errorf("failed to read value at offset %d: %w", record.Offset, err)
+		}
+	}
+	for _, h := range record.Headers {
+		msg.Headers = append(msg.Headers, Header{Key: h.Key, Value: h.Value})
+	}
+
+	return msg, nil
+}
+
+// ProduceMessage writes a message to a topic and waits for all in-sync replicas.
+// A negative partition picks one from the key. The returned message carries
+// the partition and offset the broker assigned.
+func (c *Client) ProduceMessage(ctx context.Context, topicName string, msg Message) (*Message, error) {
+	results, err := c.ProduceMessages(ctx, topicName, []Message{msg}, ProduceOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if results[0].Err != nil {
+		return nil, results[0].Err
+	}
+	return &results[0].Message, nil
+}
@@ -0,0 +1,311 @@
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cfk-dev/cfk/internal/config"
+	"github.com/segmentio/kafka-go/sasl"
+)
+
+// SASLTypeOAuthBearer is the SASL/OAUTHBEARER mechanism name
+const SASLTypeOAuthBearer = "OAUTHBEARER"
+
+// OAuth token provider names accepted in KafkaClusterConfig.OAuthProvider
+const (
+	OAuthProviderStatic  = "static"
+	OAuthProviderOIDC    = "oidc"
+	OAuthProviderCommand = "command"
+)
+
+// defaultTokenLifetime is assumed for command tokens that carry no expiry
+const defaultTokenLifetime = 5 * time.Minute
+
+// Token is an OAuth access token with its expiry time.
+// A zero Expiry means the token does not expire.
+type Token struct {
+	Value  string
+	Expiry time.Time
+}
+
+// TokenProvider supplies OAuth access tokens for SASL/OAUTHBEARER
+type TokenProvider interface {
+	Token(ctx context.Context) (*Token, error)
+}
+
+// NewTokenProvider builds the token provider configured for a cluster.
+// Tokens are cached and refreshed before they expire.
+func NewTokenProvider(clusterConfig config.KafkaClusterConfig) (TokenProvider, error) {
+	var provider TokenProvider
+
+	switch strings.ToLower(clusterConfig.OAuthProvider) {
+	case "", OAuthProviderStatic:
+		if clusterConfig.OAuthToken == "" {
+			return nil, fmt.Errorf("oauth_token is required for the static provider")
+		}
+		provider = StaticTokenProvider{Value: clusterConfig.OAuthToken}
+	case OAuthProviderOIDC:
+		if clusterConfig.OAuthTokenURL == "" {
+			return nil, fmt.Errorf("oauth_token_url is required for the oidc provider")
+		}
+		provider = &ClientCredentialsProvider{
+			TokenURL:     clusterConfig.OAuthTokenURL,
+			ClientID:     clusterConfig.OAuthClientID,
+			ClientSecret: clusterConfig.OAuthClientSecret,
+			Scopes:       strings.Fields(clusterConfig.OAuthScopes),
+		}
+	case OAuthProviderCommand:
+		if clusterConfig.OAuthTokenCommand == "" {
+			return nil, fmt.Errorf("oauth_token_command is required for the command provider")
+		}
+		provider = CommandTokenProvider{Command: clusterConfig.OAuthTokenCommand}
+	default:
+		return nil, fmt.Errorf("unsupported OAuth token provider %q", clusterConfig.OAuthProvider)
+	}
+
+	return NewRefreshingTokenProvider(provider), nil
+}
+
+// StaticTokenProvider always returns the same token
+type StaticTokenProvider struct {
+	Value string
+}
+
+// Token returns the static token, with the expiry taken from its JWT claims if present
+func (p StaticTokenProvider) Token(ctx context.Context) (*Token, error) {
+	return &Token{Value: p.Value, Expiry: jwtExpiry(p.Value)}, nil
+}
+
+// ClientCredentialsProvider fetches tokens from an OIDC token endpoint
+// using the OAuth 2.0 client credentials grant
+type ClientCredentialsProvider struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	HTTPClient   *http.Client // defaults to a client with a 10s timeout
+}
+
+// tokenResponse is the token endpoint response (RFC 6749 section 5.1)
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	ExpiresIn        int64  `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// Token requests a new access token from the token endpoint
+func (p *ClientCredentialsProvider) Token(ctx context.Context) (*Token, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(p.Scopes) > 0 {
+		form.Set("scope", strings.Join(p.Scopes, " "))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(p.ClientID), url.QueryEscape(p.ClientSecret))
+
+	httpClient := p.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token response: %w", err)
+	}
+
+	var tr tokenResponse
+	if err := json.Unmarshal(body, &tr); err != nil {
+		return nil, fmt.Errorf("invalid token response (HTTP %d): %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK || tr.Error != "" {
+		return nil, fmt.Errorf("token endpoint returned HTTP %d: %s %s", resp.StatusCode, tr.Error, tr.ErrorDescription)
+	}
+	if tr.AccessToken == "" {
+		return nil, fmt.Errorf("token endpoint returned no access_token")
+	}
+
+	return tokenWithLifetime(tr.AccessToken, tr.ExpiresIn), nil
+}
+
+// CommandTokenProvider runs an external command that prints a token on stdout.
+// The output may be a bare token or a token endpoint style JSON document.
+type CommandTokenProvider struct {
+	Command string
+}
+
+// Token runs the command and parses its output
+func (p CommandTokenProvider) Token(ctx context.Context) (*Token, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", p.Command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", p.Command)
+	}
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("token command failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	output := strings.TrimSpace(string(out))
+	if output == "" {
+		return nil, fmt.Errorf("token command printed no token")
+	}
+
+	// JSON output in the token endpoint format
+	if strings.HasPrefix(output, "{") {
+		var tr tokenResponse
+		if err := json.Unmarshal([]byte(output), &tr); err != nil {
+			return nil, fmt.Errorf("invalid token command output: %w", err)
+		}
+		if tr.AccessToken == "" {
+			return nil, fmt.Errorf("token command printed no access_token")
+		}
+		return tokenWithLifetime(tr.AccessToken, tr.ExpiresIn), nil
+	}
+
+	token := &Token{Value: output, Expiry: jwtExpiry(output)}
+	if token.Expiry.IsZero() {
+		// Opaque token without expiry: run the command again after a while
+		token.Expiry = time.Now().Add(defaultTokenLifetime)
+	}
+	return token, nil
+}
+
+// tokenWithLifetime builds a token expiring after expiresIn seconds,
+// falling back to the JWT claims when no lifetime is given
+func tokenWithLifetime(value string, expiresIn int64) *Token {
+	token := &Token{Value: value}
+	if expiresIn > 0 {
+		token.Expiry = time.Now().Add(time.Duration(expiresIn) * time.Second)
+	} else {
+		token.Expiry = jwtExpiry(value)
+	}
+	return token
+}
+
+// jwtExpiry returns the exp claim of a JWT, or the zero time if the token
+// is not a JWT or has no exp claim. The signature is not verified.
+func jwtExpiry(token string) time.Time {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}
+	}
+
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == 0 {
+		return time.Time{}
+	}
+	return time.Unix(claims.Exp, 0)
+}
+
+// RefreshingTokenProvider caches tokens from another provider and fetches a
+// new one once 80% of the token lifetime has elapsed, so connections opened
+// late in a long session never present an expired token.
+type RefreshingTokenProvider struct {
+	provider TokenProvider
+	now      func() time.Time
+
+	mu        sync.Mutex
+	token     *Token
+	refreshAt time.Time
+}
+
+// NewRefreshingTokenProvider wraps a provider with caching and early refresh
+func NewRefreshingTokenProvider(provider TokenProvider) *RefreshingTokenProvider {
+	return &RefreshingTokenProvider{
+		provider: provider,
+		now:      time.Now,
+	}
+}
+
+// Token returns the cached token, refreshing it when it is close to expiry
+func (p *RefreshingTokenProvider) Token(ctx context.Context) (*Token, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	if p.token != nil && (p.token.Expiry.IsZero() || now.Before(p.refreshAt)) {
+		return p.token, nil
+	}
+
+	token, err := p.provider.Token(ctx)
+	if err != nil {
+		// Keep using a token that is still valid if the refresh failed
+		if p.token != nil && now.Before(p.token.Expiry) {
+			return p.token, nil
+		}
+		return nil, err
+	}
+
+	p.token = token
+	if !token.Expiry.IsZero() {
+		p.refreshAt = now.Add(token.Expiry.Sub(now) * 4 / 5)
+	}
+	return token, nil
+}
+
+// oauthBearerMechanism implements SASL/OAUTHBEARER (RFC 7628)
+type oauthBearerMechanism struct {
+	provider TokenProvider
+}
+
+// NewOAuthBearerMechanism creates an OAUTHBEARER mechanism backed by a token provider
+func NewOAuthBearerMechanism(provider TokenProvider) sasl.Mechanism {
+	return oauthBearerMechanism{provider: provider}
+}
+
+// Name returns the mechanism name
+func (m oauthBearerMechanism) Name() string {
+	return SASLTypeOAuthBearer
+}
+
+// Start fetches a token and sends the initial client response
+func (m oauthBearerMechanism) Start(ctx context.Context) (sasl.StateMachine, []byte, error) {
+	token, err := m.provider.Token(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to obtain OAuth token: %w", err)
+	}
+
+	return m, []byte("n,,\x01auth=Bearer " + token.Value + "\x01\x01"), nil
+}
+
+// Next handles the server response. Any challenge is an error report from the broker.
+func (m oauthBearerMechanism) Next(ctx context.Context, challenge []byte) (bool, []byte, error) {
+	if len(challenge) > 0 {
+		return false, nil, fmt.Errorf("OAUTHBEARER authentication failed: %s", challenge)
+	}
+	return true, nil, nil
+}
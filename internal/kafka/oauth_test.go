@@ -0,0 +1,168 @@
+package kafka
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cfk-dev/cfk/internal/config"
+)
+
+// newTokenServer starts a stand-in OIDC token endpoint issuing numbered tokens
+func newTokenServer(t *testing.T, expiresIn int) (*httptest.Server, *int32) {
+	t.Helper()
+
+	var issued int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "cfk" || pass != "s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error":"invalid_client"}`)
+			return
+		}
+		if r.Form.Get("grant_type") != "client_credentials" || r.Form.Get("scope") != "kafka.read kafka.write" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":"invalid_request"}`)
+			return
+		}
+
+		n := atomic.AddInt32(&issued, 1)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token":"token-%d","token_type":"Bearer","expires_in":%d}`, n, expiresIn)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, &issued
+}
+
+func TestClientCredentialsProvider(t *testing.T) {
+	srv, _ := newTokenServer(t, 3600)
+
+	provider := &ClientCredentialsProvider{
+		TokenURL:     srv.URL,
+		ClientID:     "cfk",
+		ClientSecret: "s3cret",
+		Scopes:       []string{"kafka.read", "kafka.write"},
+	}
+
+	token, err := provider.Token(context.Background())
+	if err != nil {
+		t.Fatalf("Token() error = %v", err)
+	}
+	if token.Value != "token-1" {
+		t.Errorf("token = %q, want token-1", token.Value)
+	}
+	if remaining := time.Until(token.Expiry); remaining < 59*time.Minute || remaining > time.Hour {
+		t.Errorf("token expires in %v, want about 1h", remaining)
+	}
+
+	provider.ClientSecret = "wrong"
+	if _, err := provider.Token(context.Background()); err == nil || !strings.Contains(err.Error(), "invalid_client") {
+		t.Errorf("Token() with bad secret error = %v, want invalid_client", err)
+	}
+}
+
+func TestRefreshingTokenProviderRefreshesBeforeExpiry(t *testing.T) {
+	srv, issued := newTokenServer(t, 100)
+
+	provider := NewRefreshingTokenProvider(&ClientCredentialsProvider{
+		TokenURL:     srv.URL,
+		ClientID:     "cfk",
+		ClientSecret: "s3cret",
+		Scopes:       []string{"kafka.read", "kafka.write"},
+	})
+
+	now := time.Now()
+	provider.now = func() time.Time { return now }
+
+	first, err := provider.Token(context.Background())
+	if err != nil {
+		t.Fatalf("Token() error = %v", err)
+	}
+
+	// Still well within the lifetime: served from the cache
+	now = now.Add(50 * time.Second)
+	if token, _ := provider.Token(context.Background()); token.Value != first.Value {
+		t.Errorf("token = %q before refresh point, want cached %q", token.Value, first.Value)
+	}
+
+	// Past 80% of the lifetime but before expiry: refreshed
+	now = now.Add(35 * time.Second)
+	token, err := provider.Token(context.Background())
+	if err != nil {
+		t.Fatalf("Token() error = %v", err)
+	}
+	if token.Value == first.Value {
+		t.Errorf("token was not refreshed before expiry")
+	}
+	if got := atomic.LoadInt32(issued); got != 2 {
+		t.Errorf("token endpoint called %d times, want 2", got)
+	}
+}
+
+func TestStaticTokenProviderReadsJWTExpiry(t *testing.T) {
+	exp := time.Now().Add(time.Hour).Unix()
+	payload := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"sub":"cfk","exp":%d}`, exp)))
+	jwt := "eyJhbGciOiJub25lIn0." + payload + ".sig"
+
+	token, err := StaticTokenProvider{Value: jwt}.Token(context.Background())
+	if err != nil {
+		t.Fatalf("Token() error = %v", err)
+	}
+	if token.Expiry.Unix() != exp {
+		t.Errorf("expiry = %v, want %v", token.Expiry.Unix(), exp)
+	}
+}
+
+func TestCommandTokenProvider(t *testing.T) {
+	token, err := CommandTokenProvider{Command: `echo '{"access_token":"from-cmd","expires_in":60}'`}.Token(context.Background())
+	if err != nil {
+		t.Skipf("shell not available: %v", err)
+	}
+	if token.Value != "from-cmd" {
+		t.Errorf("token = %q, want from-cmd", token.Value)
+	}
+	if token.Expiry.IsZero() {
+		t.Errorf("expiry was not set from expires_in")
+	}
+}
+
+func TestOAuthBearerMechanismInitialResponse(t *testing.T) {
+	mechanism, err := NewSASLMechanism(config.KafkaClusterConfig{
+		SASL:          true,
+		SASLType:      "oauthbearer",
+		OAuthProvider: OAuthProviderStatic,
+		OAuthToken:    "abc",
+	})
+	if err != nil {
+		t.Fatalf("NewSASLMechanism() error = %v", err)
+	}
+	if mechanism.Name() != SASLTypeOAuthBearer {
+		t.Errorf("Name() = %q, want %q", mechanism.Name(), SASLTypeOAuthBearer)
+	}
+
+	state, ir, err := mechanism.Start(context.Background())
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if want := "n,,\x01auth=Bearer abc\x01\x01"; string(ir) != want {
+		t.Errorf("initial response = %q, want %q", ir, want)
+	}
+
+	if done, _, err := state.Next(context.Background(), nil); !done || err != nil {
+		t.Errorf("Next(nil) = %v, %v; want done", done, err)
+	}
+	if _, _, err := state.Next(context.Background(), []byte(`{"status":"invalid_token"}`)); err == nil {
+		t.Errorf("Next(error challenge) did not fail")
+	}
+}
@@ -0,0 +1,163 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Acknowledgement levels of produce requests
+const (
+	AcksAll    = "all"    // wait for all in-sync replicas
+	AcksLeader = "leader" // wait for the partition leader only
+	AcksNone   = "none"   // do not wait, offsets are not known
+)
+
+// AcksLevels lists the acknowledgement levels, the default first
+var AcksLevels = []string{AcksAll, AcksLeader, AcksNone}
+
+// CompressionCodecs lists the compression codecs of produced batches, the
+// default first
+var CompressionCodecs = []string{"none", "gzip", "snappy", "lz4", "zstd"}
+
+// ProduceOptions control how records are written
+type ProduceOptions struct {
+	Acks        string // one of AcksLevels, AcksAll when empty
+	Compression string // one of CompressionCodecs, none when empty
+}
+
+// requiredAcks converts the acknowledgement level
+func (o ProduceOptions) requiredAcks() (kafka.RequiredAcks, error) {
+	switch o.Acks {
+	case "", AcksAll:
+		return kafka.RequireAll, nil
+	case AcksLeader:
+		return kafka.RequireOne, nil
+	case AcksNone:
+		return kafka.RequireNone, nil
+	}
+	return 0, fmt.Errorf("invalid acks %q, use all, leader or none", o.Acks)
+}
+
+// compression converts the compression codec
+func (o ProduceOptions) compression() (kafka.Compression, error) {
+	if o.Compression == "" {
+		return 0, nil
+	}
+	var codec kafka.Compression
+	if err := codec.UnmarshalText([]byte(o.Compression)); err != nil {
+		return 0, fmt.Errorf("invalid compression %q, use none, gzip, snappy, lz4 or zstd", o.Compression)
+	}
+	return codec, nil
+}
+
+// Validate checks the acknowledgement level and the compression codec
+func (o ProduceOptions) Validate() error {
+	if _, err := o.requiredAcks(); err != nil {
+		return err
+	}
+	_, err := o.compression()
+	return err
+}
+
+// ProduceResult is the outcome of writing one record
+type ProduceResult struct {
+	Message Message // with the partition and offset the broker assigned
+	Err     error
+}
+
+// ProduceMessages writes records to a topic with one request per partition,
+// sent in parallel. A negative partition picks one from the key. The results
+// are in the order of the records; offsets are -1 when not acknowledged.
+func (c *Client) ProduceMessages(ctx context.Context, topicName string, messages []Message, opts ProduceOptions) ([]ProduceResult, error) {
+	if c.admin == nil {
+		return nil, fmt.Errorf("not connected to Kafka")
+	}
+	acks, err := opts.requiredAcks()
+	if err != nil {
+		return nil, err
+	}
+	codec, err := opts.compression()
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]ProduceResult, len(messages))
+	byPartition := make(map[int][]int) // record indexes by partition
+	var partitions int
+	for i, msg := range messages {
+		if msg.Partition < 0 {
+			if partitions == 0 {
+				topic, err := c.topicMetadata(ctx, topicName)
+				if err != nil {
+					return nil, err
+				}
+				partitions = len(topic.Partitions)
+			}
+			msg.Partition = PartitionForKey(msg.Key, partitions)
+		}
+		if msg.Timestamp.IsZero() {
+			msg.Timestamp = time.Now()
+		}
+		msg.Topic = topicName
+		msg.Offset = -1
+		results[i].Message = msg
+		byPartition[msg.Partition] = append(byPartition[msg.Partition], i)
+	}
+
+	var wg sync.WaitGroup
+	for partition, indexes := range byPartition {
+		wg.Add(1)
+		go func(partition int, indexes []int) {
+			defer wg.Done()
+
+			records := make([]kafka.Record, len(indexes))
+			for j, i := range indexes {
+				records[j] = produceRecord(results[i].Message)
+			}
+			resp, err := c.admin.Produce(ctx, &kafka.ProduceRequest{
+				Topic:        topicName,
+				Partition:    partition,
+				RequiredAcks: acks,
+				Compression:  codec,
+				Records:      kafka.NewRecordReader(records...),
+			})
+			if err == nil && resp != nil {
+				err = resp.Error
+			}
+			for j, i := range indexes {
+				switch {
+				case err != nil:
+					results[i].Err = fmt.Errorf("failed to produce to %s/%d: %w", topicName, partition, err)
+				case resp == nil:
+					// Not acknowledged, the offset stays unknown
+				case resp.RecordErrors[j] != nil:
+					results[i].Err = fmt.Errorf("failed to produce to %s/%d: %w", topicName, partition, resp.RecordErrors[j])
+				default:
+					results[i].Message.Offset = resp.BaseOffset + int64(j)
+				}
+			}
+		}(partition, indexes)
+	}
+	wg.Wait()
+
+	return results, nil
+}
+
+// produceRecord converts a message into a record of a produce request
+func produceRecord(msg Message) kafka.Record {
+	record := kafka.Record{Time: msg.Timestamp}
+	if msg.Key != nil {
+		record.Key = kafka.NewBytes(msg.Key)
+	}
+	if msg.Value != nil {
+		record.Value = kafka.NewBytes(msg.Value)
+	}
+	for _, h := range msg.Headers {
+		record.Headers = append(record.Headers, kafka.Header{Key: h.Key, Value: h.Value})
+	}
+	return record
+}
@@ -0,0 +1,58 @@
+package kafka
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cfk-dev/cfk/internal/config"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+)
+
+// SASL mechanism names accepted in KafkaClusterConfig.SASLType
+const (
+	SASLTypePlain       = "PLAIN"
+	SASLTypeScramSHA256 = "SCRAM-SHA-256"
+	SASLTypeScramSHA512 = "SCRAM-SHA-512"
+)
+
+// SASLTypes lists the supported SASL mechanisms in display order
+var SASLTypes = []string{SASLTypePlain, SASLTypeScramSHA256, SASLTypeScramSHA512, SASLTypeOAuthBearer}
+
+// NewSASLMechanism builds the SASL mechanism configured for a cluster.
+// It returns nil when SASL is disabled for the cluster.
+func NewSASLMechanism(clusterConfig config.KafkaClusterConfig) (sasl.Mechanism, error) {
+	if !clusterConfig.SASL {
+		return nil, nil
+	}
+
+	switch strings.ToUpper(clusterConfig.SASLType) {
+	case "", SASLTypePlain:
+		// PLAIN is the default when no mechanism is configured
+		return plain.Mechanism{
+			Username: clusterConfig.Username,
+			Password: clusterConfig.Password,
+		}, nil
+	case SASLTypeScramSHA256:
+		mechanism, err := scram.Mechanism(scram.SHA256, clusterConfig.Username, clusterConfig.Password)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set up SCRAM-SHA-256: %w", err)
+		}
+		return mechanism, nil
+	case SASLTypeScramSHA512:
+		mechanism, err := scram.Mechanism(scram.SHA512, clusterConfig.Username, clusterConfig.Password)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set up SCRAM-SHA-512: %w", err)
+		}
+		return mechanism, nil
+	case SASLTypeOAuthBearer:
+		provider, err := NewTokenProvider(clusterConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set up OAUTHBEARER: %w", err)
+		}
+		return NewOAuthBearerMechanism(provider), nil
+	default:
+		return nil, fmt.Errorf("unsupported SASL mechanism %q", clusterConfig.SASLType)
+	}
+}
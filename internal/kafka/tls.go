@@ -0,0 +1,60 @@
+package kafka
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"github.com/cfk-dev/cfk/internal/config"
+)
+
+// NewTLSConfig builds the TLS configuration for a cluster.
+// It returns nil when SSL is disabled for the cluster.
+func NewTLSConfig(clusterConfig config.KafkaClusterConfig) (*tls.Config, error) {
+	if !clusterConfig.SSL {
+		return nil, nil
+	}
+
+	return LoadTLSConfig(clusterConfig.SSLCAFile, clusterConfig.SSLCertFile, clusterConfig.SSLKeyFile,
+		clusterConfig.SSLServerName, clusterConfig.SSLInsecureSkipVerify)
+}
+
+// LoadTLSConfig builds a TLS configuration from an optional CA bundle and an
+// optional client certificate and key, all PEM files
+func LoadTLSConfig(caFile, certFile, keyFile, serverName string, insecureSkipVerify bool) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         serverName,
+		InsecureSkipVerify: insecureSkipVerify,
+	}
+
+	// Use the configured CA bundle instead of the system roots
+	if caFile != "" {
+		caPEM, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", caFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	// Client certificate and key are only meaningful together
+	if certFile != "" || keyFile != "" {
+		if certFile == "" || keyFile == "" {
+			return nil, fmt.Errorf("both client certificate and key files are required for mTLS")
+		}
+
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
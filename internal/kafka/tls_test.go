@@ -0,0 +1,145 @@
+package kafka
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cfk-dev/cfk/internal/config"
+)
+
+// writePEM writes a PEM block to a file of a temporary directory
+func writePEM(t *testing.T, dir, name, blockType string, der []byte) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// newTestCertificates writes a self-signed CA and a client certificate and
+// key it signed, returning their files
+func newTestCertificates(t *testing.T) (caFile, certFile, keyFile string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "cfk test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clientKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "cfk"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	clientDER, err := x509.CreateCertificate(rand.Reader, clientTemplate, caTemplate, &clientKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(clientKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	caFile = writePEM(t, dir, "ca.pem", "CERTIFICATE", caDER)
+	certFile = writePEM(t, dir, "client.pem", "CERTIFICATE", clientDER)
+	keyFile = writePEM(t, dir, "client.key", "EC PRIVATE KEY", keyDER)
+	return caFile, certFile, keyFile
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	caFile, certFile, keyFile := newTestCertificates(t)
+
+	tlsConfig, err := NewTLSConfig(config.KafkaClusterConfig{
+		SSL:           true,
+		SSLCAFile:     caFile,
+		SSLCertFile:   certFile,
+		SSLKeyFile:    keyFile,
+		SSLServerName: "kafka.example.com",
+	})
+	if err != nil {
+		t.Fatalf("NewTLSConfig() error = %v", err)
+	}
+	if tlsConfig.MinVersion != tls.VersionTLS12 || tlsConfig.ServerName != "kafka.example.com" {
+		t.Errorf("NewTLSConfig() = MinVersion %x, ServerName %q", tlsConfig.MinVersion, tlsConfig.ServerName)
+	}
+
+	// The client certificate chains to the CA bundle
+	if len(tlsConfig.Certificates) != 1 {
+		t.Fatalf("NewTLSConfig() has %d client certificates, want 1", len(tlsConfig.Certificates))
+	}
+	leaf, err := x509.ParseCertificate(tlsConfig.Certificates[0].Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := leaf.Verify(x509.VerifyOptions{
+		Roots:     tlsConfig.RootCAs,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}); err != nil {
+		t.Errorf("client certificate does not verify against the CA bundle: %v", err)
+	}
+}
+
+func TestNewTLSConfigDisabled(t *testing.T) {
+	tlsConfig, err := NewTLSConfig(config.KafkaClusterConfig{SSLCAFile: "/nonexistent"})
+	if err != nil || tlsConfig != nil {
+		t.Errorf("NewTLSConfig() without SSL = %v, %v, want nil, nil", tlsConfig, err)
+	}
+}
+
+func TestLoadTLSConfigErrors(t *testing.T) {
+	caFile, certFile, keyFile := newTestCertificates(t)
+
+	tests := []struct {
+		name            string
+		ca, cert, key   string
+		wantErrContains string
+	}{
+		{"key without cert", "", "", keyFile, "both client certificate and key"},
+		{"cert without key", "", certFile, "", "both client certificate and key"},
+		{"missing CA file", filepath.Join(t.TempDir(), "ca.pem"), "", "", "failed to read CA file"},
+		{"CA file without certificates", keyFile, "", "", "no certificates found"},
+		{"mismatched key", caFile, caFile, keyFile, "failed to load client certificate"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadTLSConfig(tt.ca, tt.cert, tt.key, "", false)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErrContains) {
+				t.Errorf("LoadTLSConfig() error = %v, want one containing %q", err, tt.wantErrContains)
+			}
+		})
+	}
+}
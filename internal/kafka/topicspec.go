@@ -0,0 +1,118 @@
+package kafka
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// maxTopicNameLength is the longest topic name Kafka accepts
+const maxTopicNameLength = 249
+
+// legalTopicName matches the characters Kafka allows in topic names
+var legalTopicName = regexp.MustCompile(`^[a-zA-Z0-9._-]+$`)
+
+// TopicSpec describes a topic to create
+type TopicSpec struct {
+	Name              string
+	Partitions        int
+	ReplicationFactor int
+	ReplicaAssignment [][]int // broker IDs of each partition, leader first; overrides Partitions and ReplicationFactor
+	Configs           map[string]string
+}
+
+// PartitionCount returns the number of partitions the topic will have
+func (s TopicSpec) PartitionCount() int {
+	if len(s.ReplicaAssignment) > 0 {
+		return len(s.ReplicaAssignment)
+	}
+	return s.Partitions
+}
+
+// Validate checks the name, layout and configs of the topic
+func (s TopicSpec) Validate() error {
+	if err := ValidateTopicName(s.Name); err != nil {
+		return err
+	}
+
+	if len(s.ReplicaAssignment) == 0 {
+		if s.Partitions < 1 {
+			return fmt.Errorf("number of partitions must be at least 1")
+		}
+		if s.ReplicationFactor < 1 {
+			return fmt.Errorf("replication factor must be at least 1")
+		}
+	}
+	for p, replicas := range s.ReplicaAssignment {
+		if len(replicas) == 0 {
+			return fmt.Errorf("partition %d has no replicas", p)
+		}
+		if len(replicas) != len(s.ReplicaAssignment[0]) {
+			return fmt.Errorf("partition %d has %d replicas, partition 0 has %d", p, len(replicas), len(s.ReplicaAssignment[0]))
+		}
+		seen := make(map[int]bool, len(replicas))
+		for _, id := range replicas {
+			if id < 0 {
+				return fmt.Errorf("partition %d has an invalid broker ID %d", p, id)
+			}
+			if seen[id] {
+				return fmt.Errorf("partition %d has broker %d more than once", p, id)
+			}
+			seen[id] = true
+		}
+	}
+
+	for _, config := range SetConfigs(s.Configs) {
+		if err := ValidateTopicConfig(config.Name, config.Value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ValidateTopicName checks a name against Kafka's topic naming rules
+func ValidateTopicName(name string) error {
+	switch {
+	case name == "":
+		return fmt.Errorf("topic name is empty")
+	case name == "." || name == "..":
+		return fmt.Errorf("topic name cannot be %q", name)
+	case len(name) > maxTopicNameLength:
+		return fmt.Errorf("topic name is longer than %d characters", maxTopicNameLength)
+	case !legalTopicName.MatchString(name):
+		return fmt.Errorf("topic name %q may only contain ASCII letters, digits, '.', '_' and '-'", name)
+	}
+	return nil
+}
+
+// TopicNamesCollide reports whether two different topic names only differ in
+// '.' and '_'. Kafka refuses such pairs because their metric names clash.
+func TopicNamesCollide(a, b string) bool {
+	return a != b && strings.ReplaceAll(a, ".", "_") == strings.ReplaceAll(b, ".", "_")
+}
+
+// ParseReplicaAssignment parses a replica assignment such as "0:1,1:2,2:0",
+// with the broker IDs of each partition separated by ':' and the partitions
+// by ','
+func ParseReplicaAssignment(s string) ([][]int, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+
+	var assignment [][]int
+	for p, part := range strings.Split(s, ",") {
+		var replicas []int
+		for _, field := range strings.Split(part, ":") {
+			id, err := strconv.Atoi(strings.TrimSpace(field))
+			if err != nil {
+				return nil, fmt.Errorf("invalid broker ID %q for partition %d", strings.TrimSpace(field), p)
+			}
+			replicas = append(replicas, id)
+		}
+		assignment = append(assignment, replicas)
+	}
+	return assignment, nil
+}
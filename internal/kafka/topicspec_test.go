@@ -0,0 +1,55 @@
+package kafka
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTopicSpecValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		spec  TopicSpec
+		valid bool
+	}{
+		{"plain", TopicSpec{Name: "orders.v1_eu-west", Partitions: 3, ReplicationFactor: 2}, true},
+		{"empty name", TopicSpec{Partitions: 1, ReplicationFactor: 1}, false},
+		{"dot", TopicSpec{Name: ".", Partitions: 1, ReplicationFactor: 1}, false},
+		{"illegal character", TopicSpec{Name: "orders/v1", Partitions: 1, ReplicationFactor: 1}, false},
+		{"too long", TopicSpec{Name: strings.Repeat("a", 250), Partitions: 1, ReplicationFactor: 1}, false},
+		{"no partitions", TopicSpec{Name: "orders", ReplicationFactor: 1}, false},
+		{"assignment", TopicSpec{Name: "orders", ReplicaAssignment: [][]int{{0, 1}, {1, 2}}}, true},
+		{"uneven assignment", TopicSpec{Name: "orders", ReplicaAssignment: [][]int{{0, 1}, {1}}}, false},
+		{"repeated broker", TopicSpec{Name: "orders", ReplicaAssignment: [][]int{{1, 1}}}, false},
+		{"valid config", TopicSpec{Name: "orders", Partitions: 1, ReplicationFactor: 1, Configs: map[string]string{"cleanup.policy": "compact"}}, true},
+		{"invalid config", TopicSpec{Name: "orders", Partitions: 1, ReplicationFactor: 1, Configs: map[string]string{"retention.ms": "1d"}}, false},
+	}
+
+	for _, tt := range tests {
+		if err := tt.spec.Validate(); (err == nil) != tt.valid {
+			t.Errorf("%s: Validate() error = %v, want valid %v", tt.name, err, tt.valid)
+		}
+	}
+}
+
+func TestTopicNamesCollide(t *testing.T) {
+	if !TopicNamesCollide("orders.eu", "orders_eu") {
+		t.Errorf("orders.eu and orders_eu do not collide")
+	}
+	if TopicNamesCollide("orders.eu", "orders.eu") || TopicNamesCollide("orders.eu", "orders-eu") {
+		t.Errorf("names collide that Kafka accepts side by side")
+	}
+}
+
+func TestParseReplicaAssignment(t *testing.T) {
+	got, err := ParseReplicaAssignment("0:1, 1:2 ,2:0")
+	if err != nil {
+		t.Fatalf("ParseReplicaAssignment() error = %v", err)
+	}
+	if want := [][]int{{0, 1}, {1, 2}, {2, 0}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseReplicaAssignment() = %v, want %v", got, want)
+	}
+	if _, err := ParseReplicaAssignment("0:1,x"); err == nil {
+		t.Errorf("ParseReplicaAssignment() of a non-numeric broker did not fail")
+	}
+}
@@ -0,0 +1,213 @@
+// Package schemaregistry is a client of the Confluent Schema Registry REST API
+// and decodes records serialized with its schemas
+package schemaregistry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cfk-dev/cfk/internal/config"
+	"github.com/cfk-dev/cfk/internal/kafka"
+)
+
+// requestTimeout bounds every request to the registry
+const requestTimeout = 10 * time.Second
+
+// Schema types
+const (
+	TypeAvro     = "AVRO"
+	TypeProtobuf = "PROTOBUF"
+	TypeJSON     = "JSON"
+)
+
+// Reference names a schema imported by another schema
+type Reference struct {
+	Name    string `json:"name"`
+	Subject string `json:"subject"`
+	Version int    `json:"version"`
+}
+
+// Schema is a registered schema
+type Schema struct {
+	ID         int         `json:"id,omitempty"`
+	Subject    string      `json:"subject,omitempty"`
+	Version    int         `json:"version,omitempty"`
+	Type       string      `json:"schemaType,omitempty"` // empty for Avro
+	Schema     string      `json:"schema"`
+	References []Reference `json:"references,omitempty"`
+}
+
+// SchemaType returns the type of the schema, AVRO when the registry left it out
+func (s *Schema) SchemaType() string {
+	if s.Type == "" {
+		return TypeAvro
+	}
+	return s.Type
+}
+
+// Error is an error response of the registry
+type Error struct {
+	StatusCode int    `json:"-"`
+	Code       int    `json:"error_code"`
+	Message    string `json:"message"`
+}
+
+// Error describes the error
+func (e *Error) Error() string {
+	return fmt.Sprintf("schema registry returned HTTP %d: %s (error code %d)", e.StatusCode, e.Message, e.Code)
+}
+
+// Client talks to the Schema Registry of a cluster. Schemas never change
+// once registered, so they are cached by ID and by subject version.
+type Client struct {
+	URL        string
+	Username   string
+	Password   string
+	HTTPClient *http.Client
+
+	mu       sync.Mutex
+	byID     map[int]*Schema
+	versions map[string]*Schema // by subject/version
+	decoders map[int]decoder
+}
+
+// NewClient creates the registry client of a cluster.
+// It returns nil when the cluster has no registry configured.
+func NewClient(clusterConfig config.KafkaClusterConfig) (*Client, error) {
+	if clusterConfig.SchemaRegistryURL == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(clusterConfig.SchemaRegistryURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid schema registry URL %q", clusterConfig.SchemaRegistryURL)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if u.Scheme == "https" {
+		tlsConfig, err := kafka.LoadTLSConfig(clusterConfig.SchemaRegistryCAFile, clusterConfig.SchemaRegistryCertFile,
+			clusterConfig.SchemaRegistryKeyFile, "", clusterConfig.SchemaRegistryInsecureSkipVerify)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schema registry TLS settings: %w", err)
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	return &Client{
+		URL:        clusterConfig.SchemaRegistryURL,
+		Username:   clusterConfig.SchemaRegistryUsername,
+		Password:   clusterConfig.SchemaRegistryPassword,
+		HTTPClient: &http.Client{Timeout: requestTimeout, Transport: transport},
+	}, nil
+}
+
+// SchemaByID gets a schema by its global ID
+func (c *Client) SchemaByID(ctx context.Context, id int) (*Schema, error) {
+	c.mu.Lock()
+	cached, ok := c.byID[id]
+	c.mu.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	var schema Schema
+	if err := c.do(ctx, http.MethodGet, "/schemas/ids/"+strconv.Itoa(id), nil, &schema); err != nil {
+		return nil, fmt.Errorf("failed to get schema %d: %w", id, err)
+	}
+	schema.ID = id
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.byID == nil {
+		c.byID = make(map[int]*Schema)
+	}
+	c.byID[id] = &schema
+	return &schema, nil
+}
+
+// SubjectVersion gets a version of the schema of a subject
+func (c *Client) SubjectVersion(ctx context.Context, subject string, version int) (*Schema, error) {
+	key := subject + "/" + strconv.Itoa(version)
+	c.mu.Lock()
+	cached, ok := c.versions[key]
+	c.mu.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	var schema Schema
+	path := subjectPath(subject) + "/versions/" + strconv.Itoa(version)
+	if err := c.do(ctx, http.MethodGet, path, nil, &schema); err != nil {
+		return nil, fmt.Errorf("failed to get version %d of subject %s: %w", version, subject, err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.versions == nil {
+		c.versions = make(map[string]*Schema)
+	}
+	c.versions[key] = &schema
+	return &schema, nil
+}
+
+// do sends a request with an optional JSON body and decodes the JSON
+// response into out, unless out is nil
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(c.URL, "/")+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.schemaregistry.v1+json, application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/vnd.schemaregistry.v1+json")
+	}
+	if c.Username != "" || c.Password != "" {
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: requestTimeout}
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 16<<20))
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		regErr := &Error{StatusCode: resp.StatusCode}
+		if json.Unmarshal(data, regErr) != nil || regErr.Message == "" {
+			regErr.Message = strings.TrimSpace(string(data))
+		}
+		return regErr
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("invalid response: %w", err)
+	}
+	return nil
+}
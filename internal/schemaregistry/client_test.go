@@ -0,0 +1,242 @@
+package schemaregistry
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cfk-dev/cfk/internal/config"
+	"github.com/cfk-dev/cfk/internal/schemaregistry/registrytest"
+	"github.com/cfk-dev/cfk/internal/serde"
+	"github.com/hamba/avro/v2"
+)
+
+// wire prefixes a payload with the magic byte and a schema ID
+func wire(id int, payload ...byte) []byte {
+	return append(binary.BigEndian.AppendUint32([]byte{magicByte}, uint32(id)), payload...)
+}
+
+// newTestClient returns a client of a registry
+func newTestClient(t *testing.T, srv *registrytest.Server) *Client {
+	t.Helper()
+	client, err := NewClient(config.KafkaClusterConfig{SchemaRegistryURL: srv.URL})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	return client
+}
+
+func TestDecodeAvro(t *testing.T) {
+	srv := registrytest.NewServer()
+	defer srv.Close()
+
+	address := `{"type":"record","name":"Address","namespace":"com.example","fields":[{"name":"city","type":"string"}]}`
+	user := `{"type":"record","name":"User","namespace":"com.example","fields":[
+		{"name":"name","type":"string"},{"name":"age","type":["null","int"]},{"name":"address","type":"com.example.Address"}]}`
+	srv.Register("address", "", address)
+	id := srv.Register("users-value", "AVRO", user, registrytest.Reference{Name: "com.example.Address", Subject: "address", Version: 1})
+
+	cache := &avro.SchemaCache{}
+	if _, err := avro.ParseWithCache(address, "", cache); err != nil {
+		t.Fatal(err)
+	}
+	schema, err := avro.ParseWithCache(user, "", cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := avro.Marshal(schema, map[string]interface{}{
+		"name": "ada", "age": 36, "address": map[string]interface{}{"city": "London"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := newTestClient(t, srv)
+	got, err := client.Decode(context.Background(), wire(id, payload...))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	for _, want := range []string{`"name": "ada"`, `"age": 36`, `"city": "London"`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("decoded record does not contain %s:\n%s", want, got)
+		}
+	}
+
+	// Schemas and parsed decoders are cached
+	requests := srv.Requests()
+	if _, err := client.Decode(context.Background(), wire(id, payload...)); err != nil || srv.Requests() != requests {
+		t.Errorf("second decode: err = %v, %d requests, want %d", err, srv.Requests(), requests)
+	}
+}
+
+func TestDecodeProtobufAndJSON(t *testing.T) {
+	srv := registrytest.NewServer()
+	defer srv.Close()
+
+	srv.Register("common", "PROTOBUF", `syntax = "proto3"; package common; message Money { int64 cents = 1; }`)
+	protoID := srv.Register("orders-value", "PROTOBUF", `syntax = "proto3";
+		import "common/money.proto";
+		import "google/protobuf/timestamp.proto";
+		message Header { string source = 1; }
+		message Order { string id = 1; common.Money total = 2; google.protobuf.Timestamp at = 3; }`,
+		registrytest.Reference{Name: "common/money.proto", Subject: "common", Version: 1})
+	jsonID := srv.Register("events-value", "JSON", `{"type":"object"}`)
+	client := newTestClient(t, srv)
+	ctx := context.Background()
+
+	// Message indexes [1] select Order; id "o-1", total 250 cents
+	order := []byte{0x02, 0x02, 0x0a, 0x03, 'o', '-', '1', 0x12, 0x03, 0x08, 0xfa, 0x01}
+	got, err := client.Decode(ctx, wire(protoID, order...))
+	if err != nil {
+		t.Fatalf("Decode(protobuf) error = %v", err)
+	}
+	if want := "{\n  \"id\": \"o-1\",\n  \"total\": {\n    \"cents\": \"250\"\n  }\n}"; got != want {
+		t.Errorf("Decode(protobuf) = %s, want %s", got, want)
+	}
+
+	// An empty index array selects the first message
+	if got, err := client.Decode(ctx, wire(protoID, 0x00, 0x0a, 0x01, 'x')); err != nil || !strings.Contains(got, `"source": "x"`) {
+		t.Errorf("Decode(first message) = %s, %v", got, err)
+	}
+
+	if got, err := client.Decode(ctx, wire(jsonID, []byte(`{"a":[1]}`)...)); err != nil || got != "{\n  \"a\": [\n    1\n  ]\n}" {
+		t.Errorf("Decode(json) = %s, %v", got, err)
+	}
+
+	var regErr *Error
+	if _, err := client.Decode(ctx, wire(99, 0x00)); !errors.As(err, &regErr) || regErr.Code != 40403 {
+		t.Errorf("Decode(unknown schema) error = %v, want error code 40403", err)
+	}
+	if _, err := client.Decode(ctx, []byte("plain")); err == nil {
+		t.Error("expected an error decoding bytes not in the wire format")
+	}
+}
+
+func TestReadMessageIndexesMalformed(t *testing.T) {
+	tests := map[string][]byte{
+		"empty":              nil,
+		"negative count":     binary.AppendVarint(nil, -1),
+		"count past payload": binary.AppendVarint(nil, 1<<40),
+		"truncated indexes":  append(binary.AppendVarint(nil, 3), 0x02, 0x04),
+	}
+	for name, payload := range tests {
+		if _, _, err := readMessageIndexes(payload); err == nil {
+			t.Errorf("readMessageIndexes(%s) succeeded, want an error", name)
+		}
+	}
+
+	indexes, rest, err := readMessageIndexes([]byte{0x04, 0x02, 0x00, 0x0a})
+	if err != nil || len(indexes) != 2 || indexes[0] != 1 || indexes[1] != 0 || !bytes.Equal(rest, []byte{0x0a}) {
+		t.Errorf("readMessageIndexes() = %v, %v, %v, want [1 0], [10]", indexes, rest, err)
+	}
+}
+
+func TestClientAuthAndTLS(t *testing.T) {
+	srv := registrytest.NewTLSServer()
+	defer srv.Close()
+	srv.Username, srv.Password = "sr-user", "sr-secret"
+	id := srv.Register("events-value", "JSON", `{}`)
+
+	// Trust the self-signed certificate of the registry
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(caFile, caPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	cluster := config.KafkaClusterConfig{
+		SchemaRegistryURL:    srv.URL,
+		SchemaRegistryCAFile: caFile,
+	}
+
+	client, err := NewClient(cluster)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	var regErr *Error
+	if _, err := client.SchemaByID(context.Background(), id); !errors.As(err, &regErr) || regErr.StatusCode != 401 {
+		t.Errorf("SchemaByID() without credentials error = %v, want HTTP 401", err)
+	}
+
+	cluster.SchemaRegistryUsername, cluster.SchemaRegistryPassword = "sr-user", "sr-secret"
+	if client, err = NewClient(cluster); err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	if schema, err := client.SchemaByID(context.Background(), id); err != nil || schema.SchemaType() != TypeJSON {
+		t.Errorf("SchemaByID() = %+v, %v", schema, err)
+	}
+
+	if client, err := NewClient(config.KafkaClusterConfig{}); client != nil || err != nil {
+		t.Errorf("NewClient() without a registry = %v, %v, want nil", client, err)
+	}
+	if _, err := NewClient(config.KafkaClusterConfig{SchemaRegistryURL: "registry:8081"}); err == nil {
+		t.Error("expected an error for a URL without a scheme")
+	}
+}
+
+func TestDeserializerDetectsWireFormat(t *testing.T) {
+	srv := registrytest.NewServer()
+	defer srv.Close()
+	id := srv.Register("events-value", "JSON", `{}`)
+
+	var client *Client
+	serdes := serde.NewRegistry()
+	serdes.Register(NewDeserializer(func() *Client { return client }))
+
+	record := wire(id, []byte(`{"ok":true}`)...)
+	if got := serdes.Detect(record); got == Format {
+		t.Errorf("Detect() without a registry = %s", got)
+	}
+
+	client = newTestClient(t, srv)
+	text, used, err := serdes.Deserialize(serde.FormatAuto, record)
+	if err != nil || used != Format || text != "{\n  \"ok\": true\n}" {
+		t.Errorf("Deserialize(auto) = %q, %s, %v", text, used, err)
+	}
+}
+
+func TestAvroSerializer(t *testing.T) {
+	srv := registrytest.NewServer()
+	defer srv.Close()
+	srv.Register("orders-value", "AVRO", `{"type":"record","name":"Order","namespace":"com.example","fields":[
+		{"name":"id","type":"string"},{"name":"total","type":"long"},{"name":"note","type":["null","string"],"default":null},
+		{"name":"items","type":{"type":"array","items":"int"}},{"name":"source","type":"string","default":"web"}]}`)
+	id := srv.Register("orders-value", "AVRO", `{"type":"record","name":"Order","namespace":"com.example","fields":[
+		{"name":"id","type":"string"},{"name":"total","type":"long"},{"name":"note","type":["null","string"],"default":null},
+		{"name":"items","type":{"type":"array","items":"int"}},{"name":"source","type":"string","default":"web"}]}`)
+	srv.Register("events-value", "JSON", `{}`)
+	client := newTestClient(t, srv)
+	ctx := context.Background()
+
+	serialize, err := client.AvroSerializer(ctx, "orders-value")
+	if err != nil {
+		t.Fatalf("AvroSerializer() error = %v", err)
+	}
+	data, err := serialize(`{"id":"o-1","total":9007199254740993,"note":{"string":"gift"},"items":[1,2]}`)
+	if err != nil {
+		t.Fatalf("serialize() error = %v", err)
+	}
+	if !bytes.Equal(data[:5], wire(id)) {
+		t.Errorf("serialized record starts with %v, want schema %d of the latest version", data[:5], id)
+	}
+	got, err := client.Decode(ctx, data)
+	for _, want := range []string{`"total": 9007199254740993`, `"note": "gift"`, `"source": "web"`} {
+		if err != nil || !strings.Contains(got, want) {
+			t.Errorf("decoded record does not contain %s: %s, %v", want, got, err)
+		}
+	}
+
+	for _, doc := range []string{`{"id":"o-1","total":1.5,"items":[]}`, `{"id":"o-1","items":[]}`, `{"id":"o-1"} {}`} {
+		if _, err := serialize(doc); err == nil {
+			t.Errorf("serialize(%s) succeeded", doc)
+		}
+	}
+	if _, err := client.AvroSerializer(ctx, "events-value"); err == nil {
+		t.Error("expected an error serializing with a JSON schema")
+	}
+}
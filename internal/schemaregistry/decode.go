@@ -0,0 +1,284 @@
+package schemaregistry
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/bufbuild/protocompile"
+	"github.com/cfk-dev/cfk/internal/serde"
+	"github.com/hamba/avro/v2"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// Format decodes records in the Confluent wire format: a zero magic byte,
+// the big-endian schema ID, then the payload
+const Format serde.Format = "schema-registry"
+
+// magicByte starts every record in the wire format
+const magicByte = 0
+
+// Deserializer decodes records in the wire format with the schemas of the
+// registry of the connected cluster
+type Deserializer struct {
+	client func() *Client
+}
+
+// NewDeserializer creates a deserializer using the registry client returned
+// by client, which is nil while the cluster has no registry
+func NewDeserializer(client func() *Client) *Deserializer {
+	return &Deserializer{client: client}
+}
+
+// Format returns the format of the deserializer
+func (d *Deserializer) Format() serde.Format {
+	return Format
+}
+
+// Detects reports whether data looks like the wire format and there is a
+// registry to decode it with
+func (d *Deserializer) Detects(data []byte) bool {
+	return len(data) > 5 && data[0] == magicByte && d.client() != nil
+}
+
+// Deserialize decodes a record as pretty-printed JSON
+func (d *Deserializer) Deserialize(data []byte) (string, error) {
+	client := d.client()
+	if client == nil {
+		return "", fmt.Errorf("no schema registry configured for the cluster")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	return client.Decode(ctx, data)
+}
+
+// decoder decodes the payloads written with one schema
+type decoder interface {
+	decode(payload []byte) (string, error)
+}
+
+// Decode decodes a record in the wire format as pretty-printed JSON
+func (c *Client) Decode(ctx context.Context, data []byte) (string, error) {
+	if len(data) < 5 || data[0] != magicByte {
+		return "", fmt.Errorf("not in the schema registry wire format")
+	}
+	id := int(binary.BigEndian.Uint32(data[1:5]))
+
+	dec, err := c.decoder(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	return dec.decode(data[5:])
+}
+
+// decoder gets the decoder of a schema, parsing the schema on first use
+func (c *Client) decoder(ctx context.Context, id int) (decoder, error) {
+	c.mu.Lock()
+	cached, ok := c.decoders[id]
+	c.mu.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	schema, err := c.SchemaByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var dec decoder
+	switch schema.SchemaType() {
+	case TypeAvro:
+		dec, err = c.newAvroDecoder(ctx, schema)
+	case TypeProtobuf:
+		dec, err = c.newProtobufDecoder(ctx, schema)
+	case TypeJSON:
+		dec = jsonDecoder{}
+	default:
+		err = fmt.Errorf("unsupported schema type %s", schema.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema %d: %w", id, err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.decoders == nil {
+		c.decoders = make(map[int]decoder)
+	}
+	c.decoders[id] = dec
+	return dec, nil
+}
+
+// avroDecoder decodes Avro binary payloads
+type avroDecoder struct {
+	schema avro.Schema
+}
+
+// newAvroDecoder creates the decoder of an Avro schema
+func (c *Client) newAvroDecoder(ctx context.Context, schema *Schema) (decoder, error) {
+	parsed, err := c.parseAvro(ctx, schema)
+	if err != nil {
+		return nil, err
+	}
+	return avroDecoder{schema: parsed}, nil
+}
+
+// parseAvro parses an Avro schema after the named types it references
+func (c *Client) parseAvro(ctx context.Context, schema *Schema) (avro.Schema, error) {
+	cache := &avro.SchemaCache{}
+	err := c.walkReferences(ctx, schema.References, map[string]bool{}, func(_ Reference, ref *Schema) error {
+		_, err := avro.ParseWithCache(ref.Schema, "", cache)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return avro.ParseWithCache(schema.Schema, "", cache)
+}
+
+// decode decodes a payload as JSON
+func (d avroDecoder) decode(payload []byte) (string, error) {
+	var v interface{}
+	if err := avro.Unmarshal(d.schema, payload, &v); err != nil {
+		return "", fmt.Errorf("invalid Avro payload: %w", err)
+	}
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// protobufDecoder decodes Protobuf payloads with the messages of a file
+type protobufDecoder struct {
+	file protoreflect.FileDescriptor
+}
+
+// newProtobufDecoder compiles a .proto schema with the files it imports
+func (c *Client) newProtobufDecoder(ctx context.Context, schema *Schema) (decoder, error) {
+	const main = "schema.proto"
+	files := map[string]string{main: schema.Schema}
+	err := c.walkReferences(ctx, schema.References, map[string]bool{}, func(ref Reference, imported *Schema) error {
+		files[ref.Name] = imported.Schema
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			Accessor: protocompile.SourceAccessorFromMap(files),
+		}),
+	}
+	compiled, err := compiler.Compile(ctx, main)
+	if err != nil {
+		return nil, err
+	}
+	return protobufDecoder{file: compiled[0]}, nil
+}
+
+// decode decodes a payload, prefixed with the path to its message type, as JSON
+func (d protobufDecoder) decode(payload []byte) (string, error) {
+	indexes, payload, err := readMessageIndexes(payload)
+	if err != nil {
+		return "", err
+	}
+
+	messages := d.file.Messages()
+	var desc protoreflect.MessageDescriptor
+	for _, i := range indexes {
+		if i < 0 || i >= messages.Len() {
+			return "", fmt.Errorf("message index %d not found in the schema", i)
+		}
+		desc = messages.Get(i)
+		messages = desc.Messages()
+	}
+
+	msg := dynamicpb.NewMessage(desc)
+	if err := proto.Unmarshal(payload, msg); err != nil {
+		return "", fmt.Errorf("invalid Protobuf payload: %w", err)
+	}
+	compact, err := protojson.Marshal(msg)
+	if err != nil {
+		return "", err
+	}
+	// protojson output is deliberately unstable, indent it ourselves
+	var out bytes.Buffer
+	if err := json.Indent(&out, compact, "", "  "); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// readMessageIndexes reads the zigzag varint array locating the message type
+// of a Protobuf payload. An empty array stands for the first message.
+func readMessageIndexes(payload []byte) ([]int, []byte, error) {
+	count, n := binary.Varint(payload)
+	if n <= 0 || count < 0 {
+		return nil, nil, fmt.Errorf("invalid Protobuf message indexes")
+	}
+	payload = payload[n:]
+	// Every index takes at least a byte
+	if count > int64(len(payload)) {
+		return nil, nil, fmt.Errorf("invalid Protobuf message indexes: %d indexes in %d bytes", count, len(payload))
+	}
+	if count == 0 {
+		return []int{0}, payload, nil
+	}
+
+	indexes := make([]int, count)
+	for i := range indexes {
+		index, n := binary.Varint(payload)
+		if n <= 0 {
+			return nil, nil, fmt.Errorf("invalid Protobuf message indexes")
+		}
+		indexes[i] = int(index)
+		payload = payload[n:]
+	}
+	return indexes, payload, nil
+}
+
+// jsonDecoder decodes JSON Schema payloads, which are plain JSON
+type jsonDecoder struct{}
+
+// decode pretty-prints a payload
+func (jsonDecoder) decode(payload []byte) (string, error) {
+	var out bytes.Buffer
+	if err := json.Indent(&out, payload, "", "  "); err != nil {
+		return "", fmt.Errorf("invalid JSON payload: %w", err)
+	}
+	return out.String(), nil
+}
+
+// walkReferences visits the schemas referenced by a schema, depth first so
+// that every schema comes after the ones it references
+func (c *Client) walkReferences(ctx context.Context, refs []Reference, seen map[string]bool, visit func(Reference, *Schema) error) error {
+	for _, ref := range refs {
+		key := ref.Subject + "/" + strconv.Itoa(ref.Version)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		schema, err := c.SubjectVersion(ctx, ref.Subject, ref.Version)
+		if err != nil {
+			return err
+		}
+		if err := c.walkReferences(ctx, schema.References, seen, visit); err != nil {
+			return err
+		}
+		if err := visit(ref, schema); err != nil {
+			return fmt.Errorf("invalid referenced schema %s: %w", ref.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+package schemaregistry
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/hamba/avro/v2"
+)
+
+// AvroSerializer gets the latest Avro schema of a subject and returns a
+// function encoding JSON documents with it in the wire format. JSON values
+// follow the Avro JSON encoding: non-null union values may be wrapped in an
+// object keyed by the branch type, and bytes are strings.
+func (c *Client) AvroSerializer(ctx context.Context, subject string) (func(text string) ([]byte, error), error) {
+	schema, err := c.LatestVersion(ctx, subject)
+	if err != nil {
+		return nil, err
+	}
+	if schema.SchemaType() != TypeAvro {
+		return nil, fmt.Errorf("subject %s has a %s schema, only Avro can be produced", subject, schema.SchemaType())
+	}
+	parsed, err := c.parseAvro(ctx, schema)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema %d: %w", schema.ID, err)
+	}
+
+	prefix := binary.BigEndian.AppendUint32([]byte{magicByte}, uint32(schema.ID))
+	return func(text string) ([]byte, error) {
+		dec := json.NewDecoder(bytes.NewReader([]byte(text)))
+		dec.UseNumber()
+		var doc interface{}
+		if err := dec.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+		if dec.Decode(new(interface{})) != io.EOF {
+			return nil, fmt.Errorf("invalid JSON: more than one value")
+		}
+
+		native, err := avroNative(parsed, doc)
+		if err != nil {
+			return nil, fmt.Errorf("does not match schema %d of subject %s: %w", schema.ID, subject, err)
+		}
+		payload, err := avro.Marshal(parsed, native)
+		if err != nil {
+			return nil, fmt.Errorf("does not match schema %d of subject %s: %w", schema.ID, subject, err)
+		}
+		return append(append([]byte(nil), prefix...), payload...), nil
+	}, nil
+}
+
+// avroNative converts a decoded JSON value into the Go value the Avro
+// encoder expects for a schema
+func avroNative(schema avro.Schema, v interface{}) (interface{}, error) {
+	switch s := schema.(type) {
+	case *avro.RefSchema:
+		return avroNative(s.Schema(), v)
+	case *avro.RecordSchema:
+		fields, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s: expected an object, got %s", s.FullName(), jsonKind(v))
+		}
+		record := make(map[string]interface{}, len(s.Fields()))
+		for _, f := range s.Fields() {
+			value, ok := fields[f.Name()]
+			if !ok {
+				if !f.HasDefault() {
+					return nil, fmt.Errorf("%s: missing field %s", s.FullName(), f.Name())
+				}
+				record[f.Name()] = f.Default()
+				continue
+			}
+			native, err := avroNative(f.Type(), value)
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %w", f.Name(), err)
+			}
+			record[f.Name()] = native
+		}
+		return record, nil
+	case *avro.ArraySchema:
+		items, ok := v.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected an array, got %s", jsonKind(v))
+		}
+		native := make([]interface{}, len(items))
+		for i, item := range items {
+			var err error
+			if native[i], err = avroNative(s.Items(), item); err != nil {
+				return nil, fmt.Errorf("[%d]: %w", i, err)
+			}
+		}
+		return native, nil
+	case *avro.MapSchema:
+		values, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected an object, got %s", jsonKind(v))
+		}
+		native := make(map[string]interface{}, len(values))
+		for k, value := range values {
+			var err error
+			if native[k], err = avroNative(s.Values(), value); err != nil {
+				return nil, fmt.Errorf("[%s]: %w", k, err)
+			}
+		}
+		return native, nil
+	case *avro.UnionSchema:
+		return avroUnion(s, v)
+	}
+
+	switch schema.Type() {
+	case avro.Int, avro.Long, avro.Float, avro.Double:
+		n, ok := v.(json.Number)
+		if !ok {
+			return nil, fmt.Errorf("expected a number, got %s", jsonKind(v))
+		}
+		switch schema.Type() {
+		case avro.Int:
+			i, err := n.Int64()
+			if err != nil || int64(int32(i)) != i {
+				return nil, fmt.Errorf("%s is not an int", n)
+			}
+			return int(i), nil
+		case avro.Long:
+			i, err := n.Int64()
+			if err != nil {
+				return nil, fmt.Errorf("%s is not a long", n)
+			}
+			return i, nil
+		case avro.Float:
+			f, err := n.Float64()
+			return float32(f), err
+		default:
+			return n.Float64()
+		}
+	case avro.Bytes, avro.Fixed:
+		text, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected a string of bytes, got %s", jsonKind(v))
+		}
+		return []byte(text), nil
+	}
+	return v, nil
+}
+
+// avroUnion converts a union value, given bare or wrapped in an object keyed
+// by the branch type, into the wrapped form the encoder resolves
+func avroUnion(union *avro.UnionSchema, v interface{}) (interface{}, error) {
+	if v == nil {
+		if union.Nullable() {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("null is not allowed")
+	}
+
+	if wrapped, ok := v.(map[string]interface{}); ok && len(wrapped) == 1 {
+		for name, value := range wrapped {
+			for _, branch := range union.Types() {
+				if unionBranchName(branch) == name {
+					native, err := avroNative(branch, value)
+					if err != nil {
+						return nil, err
+					}
+					return map[string]interface{}{name: native}, nil
+				}
+			}
+		}
+	}
+
+	// Take the first branch the bare value converts to
+	for _, branch := range union.Types() {
+		if branch.Type() == avro.Null {
+			continue
+		}
+		if native, err := avroNative(branch, v); err == nil {
+			if _, err := avro.Marshal(branch, native); err == nil {
+				return map[string]interface{}{unionBranchName(branch): native}, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%s matches no type of the union", jsonKind(v))
+}
+
+// unionBranchName names a union branch the way the Avro JSON encoding does
+func unionBranchName(schema avro.Schema) string {
+	if ref, ok := schema.(*avro.RefSchema); ok {
+		return ref.Schema().FullName()
+	}
+	if named, ok := schema.(avro.NamedSchema); ok {
+		return named.FullName()
+	}
+	return string(schema.Type())
+}
+
+// jsonKind describes a decoded JSON value for errors
+func jsonKind(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "a boolean"
+	case json.Number:
+		return "a number"
+	case string:
+		return "a string"
+	case []interface{}:
+		return "an array"
+	}
+	return "an object"
+}
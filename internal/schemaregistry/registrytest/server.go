@@ -0,0 +1,354 @@
+// Package registrytest provides an in-memory Schema Registry for tests. It
+// serves the subset of the REST API cfk uses.
+package registrytest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// Reference names a schema imported by another schema
+type Reference struct {
+	Name    string `json:"name"`
+	Subject string `json:"subject"`
+	Version int    `json:"version"`
+}
+
+// schema is a registered schema version
+type schema struct {
+	ID         int         `json:"id"`
+	Subject    string      `json:"subject,omitempty"`
+	Version    int         `json:"version,omitempty"`
+	Type       string      `json:"schemaType,omitempty"`
+	Schema     string      `json:"schema"`
+	References []Reference `json:"references,omitempty"`
+
+	deleted bool // soft-deleted
+	removed bool // permanently deleted
+}
+
+// Server is an in-memory Schema Registry
+type Server struct {
+	*httptest.Server
+
+	// Username and Password, when set, are required as basic auth
+	Username string
+	Password string
+
+	// Check tests a candidate schema against the latest version of a subject
+	// under a compatibility level and returns why it is incompatible. The
+	// default finds every schema compatible.
+	Check func(subject, level, candidate string) []string
+
+	mu            sync.Mutex
+	schemas       []*schema            // by ID - 1
+	subjects      map[string][]*schema // versions by subject, by version - 1
+	compatibility map[string]string    // by subject, "" for the global level
+	requests      int32
+}
+
+// NewServer starts a registry. Close it when done.
+func NewServer() *Server {
+	s := newServer()
+	s.Server = httptest.NewServer(s.handler())
+	return s
+}
+
+// NewTLSServer starts a registry serving HTTPS with a self-signed
+// certificate, trusted by the client of the embedded httptest server
+func NewTLSServer() *Server {
+	s := newServer()
+	s.Server = httptest.NewTLSServer(s.handler())
+	return s
+}
+
+// newServer creates an empty registry with the BACKWARD global level
+func newServer() *Server {
+	return &Server{
+		subjects:      make(map[string][]*schema),
+		compatibility: map[string]string{"": "BACKWARD"},
+	}
+}
+
+// handler routes the REST API
+func (s *Server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /schemas/ids/{id}", s.schemaByID)
+	mux.HandleFunc("GET /subjects", s.listSubjects)
+	mux.HandleFunc("GET /subjects/{subject}/versions", s.listVersions)
+	mux.HandleFunc("GET /subjects/{subject}/versions/{version}", s.subjectVersion)
+	mux.HandleFunc("DELETE /subjects/{subject}/versions/{version}", s.deleteVersion)
+	mux.HandleFunc("GET /config", s.getConfig)
+	mux.HandleFunc("PUT /config", s.putConfig)
+	mux.HandleFunc("GET /config/{subject}", s.getConfig)
+	mux.HandleFunc("PUT /config/{subject}", s.putConfig)
+	mux.HandleFunc("POST /compatibility/subjects/{subject}/versions/{version}", s.testCompatibility)
+	return s.authenticate(mux)
+}
+
+// Register adds a schema version to a subject and returns its ID. The type
+// is AVRO, PROTOBUF or JSON; Avro is the default when it is empty.
+func (s *Server) Register(subject, schemaType, text string, references ...Reference) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if schemaType == "AVRO" {
+		schemaType = ""
+	}
+	registered := &schema{
+		ID:         len(s.schemas) + 1,
+		Subject:    subject,
+		Version:    len(s.subjects[subject]) + 1,
+		Type:       schemaType,
+		Schema:     text,
+		References: references,
+	}
+	s.schemas = append(s.schemas, registered)
+	s.subjects[subject] = append(s.subjects[subject], registered)
+	return registered.ID
+}
+
+// Compatibility returns the compatibility level set for a subject, or the
+// global level for an empty subject
+func (s *Server) Compatibility(subject string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.compatibility[subject]
+}
+
+// Requests returns the number of requests served
+func (s *Server) Requests() int {
+	return int(atomic.LoadInt32(&s.requests))
+}
+
+// authenticate counts requests and checks the basic auth credentials
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&s.requests, 1)
+		if s.Username != "" || s.Password != "" {
+			user, password, ok := r.BasicAuth()
+			if !ok || user != s.Username || password != s.Password {
+				writeError(w, http.StatusUnauthorized, 401, "Unauthorized")
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// schemaByID serves GET /schemas/ids/{id}. Soft-deleted schemas stay readable.
+func (s *Server) schemaByID(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 || id > len(s.schemas) || s.schemas[id-1].removed {
+		writeError(w, http.StatusNotFound, 40403, "Schema "+r.PathValue("id")+" not found")
+		return
+	}
+	found := s.schemas[id-1]
+	writeJSON(w, schema{Type: found.Type, Schema: found.Schema, References: found.References})
+}
+
+// listSubjects serves GET /subjects
+func (s *Server) listSubjects(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subjects := []string{}
+	for subject := range s.subjects {
+		if len(s.live(subject)) > 0 {
+			subjects = append(subjects, subject)
+		}
+	}
+	sort.Strings(subjects)
+	writeJSON(w, subjects)
+}
+
+// listVersions serves GET /subjects/{subject}/versions, with the soft-deleted
+// versions when deleted=true
+func (s *Server) listVersions(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	listed := s.live(r.PathValue("subject"))
+	if r.URL.Query().Get("deleted") == "true" {
+		listed = nil
+		for _, v := range s.subjects[r.PathValue("subject")] {
+			if !v.removed {
+				listed = append(listed, v)
+			}
+		}
+	}
+	if len(listed) == 0 {
+		writeError(w, http.StatusNotFound, 40401, "Subject '"+r.PathValue("subject")+"' not found.")
+		return
+	}
+	versions := make([]int, len(listed))
+	for i, v := range listed {
+		versions[i] = v.Version
+	}
+	writeJSON(w, versions)
+}
+
+// subjectVersion serves GET /subjects/{subject}/versions/{version}
+func (s *Server) subjectVersion(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	found, ok := s.version(w, r)
+	if !ok {
+		return
+	}
+	if found.deleted {
+		writeError(w, http.StatusNotFound, 40402, "Version "+r.PathValue("version")+" not found.")
+		return
+	}
+	writeJSON(w, found)
+}
+
+// deleteVersion serves DELETE /subjects/{subject}/versions/{version}
+func (s *Server) deleteVersion(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	found, ok := s.version(w, r)
+	if !ok {
+		return
+	}
+	permanent := r.URL.Query().Get("permanent") == "true"
+	switch {
+	case permanent && !found.deleted:
+		writeError(w, http.StatusNotFound, 40407, "Subject '"+found.Subject+"' Version "+strconv.Itoa(found.Version)+
+			" was not deleted first before being permanently deleted")
+		return
+	case !permanent && found.deleted:
+		writeError(w, http.StatusNotFound, 40406, "Subject '"+found.Subject+"' Version "+strconv.Itoa(found.Version)+
+			" was soft deleted. Set permanent=true to delete permanently")
+		return
+	}
+	found.deleted = true
+	found.removed = permanent
+	writeJSON(w, found.Version)
+}
+
+// getConfig serves GET /config and GET /config/{subject}
+func (s *Server) getConfig(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	level, ok := s.compatibility[r.PathValue("subject")]
+	if !ok {
+		writeError(w, http.StatusNotFound, 40408, "Subject '"+r.PathValue("subject")+"' does not have subject-level compatibility configured")
+		return
+	}
+	writeJSON(w, map[string]string{"compatibilityLevel": level})
+}
+
+// putConfig serves PUT /config and PUT /config/{subject}
+func (s *Server) putConfig(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Compatibility string `json:"compatibility"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || !validLevel(req.Compatibility) {
+		writeError(w, http.StatusUnprocessableEntity, 42203, "Invalid compatibility level")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.compatibility[r.PathValue("subject")] = req.Compatibility
+	writeJSON(w, req)
+}
+
+// testCompatibility serves POST /compatibility/subjects/{subject}/versions/{version}
+func (s *Server) testCompatibility(w http.ResponseWriter, r *http.Request) {
+	var candidate schema
+	if err := json.NewDecoder(r.Body).Decode(&candidate); err != nil || candidate.Schema == "" {
+		writeError(w, http.StatusUnprocessableEntity, 42201, "Invalid schema")
+		return
+	}
+
+	s.mu.Lock()
+	subject := r.PathValue("subject")
+	if len(s.live(subject)) == 0 {
+		s.mu.Unlock()
+		writeError(w, http.StatusNotFound, 40401, "Subject '"+subject+"' not found.")
+		return
+	}
+	level, ok := s.compatibility[subject]
+	if !ok {
+		level = s.compatibility[""]
+	}
+	s.mu.Unlock()
+
+	var messages []string
+	if s.Check != nil && level != "NONE" {
+		messages = s.Check(subject, level, candidate.Schema)
+	}
+	writeJSON(w, map[string]interface{}{"is_compatible": len(messages) == 0, "messages": messages})
+}
+
+// version finds the version of a request, writing an error if there is none
+func (s *Server) version(w http.ResponseWriter, r *http.Request) (*schema, bool) {
+	subject := r.PathValue("subject")
+	versions := s.subjects[subject]
+	if len(versions) == 0 {
+		writeError(w, http.StatusNotFound, 40401, "Subject '"+subject+"' not found.")
+		return nil, false
+	}
+
+	v := r.PathValue("version")
+	if v == "latest" {
+		live := s.live(subject)
+		if len(live) == 0 {
+			writeError(w, http.StatusNotFound, 40402, "Version latest not found.")
+			return nil, false
+		}
+		return live[len(live)-1], true
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 || n > len(versions) || versions[n-1].removed {
+		writeError(w, http.StatusNotFound, 40402, "Version "+v+" not found.")
+		return nil, false
+	}
+	return versions[n-1], true
+}
+
+// live returns the versions of a subject that are not deleted
+func (s *Server) live(subject string) []*schema {
+	var live []*schema
+	for _, v := range s.subjects[subject] {
+		if !v.deleted {
+			live = append(live, v)
+		}
+	}
+	return live
+}
+
+// validLevel reports whether a compatibility level exists
+func validLevel(level string) bool {
+	switch level {
+	case "BACKWARD", "BACKWARD_TRANSITIVE", "FORWARD", "FORWARD_TRANSITIVE", "FULL", "FULL_TRANSITIVE", "NONE":
+		return true
+	}
+	return false
+}
+
+// writeJSON writes a JSON response
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/vnd.schemaregistry.v1+json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes a registry error response
+func writeError(w http.ResponseWriter, status, code int, message string) {
+	w.Header().Set("Content-Type", "application/vnd.schemaregistry.v1+json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{"error_code": code, "message": message})
+}
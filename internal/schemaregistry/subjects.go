@@ -0,0 +1,158 @@
+package schemaregistry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+)
+
+// CompatibilityLevels lists the compatibility levels of subjects
+var CompatibilityLevels = []string{
+	"BACKWARD", "BACKWARD_TRANSITIVE",
+	"FORWARD", "FORWARD_TRANSITIVE",
+	"FULL", "FULL_TRANSITIVE",
+	"NONE",
+}
+
+// Registry error codes
+const (
+	CodeSubjectNotFound                   = 40401
+	CodeVersionNotFound                   = 40402
+	CodeSchemaNotFound                    = 40403
+	CodeSubjectCompatibilityNotConfigured = 40408
+)
+
+// ErrorCode returns the registry error code of an error, or 0
+func ErrorCode(err error) int {
+	var regErr *Error
+	if errors.As(err, &regErr) {
+		return regErr.Code
+	}
+	return 0
+}
+
+// CompatibilityResult is the outcome of testing a schema against a subject
+type CompatibilityResult struct {
+	Compatible bool     `json:"is_compatible"`
+	Messages   []string `json:"messages,omitempty"` // why the schema is incompatible
+}
+
+// Subjects lists the subjects with at least one live version, sorted
+func (c *Client) Subjects(ctx context.Context) ([]string, error) {
+	var subjects []string
+	if err := c.do(ctx, http.MethodGet, "/subjects", nil, &subjects); err != nil {
+		return nil, fmt.Errorf("failed to list subjects: %w", err)
+	}
+	sort.Strings(subjects)
+	return subjects, nil
+}
+
+// SubjectVersion is a version of a subject
+type SubjectVersion struct {
+	Version int
+	Deleted bool // soft-deleted: hidden, but its schema ID stays readable
+}
+
+// Versions lists the versions of a subject, the soft-deleted ones included
+// and marked, oldest first
+func (c *Client) Versions(ctx context.Context, subject string) ([]SubjectVersion, error) {
+	var all, live []int
+	if err := c.do(ctx, http.MethodGet, subjectPath(subject)+"/versions?deleted=true", nil, &all); err != nil {
+		return nil, fmt.Errorf("failed to list versions of subject %s: %w", subject, err)
+	}
+	// A subject whose versions are all soft-deleted has no live ones
+	err := c.do(ctx, http.MethodGet, subjectPath(subject)+"/versions", nil, &live)
+	if err != nil && ErrorCode(err) != CodeSubjectNotFound {
+		return nil, fmt.Errorf("failed to list versions of subject %s: %w", subject, err)
+	}
+
+	isLive := make(map[int]bool, len(live))
+	for _, v := range live {
+		isLive[v] = true
+	}
+	sort.Ints(all)
+	versions := make([]SubjectVersion, len(all))
+	for i, v := range all {
+		versions[i] = SubjectVersion{Version: v, Deleted: !isLive[v]}
+	}
+	return versions, nil
+}
+
+// LatestVersion gets the newest live version of a subject. Unlike older
+// versions it can change, so it is not cached.
+func (c *Client) LatestVersion(ctx context.Context, subject string) (*Schema, error) {
+	var schema Schema
+	if err := c.do(ctx, http.MethodGet, subjectPath(subject)+"/versions/latest", nil, &schema); err != nil {
+		return nil, fmt.Errorf("failed to get the latest version of subject %s: %w", subject, err)
+	}
+	return &schema, nil
+}
+
+// Compatibility gets the compatibility level of a subject. Subjects without
+// their own level inherit the global one.
+func (c *Client) Compatibility(ctx context.Context, subject string) (level string, inherited bool, err error) {
+	var resp struct {
+		Level string `json:"compatibilityLevel"`
+	}
+	err = c.do(ctx, http.MethodGet, "/config/"+url.PathEscape(subject), nil, &resp)
+	if code := ErrorCode(err); code == CodeSubjectCompatibilityNotConfigured || code == CodeSubjectNotFound {
+		inherited = true
+		err = c.do(ctx, http.MethodGet, "/config", nil, &resp)
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to get the compatibility of subject %s: %w", subject, err)
+	}
+	return resp.Level, inherited, nil
+}
+
+// SetCompatibility sets the compatibility level of a subject
+func (c *Client) SetCompatibility(ctx context.Context, subject, level string) error {
+	body := map[string]string{"compatibility": level}
+	if err := c.do(ctx, http.MethodPut, "/config/"+url.PathEscape(subject), body, nil); err != nil {
+		return fmt.Errorf("failed to set the compatibility of subject %s: %w", subject, err)
+	}
+	return nil
+}
+
+// TestCompatibility tests whether a schema could be registered as the next
+// version of a subject under its compatibility level
+func (c *Client) TestCompatibility(ctx context.Context, subject string, schema Schema) (*CompatibilityResult, error) {
+	body := Schema{Type: schema.Type, Schema: schema.Schema, References: schema.References}
+	if body.Type == TypeAvro {
+		body.Type = ""
+	}
+
+	var result CompatibilityResult
+	path := "/compatibility" + subjectPath(subject) + "/versions/latest?verbose=true"
+	if err := c.do(ctx, http.MethodPost, path, body, &result); err != nil {
+		return nil, fmt.Errorf("failed to test compatibility with subject %s: %w", subject, err)
+	}
+	return &result, nil
+}
+
+// DeleteVersion deletes a version of a subject. A soft-deleted version is
+// hidden but its schema ID stays readable; a permanent delete removes a
+// soft-deleted version for good.
+func (c *Client) DeleteVersion(ctx context.Context, subject string, version int, permanent bool) error {
+	path := subjectPath(subject) + "/versions/" + strconv.Itoa(version)
+	if permanent {
+		path += "?permanent=true"
+	}
+	if err := c.do(ctx, http.MethodDelete, path, nil, nil); err != nil {
+		return fmt.Errorf("failed to delete version %d of subject %s: %w", version, subject, err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.versions, subject+"/"+strconv.Itoa(version))
+	return nil
+}
+
+// subjectPath returns the path of a subject
+func subjectPath(subject string) string {
+	return "/subjects/" + url.PathEscape(subject)
+}
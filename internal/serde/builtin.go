@@ -0,0 +1,178 @@
+package serde
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// deserializer is a deserializer backed by a function
+type deserializer struct {
+	format Format
+	decode func(data []byte) (string, error)
+}
+
+// Format returns the format of the deserializer
+func (d deserializer) Format() Format {
+	return d.format
+}
+
+// Deserialize decodes record bytes
+func (d deserializer) Deserialize(data []byte) (string, error) {
+	return d.decode(data)
+}
+
+// builtins returns the built-in deserializers
+func builtins() []Deserializer {
+	return []Deserializer{
+		deserializer{FormatString, decodeString},
+		deserializer{FormatJSON, decodeJSON},
+		deserializer{FormatHex, decodeHex},
+		deserializer{FormatBase64, decodeBase64},
+		deserializer{FormatMsgPack, decodeMsgPack},
+		deserializer{FormatInt32, decodeInt32},
+		deserializer{FormatInt64, decodeInt64},
+		deserializer{FormatFloat64, decodeFloat64},
+	}
+}
+
+// decodeString decodes UTF-8 text
+func decodeString(data []byte) (string, error) {
+	if !utf8.Valid(data) {
+		return "", fmt.Errorf("invalid UTF-8")
+	}
+	return string(data), nil
+}
+
+// decodeJSON pretty-prints a JSON document
+func decodeJSON(data []byte) (string, error) {
+	var out bytes.Buffer
+	if err := json.Indent(&out, data, "", "  "); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// decodeHex renders a hex dump with offsets and the printable characters
+func decodeHex(data []byte) (string, error) {
+	return strings.TrimSuffix(hex.Dump(data), "\n"), nil
+}
+
+// decodeBase64 encodes the bytes as standard base64
+func decodeBase64(data []byte) (string, error) {
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+// decodeMsgPack renders a MessagePack value as pretty-printed JSON
+func decodeMsgPack(data []byte) (string, error) {
+	r := bytes.NewReader(data)
+	v, err := msgpack.NewDecoder(r).DecodeInterface()
+	if err != nil {
+		return "", err
+	}
+	if r.Len() > 0 {
+		return "", fmt.Errorf("trailing bytes after the MessagePack value")
+	}
+	out, err := json.MarshalIndent(jsonValue(v), "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// jsonValue converts the maps with non-string keys MessagePack may decode
+// into maps JSON can encode
+func jsonValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[fmt.Sprint(k)] = jsonValue(e)
+		}
+		return m
+	case map[string]interface{}:
+		for k, e := range v {
+			v[k] = jsonValue(e)
+		}
+		return v
+	case []interface{}:
+		for i, e := range v {
+			v[i] = jsonValue(e)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// decodeInt32 decodes a big-endian signed 32-bit integer
+func decodeInt32(data []byte) (string, error) {
+	if len(data) != 4 {
+		return "", fmt.Errorf("expected 4 bytes, got %d", len(data))
+	}
+	return strconv.FormatInt(int64(int32(binary.BigEndian.Uint32(data))), 10), nil
+}
+
+// decodeInt64 decodes a big-endian signed 64-bit integer
+func decodeInt64(data []byte) (string, error) {
+	if len(data) != 8 {
+		return "", fmt.Errorf("expected 8 bytes, got %d", len(data))
+	}
+	return strconv.FormatInt(int64(binary.BigEndian.Uint64(data)), 10), nil
+}
+
+// decodeFloat64 decodes a big-endian IEEE 754 double
+func decodeFloat64(data []byte) (string, error) {
+	if len(data) != 8 {
+		return "", fmt.Errorf("expected 8 bytes, got %d", len(data))
+	}
+	return strconv.FormatFloat(math.Float64frombits(binary.BigEndian.Uint64(data)), 'g', -1, 64), nil
+}
+
+// isJSON reports whether data is a JSON object or array
+func isJSON(data []byte) bool {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 || (trimmed[0] != '{' && trimmed[0] != '[') {
+		return false
+	}
+	return json.Valid(trimmed)
+}
+
+// isText reports whether data is UTF-8 without control characters other than
+// whitespace
+func isText(data []byte) bool {
+	if !utf8.Valid(data) {
+		return false
+	}
+	for _, r := range string(data) {
+		if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// isMsgPack reports whether data is exactly one MessagePack map or array
+func isMsgPack(data []byte) bool {
+	if len(data) == 0 {
+		return false
+	}
+	switch b := data[0]; {
+	case b >= 0x80 && b <= 0x9f: // fixmap, fixarray
+	case b >= 0xdc && b <= 0xdf: // array 16/32, map 16/32
+	default:
+		return false
+	}
+	_, err := decodeMsgPack(data)
+	return err == nil
+}
@@ -0,0 +1,158 @@
+// Package serde decodes the keys and values of Kafka records for display
+package serde
+
+import (
+	"fmt"
+
+	"github.com/cfk-dev/cfk/internal/config"
+	"github.com/cfk-dev/cfk/internal/glob"
+)
+
+// Format names a way of decoding record bytes
+type Format string
+
+// Built-in formats
+const (
+	FormatAuto    Format = "auto"
+	FormatString  Format = "string"
+	FormatJSON    Format = "json"
+	FormatHex     Format = "hex"
+	FormatBase64  Format = "base64"
+	FormatMsgPack Format = "msgpack"
+	FormatInt32   Format = "int32"
+	FormatInt64   Format = "int64"
+	FormatFloat64 Format = "float64"
+)
+
+// Deserializer turns record bytes into text. The text may span several lines.
+type Deserializer interface {
+	Format() Format
+	Deserialize(data []byte) (string, error)
+}
+
+// Detector is implemented by deserializers that recognize their own format.
+// Detection asks them first, in registration order.
+type Detector interface {
+	Detects(data []byte) bool
+}
+
+// Registry holds the deserializers by format
+type Registry struct {
+	deserializers map[Format]Deserializer
+	formats       []Format
+}
+
+// NewRegistry creates a registry with the built-in deserializers
+func NewRegistry() *Registry {
+	r := &Registry{deserializers: make(map[Format]Deserializer)}
+	for _, d := range builtins() {
+		r.Register(d)
+	}
+	return r
+}
+
+// Register adds a deserializer, replacing any with the same format
+func (r *Registry) Register(d Deserializer) {
+	if _, ok := r.deserializers[d.Format()]; !ok {
+		r.formats = append(r.formats, d.Format())
+	}
+	r.deserializers[d.Format()] = d
+}
+
+// Get returns the deserializer of a format
+func (r *Registry) Get(format Format) (Deserializer, bool) {
+	d, ok := r.deserializers[format]
+	return d, ok
+}
+
+// Formats lists auto followed by the registered formats, in registration order
+func (r *Registry) Formats() []Format {
+	return append([]Format{FormatAuto}, r.formats...)
+}
+
+// Next returns the format after a format, wrapping around to auto
+func (r *Registry) Next(format Format) Format {
+	formats := r.Formats()
+	for i, f := range formats {
+		if f == format {
+			return formats[(i+1)%len(formats)]
+		}
+	}
+	return FormatAuto
+}
+
+// Detect guesses the format of record bytes: the format of the first
+// detector recognizing them, else JSON documents, printable text, MessagePack
+// maps and arrays, and hex for anything else
+func (r *Registry) Detect(data []byte) Format {
+	for _, f := range r.formats {
+		if d, ok := r.deserializers[f].(Detector); ok && d.Detects(data) {
+			return f
+		}
+	}
+
+	switch {
+	case isJSON(data):
+		return FormatJSON
+	case isText(data):
+		return FormatString
+	case isMsgPack(data):
+		return FormatMsgPack
+	default:
+		return FormatHex
+	}
+}
+
+// Deserialize decodes record bytes in a format, detecting it for auto. It
+// returns the format actually used.
+func (r *Registry) Deserialize(format Format, data []byte) (string, Format, error) {
+	if format == FormatAuto || format == "" {
+		format = r.Detect(data)
+	}
+	d, ok := r.deserializers[format]
+	if !ok {
+		return "", format, fmt.Errorf("unknown format %q", format)
+	}
+	text, err := d.Deserialize(data)
+	if err != nil {
+		return "", format, fmt.Errorf("failed to decode as %s: %w", format, err)
+	}
+	return text, format, nil
+}
+
+// Validate checks the topic patterns and formats of rules
+func (r *Registry) Validate(rules []config.SerdeRule) error {
+	for _, rule := range rules {
+		if _, err := glob.Compile(rule.Topic); err != nil {
+			return fmt.Errorf("invalid topic pattern: %w", err)
+		}
+		for _, f := range []string{rule.Key, rule.Value} {
+			if f == "" || Format(f) == FormatAuto {
+				continue
+			}
+			if _, ok := r.deserializers[Format(f)]; !ok {
+				return fmt.Errorf("unknown format %q for topics %q", f, rule.Topic)
+			}
+		}
+	}
+	return nil
+}
+
+// FormatsFor returns the key and value formats of the first rule matching a
+// topic, or auto when no rule matches
+func FormatsFor(rules []config.SerdeRule, topic string) (key, value Format) {
+	for _, rule := range rules {
+		if !glob.Match(rule.Topic, topic) {
+			continue
+		}
+		key, value = Format(rule.Key), Format(rule.Value)
+		if key == "" {
+			key = FormatAuto
+		}
+		if value == "" {
+			value = FormatAuto
+		}
+		return key, value
+	}
+	return FormatAuto, FormatAuto
+}
@@ -0,0 +1,146 @@
+package serde
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/cfk-dev/cfk/internal/config"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func TestDeserialize(t *testing.T) {
+	r := NewRegistry()
+	packed, err := msgpack.Marshal(map[string]interface{}{"id": 7, "tags": []string{"a"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	int32Bytes := binary.BigEndian.AppendUint32(nil, uint32(0xfffffffe))
+	int64Bytes := binary.BigEndian.AppendUint64(nil, 1<<40)
+	floatBytes := binary.BigEndian.AppendUint64(nil, math.Float64bits(2.5))
+
+	tests := []struct {
+		format Format
+		data   []byte
+		want   string
+	}{
+		{FormatString, []byte("héllo"), "héllo"},
+		{FormatJSON, []byte(`{"a":1,"b":[true]}`), "{\n  \"a\": 1,\n  \"b\": [\n    true\n  ]\n}"},
+		{FormatHex, []byte("AB"), "00000000  41 42                                             |AB|"},
+		{FormatBase64, []byte{0xff, 0x00}, "/wA="},
+		{FormatMsgPack, packed, "{\n  \"id\": 7,\n  \"tags\": [\n    \"a\"\n  ]\n}"},
+		{FormatInt32, int32Bytes, "-2"},
+		{FormatInt64, int64Bytes, "1099511627776"},
+		{FormatFloat64, floatBytes, "2.5"},
+	}
+	for _, tt := range tests {
+		got, used, err := r.Deserialize(tt.format, tt.data)
+		if err != nil || got != tt.want || used != tt.format {
+			t.Errorf("Deserialize(%s) = %q, %s, %v, want %q", tt.format, got, used, err, tt.want)
+		}
+	}
+
+	if _, _, err := r.Deserialize(FormatInt32, []byte{1, 2}); err == nil {
+		t.Error("expected an error decoding 2 bytes as int32")
+	}
+	if _, _, err := r.Deserialize(FormatString, []byte{0xff}); err == nil {
+		t.Error("expected an error decoding invalid UTF-8 as a string")
+	}
+	if _, _, err := r.Deserialize("avro", nil); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+func TestDetect(t *testing.T) {
+	r := NewRegistry()
+	packed, _ := msgpack.Marshal([]int{1, 2, 3})
+
+	tests := []struct {
+		data []byte
+		want Format
+	}{
+		{[]byte(` {"a": 1}`), FormatJSON},
+		{[]byte(`[1,2]`), FormatJSON},
+		{[]byte(`42`), FormatString},
+		{[]byte("plain text\n"), FormatString},
+		{[]byte{}, FormatString},
+		{packed, FormatMsgPack},
+		{[]byte{0x00, 0x01, 0xff}, FormatHex},
+		{[]byte{0x92, 0x01}, FormatHex}, // truncated array
+	}
+	for _, tt := range tests {
+		if got := r.Detect(tt.data); got != tt.want {
+			t.Errorf("Detect(%q) = %s, want %s", tt.data, got, tt.want)
+		}
+	}
+
+	if _, used, _ := r.Deserialize(FormatAuto, []byte(`{}`)); used != FormatJSON {
+		t.Errorf("auto resolved to %s, want json", used)
+	}
+}
+
+func TestRules(t *testing.T) {
+	r := NewRegistry()
+	rules := []config.SerdeRule{
+		{Topic: "metrics.*", Key: "string", Value: "float64"},
+		{Topic: "events-?", Value: "msgpack"},
+		{Topic: "*", Key: "string"},
+	}
+	if err := r.Validate(rules); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	tests := []struct {
+		topic      string
+		key, value Format
+	}{
+		{"metrics.cpu", FormatString, FormatFloat64},
+		{"events-1", FormatAuto, FormatMsgPack},
+		{"events-12", FormatString, FormatAuto},
+	}
+	for _, tt := range tests {
+		if key, value := FormatsFor(rules, tt.topic); key != tt.key || value != tt.value {
+			t.Errorf("FormatsFor(%s) = %s, %s, want %s, %s", tt.topic, key, value, tt.key, tt.value)
+		}
+	}
+	if key, value := FormatsFor(nil, "any"); key != FormatAuto || value != FormatAuto {
+		t.Errorf("FormatsFor without rules = %s, %s, want auto", key, value)
+	}
+
+	if err := r.Validate([]config.SerdeRule{{Topic: "[", Value: "json"}}); err == nil {
+		t.Error("expected an error for an invalid pattern")
+	}
+	if err := r.Validate([]config.SerdeRule{{Topic: "*", Value: "yaml"}}); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+
+	// Topic patterns have the syntax of group patterns
+	classes := []config.SerdeRule{
+		{Topic: `orders.[0-9]*`, Value: "json"},
+		{Topic: `audit\*`, Value: "hex"},
+		{Topic: `[^_]*`, Value: "string"},
+	}
+	if err := r.Validate(classes); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	for topic, want := range map[string]Format{
+		"orders.1-eu": FormatJSON,
+		"audit*":      FormatHex,
+		"audit-1":     FormatString,
+		"_schemas":    FormatAuto,
+	} {
+		if _, value := FormatsFor(classes, topic); value != want {
+			t.Errorf("FormatsFor(%s) value = %s, want %s", topic, value, want)
+		}
+	}
+	if err := r.Validate([]config.SerdeRule{{Topic: "events-[9-0]", Value: "json"}}); err == nil {
+		t.Error("expected an error for a pattern with an inverted range")
+	}
+
+	if got := r.Next(FormatFloat64); got != FormatAuto {
+		t.Errorf("Next(float64) = %s, want auto", got)
+	}
+	if got := r.Next(FormatAuto); got != FormatString {
+		t.Errorf("Next(auto) = %s, want string", got)
+	}
+}
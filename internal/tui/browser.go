@@ -0,0 +1,441 @@
+package tui
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/cfk-dev/cfk/internal/config"
+	"github.com/cfk-dev/cfk/internal/core"
+	"github.com/cfk-dev/cfk/internal/kafka"
+	"github.com/cfk-dev/cfk/internal/serde"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// timestampLayout is how record timestamps are shown
+const timestampLayout = "2006-01-02 15:04:05.000"
+
+// BrowseForm picks the partition and start position of the message browser
+type BrowseForm struct {
+	topic      string
+	pageSize   int
+	partition  formChoice
+	start      formChoice
+	value      textinput.Model
+	focusIndex int // 0 partition, 1 start, 2 value
+	message    string
+	width      int
+}
+
+// NewBrowseForm creates a browse form for a topic with a number of partitions,
+// or 0 if the partitions are not known yet
+func NewBrowseForm(width int, topic string, partitions, pageSize int) BrowseForm {
+	options := []string{"all"}
+	for p := 0; p < partitions; p++ {
+		options = append(options, strconv.Itoa(p))
+	}
+	starts := make([]string, len(core.BrowseStarts))
+	for i, s := range core.BrowseStarts {
+		starts[i] = string(s)
+	}
+
+	value := textinput.New()
+	value.Width = 40
+	value.Prompt = "› "
+	value.PromptStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("205"))
+	value.TextStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("252"))
+
+	f := BrowseForm{
+		topic:     topic,
+		pageSize:  pageSize,
+		partition: newFormChoice(options, "all"),
+		start:     newFormChoice(starts, string(core.FromEarliest)),
+		value:     value,
+		width:     width,
+	}
+	f.updatePlaceholder()
+	return f
+}
+
+// Init initializes the form
+func (f BrowseForm) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+// SetSize resizes the form
+func (f *BrowseForm) SetSize(width int) {
+	f.width = width
+}
+
+// hasValue reports whether the chosen start position needs a value
+func (f BrowseForm) hasValue() bool {
+	return f.start.Value() != string(core.FromEarliest)
+}
+
+// updatePlaceholder describes the value the chosen start position needs
+func (f *BrowseForm) updatePlaceholder() {
+	switch core.BrowseFrom(f.start.Value()) {
+	case core.FromLatest:
+		f.value.Placeholder = fmt.Sprintf("Number of records (default %d)", f.pageSize)
+	case core.FromOffset:
+		f.value.Placeholder = "Offset"
+	case core.FromTimestamp:
+		f.value.Placeholder = "e.g. 2024-05-01T12:00:00"
+	}
+}
+
+// Update handles form events
+func (f BrowseForm) Update(msg tea.Msg) (BrowseForm, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		var cmd tea.Cmd
+		f.value, cmd = f.value.Update(msg)
+		return f, cmd
+	}
+
+	f.message = ""
+	fields := 2
+	if f.hasValue() {
+		fields = 3
+	}
+
+	switch keyMsg.String() {
+	case "esc":
+		return f, func() tea.Msg { return BrowseFormCancelledMsg{} }
+	case "tab", "down", "shift+tab", "up":
+		if keyMsg.String() == "tab" || keyMsg.String() == "down" {
+			f.focusIndex = (f.focusIndex + 1) % fields
+		} else {
+			f.focusIndex = (f.focusIndex - 1 + fields) % fields
+		}
+		if f.focusIndex == 2 {
+			return f, f.value.Focus()
+		}
+		f.value.Blur()
+		return f, nil
+	case " ", "left", "right":
+		var choice *formChoice
+		switch f.focusIndex {
+		case 0:
+			choice = &f.partition
+		case 1:
+			choice = &f.start
+		}
+		if choice != nil {
+			if keyMsg.String() == "left" {
+				choice.Prev()
+			} else {
+				choice.Next()
+			}
+			f.updatePlaceholder()
+			return f, nil
+		}
+	case "enter":
+		req, err := f.request()
+		if err != nil {
+			f.message = err.Error()
+			return f, nil
+		}
+		return f, func() tea.Msg { return BrowseRequestedMsg{Request: req} }
+	}
+
+	if f.focusIndex == 2 {
+		var cmd tea.Cmd
+		f.value, cmd = f.value.Update(msg)
+		return f, cmd
+	}
+	return f, nil
+}
+
+// request builds the browse request from the form inputs
+func (f BrowseForm) request() (core.BrowseRequest, error) {
+	req := core.BrowseRequest{
+		Topic:     f.topic,
+		Partition: core.AllPartitions,
+		From:      core.BrowseFrom(f.start.Value()),
+	}
+	if f.partition.Value() != "all" {
+		req.Partition, _ = strconv.Atoi(f.partition.Value())
+	}
+
+	value := strings.TrimSpace(f.value.Value())
+	var err error
+	switch req.From {
+	case core.FromLatest:
+		req.Offset = int64(f.pageSize)
+		if value != "" {
+			if req.Offset, err = strconv.ParseInt(value, 10, 64); err != nil || req.Offset <= 0 {
+				return req, fmt.Errorf("invalid number of records %q", value)
+			}
+		}
+	case core.FromOffset:
+		if req.Offset, err = strconv.ParseInt(value, 10, 64); err != nil || req.Offset < 0 {
+			return req, fmt.Errorf("invalid offset %q", value)
+		}
+	case core.FromTimestamp:
+		if req.Timestamp, err = parseDatetime(value); err != nil {
+			return req, err
+		}
+	}
+	return req, nil
+}
+
+// View renders the form
+func (f BrowseForm) View() string {
+	formStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("240")).
+		Padding(1, 2).
+		Width(f.width - 4)
+
+	titleStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("205")).
+		Bold(true).
+		MarginBottom(1)
+
+	labelStyle := lipgloss.NewStyle().Width(20)
+	view := titleStyle.Render("Browse messages of "+f.topic) + "\n"
+	view += labelStyle.Render("Partition:") + " " + f.partition.View(f.focusIndex == 0) + "\n\n"
+	view += labelStyle.Render("Start at:") + " " + f.start.View(f.focusIndex == 1) + "\n\n"
+	if f.hasValue() {
+		view += labelStyle.Render("Value:") + " " + f.value.View() + "\n\n"
+	}
+	if f.message != "" {
+		view += errorTextStyle.Render(f.message) + "\n\n"
+	}
+	view += "Use tab/shift+tab to navigate, left/right to change options, enter to browse, esc to cancel"
+
+	return formStyle.Render(view)
+}
+
+// messageSummary describes the browsed partitions and the page
+func messageSummary(page *core.MessagePage) string {
+	summary := "Topic: " + page.Topic + "  •  "
+	if page.Partition == core.AllPartitions {
+		summary += "all partitions by timestamp"
+	} else {
+		b := page.Bounds[page.Partition]
+		summary += fmt.Sprintf("partition %d  •  offsets %d-%d of %d-%d",
+			page.Partition, page.Start[page.Partition], page.End[page.Partition], b.Earliest, b.Latest)
+	}
+	return summary + fmt.Sprintf("  •  %d records", len(page.Messages))
+}
+
+// recordFormat picks how the keys and values of the shown records are
+// decoded, and whether records are shown in full or one line each
+type recordFormat struct {
+	serdes *serde.Registry
+	key    serde.Format
+	value  serde.Format
+	full   bool
+}
+
+// newRecordFormat picks the formats the serde rules set for a topic
+func newRecordFormat(serdes *serde.Registry, rules []config.SerdeRule, topic string) recordFormat {
+	key, value := serde.FormatsFor(rules, topic)
+	return recordFormat{serdes: serdes, key: key, value: value}
+}
+
+// NextKey switches to the next key format
+func (f *recordFormat) NextKey() {
+	f.key = f.serdes.Next(f.key)
+}
+
+// NextValue switches to the next value format
+func (f *recordFormat) NextValue() {
+	f.value = f.serdes.Next(f.value)
+}
+
+// ToggleFull switches between one line per record and full records
+func (f *recordFormat) ToggleFull() {
+	f.full = !f.full
+}
+
+// String describes the formats
+func (f recordFormat) String() string {
+	return fmt.Sprintf("key: %s  •  value: %s", f.key, f.value)
+}
+
+// decode decodes a key or value. Without a registry, or when the bytes do
+// not decode, the raw bytes are shown.
+func (f recordFormat) decode(format serde.Format, data []byte) (string, serde.Format, error) {
+	if data == nil {
+		return "null", format, nil
+	}
+	if f.serdes == nil {
+		return displayBytes(data), format, nil
+	}
+	text, used, err := f.serdes.Deserialize(format, data)
+	if err != nil {
+		return displayBytes(data), used, err
+	}
+	return text, used, nil
+}
+
+// cell decodes a key or value on one line
+func (f recordFormat) cell(format serde.Format, data []byte) string {
+	text, used, err := f.decode(format, data)
+	if err != nil || f.serdes == nil || data == nil {
+		return text
+	}
+	if used == serde.FormatString {
+		return escapeText(text)
+	}
+	// Join the lines of pretty-printed formats
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	return strings.Join(lines, " ")
+}
+
+// messageTable renders one line per record of a page, cut to the width, with
+// the record at the cursor highlighted. It returns the line each record
+// starts on.
+func messageTable(page *core.MessagePage, width int, format recordFormat, cursor int) (string, []int) {
+	return messageLines(page.Messages, page.Partition == core.AllPartitions, width, format, cursor)
+}
+
+// messageLines renders one line per record, with the partition of each
+// record if they come from several partitions, or every record in full. The
+// record at the cursor, if not -1, is highlighted. It returns the line each
+// record starts on.
+func messageLines(messages []kafka.Message, merged bool, width int, format recordFormat, cursor int) (string, []int) {
+	if len(messages) == 0 {
+		return dimStyle.Render("No records"), nil
+	}
+	if format.full {
+		return fullMessages(messages, width, format, cursor)
+	}
+
+	header := fmt.Sprintf("%10s  %-23s  %-20s  %-24s  %s", "Offset", "Timestamp", "Key", "Headers", "Value")
+	if merged {
+		header = fmt.Sprintf("%4s ", "P") + header
+	}
+	lines := []string{tableHeaderStyle.Render(truncate(header, width))}
+	starts := make([]int, len(messages))
+
+	for i, m := range messages {
+		line := fmt.Sprintf("%10d  %-23s  %-20s  %-24s  %s", m.Offset, m.Timestamp.Format(timestampLayout),
+			truncate(format.cell(format.key, m.Key), 20), truncate(headerList(m.Headers), 24), format.cell(format.value, m.Value))
+		if merged {
+			line = fmt.Sprintf("%4d ", m.Partition) + line
+		}
+		line = truncate(line, width)
+		if i == cursor {
+			line = selectedRowStyle.Render("›" + line[1:])
+		}
+		starts[i] = len(lines)
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n"), starts
+}
+
+// fullMessages renders every record with its whole decoded key and value,
+// and returns the line each record starts on
+func fullMessages(messages []kafka.Message, width int, format recordFormat, cursor int) (string, []int) {
+	var blocks []string
+	starts := make([]int, len(messages))
+	line := 0
+	for i, m := range messages {
+		title := truncate(fmt.Sprintf("Partition %d  •  offset %d  •  %s", m.Partition, m.Offset, m.Timestamp.Format(timestampLayout)), width)
+		if i == cursor {
+			title = selectedRowStyle.Render("› " + title)
+		} else {
+			title = tableHeaderStyle.Render(title)
+		}
+		block := []string{title}
+		if len(m.Headers) > 0 {
+			block = append(block, "Headers: "+headerList(m.Headers))
+		}
+		block = append(block, fullField("Key", format, format.key, m.Key)...)
+		block = append(block, fullField("Value", format, format.value, m.Value)...)
+		starts[i] = line
+		text := strings.Join(block, "\n")
+		line += strings.Count(text, "\n") + 2 // and the blank line between records
+		blocks = append(blocks, text)
+	}
+	return strings.Join(blocks, "\n\n"), starts
+}
+
+// fullField renders a decoded key or value below a label naming its format
+func fullField(label string, format recordFormat, f serde.Format, data []byte) []string {
+	text, used, err := format.decode(f, data)
+	if f == serde.FormatAuto && data != nil {
+		label += fmt.Sprintf(" (%s, detected %s):", f, used)
+	} else {
+		label += fmt.Sprintf(" (%s):", f)
+	}
+	lines := []string{label}
+	if err != nil {
+		lines = append(lines, errorTextStyle.Render(err.Error()))
+	}
+	for _, line := range strings.Split(text, "\n") {
+		lines = append(lines, "  "+line)
+	}
+	return lines
+}
+
+// headerList renders headers as key=value pairs
+func headerList(headers []kafka.Header) string {
+	if len(headers) == 0 {
+		return "-"
+	}
+	parts := make([]string, len(headers))
+	for i, h := range headers {
+		parts[i] = h.Key + "=" + displayBytes(h.Value)
+	}
+	return strings.Join(parts, ",")
+}
+
+// displayBytes renders a key, value or header on one line: printable UTF-8
+// as text with control characters escaped, anything else as hex
+func displayBytes(b []byte) string {
+	if b == nil {
+		return "null"
+	}
+	if !utf8.Valid(b) {
+		return "0x" + hex.EncodeToString(b)
+	}
+	return escapeText(string(b))
+}
+
+// escapeText escapes the control characters of text
+func escapeText(text string) string {
+	var s strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '\n':
+			s.WriteString(`\n`)
+		case r == '\t':
+			s.WriteString(`\t`)
+		case !unicode.IsPrint(r) && r != ' ':
+			fmt.Fprintf(&s, `\x%02x`, r)
+		default:
+			s.WriteRune(r)
+		}
+	}
+	return s.String()
+}
+
+// truncate cuts a string to a width, marking the cut with an ellipsis
+func truncate(s string, width int) string {
+	if width <= 0 || utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:width-1]) + "…"
+}
+
+// BrowseRequestedMsg is sent when the browse form is submitted
+type BrowseRequestedMsg struct {
+	Request core.BrowseRequest
+}
+
+// BrowseFormCancelledMsg is sent when the browse form is cancelled
+type BrowseFormCancelledMsg struct{}
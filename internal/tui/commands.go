@@ -3,11 +3,11 @@ package tui
 import (
 	"context"
 	"fmt"
-	"time"
 
 	"github.com/cfk-dev/cfk/internal/config"
 	"github.com/cfk-dev/cfk/internal/core"
 	"github.com/cfk-dev/cfk/internal/kafka"
+	"github.com/cfk-dev/cfk/internal/schemaregistry"
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
 )
@@ -35,17 +35,137 @@ type ConnectedMsg struct {
 	ClusterName string
 }
 
+// TopicDetailsLoadedMsg is a message containing the partitions and configs of a topic
+type TopicDetailsLoadedMsg struct {
+	Details *kafka.TopicDetails
+}
+
+// TopicConfigAlteredMsg is a message containing a topic after its configs changed
+type TopicConfigAlteredMsg struct {
+	Details *kafka.TopicDetails
+	Changed int
+}
+
+// GroupsLoadedMsg is a message containing the consumer groups with their lag
+type GroupsLoadedMsg struct {
+	Groups []kafka.GroupInfo
+}
+
+// GroupDetailsLoadedMsg is a message containing the members and offsets of a group
+type GroupDetailsLoadedMsg struct {
+	Group *kafka.GroupInfo
+}
+
+// OffsetResetPlannedMsg is a message containing the dry run of an offset reset
+type OffsetResetPlannedMsg struct {
+	Changes []core.OffsetChange
+	Err     error
+}
+
+// TruncatePlannedMsg is a message containing the records a truncation deletes
+type TruncatePlannedMsg struct {
+	Plan []core.RecordDeletion
+	Err  error
+}
+
+// RecordsDeletedMsg is a message containing the first page of a topic after
+// records were deleted from it
+type RecordsDeletedMsg struct {
+	Page    *core.MessagePage
+	Deleted int64
+}
+
+// OffsetsResetMsg is a message containing a group after its offsets were reset
+type OffsetsResetMsg struct {
+	Group *kafka.GroupInfo
+	Reset int
+}
+
+// GroupsDeletedMsg is a message containing the groups left after a bulk delete
+// and the result of deleting each group
+type GroupsDeletedMsg struct {
+	Groups  []kafka.GroupInfo
+	Results map[string]error
+}
+
+// GroupOffsetsDeletedMsg is a message containing a group after the offsets of
+// a topic were removed from it
+type GroupOffsetsDeletedMsg struct {
+	Group *kafka.GroupInfo
+	Topic string
+}
+
+// MessagesLoadedMsg is a message containing a page of records
+type MessagesLoadedMsg struct {
+	Page *core.MessagePage
+}
+
+// TailStartedMsg is a message containing a tail started at the end of a topic
+type TailStartedMsg struct {
+	Tail *core.Tail
+}
+
+// TailPolledMsg is a message containing the records read by a tail poll
+type TailPolledMsg struct {
+	Tail     *core.Tail
+	Messages []kafka.Message
+	Err      error
+}
+
+// SubjectsLoadedMsg is a message containing the subjects of the schema registry
+type SubjectsLoadedMsg struct {
+	Items []list.Item
+}
+
+// SubjectLoadedMsg is a message containing the versions and compatibility of a subject
+type SubjectLoadedMsg struct {
+	Details *core.SubjectDetails
+}
+
+// SubjectCompatibilitySetMsg is a message containing a subject after its
+// compatibility level changed
+type SubjectCompatibilitySetMsg struct {
+	Details *core.SubjectDetails
+}
+
+// SchemaVersionDeletedMsg is a message containing a subject after one of its
+// versions was deleted, or the remaining subjects if it has no versions left
+type SchemaVersionDeletedMsg struct {
+	Subject   string
+	Version   int
+	Permanent bool
+	Details   *core.SubjectDetails
+	Subjects  []list.Item
+}
+
+// SchemaCompatibilityTestedMsg is a message containing the outcome of testing
+// a schema against a subject
+type SchemaCompatibilityTestedMsg struct {
+	Result *schemaregistry.CompatibilityResult
+	Err    error
+}
+
+// MessageProducedMsg is a message containing a record written by the produce
+// form, or the error writing it
+type MessageProducedMsg struct {
+	Message *kafka.Message
+	Err     error
+}
+
+// TopicEditLoadedMsg is a message containing the details of a topic being edited
+type TopicEditLoadedMsg struct {
+	Info *kafka.TopicInfo
+}
+
 // ItemsUpdatedMsg is a message containing updated list items
 type ItemsUpdatedMsg struct {
-	Items []list.Item
+	Items  []list.Item
+	Topics bool // the items are topics rather than clusters
 }
 
 // LoadTopicsCmd returns a command that loads topics from Kafka
-func LoadTopicsCmd(client *kafka.Client) Command {
+func LoadTopicsCmd(ctx context.Context, client core.KafkaAdmin) Command {
 	return func() tea.Msg {
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
-
 		topics, err := client.ListTopics(ctx)
 		if err != nil {
 			return ErrorMsg{err: fmt.Errorf("failed to list topics: %w", err)}
@@ -56,10 +176,10 @@ func LoadTopicsCmd(client *kafka.Client) Command {
 }
 
 // ConnectToClusterCmd returns a command that connects to a Kafka cluster
-func ConnectToClusterCmd(clusterConfig config.KafkaClusterConfig) Command {
+func ConnectToClusterCmd(ctx context.Context, clusterConfig config.KafkaClusterConfig) Command {
 	return func() tea.Msg {
 		client := kafka.NewClient(clusterConfig)
-		err := client.Connect()
+		err := client.Connect(ctx)
 		if err != nil {
 			return ErrorMsg{err: fmt.Errorf("failed to connect to cluster %s: %w", clusterConfig.Name, err)}
 		}
@@ -69,11 +189,8 @@ func ConnectToClusterCmd(clusterConfig config.KafkaClusterConfig) Command {
 }
 
 // UpdateTopicListCmd returns a command that updates the topic list
-func UpdateTopicListCmd(app *core.App) Command {
+func UpdateTopicListCmd(ctx context.Context, app *core.App) Command {
 	return func() tea.Msg {
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
-
 		topics, err := app.ListTopics(ctx)
 		if err != nil {
 			return ErrorMsg{err: fmt.Errorf("failed to list topics: %w", err)}
@@ -81,6 +198,11 @@ func UpdateTopicListCmd(app *core.App) Command {
 
 		items := make([]list.Item, len(topics))
 		for i, topic := range topics {
+			// Stop early if the operation was cancelled
+			if err := ctx.Err(); err != nil {
+				return ErrorMsg{err: fmt.Errorf("failed to list topics: %w", err)}
+			}
+
 			// Get topic info to show partitions
 			topicInfo, err := app.GetTopicInfo(ctx, topic)
 			if err != nil {
@@ -91,7 +213,305 @@ func UpdateTopicListCmd(app *core.App) Command {
 			}
 		}
 
-		return ItemsUpdatedMsg{Items: items}
+		return ItemsUpdatedMsg{Items: items, Topics: true}
+	}
+}
+
+// LoadTopicDetailsCmd returns a command that loads the details of a topic
+func LoadTopicDetailsCmd(ctx context.Context, app *core.App, topicName string) Command {
+	return func() tea.Msg {
+		details, err := app.DescribeTopic(ctx, topicName)
+		if err != nil {
+			return ErrorMsg{err}
+		}
+
+		return TopicDetailsLoadedMsg{Details: details}
+	}
+}
+
+// AlterTopicConfigCmd returns a command that applies config changes to a topic
+// and reloads its details
+func AlterTopicConfigCmd(ctx context.Context, app *core.App, topicName string, changes []kafka.ConfigChange) Command {
+	return func() tea.Msg {
+		if err := app.AlterTopicConfig(ctx, topicName, changes); err != nil {
+			return ErrorMsg{err}
+		}
+
+		details, err := app.DescribeTopic(ctx, topicName)
+		if err != nil {
+			return ErrorMsg{err}
+		}
+
+		return TopicConfigAlteredMsg{Details: details, Changed: len(changes)}
+	}
+}
+
+// LoadGroupsCmd returns a command that loads the consumer groups with their lag
+func LoadGroupsCmd(ctx context.Context, app *core.App) Command {
+	return func() tea.Msg {
+		groups, err := app.ListGroupsWithOffsets(ctx)
+		if err != nil {
+			return ErrorMsg{err}
+		}
+
+		return GroupsLoadedMsg{Groups: groups}
+	}
+}
+
+// LoadGroupDetailsCmd returns a command that loads the members and offsets of a group
+func LoadGroupDetailsCmd(ctx context.Context, app *core.App, groupID string) Command {
+	return func() tea.Msg {
+		group, err := app.DescribeGroup(ctx, groupID)
+		if err != nil {
+			return ErrorMsg{err}
+		}
+
+		return GroupDetailsLoadedMsg{Group: group}
+	}
+}
+
+// PlanOffsetResetCmd returns a command that plans an offset reset for the dry run.
+// Planning errors are shown in the form rather than ending the session.
+func PlanOffsetResetCmd(ctx context.Context, app *core.App, groupID string, reset core.OffsetReset) Command {
+	return func() tea.Msg {
+		changes, err := app.PlanOffsetReset(ctx, groupID, reset)
+		return OffsetResetPlannedMsg{Changes: changes, Err: err}
+	}
+}
+
+// ApplyOffsetResetCmd returns a command that commits the planned offsets of a
+// group and reloads it
+func ApplyOffsetResetCmd(ctx context.Context, app *core.App, groupID string, changes []core.OffsetChange) Command {
+	return func() tea.Msg {
+		if err := app.ApplyOffsetReset(ctx, groupID, changes); err != nil {
+			return ErrorMsg{err}
+		}
+
+		group, err := app.DescribeGroup(ctx, groupID)
+		if err != nil {
+			return ErrorMsg{err}
+		}
+
+		return OffsetsResetMsg{Group: group, Reset: len(changes)}
+	}
+}
+
+// PlanTruncateCmd returns a command that works out the records a truncation
+// deletes. Planning errors are shown in the form rather than ending the session.
+func PlanTruncateCmd(ctx context.Context, app *core.App, req core.TruncateRequest) Command {
+	return func() tea.Msg {
+		plan, err := app.PlanTruncate(ctx, req)
+		return TruncatePlannedMsg{Plan: plan, Err: err}
+	}
+}
+
+// ApplyTruncateCmd returns a command that deletes the records of a planned
+// truncation and reads the first page of a partition, or of all partitions,
+// again
+func ApplyTruncateCmd(ctx context.Context, app *core.App, topic string, partition int, plan []core.RecordDeletion, pageSize int) Command {
+	return func() tea.Msg {
+		if err := app.ApplyTruncate(ctx, topic, plan); err != nil {
+			return ErrorMsg{err}
+		}
+
+		page, err := app.BrowseMessages(ctx, core.BrowseRequest{Topic: topic, Partition: partition, From: core.FromEarliest}, pageSize)
+		if err != nil {
+			return ErrorMsg{err}
+		}
+
+		return RecordsDeletedMsg{Page: page, Deleted: truncateTotal(plan)}
+	}
+}
+
+// DeleteGroupsCmd returns a command that deletes consumer groups and reloads
+// the remaining ones
+func DeleteGroupsCmd(ctx context.Context, app *core.App, groupIDs []string) Command {
+	return func() tea.Msg {
+		results, err := app.DeleteGroups(ctx, groupIDs)
+		if err != nil {
+			return ErrorMsg{err}
+		}
+
+		groups, err := app.ListGroupsWithOffsets(ctx)
+		if err != nil {
+			return ErrorMsg{err}
+		}
+
+		return GroupsDeletedMsg{Groups: groups, Results: results}
+	}
+}
+
+// DeleteGroupOffsetsCmd returns a command that removes the offsets of a topic
+// from a group and reloads it
+func DeleteGroupOffsetsCmd(ctx context.Context, app *core.App, groupID, topicName string) Command {
+	return func() tea.Msg {
+		if err := app.DeleteGroupOffsets(ctx, groupID, topicName); err != nil {
+			return ErrorMsg{err}
+		}
+
+		group, err := app.DescribeGroup(ctx, groupID)
+		if err != nil {
+			return ErrorMsg{err}
+		}
+
+		return GroupOffsetsDeletedMsg{Group: group, Topic: topicName}
+	}
+}
+
+// BrowseMessagesCmd returns a command that reads the first page of records
+func BrowseMessagesCmd(ctx context.Context, app *core.App, req core.BrowseRequest, pageSize int) Command {
+	return func() tea.Msg {
+		page, err := app.BrowseMessages(ctx, req, pageSize)
+		if err != nil {
+			return ErrorMsg{err}
+		}
+
+		return MessagesLoadedMsg{Page: page}
+	}
+}
+
+// NextMessagesCmd returns a command that reads the page after a page
+func NextMessagesCmd(ctx context.Context, app *core.App, page *core.MessagePage, pageSize int) Command {
+	return func() tea.Msg {
+		next, err := app.NextMessages(ctx, page, pageSize)
+		if err != nil {
+			return ErrorMsg{err}
+		}
+
+		return MessagesLoadedMsg{Page: next}
+	}
+}
+
+// PrevMessagesCmd returns a command that reads the page before a page
+func PrevMessagesCmd(ctx context.Context, app *core.App, page *core.MessagePage, pageSize int) Command {
+	return func() tea.Msg {
+		prev, err := app.PrevMessages(ctx, page, pageSize)
+		if err != nil {
+			return ErrorMsg{err}
+		}
+
+		return MessagesLoadedMsg{Page: prev}
+	}
+}
+
+// StartTailCmd returns a command that starts following a topic
+func StartTailCmd(ctx context.Context, app *core.App, topic string) Command {
+	return func() tea.Msg {
+		tail, err := app.TailMessages(ctx, topic)
+		if err != nil {
+			return ErrorMsg{err}
+		}
+
+		return TailStartedMsg{Tail: tail}
+	}
+}
+
+// TailPollCmd returns a command that reads the records appended since the
+// last poll. Errors are kept for the tail to show and retry.
+func TailPollCmd(ctx context.Context, tail *core.Tail, limit int) Command {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(ctx, operationTimeout)
+		defer cancel()
+
+		messages, err := tail.Poll(ctx, limit)
+		return TailPolledMsg{Tail: tail, Messages: messages, Err: err}
+	}
+}
+
+// LoadSubjectsCmd returns a command that lists the subjects of the schema registry
+func LoadSubjectsCmd(ctx context.Context, app *core.App) Command {
+	return func() tea.Msg {
+		items, err := subjectItems(ctx, app)
+		if err != nil {
+			return ErrorMsg{err}
+		}
+
+		return SubjectsLoadedMsg{Items: items}
+	}
+}
+
+// subjectItems lists the subjects as list items
+func subjectItems(ctx context.Context, app *core.App) ([]list.Item, error) {
+	subjects, err := app.ListSubjects(ctx)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]list.Item, len(subjects))
+	for i, subject := range subjects {
+		items[i] = NewSubjectItem(subject)
+	}
+	return items, nil
+}
+
+// LoadSubjectCmd returns a command that loads the versions and compatibility of a subject
+func LoadSubjectCmd(ctx context.Context, app *core.App, subject string) Command {
+	return func() tea.Msg {
+		details, err := app.DescribeSubject(ctx, subject)
+		if err != nil {
+			return ErrorMsg{err}
+		}
+
+		return SubjectLoadedMsg{Details: details}
+	}
+}
+
+// SetSubjectCompatibilityCmd returns a command that sets the compatibility
+// level of a subject and reloads it
+func SetSubjectCompatibilityCmd(ctx context.Context, app *core.App, subject, level string) Command {
+	return func() tea.Msg {
+		if err := app.SetSubjectCompatibility(ctx, subject, level); err != nil {
+			return ErrorMsg{err}
+		}
+
+		details, err := app.DescribeSubject(ctx, subject)
+		if err != nil {
+			return ErrorMsg{err}
+		}
+
+		return SubjectCompatibilitySetMsg{Details: details}
+	}
+}
+
+// DeleteSchemaVersionCmd returns a command that deletes a version of a
+// subject and reloads the subject, or the subjects once it has no versions left
+func DeleteSchemaVersionCmd(ctx context.Context, app *core.App, subject string, version int, permanent bool) Command {
+	return func() tea.Msg {
+		if err := app.DeleteSchemaVersion(ctx, subject, version, permanent); err != nil {
+			return ErrorMsg{err}
+		}
+
+		deleted := SchemaVersionDeletedMsg{Subject: subject, Version: version, Permanent: permanent}
+		details, err := app.DescribeSubject(ctx, subject)
+		if schemaregistry.ErrorCode(err) == schemaregistry.CodeSubjectNotFound {
+			if deleted.Subjects, err = subjectItems(ctx, app); err != nil {
+				return ErrorMsg{err}
+			}
+			return deleted
+		}
+		if err != nil {
+			return ErrorMsg{err}
+		}
+
+		deleted.Details = details
+		return deleted
+	}
+}
+
+// TestSchemaCompatibilityCmd returns a command that tests a candidate schema
+// against a subject. Errors are shown in the form rather than ending the session.
+func TestSchemaCompatibilityCmd(ctx context.Context, app *core.App, subject, schemaType, schema string) Command {
+	return func() tea.Msg {
+		result, err := app.TestSchemaCompatibility(ctx, subject, schemaType, schema)
+		return SchemaCompatibilityTestedMsg{Result: result, Err: err}
+	}
+}
+
+// ProduceMessageCmd returns a command that writes one record. Errors are
+// shown in the form rather than ending the session.
+func ProduceMessageCmd(ctx context.Context, app *core.App, req core.ProduceRequest) Command {
+	return func() tea.Msg {
+		msg, err := app.Produce(ctx, req)
+		return MessageProducedMsg{Message: msg, Err: err}
 	}
 }
 
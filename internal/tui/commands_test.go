@@ -0,0 +1,923 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cfk-dev/cfk/internal/config"
+	"github.com/cfk-dev/cfk/internal/core"
+	"github.com/cfk-dev/cfk/internal/kafka"
+	"github.com/cfk-dev/cfk/internal/kafka/fake"
+	"github.com/cfk-dev/cfk/internal/kafka/kafkatest"
+	"github.com/cfk-dev/cfk/internal/schemaregistry"
+	"github.com/cfk-dev/cfk/internal/schemaregistry/registrytest"
+	"github.com/cfk-dev/cfk/internal/serde"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// newDemoApp returns an app connected to the demo cluster
+func newDemoApp(t *testing.T) *core.App {
+	t.Helper()
+
+	cluster := fake.NewDemoCluster()
+	app := core.NewApp(&config.AppConfig{
+		Clusters: []config.KafkaClusterConfig{{Name: "demo"}},
+	})
+	app.Ephemeral = true
+	app.Connect = func(ctx context.Context, clusterConfig config.KafkaClusterConfig) (core.KafkaAdmin, error) {
+		return cluster, nil
+	}
+	if err := app.ConnectToCluster(context.Background(), "demo"); err != nil {
+		t.Fatalf("ConnectToCluster() error = %v", err)
+	}
+	return app
+}
+
+// followToEnd follows the messages of a background run as the model does,
+// until the run ends
+func followToEnd[P, R any](run *backgroundRun[P, R], setProgress func(P), setResult func(R)) {
+	if setProgress == nil {
+		setProgress = func(P) {}
+	}
+	for cmd := run.wait(); cmd != nil; {
+		cmd = followRun(run, cmd().(RunMsg[P, R]), setProgress, setResult)
+	}
+}
+
+// runCancelled runs the command of an operation and cancels the operation
+// once the command started, returning the message of the command
+func runCancelled(t *testing.T, ops *operations, cmd tea.Cmd, started <-chan struct{}) tea.Msg {
+	t.Helper()
+
+	done := make(chan tea.Msg, 1)
+	go func() { done <- cmd() }()
+	<-started
+	if !ops.Cancel() {
+		t.Fatal("Cancel() found no operation in flight")
+	}
+	select {
+	case msg := <-done:
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatal("cancelled operation did not return")
+		return nil
+	}
+}
+
+func TestOperationsCancel(t *testing.T) {
+	var ops operations
+	started := make(chan struct{})
+	cmd := ops.run("Loading topics", func(ctx context.Context) tea.Msg {
+		close(started)
+		<-ctx.Done()
+		return TopicsLoadedMsg{}
+	})
+	if ops.Running() != "Loading topics" {
+		t.Errorf("Running() = %q, want the label of the operation", ops.Running())
+	}
+
+	msg := runCancelled(t, &ops, cmd, started)
+	if want := (OperationCancelledMsg{Label: "Loading topics"}); msg != want {
+		t.Errorf("cancelled operation = %#v, want %#v", msg, want)
+	}
+	if ops.Running() != "" || ops.Cancel() {
+		t.Errorf("operation still in flight after its cancellation: %q", ops.Running())
+	}
+
+	// Operations that were not cancelled return their message
+	cmd = ops.run("Loading topics", func(ctx context.Context) tea.Msg { return TopicsLoadedMsg{} })
+	if msg, ok := cmd().(TopicsLoadedMsg); !ok {
+		t.Errorf("operation = %#v, want its TopicsLoadedMsg", msg)
+	}
+	if ops.Running() != "" {
+		t.Errorf("Running() = %q after the operation returned", ops.Running())
+	}
+}
+
+func TestOperationsCancelKafkaCall(t *testing.T) {
+	srv, err := kafkatest.NewServer(kafkatest.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	app := core.NewApp(&config.AppConfig{
+		Clusters: []config.KafkaClusterConfig{{Name: "local", Bootstrap: srv.Addrs}},
+	})
+	app.Ephemeral = true
+	if err := app.ConnectToCluster(context.Background(), "local"); err != nil {
+		t.Fatalf("ConnectToCluster() error = %v", err)
+	}
+	defer app.KafkaClient.Close()
+
+	// The broker answers after the timeout of the operation
+	srv.SetLatency(time.Minute)
+	var ops operations
+	started := make(chan struct{})
+	cmd := ops.run("Loading consumer groups", func(ctx context.Context) tea.Msg {
+		close(started)
+		return LoadGroupsCmd(ctx, app)()
+	})
+	start := time.Now()
+	msg := runCancelled(t, &ops, cmd, started)
+	if _, ok := msg.(OperationCancelledMsg); !ok {
+		t.Errorf("cancelled Kafka call = %#v, want an OperationCancelledMsg", msg)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("cancelled Kafka call returned after %s", elapsed)
+	}
+}
+
+func TestUpdateTopicListCmd(t *testing.T) {
+	app := newDemoApp(t)
+
+	msg, ok := UpdateTopicListCmd(context.Background(), app)().(ItemsUpdatedMsg)
+	if !ok {
+		t.Fatalf("UpdateTopicListCmd() did not return ItemsUpdatedMsg")
+	}
+	if !msg.Topics {
+		t.Errorf("topic list update is not flagged as topics")
+	}
+	if len(msg.Items) != 4 {
+		t.Fatalf("got %d topics, want 4", len(msg.Items))
+	}
+	if item := msg.Items[0].(Item); item.Title() != "audit-log" || item.Description() != "1 partitions" {
+		t.Errorf("first item = %q (%q), want audit-log (1 partitions)", item.Title(), item.Description())
+	}
+}
+
+func TestLoadTopicDetailsCmdReportsMissingTopic(t *testing.T) {
+	app := newDemoApp(t)
+
+	if _, ok := LoadTopicDetailsCmd(context.Background(), app, "missing")().(ErrorMsg); !ok {
+		t.Errorf("LoadTopicDetailsCmd() for a missing topic did not return ErrorMsg")
+	}
+}
+
+func TestTopicDetailsView(t *testing.T) {
+	cluster := fake.NewCluster()
+	ctx := context.Background()
+	cluster.CreateTopic(ctx, "events", 3, 2)
+	cluster.UpdateTopicConfig(ctx, "events", map[string]string{"retention.ms": "3600000"})
+	cluster.ProduceMessage(ctx, "events", kafka.Message{Partition: 1, Value: []byte("hello")})
+	cluster.StopBroker(1)
+
+	details, err := cluster.DescribeTopic(ctx, "events")
+	if err != nil {
+		t.Fatalf("DescribeTopic() error = %v", err)
+	}
+
+	// Partition 0 is on brokers 0,1, partition 1 on 1,2 and partition 2 on 2,0
+	panes := newTopicDetailsPanes(160, 40)
+	panes.SetDetails(details)
+	view := panes.View()
+	for _, want := range []string{"3 partitions", "1 messages", "2 under-replicated", "retention.ms", "dynamic"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("view does not contain %q:\n%s", want, view)
+		}
+	}
+	if strings.Contains(view, "compression.type") {
+		t.Errorf("view lists default configs before toggling them on")
+	}
+
+	panes.ToggleDefaults()
+	if view := panes.View(); !strings.Contains(view, "compression.type") {
+		t.Errorf("view does not list default configs after toggling them on")
+	}
+}
+
+// typeKeys sends text to a form one key at a time
+func typeKeys(f ConfigForm, keys ...tea.KeyMsg) ConfigForm {
+	for _, key := range keys {
+		f, _ = f.Update(key)
+	}
+	return f
+}
+
+func TestConfigFormReviewsChanges(t *testing.T) {
+	cluster := fake.NewCluster()
+	ctx := context.Background()
+	cluster.CreateTopic(ctx, "events", 1, 1)
+	cluster.UpdateTopicConfig(ctx, "events", map[string]string{"cleanup.policy": "compact"})
+	details, err := cluster.DescribeTopic(ctx, "events")
+	if err != nil {
+		t.Fatalf("DescribeTopic() error = %v", err)
+	}
+
+	form := NewConfigForm(160, 60, details)
+	if form.fields[0].entry.Name != "cleanup.policy" {
+		t.Fatalf("first field = %q, want cleanup.policy", form.fields[0].entry.Name)
+	}
+
+	// Clear cleanup.policy, then type an invalid retention.ms
+	for range "compact" {
+		form = typeKeys(form, tea.KeyMsg{Type: tea.KeyBackspace})
+	}
+	for form.fields[form.focusIndex].entry.Name != "retention.ms" {
+		form = typeKeys(form, tea.KeyMsg{Type: tea.KeyTab})
+	}
+	form.fields[form.focusIndex].input.SetValue("")
+	form = typeKeys(form, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("1h")})
+	if form.fields[form.focusIndex].err == nil {
+		t.Errorf("retention.ms=1h is not flagged as invalid")
+	}
+	form = typeKeys(form, tea.KeyMsg{Type: tea.KeyEnter})
+	if form.confirming || !strings.Contains(form.View(), "whole number") {
+		t.Fatalf("form with an invalid value went on to review:\n%s", form.View())
+	}
+
+	form = typeKeys(form, tea.KeyMsg{Type: tea.KeyBackspace}, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("000")})
+	form = typeKeys(form, tea.KeyMsg{Type: tea.KeyEnter})
+	if !form.confirming {
+		t.Fatalf("form did not show the changes for review")
+	}
+	view := form.View()
+	for _, want := range []string{"cleanup.policy", "compact", "delete (default)", "604800000", "1000"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("diff does not contain %q:\n%s", want, view)
+		}
+	}
+
+	_, cmd := form.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	msg, ok := cmd().(ConfigChangesSubmittedMsg)
+	if !ok {
+		t.Fatalf("confirming did not submit the changes")
+	}
+	want := []kafka.ConfigChange{{Name: "cleanup.policy", Delete: true}, {Name: "retention.ms", Value: "1000"}}
+	if msg.Topic != "events" || !reflect.DeepEqual(msg.Changes, want) {
+		t.Errorf("submitted %+v, want changes %+v to events", msg, want)
+	}
+}
+
+func TestAddTopicFormTemplate(t *testing.T) {
+	form := NewAddTopicForm(120, 40, config.DefaultTopicTemplates())
+	form.inputs[0].SetValue("orders-changelog")
+
+	// Tab to the template choice and pick the first template
+	for form.focusIndex != len(form.inputs) {
+		form, _ = form.Update(tea.KeyMsg{Type: tea.KeyTab})
+	}
+	form, _ = form.Update(tea.KeyMsg{Type: tea.KeyRight})
+	if form.inputs[1].Value() != "6" || form.inputs[2].Value() != "3" {
+		t.Errorf("template set partitions %q and replication factor %q, want 6 and 3", form.inputs[1].Value(), form.inputs[2].Value())
+	}
+
+	form.inputs[4].SetValue(form.inputs[4].Value() + ", cleanup.policy=compact,delete")
+	spec, err := form.topicSpec()
+	if err != nil {
+		t.Fatalf("topicSpec() error = %v", err)
+	}
+	if spec.Name != "orders-changelog" || spec.Partitions != 6 || spec.Configs["cleanup.policy"] != "compact,delete" || spec.Configs["min.insync.replicas"] != "2" {
+		t.Errorf("topicSpec() = %+v", spec)
+	}
+
+	// Invalid names are reported on the form rather than submitted
+	form.inputs[0].SetValue("orders changelog")
+	form.focusIndex, form.buttonFocus = -1, 0
+	form, cmd := form.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd != nil || !strings.Contains(form.View(), "may only contain") {
+		t.Errorf("invalid topic name was not reported on the form:\n%s", form.View())
+	}
+}
+
+func TestGroupViews(t *testing.T) {
+	app := newDemoApp(t)
+	ctx := context.Background()
+
+	msg, ok := LoadGroupsCmd(ctx, app)().(GroupsLoadedMsg)
+	if !ok {
+		t.Fatalf("LoadGroupsCmd() did not return GroupsLoadedMsg")
+	}
+	list := newGroupList(160, 40)
+	list.SetGroups(msg.Groups)
+	view := list.View()
+	for _, want := range []string{"order-service", "Stable", "range", "payments-reconciler", "Empty"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("group list does not contain %q:\n%s", want, view)
+		}
+	}
+	if group, _ := list.Selected(); group.GroupID != "order-service" || group.TotalLag() != 6 {
+		t.Errorf("selected group %s with lag %d, want order-service with lag 6", group.GroupID, group.TotalLag())
+	}
+
+	details, ok := LoadGroupDetailsCmd(ctx, app, "order-service")().(GroupDetailsLoadedMsg)
+	if !ok {
+		t.Fatalf("LoadGroupDetailsCmd() did not return GroupDetailsLoadedMsg")
+	}
+	panes := newGroupDetailsPanes(160, 50)
+	panes.SetGroup(details.Group)
+	view = panes.View()
+	for _, want := range []string{"2 members", "total lag 6", "order-service-1", "/10.0.1.15", "orders: 0,1,2", "orders: 3,4,5"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("group details do not contain %q:\n%s", want, view)
+		}
+	}
+
+	if _, ok := LoadGroupDetailsCmd(ctx, app, "missing")().(ErrorMsg); !ok {
+		t.Errorf("LoadGroupDetailsCmd() for a missing group did not return ErrorMsg")
+	}
+}
+
+func TestOffsetResetForm(t *testing.T) {
+	app := newDemoApp(t)
+	ctx := context.Background()
+
+	details := LoadGroupDetailsCmd(ctx, app, "payments-reconciler")().(GroupDetailsLoadedMsg)
+	before := details.Group.Offsets[0].CommittedOffset
+	form := NewOffsetResetForm(160, 50, details.Group)
+	update := func(key tea.KeyMsg) tea.Cmd {
+		var cmd tea.Cmd
+		form, cmd = form.Update(key)
+		return cmd
+	}
+
+	// Choose shift-by, skip the scope and type an invalid shift
+	for form.strategy.Value() != string(core.ResetShiftBy) {
+		update(tea.KeyMsg{Type: tea.KeyRight})
+	}
+	update(tea.KeyMsg{Type: tea.KeyTab})
+	update(tea.KeyMsg{Type: tea.KeyTab})
+	if form.focusIndex != resetFieldValue {
+		t.Fatalf("focus on field %d, want the value", form.focusIndex)
+	}
+	update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("back")})
+	if cmd := update(tea.KeyMsg{Type: tea.KeyEnter}); cmd != nil || !strings.Contains(form.View(), `invalid shift "back"`) {
+		t.Fatalf("invalid shift was not reported:\n%s", form.View())
+	}
+
+	form.value.SetValue("-1")
+	plan, ok := update(tea.KeyMsg{Type: tea.KeyEnter})().(OffsetResetPlanMsg)
+	if !ok || plan.Reset.Strategy != core.ResetShiftBy || plan.Reset.Shift != -1 {
+		t.Fatalf("enter did not plan a shift by -1: %+v", plan)
+	}
+	planned := PlanOffsetResetCmd(ctx, app, plan.GroupID, plan.Reset)().(OffsetResetPlannedMsg)
+	form.SetPlan(planned.Changes, planned.Err)
+	if !form.planned || !strings.Contains(form.View(), "-1") || !strings.Contains(form.View(), "to commit") {
+		t.Fatalf("dry run does not offer to commit:\n%s", form.View())
+	}
+
+	confirmed, ok := update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("y")})().(OffsetResetConfirmedMsg)
+	if !ok {
+		t.Fatalf("confirming the dry run did not return OffsetResetConfirmedMsg")
+	}
+	reset, ok := ApplyOffsetResetCmd(ctx, app, confirmed.GroupID, confirmed.Changes)().(OffsetsResetMsg)
+	if !ok || reset.Group.Offsets[0].CommittedOffset != before-1 {
+		t.Errorf("ApplyOffsetResetCmd() = %+v, want offsets shifted by -1", reset)
+	}
+
+	// The dry run of a group with active members cannot be confirmed
+	stable := LoadGroupDetailsCmd(ctx, app, "order-service")().(GroupDetailsLoadedMsg)
+	form = NewOffsetResetForm(160, 50, stable.Group)
+	plan = update(tea.KeyMsg{Type: tea.KeyEnter})().(OffsetResetPlanMsg)
+	planned = PlanOffsetResetCmd(ctx, app, plan.GroupID, plan.Reset)().(OffsetResetPlannedMsg)
+	form.SetPlan(planned.Changes, planned.Err)
+	if cmd := update(tea.KeyMsg{Type: tea.KeyEnter}); cmd != nil || !strings.Contains(form.View(), "Stop its consumers") {
+		t.Errorf("dry run of a Stable group can be confirmed:\n%s", form.View())
+	}
+}
+
+func TestParseDatetime(t *testing.T) {
+	for _, s := range []string{"2024-05-01T12:30:00Z", "2024-05-01T12:30:00", "2024-05-01 12:30", "2024-05-01"} {
+		if _, err := parseDatetime(s); err != nil {
+			t.Errorf("parseDatetime(%q) error = %v", s, err)
+		}
+	}
+	if _, err := parseDatetime("yesterday"); err == nil {
+		t.Error("parseDatetime(yesterday) succeeded")
+	}
+}
+
+func TestGroupBulkDelete(t *testing.T) {
+	app := newDemoApp(t)
+	ctx := context.Background()
+
+	list := newGroupList(160, 40)
+	list.SetGroups(LoadGroupsCmd(ctx, app)().(GroupsLoadedMsg).Groups)
+
+	// Select order-service with space and confirm deleting it with the reconciler
+	list = list.Update(tea.KeyMsg{Type: tea.KeySpace, Runes: []rune(" ")})
+	list.Select(append(list.SelectedIDs(), "payments-reconciler"))
+	groupIDs := list.SelectedIDs()
+	if len(groupIDs) != 2 || !strings.Contains(list.View(), "✓") {
+		t.Fatalf("selected %v, want order-service and payments-reconciler", groupIDs)
+	}
+	if summary := deleteGroupsSummary(list.groups, groupIDs); !strings.Contains(summary, "Stable, will be refused") {
+		t.Errorf("delete summary does not flag the Stable group:\n%s", summary)
+	}
+
+	p := newPrompt(160, "Delete groups?", "", "groups", func(string) tea.Msg {
+		return GroupsDeleteConfirmedMsg{GroupIDs: groupIDs}
+	})
+	_, cmd := p.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("y")})
+	confirmed, ok := cmd().(GroupsDeleteConfirmedMsg)
+	if !ok {
+		t.Fatalf("confirming the prompt did not return GroupsDeleteConfirmedMsg")
+	}
+
+	deleted, ok := DeleteGroupsCmd(ctx, app, confirmed.GroupIDs)().(GroupsDeletedMsg)
+	if !ok {
+		t.Fatalf("DeleteGroupsCmd() did not return GroupsDeletedMsg")
+	}
+	if status := deleteGroupsStatus(deleted.Results); !strings.HasPrefix(status, "Deleted 1 of 2 groups, 1 failed") {
+		t.Errorf("status = %q", status)
+	}
+	list.SetGroups(deleted.Groups)
+	if len(list.selected) != 1 {
+		t.Errorf("selection after delete = %v, want only the refused group", list.selected)
+	}
+}
+
+func TestMessageBrowser(t *testing.T) {
+	app := newDemoApp(t)
+	ctx := context.Background()
+
+	form := NewBrowseForm(160, "orders", 6, 50)
+	if len(form.partition.options) != 7 {
+		t.Fatalf("partition options = %v, want all and 0-5", form.partition.options)
+	}
+	keys := []tea.KeyMsg{
+		{Type: tea.KeyRight},                     // partition 0
+		{Type: tea.KeyTab}, {Type: tea.KeyRight}, // start at latest
+		{Type: tea.KeyTab}, {Type: tea.KeyRunes, Runes: []rune("2")},
+	}
+	for _, key := range keys {
+		form, _ = form.Update(key)
+	}
+	_, cmd := form.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	requested, ok := cmd().(BrowseRequestedMsg)
+	if want := (core.BrowseRequest{Topic: "orders", Partition: 0, From: core.FromLatest, Offset: 2}); !ok || requested.Request != want {
+		t.Fatalf("browse request = %+v, want %+v", requested.Request, want)
+	}
+
+	loaded, ok := BrowseMessagesCmd(ctx, app, requested.Request, 50)().(MessagesLoadedMsg)
+	if !ok {
+		t.Fatalf("BrowseMessagesCmd() did not return MessagesLoadedMsg")
+	}
+	if len(loaded.Page.Messages) != 2 || !loaded.Page.HasPrev() {
+		t.Errorf("last 2 records of orders/0 = %+v", loaded.Page.Messages)
+	}
+	table, starts := messageTable(loaded.Page, 200, recordFormat{}, 1)
+	last := loaded.Page.Messages[1]
+	for _, want := range []string{"Offset", "Headers", fmt.Sprint(last.Offset), last.Timestamp.Format(timestampLayout)} {
+		if !strings.Contains(table, want) {
+			t.Errorf("message table does not contain %q:\n%s", want, table)
+		}
+	}
+	if lines := strings.Split(table, "\n"); len(starts) != 2 || starts[1] != 2 || !strings.HasPrefix(lines[2], "›") {
+		t.Errorf("record under the cursor starts at %v of:\n%s", starts, table)
+	}
+
+	if got := displayBytes([]byte("a\nb")); got != `a\nb` {
+		t.Errorf("displayBytes(text) = %q", got)
+	}
+	if got := displayBytes([]byte{0xff, 0x00}); got != "0xff00" {
+		t.Errorf("displayBytes(binary) = %q", got)
+	}
+	if got := truncate("abcdef", 4); got != "abc…" {
+		t.Errorf("truncate() = %q", got)
+	}
+}
+
+func TestMessageTailPauseKeepsRecords(t *testing.T) {
+	app := newDemoApp(t)
+	ctx := context.Background()
+	produce := func(value string) {
+		if _, err := app.ProduceMessage(ctx, "orders", kafka.Message{Partition: 0, Value: []byte(value)}); err != nil {
+			t.Fatalf("ProduceMessage() error = %v", err)
+		}
+	}
+
+	started, ok := StartTailCmd(ctx, app, "orders")().(TailStartedMsg)
+	if !ok {
+		t.Fatalf("StartTailCmd() did not return TailStartedMsg")
+	}
+	tail := newMessageTail(160, 40, started.Tail, 2, 10, recordFormat{})
+	defer tail.Stop()
+
+	produce("first")
+	next := tail.Polled(tail.poll()().(TailPolledMsg))
+	// A poll that found records is followed by another one, which finds none
+	tail.Polled(next().(TailPolledMsg))
+	if tail.ring.Len() != 1 || !strings.Contains(tail.View(), "first") {
+		t.Fatalf("tail after the first poll:\n%s", tail.View())
+	}
+
+	// Records produced while paused are read on resume
+	tail.TogglePause()
+	if cmd := tail.poll(); cmd != nil || !strings.Contains(tail.View(), "PAUSED") {
+		t.Errorf("paused tail still polls")
+	}
+	produce("second")
+	produce("third")
+	tail.Polled(tail.TogglePause()().(TailPolledMsg))
+
+	// The buffer of 2 keeps the newest records
+	view := tail.View()
+	if strings.Contains(view, "first") || !strings.Contains(view, "third") || !strings.Contains(view, "1 oldest dropped") {
+		t.Errorf("tail after resuming:\n%s", view)
+	}
+}
+
+func TestRecordFormat(t *testing.T) {
+	rules := []config.SerdeRule{{Topic: "metrics.*", Value: "int32"}}
+	format := newRecordFormat(serde.NewRegistry(), rules, "orders")
+	messages := []kafka.Message{
+		{Offset: 1, Key: []byte("k1"), Value: []byte(`{"id": 1}`)},
+		{Offset: 2, Key: nil, Value: []byte{0xff, 0x01}},
+	}
+
+	// Auto-detected JSON is joined on one line, undecodable bytes stay raw
+	table, _ := messageLines(messages, false, 200, format, -1)
+	for _, want := range []string{`{ "id": 1 }`, "k1", "null", "00000000  ff 01"} {
+		if !strings.Contains(table, want) {
+			t.Errorf("table does not contain %q:\n%s", want, table)
+		}
+	}
+
+	format.NextValue() // string
+	format.NextValue() // json
+	if format.value != serde.FormatJSON {
+		t.Fatalf("value format = %s, want json", format.value)
+	}
+	format.ToggleFull()
+	full, _ := messageLines(messages, false, 200, format, -1)
+	for _, want := range []string{"Value (json):\n  {\n    \"id\": 1\n  }", "Key (auto, detected string):", "0xff01"} {
+		if !strings.Contains(full, want) {
+			t.Errorf("full records do not contain %q:\n%s", want, full)
+		}
+	}
+
+	if metrics := newRecordFormat(nil, rules, "metrics.cpu"); metrics.key != serde.FormatAuto || metrics.value != serde.FormatInt32 {
+		t.Errorf("formats of metrics.cpu = %s", metrics)
+	}
+}
+
+func TestSchemaRegistryViews(t *testing.T) {
+	srv := registrytest.NewServer()
+	defer srv.Close()
+	srv.Register("orders-value", "AVRO", `{"type":"record","name":"Order","fields":[{"name":"id","type":"string"}]}`)
+	srv.Register("orders-value", "AVRO", `{"type":"record","name":"Order","fields":[{"name":"id","type":"string"},{"name":"total","type":"long"}]}`)
+	srv.Check = func(subject, level, candidate string) []string {
+		if !strings.Contains(candidate, `"total"`) {
+			return []string{"READER_FIELD_MISSING_DEFAULT_VALUE: total"}
+		}
+		return nil
+	}
+
+	app := newDemoApp(t)
+	app.SchemaRegistry, _ = schemaregistry.NewClient(config.KafkaClusterConfig{SchemaRegistryURL: srv.URL})
+	ctx := context.Background()
+
+	subjects, ok := LoadSubjectsCmd(ctx, app)().(SubjectsLoadedMsg)
+	if !ok || len(subjects.Items) != 1 || subjects.Items[0].(Item).Description() != "value schema of orders" {
+		t.Fatalf("LoadSubjectsCmd() = %+v", subjects)
+	}
+
+	// The latest version is shown; marking it as base and moving up diffs the versions
+	loaded := LoadSubjectCmd(ctx, app, "orders-value")().(SubjectLoadedMsg)
+	panes := newSubjectPanes(160, 50)
+	panes.SetDetails(loaded.Details)
+	if view := panes.View(); !strings.Contains(view, "compatibility: BACKWARD") || !strings.Contains(view, `"name": "total"`) {
+		t.Errorf("subject view does not show the level and latest schema:\n%s", view)
+	}
+	panes, _ = panes.Update(tea.KeyMsg{Type: tea.KeySpace, Runes: []rune(" ")})
+	panes, _ = panes.Update(tea.KeyMsg{Type: tea.KeyUp})
+	if view := panes.View(); !strings.Contains(view, "Changes from version 2 to version 1") || !strings.Contains(view, "-       \"name\": \"total\",") {
+		t.Errorf("subject view does not diff the versions:\n%s", view)
+	}
+
+	// Testing a schema without the new field reports why it is incompatible
+	latest, _ := panes.Latest()
+	form := NewSchemaTestForm(160, 50, "orders-value", latest)
+	_, cmd := form.Update(tea.KeyMsg{Type: tea.KeyCtrlS})
+	req := cmd().(SchemaTestRequestedMsg)
+	if tested := TestSchemaCompatibilityCmd(ctx, app, req.Subject, req.SchemaType, req.Schema)().(SchemaCompatibilityTestedMsg); tested.Err != nil || !tested.Result.Compatible {
+		t.Errorf("latest schema tested %+v, want compatible", tested)
+	}
+	form.schema.SetValue(core.FormatSchema(loaded.Details.Versions[0]))
+	tested := TestSchemaCompatibilityCmd(ctx, app, "orders-value", schemaregistry.TypeAvro, form.schema.Value())().(SchemaCompatibilityTestedMsg)
+	form.SetResult(tested.Result, tested.Err)
+	if view := form.View(); !strings.Contains(view, "Incompatible") || !strings.Contains(view, "READER_FIELD_MISSING_DEFAULT_VALUE") {
+		t.Errorf("schema test does not report the incompatibility:\n%s", view)
+	}
+
+	set := SetSubjectCompatibilityCmd(ctx, app, "orders-value", "NONE")().(SubjectCompatibilitySetMsg)
+	if set.Details.Compatibility != "NONE" || set.Details.Inherited {
+		t.Errorf("compatibility after set = %s (inherited %v), want NONE", set.Details.Compatibility, set.Details.Inherited)
+	}
+
+	// Hard-deleting both versions leaves no subjects
+	p := newPrompt(160, "Delete version 1?", deleteVersionBody("orders-value", 1), "subject", func(mode string) tea.Msg {
+		return SchemaVersionDeleteConfirmedMsg{Subject: "orders-value", Version: 1, Permanent: mode == "hard"}
+	}).WithChoice([]string{"soft", "hard"})
+	p, _ = p.Update(tea.KeyMsg{Type: tea.KeyRight})
+	_, cmd = p.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	confirmed := cmd().(SchemaVersionDeleteConfirmedMsg)
+	deleted := DeleteSchemaVersionCmd(ctx, app, confirmed.Subject, confirmed.Version, confirmed.Permanent)().(SchemaVersionDeletedMsg)
+	if !deleted.Permanent || deleted.Details == nil || len(deleted.Details.Versions) != 1 {
+		t.Fatalf("DeleteSchemaVersionCmd() = %+v, want one version left", deleted)
+	}
+	deleted = DeleteSchemaVersionCmd(ctx, app, "orders-value", 2, true)().(SchemaVersionDeletedMsg)
+	if deleted.Details != nil || len(deleted.Subjects) != 0 {
+		t.Errorf("DeleteSchemaVersionCmd() of the last version = %+v, want no subjects", deleted)
+	}
+}
+
+func TestProduceForms(t *testing.T) {
+	app := newDemoApp(t)
+	ctx := context.Background()
+
+	// Type a key, tab to the value, pick partition 2 and produce
+	form := NewProduceForm(120, 60, "audit-log", 1)
+	form, _ = form.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("k1")})
+	form, _ = form.Update(tea.KeyMsg{Type: tea.KeyTab})
+	form, _ = form.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(`{"event":"login"}`)})
+	form.headers.SetValue("broken")
+	_, cmd := form.Update(tea.KeyMsg{Type: tea.KeyCtrlS})
+	if cmd != nil {
+		t.Fatalf("form with invalid headers produced %+v", cmd())
+	}
+	form.headers.SetValue("source=test")
+	_, cmd = form.Update(tea.KeyMsg{Type: tea.KeyCtrlS})
+	req := cmd().(ProduceRequestedMsg).Request
+	if req.Key != "k1" || req.Partition != -1 || len(req.Headers) != 1 || req.Options.Acks != kafka.AcksAll {
+		t.Fatalf("produce request = %+v", req)
+	}
+	produced := ProduceMessageCmd(ctx, app, req)().(MessageProducedMsg)
+	form.SetResult(produced.Message, produced.Err)
+	if view := form.View(); !strings.Contains(view, "Produced to audit-log/0 at offset") {
+		t.Errorf("produce form does not show where the record went:\n%s", view)
+	}
+	req.Partition = 5
+	produced = ProduceMessageCmd(ctx, app, req)().(MessageProducedMsg)
+	form.SetResult(produced.Message, produced.Err)
+	if produced.Err == nil || !strings.Contains(form.View(), "partition") {
+		t.Errorf("produce to a missing partition = %+v", produced)
+	}
+
+	// Run a bulk produce of a file with one bad line to its end
+	path := t.TempDir() + "/records.jsonl"
+	if err := os.WriteFile(path, []byte("{\"key\":\"a\",\"value\":\"1\"}\n{oops\n{\"value\":\"3\"}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	bulk := NewBulkProduceForm(120, "audit-log")
+	bulk.path.SetValue(path)
+	bulk.rate.SetValue("-1")
+	if _, cmd := bulk.Update(tea.KeyMsg{Type: tea.KeyEnter}); cmd != nil {
+		t.Fatalf("bulk form with a negative rate started %+v", cmd())
+	}
+	bulk.rate.SetValue("")
+	_, cmd = bulk.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	run := startBulkRun(app, cmd().(BulkProduceRequestedMsg))
+	bulk.Start(run)
+	followToEnd(run, bulk.SetProgress, bulk.SetResult)
+	if cmd := followRun(nil, RunMsg[core.BulkProgress, bulkResult]{Run: run, Done: true}, bulk.SetProgress, func(bulkResult) {
+		t.Errorf("the result of a run of a closed form was shown")
+	}); cmd != nil {
+		t.Errorf("a run of a closed form is still followed")
+	}
+	if view := bulk.View(); !strings.Contains(view, "Done with failures") || !strings.Contains(view, "sent 2") || !strings.Contains(view, "line 2:") {
+		t.Errorf("bulk form does not report the run:\n%s", view)
+	}
+}
+
+func TestMessageSearch(t *testing.T) {
+	app := newDemoApp(t)
+
+	form := NewSearchForm(120, "orders", 6)
+	form.filter.SetValue(`$.customer_id == "customer-03" AND`)
+	if _, cmd := form.Update(tea.KeyMsg{Type: tea.KeyEnter}); cmd != nil {
+		t.Fatalf("search form with an incomplete filter submitted %+v", cmd())
+	}
+	form.filter.SetValue(`$.customer_id == "customer-03" AND $.status == paid`)
+	form, _ = form.Update(tea.KeyMsg{Type: tea.KeyRight}) // partition 0
+	form, _ = form.Update(tea.KeyMsg{Type: tea.KeyLeft})  // back to all
+	_, cmd := form.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	req := cmd().(SearchRequestedMsg).Request
+	if req.Partition != core.AllPartitions || req.ToOffset != -1 || req.Filter == nil {
+		t.Fatalf("search request = %+v", req)
+	}
+
+	search := startMessageSearch(120, 40, app, req, newRecordFormat(serde.NewRegistry(), nil, "orders"))
+	followToEnd(search.run, search.SetProgress, search.SetResult)
+	view := search.View()
+	if !strings.Contains(view, "100%") || !strings.Contains(view, "2 matches") ||
+		!strings.Contains(view, "order-1037") || !strings.Contains(view, "order-1105") {
+		t.Errorf("search view does not show the two matches:\n%s", view)
+	}
+	if cmd := search.Update(tea.KeyMsg{Type: tea.KeyEsc}); cmd == nil {
+		t.Errorf("esc on the results does not return to the form")
+	} else if _, ok := cmd().(SearchClosedMsg); !ok {
+		t.Errorf("esc on the results sent %T", cmd())
+	}
+}
+
+func TestExportForm(t *testing.T) {
+	app := newDemoApp(t)
+	dir := t.TempDir()
+	shown := []kafka.Message{{Topic: "orders", Key: []byte("order-1"), Value: []byte(`{"n":1}`)}}
+	req := core.SearchRequest{Topic: "orders", Partition: core.AllPartitions, ToOffset: -1, Limit: 10}
+
+	export := func(form ExportForm) (done exportResult) {
+		t.Helper()
+		_, cmd := form.Update(tea.KeyMsg{Type: tea.KeyEnter})
+		followToEnd(startExportRun(app, cmd().(ExportRequestedMsg)), nil, func(r exportResult) { done = r })
+		return done
+	}
+
+	form := NewExportForm(120, "orders", shown, req, "every match")
+	if form.path.Placeholder != "orders.jsonl" {
+		t.Errorf("default file name = %q", form.path.Placeholder)
+	}
+	form.path.SetValue(filepath.Join(dir, "shown.jsonl"))
+	done := export(form)
+	data, err := os.ReadFile(done.Path)
+	if err != nil || done.Err != nil || done.Records != 1 || !strings.Contains(string(data), `"key":"order-1"`) {
+		t.Fatalf("export of the shown records = %+v, %q, %v", done, data, err)
+	}
+	form.SetResult(done)
+	if view := form.View(); !strings.Contains(view, "Done") || !strings.Contains(view, "1 records") {
+		t.Errorf("export view does not show the result:\n%s", view)
+	}
+	if done := export(form); done.Err == nil {
+		t.Errorf("export over an existing file did not fail")
+	}
+
+	// The whole range, without the limit of the search
+	form, _ = form.Update(tea.KeyMsg{Type: tea.KeyRight})    // scope
+	form, _ = form.Update(tea.KeyMsg{Type: tea.KeyShiftTab}) // compression
+	form, _ = form.Update(tea.KeyMsg{Type: tea.KeyRight})    // gzip
+	if form.path.Placeholder != "orders.jsonl.gz" {
+		t.Errorf("default file name with gzip = %q", form.path.Placeholder)
+	}
+	form.path.SetValue(filepath.Join(dir, "range.jsonl.gz"))
+	done = export(form)
+	if done.Err != nil || done.Records <= 10 || done.Bytes == 0 {
+		t.Errorf("export of the range = %+v", done)
+	}
+}
+
+func TestCopyForm(t *testing.T) {
+	app := newDemoApp(t)
+	if err := app.CreateTopic(context.Background(), "orders-replay", 6, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	form := NewCopyForm(120, "demo", []string{"demo"}, "orders", 6)
+	form.topic.SetValue("")
+	if _, cmd := form.Update(tea.KeyMsg{Type: tea.KeyEnter}); cmd != nil {
+		t.Fatalf("copy form without a target topic submitted %+v", cmd())
+	}
+	form.topic.SetValue("orders-replay")
+	form.source.filter.SetValue(`$.status == paid`)
+	form.setHeaders.SetValue("replay=1")
+	for i := 0; i < searchFields+3; i++ {
+		form, _ = form.Update(tea.KeyMsg{Type: tea.KeyTab})
+	}
+	form, _ = form.Update(tea.KeyMsg{Type: tea.KeyRight}) // timestamps now
+	_, cmd := form.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	req := cmd().(CopyRequestedMsg).Request
+	if req.TargetCluster != "" || req.TargetTopic != "orders-replay" || req.Timestamps != core.TimestampNow ||
+		req.Source.Filter == nil || req.Source.Limit != 0 || len(req.SetHeaders) != 1 {
+		t.Fatalf("copy request = %+v", req)
+	}
+
+	var done copyResult
+	followToEnd(startCopyRun(app, req), nil, func(r copyResult) { done = r })
+	form.SetResult(done)
+	if done.Err != nil || done.Progress.Matches == 0 {
+		t.Fatalf("copy = %+v", done)
+	}
+	offsets, err := app.ListOffsets(context.Background(), "orders-replay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var copied int64
+	for _, o := range offsets {
+		copied += o.Latest
+	}
+	if copied != int64(done.Progress.Matches) {
+		t.Errorf("copied %d records, reported %d", copied, done.Progress.Matches)
+	}
+	if view := form.View(); !strings.Contains(view, fmt.Sprintf("%d records copied to orders-replay", copied)) {
+		t.Errorf("copy view does not show the result:\n%s", view)
+	}
+}
+
+func TestImportForm(t *testing.T) {
+	app := newDemoApp(t)
+	ctx := context.Background()
+	if err := app.CreateTopic(ctx, "orders-restored", 6, 1); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "orders.bin.zst")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := core.NewExportWriter(file, "orders", core.ExportBinary, core.ExportZstd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exported, err := app.ExportMessages(ctx, core.SearchRequest{Topic: "orders", Partition: core.AllPartitions, ToOffset: -1}, out, nil)
+	if err != nil || out.Close() != nil || file.Close() != nil {
+		t.Fatalf("export = %+v, %v", exported, err)
+	}
+
+	form := NewImportForm(120, "demo", []string{"demo"}, "orders")
+	if _, cmd := form.Update(tea.KeyMsg{Type: tea.KeyEnter}); cmd != nil {
+		t.Fatalf("import form without a file submitted %+v", cmd())
+	}
+	form.path.SetValue(path)
+	form.topic.SetValue("orders-restored")
+	form.focusIndex = 3
+	form, _ = form.Update(tea.KeyMsg{Type: tea.KeyRight}) // original partitions
+	_, cmd := form.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	req := cmd().(ImportRequestedMsg)
+	if req.Request.TargetCluster != "" || req.Request.Topic != "orders-restored" || !req.Request.KeepPartitions || req.Request.Dedupe {
+		t.Fatalf("import request = %+v", req)
+	}
+
+	var done importResult
+	followToEnd(startImportRun(app, req), nil, func(r importResult) { done = r })
+	form.SetResult(done)
+	if done.Err != nil || done.Progress.Written != int64(exported.Matches) {
+		t.Fatalf("import = %+v, exported %d", done, exported.Matches)
+	}
+	if view := form.View(); !strings.Contains(view, "Done") || !strings.Contains(view, fmt.Sprintf("written %d", exported.Matches)) {
+		t.Errorf("import view does not show the result:\n%s", view)
+	}
+
+	// With dedupe, the file is identified by its contents and read in full,
+	// and importing it again skips every record
+	req.Request.Topic = "orders"
+	req.Request.Dedupe = true
+	for _, want := range []core.ImportProgress{{Written: int64(exported.Matches)}, {Skipped: int64(exported.Matches)}} {
+		followToEnd(startImportRun(app, req), nil, func(r importResult) { done = r })
+		if done.Err != nil || done.Progress.Written != want.Written || done.Progress.Skipped != want.Skipped {
+			t.Errorf("import with dedupe = %+v, want %+v", done, want)
+		}
+	}
+}
+
+func TestTruncateForm(t *testing.T) {
+	app := newDemoApp(t)
+	ctx := context.Background()
+
+	// Truncate partition 0 up to the second record of the page, at offset 4
+	loaded := BrowseMessagesCmd(ctx, app, core.BrowseRequest{Topic: "orders", Partition: 0, From: core.FromOffset, Offset: 3}, 5)().(MessagesLoadedMsg)
+	earliest := loaded.Page.Bounds[0].Earliest
+	form := NewTruncateForm(160, 50, loaded.Page, loaded.Page.Messages[1])
+	if form.focusIndex != truncateFieldBefore || form.before.Value() != "4" {
+		t.Fatalf("truncate form focus %d before %q, want the offset 4 of the record", form.focusIndex, form.before.Value())
+	}
+	_, cmd := form.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	plan, ok := cmd().(TruncatePlanMsg)
+	if want := (core.TruncateRequest{Topic: "orders", Partition: 0, Offset: 4}); !ok || plan.Request != want {
+		t.Fatalf("truncate request = %+v, want %+v", plan.Request, want)
+	}
+	planned := PlanTruncateCmd(ctx, app, plan.Request)().(TruncatePlannedMsg)
+	form.SetPlan(planned.Plan, planned.Err)
+	if view := form.View(); !strings.Contains(view, fmt.Sprintf("delete %d records", 4-earliest)) {
+		t.Fatalf("summary does not offer to delete the records before offset 4:\n%s", view)
+	}
+
+	_, cmd = form.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("y")})
+	confirmed, ok := cmd().(TruncateConfirmedMsg)
+	if !ok || confirmed.Partition != 0 {
+		t.Fatalf("confirming the summary did not return TruncateConfirmedMsg")
+	}
+	deleted, ok := ApplyTruncateCmd(ctx, app, confirmed.Topic, confirmed.Partition, confirmed.Plan, 5)().(RecordsDeletedMsg)
+	if !ok || deleted.Deleted != 4-earliest || deleted.Page.Start[0] != 4 || deleted.Page.HasPrev() {
+		t.Fatalf("ApplyTruncateCmd() = %+v, want partition 0 starting at 4", deleted)
+	}
+
+	// Truncating all partitions takes the time of the record
+	merged := BrowseMessagesCmd(ctx, app, core.BrowseRequest{Topic: "orders", Partition: core.AllPartitions, From: core.FromEarliest}, 5)().(MessagesLoadedMsg)
+	form = NewTruncateForm(160, 50, merged.Page, merged.Page.Messages[0])
+	at := merged.Page.Messages[0].Timestamp.Local().Format(timestampLayout)
+	if form.scope.Value() != truncateScopeAll || strings.Contains(form.View(), "Partition:") || form.before.Value() != at {
+		t.Fatalf("truncate form of all partitions:\n%s", form.View())
+	}
+	form.focusIndex = truncateFieldBefore
+	form.before.SetValue("yesterday")
+	if form, cmd = form.Update(tea.KeyMsg{Type: tea.KeyEnter}); cmd != nil || !strings.Contains(form.View(), "invalid datetime") {
+		t.Fatalf("invalid time was not reported:\n%s", form.View())
+	}
+	form.before.SetValue(at)
+	_, cmd = form.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	plan = cmd().(TruncatePlanMsg)
+	planned = PlanTruncateCmd(ctx, app, plan.Request)().(TruncatePlannedMsg)
+	if planned.Err != nil || len(planned.Plan) != 6 || truncateTotal(planned.Plan) != 0 {
+		t.Errorf("truncating before the first record = %+v, %v, want nothing to delete", planned.Plan, planned.Err)
+	}
+	form.SetPlan(planned.Plan, planned.Err)
+	if _, cmd := form.Update(tea.KeyMsg{Type: tea.KeyEnter}); cmd != nil || !strings.Contains(form.View(), "Nothing to delete") {
+		t.Errorf("a summary without records to delete can be confirmed:\n%s", form.View())
+	}
+}
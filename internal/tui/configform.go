@@ -0,0 +1,325 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cfk-dev/cfk/internal/kafka"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	errorTextStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+	oldValueStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+	newValueStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("42"))
+)
+
+// configField is one editable config of the config form
+type configField struct {
+	entry kafka.ConfigEntry // current value and where it comes from
+	def   kafka.ConfigDef
+	known bool // def is set
+	input textinput.Model
+	err   error
+}
+
+// ConfigForm edits the topic-level configs of a topic. Submitting it shows
+// a before/after diff that has to be confirmed before the changes are sent.
+type ConfigForm struct {
+	topicName  string
+	fields     []configField
+	focusIndex int
+	offset     int // first visible field
+	confirming bool
+	changes    []kafka.ConfigChange
+	message    string
+	width      int
+	height     int
+}
+
+// NewConfigForm creates a config form holding the current configs of a topic.
+// It lists every known topic config and any other override of the topic.
+func NewConfigForm(width, height int, details *kafka.TopicDetails) ConfigForm {
+	current := make(map[string]kafka.ConfigEntry)
+	for _, entry := range details.Configs {
+		current[entry.Name] = entry
+	}
+
+	var fields []configField
+	for _, def := range kafka.TopicConfigDefs {
+		entry, ok := current[def.Name]
+		if !ok {
+			entry = kafka.ConfigEntry{Name: def.Name, Value: def.Default, Source: kafka.ConfigSourceDefault}
+		}
+		if entry.ReadOnly || entry.Sensitive {
+			continue
+		}
+		fields = append(fields, configField{entry: entry, def: def, known: true})
+	}
+	for _, entry := range details.Configs {
+		if _, known := kafka.TopicConfigDef(entry.Name); !known && entry.Source == kafka.ConfigSourceDynamicTopic && !entry.ReadOnly && !entry.Sensitive {
+			fields = append(fields, configField{entry: entry})
+		}
+	}
+
+	for i := range fields {
+		input := textinput.New()
+		input.Prompt = "› "
+		input.Width = 30
+		input.PromptStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("205"))
+		input.TextStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("252"))
+		input.SetValue(fields[i].entry.Value)
+		fields[i].input = input
+	}
+	if len(fields) > 0 {
+		fields[0].input.Focus()
+	}
+
+	return ConfigForm{
+		topicName: details.Name,
+		fields:    fields,
+		width:     width,
+		height:    height,
+	}
+}
+
+// Init initializes the form
+func (f ConfigForm) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+// SetSize resizes the form
+func (f *ConfigForm) SetSize(width, height int) {
+	f.width = width
+	f.height = height
+	f.scroll()
+}
+
+// visibleFields returns how many fields fit on the screen
+func (f ConfigForm) visibleFields() int {
+	n := (f.height - 16) / 2
+	if n < 3 {
+		n = 3
+	}
+	return n
+}
+
+// scroll keeps the focused field visible
+func (f *ConfigForm) scroll() {
+	visible := f.visibleFields()
+	if f.focusIndex < f.offset {
+		f.offset = f.focusIndex
+	}
+	if f.focusIndex >= f.offset+visible {
+		f.offset = f.focusIndex - visible + 1
+	}
+}
+
+// Update handles form events
+func (f ConfigForm) Update(msg tea.Msg) (ConfigForm, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		if len(f.fields) == 0 {
+			return f, nil
+		}
+		var cmd tea.Cmd
+		f.fields[f.focusIndex].input, cmd = f.fields[f.focusIndex].input.Update(msg)
+		return f, cmd
+	}
+
+	if f.confirming {
+		switch keyMsg.String() {
+		case "enter", "y":
+			topicName, changes := f.topicName, f.changes
+			return f, func() tea.Msg {
+				return ConfigChangesSubmittedMsg{Topic: topicName, Changes: changes}
+			}
+		case "esc", "n":
+			// Back to editing
+			f.confirming = false
+		}
+		return f, nil
+	}
+
+	f.message = ""
+	switch keyMsg.String() {
+	case "esc":
+		return f, func() tea.Msg { return ConfigFormCancelledMsg{} }
+	case "tab", "down", "shift+tab", "up":
+		if len(f.fields) == 0 {
+			return f, nil
+		}
+		f.fields[f.focusIndex].input.Blur()
+		if keyMsg.String() == "tab" || keyMsg.String() == "down" {
+			f.focusIndex = (f.focusIndex + 1) % len(f.fields)
+		} else {
+			f.focusIndex = (f.focusIndex - 1 + len(f.fields)) % len(f.fields)
+		}
+		f.scroll()
+		return f, f.fields[f.focusIndex].input.Focus()
+	case "enter":
+		// Review the changes
+		changes, err := f.Changes()
+		if err != nil {
+			f.message = err.Error()
+			return f, nil
+		}
+		if len(changes) == 0 {
+			f.message = "No changes to apply"
+			return f, nil
+		}
+		f.changes = changes
+		f.confirming = true
+		return f, nil
+	}
+
+	if len(f.fields) == 0 {
+		return f, nil
+	}
+	field := &f.fields[f.focusIndex]
+	var cmd tea.Cmd
+	field.input, cmd = field.input.Update(msg)
+	field.err = field.validate()
+	return f, cmd
+}
+
+// validate type-checks the edited value of a field
+func (field configField) validate() error {
+	value := strings.TrimSpace(field.input.Value())
+	if value == "" || !field.known {
+		return nil
+	}
+	return field.def.Validate(value)
+}
+
+// Changes returns the edits as config changes. A value that is cleared
+// removes the topic's override, returning the config to its default.
+func (f ConfigForm) Changes() ([]kafka.ConfigChange, error) {
+	var changes []kafka.ConfigChange
+	for _, field := range f.fields {
+		value := strings.TrimSpace(field.input.Value())
+		switch {
+		case value == "":
+			if field.entry.Source == kafka.ConfigSourceDynamicTopic {
+				changes = append(changes, kafka.ConfigChange{Name: field.entry.Name, Delete: true})
+			}
+		case value != field.entry.Value:
+			if err := field.validate(); err != nil {
+				return nil, err
+			}
+			changes = append(changes, kafka.ConfigChange{Name: field.entry.Name, Value: value})
+		}
+	}
+	return changes, nil
+}
+
+// View renders the form, or the diff while confirming
+func (f ConfigForm) View() string {
+	formStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("240")).
+		Padding(1, 2).
+		Width(f.width - 4)
+
+	titleStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("205")).
+		Bold(true).
+		MarginBottom(1)
+
+	if f.confirming {
+		return formStyle.Render(
+			titleStyle.Render("Apply config changes to "+f.topicName+"?") + "\n" +
+				f.diffView() +
+				"\n\nPress 'enter' or 'y' to apply, 'esc' or 'n' to keep editing",
+		)
+	}
+
+	labelStyle := lipgloss.NewStyle().Width(42)
+	var rows []string
+	end := f.offset + f.visibleFields()
+	if end > len(f.fields) {
+		end = len(f.fields)
+	}
+	for i := f.offset; i < end; i++ {
+		field := f.fields[i]
+		row := labelStyle.Render(field.entry.Name) + " " + field.input.View() + " " +
+			dimStyle.Render(kafka.ConfigSourceName(field.entry.Source))
+		if field.err != nil {
+			row += "\n" + strings.Repeat(" ", 43) + errorTextStyle.Render(field.err.Error())
+		} else {
+			row += "\n"
+		}
+		rows = append(rows, row)
+	}
+
+	view := titleStyle.Render("Edit configs of "+f.topicName) + "\n" + strings.Join(rows, "\n")
+	if len(f.fields) > 0 {
+		view += "\n" + f.fieldHelp(f.fields[f.focusIndex])
+	}
+	if f.message != "" {
+		view += "\n\n" + errorTextStyle.Render(f.message)
+	}
+	view += "\n\nUse tab/shift+tab to navigate, clear a value to reset it to its default, enter to review changes, esc to cancel"
+
+	return formStyle.Render(view)
+}
+
+// fieldHelp describes the type, default and allowed values of a config
+func (f ConfigForm) fieldHelp(field configField) string {
+	if !field.known {
+		return dimStyle.Render("No definition known for this config, the broker validates it")
+	}
+
+	def := field.def
+	help := fmt.Sprintf("%s  •  type %s  •  default %q", def.Doc, def.Type, def.Default)
+	if len(def.ValidValues) > 0 {
+		help += "  •  one of " + strings.Join(def.ValidValues, ", ")
+	}
+	if def.Min != "" {
+		help += "  •  min " + def.Min
+	}
+	if def.Max != "" {
+		help += "  •  max " + def.Max
+	}
+	return dimStyle.Render(help)
+}
+
+// diffView renders the before and after values of the pending changes
+func (f ConfigForm) diffView() string {
+	width := 0
+	for _, change := range f.changes {
+		if len(change.Name) > width {
+			width = len(change.Name)
+		}
+	}
+
+	before := make(map[string]kafka.ConfigEntry, len(f.fields))
+	for _, field := range f.fields {
+		before[field.entry.Name] = field.entry
+	}
+
+	lines := make([]string, 0, len(f.changes))
+	for _, change := range f.changes {
+		after := change.Value
+		if change.Delete {
+			after = "(default)"
+			if def, ok := kafka.TopicConfigDef(change.Name); ok {
+				after = def.Default + " (default)"
+			}
+		}
+		lines = append(lines, fmt.Sprintf("%-*s  %s → %s", width, change.Name,
+			oldValueStyle.Render(before[change.Name].Value), newValueStyle.Render(after)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ConfigChangesSubmittedMsg is sent when config changes are confirmed
+type ConfigChangesSubmittedMsg struct {
+	Topic   string
+	Changes []kafka.ConfigChange
+}
+
+// ConfigFormCancelledMsg is sent when the config form is cancelled
+type ConfigFormCancelledMsg struct{}
@@ -0,0 +1,303 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/cfk-dev/cfk/internal/core"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// CopyForm copies a range of records of a topic to a topic of the same or of
+// another cluster, and follows the progress of the copy. Its first fields are
+// those of the search form and pick the source records.
+type CopyForm struct {
+	cluster    string // the connected cluster, the source
+	source     SearchForm
+	target     formChoice // cluster
+	topic      textinput.Model
+	partitions formChoice
+	timestamps formChoice
+	headers    formChoice
+	setHeaders textinput.Model
+	focusIndex int // the source fields, then 6 cluster, 7 topic, 8 partitions, 9 timestamps, 10 headers, 11 set headers
+	run        *copyRun
+	progress   core.SearchProgress
+	result     *copyResult
+	message    string
+	width      int
+}
+
+// copyFields is the number of fields of the copy form
+const copyFields = searchFields + 6
+
+// NewCopyForm creates a form copying records of a topic with a number of
+// partitions, on the connected cluster, to one of the clusters
+func NewCopyForm(width int, cluster string, clusters []string, topic string, partitions int) CopyForm {
+	source := NewSearchForm(width, topic, partitions)
+	source.limit.Placeholder = "Records to stop after (default all)"
+
+	mappings := make([]string, len(core.PartitionMappings))
+	for i, mapping := range core.PartitionMappings {
+		mappings[i] = string(mapping)
+	}
+	timestamps := make([]string, len(core.TimestampModes))
+	for i, mode := range core.TimestampModes {
+		timestamps[i] = string(mode)
+	}
+	headers := make([]string, len(core.HeaderModes))
+	for i, mode := range core.HeaderModes {
+		headers[i] = string(mode)
+	}
+
+	target := newFormInput("Topic to write to")
+	target.SetValue(topic)
+	return CopyForm{
+		cluster:    cluster,
+		source:     source,
+		target:     newFormChoice(clusters, cluster),
+		topic:      target,
+		partitions: newFormChoice(mappings, mappings[0]),
+		timestamps: newFormChoice(timestamps, timestamps[0]),
+		headers:    newFormChoice(headers, headers[0]),
+		setHeaders: newFormInput("e.g. replay=2024-05-01 (optional)"),
+		width:      width,
+	}
+}
+
+// Init initializes the form
+func (f CopyForm) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+// SetSize resizes the form
+func (f *CopyForm) SetSize(width int) {
+	f.width = width
+	f.source.SetSize(width)
+}
+
+// Start follows a run of the form's request
+func (f *CopyForm) Start(run *copyRun) {
+	f.run = run
+	f.progress = core.SearchProgress{}
+	f.result = nil
+}
+
+// SetProgress shows the progress of the copy
+func (f *CopyForm) SetProgress(progress core.SearchProgress) {
+	f.progress = progress
+}
+
+// SetResult shows the outcome of the copy, which may have ended early
+func (f *CopyForm) SetResult(result copyResult) {
+	f.run = nil
+	f.result = &result
+	f.progress = result.Progress
+}
+
+// input returns the focused text field of the target, if any
+func (f *CopyForm) input() *textinput.Model {
+	switch f.focusIndex {
+	case searchFields + 1:
+		return &f.topic
+	case searchFields + 5:
+		return &f.setHeaders
+	}
+	return nil
+}
+
+// choice returns the focused choice field of the target, if any
+func (f *CopyForm) choice() *formChoice {
+	switch f.focusIndex {
+	case searchFields:
+		return &f.target
+	case searchFields + 2:
+		return &f.partitions
+	case searchFields + 3:
+		return &f.timestamps
+	case searchFields + 4:
+		return &f.headers
+	}
+	return nil
+}
+
+// setFocus moves the focus to a field, of the source or of the target
+func (f *CopyForm) setFocus(index int) tea.Cmd {
+	f.focusIndex = index
+	f.topic.Blur()
+	f.setHeaders.Blur()
+	if index < searchFields {
+		return f.source.setFocus(index)
+	}
+	f.source.setFocus(-1)
+	if input := f.input(); input != nil {
+		return input.Focus()
+	}
+	return nil
+}
+
+// Update handles form events. While a copy is in progress the only keys are
+// esc and ctrl+x, which cancel it.
+func (f CopyForm) Update(msg tea.Msg) (CopyForm, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if f.run != nil {
+		if ok && (keyMsg.String() == "esc" || keyMsg.String() == "ctrl+x") {
+			f.run.cancel()
+		}
+		return f, nil
+	}
+
+	var cmd tea.Cmd
+	if ok {
+		f.message = ""
+		switch keyMsg.String() {
+		case "esc":
+			return f, func() tea.Msg { return CopyFormCancelledMsg{} }
+		case "tab", "down":
+			return f, f.setFocus((f.focusIndex + 1) % copyFields)
+		case "shift+tab", "up":
+			return f, f.setFocus((f.focusIndex - 1 + copyFields) % copyFields)
+		case "enter":
+			req, err := f.request()
+			if err != nil {
+				f.message = err.Error()
+				return f, nil
+			}
+			return f, func() tea.Msg { return CopyRequestedMsg{Request: req} }
+		}
+		if choice := f.choice(); choice != nil {
+			switch keyMsg.String() {
+			case "left":
+				choice.Prev()
+			case " ", "right":
+				choice.Next()
+			}
+			return f, nil
+		}
+	}
+
+	if f.focusIndex < searchFields {
+		f.source, cmd = f.source.Update(msg)
+	} else if input := f.input(); input != nil {
+		*input, cmd = input.Update(msg)
+	}
+	return f, cmd
+}
+
+// request builds the copy request from the form inputs
+func (f CopyForm) request() (core.CopyRequest, error) {
+	source, err := f.source.request()
+	if err != nil {
+		return core.CopyRequest{}, err
+	}
+	req := core.CopyRequest{
+		Source:      source,
+		TargetTopic: strings.TrimSpace(f.topic.Value()),
+		Partitions:  core.PartitionMapping(f.partitions.Value()),
+		Timestamps:  core.TimestampMode(f.timestamps.Value()),
+		Headers:     core.HeaderMode(f.headers.Value()),
+	}
+	if f.target.Value() != f.cluster {
+		req.TargetCluster = f.target.Value()
+	}
+	if req.TargetTopic == "" {
+		return req, fmt.Errorf("the target topic is required")
+	}
+	if req.SetHeaders, err = core.ParseHeaders(f.setHeaders.Value()); err != nil {
+		return req, err
+	}
+	return req, nil
+}
+
+// View renders the form, with the progress or outcome of the copy
+func (f CopyForm) View() string {
+	formStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("240")).
+		Padding(1, 2).
+		Width(f.width - 4)
+
+	titleStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("205")).
+		Bold(true).
+		MarginBottom(1)
+
+	labelStyle := lipgloss.NewStyle().Width(20)
+	s := f.source
+	view := titleStyle.Render("Copy messages of "+s.topic+" on "+f.cluster) + "\n"
+	view += labelStyle.Render("Partition:") + " " + s.partition.View(f.focusIndex == 0) + "\n\n"
+	view += labelStyle.Render("Range:") + " " + s.rangeKind.View(f.focusIndex == 1) + "\n\n"
+	view += labelStyle.Render("From:") + " " + s.from.View() + "\n\n"
+	view += labelStyle.Render("To:") + " " + s.to.View() + "\n\n"
+	view += labelStyle.Render("Filter:") + " " + s.filter.View() + "\n\n"
+	view += labelStyle.Render("Limit:") + " " + s.limit.View() + "\n\n"
+	view += labelStyle.Render("Target cluster:") + " " + f.target.View(f.focusIndex == searchFields) + "\n\n"
+	view += labelStyle.Render("Target topic:") + " " + f.topic.View() + "\n\n"
+	view += labelStyle.Render("Partitions:") + " " + f.partitions.View(f.focusIndex == searchFields+2) + "\n\n"
+	view += labelStyle.Render("Timestamps:") + " " + f.timestamps.View(f.focusIndex == searchFields+3) + "\n\n"
+	view += labelStyle.Render("Headers:") + " " + f.headers.View(f.focusIndex == searchFields+4) + "\n\n"
+	view += labelStyle.Render("Set headers:") + " " + f.setHeaders.View() + "\n\n"
+
+	if f.message != "" {
+		view += errorTextStyle.Render(f.message) + "\n\n"
+	}
+	switch {
+	case f.run != nil:
+		view += liveStyle.Render("Copying") + "\n" + searchProgressBar(f.progress) + "\n\n"
+		view += "Press esc or ctrl+x to cancel"
+	case f.result == nil:
+		view += "Use tab/shift+tab to navigate, left/right to change options, enter to copy, esc to cancel"
+	default:
+		view += copyResultView(f.result) + "\n\n"
+		view += "Press enter to copy again, esc to go back"
+	}
+
+	return formStyle.Render(view)
+}
+
+// copyResultView describes how a copy ended
+func copyResultView(r *copyResult) string {
+	copied := fmt.Sprintf("%d records copied to %s", r.Progress.Matches, r.Target)
+	switch {
+	case errors.Is(r.Err, context.Canceled):
+		return underReplicatedStyle.Render("Cancelled") + "  " + copied
+	case r.Err != nil:
+		return errorTextStyle.Render(r.Err.Error()) + "\n" + copied
+	default:
+		return stableStyle.Render("Done") + "  " + copied
+	}
+}
+
+// copyRun is a copy running in the background; it ends with the range
+type copyRun = backgroundRun[core.SearchProgress, copyResult]
+
+// copyResult is the outcome of a copy, cancelled or not
+type copyResult struct {
+	Target   string
+	Progress core.SearchProgress
+	Err      error
+}
+
+// startCopyRun starts copying records
+func startCopyRun(app *core.App, req core.CopyRequest) *copyRun {
+	target := req.TargetTopic
+	if req.TargetCluster != "" {
+		target += " on " + req.TargetCluster
+	}
+	return startRun(func(ctx context.Context, progress func(core.SearchProgress)) copyResult {
+		p, err := app.CopyMessages(ctx, req, progress)
+		return copyResult{Target: target, Progress: p, Err: err}
+	})
+}
+
+// CopyRequestedMsg is sent to start a copy from the copy form
+type CopyRequestedMsg struct {
+	Request core.CopyRequest
+}
+
+// CopyFormCancelledMsg is sent when the copy form is closed
+type CopyFormCancelledMsg struct{}
@@ -0,0 +1,204 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cfk-dev/cfk/internal/kafka"
+	"github.com/charmbracelet/bubbles/viewport"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	// paneStyle frames the detail panes; the focused one is highlighted
+	paneStyle        = lipgloss.NewStyle().BorderStyle(lipgloss.RoundedBorder()).BorderForeground(lipgloss.Color("240"))
+	focusedPaneStyle = paneStyle.Copy().BorderForeground(lipgloss.Color("205"))
+
+	tableHeaderStyle     = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("252"))
+	underReplicatedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("214"))
+	leaderlessStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Bold(true)
+	dimStyle             = lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+)
+
+// topicDetailsPanes shows the partitions and configs of a topic in two
+// scrollable panes, one of which has the focus
+type topicDetailsPanes struct {
+	details      *kafka.TopicDetails
+	partitions   viewport.Model
+	configs      viewport.Model
+	focus        int  // 0 for partitions, 1 for configs
+	showDefaults bool // also list configs left at their default
+}
+
+// newTopicDetailsPanes creates the panes for a window size
+func newTopicDetailsPanes(width, height int) topicDetailsPanes {
+	p := topicDetailsPanes{
+		partitions: viewport.New(0, 0),
+		configs:    viewport.New(0, 0),
+	}
+	p.SetSize(width, height)
+	return p
+}
+
+// SetSize splits the window between the panes, giving most of it to the partitions
+func (p *topicDetailsPanes) SetSize(width, height int) {
+	// Leave room for the summary, help text, status line and pane borders
+	available := height - 12
+	if available < 6 {
+		available = 6
+	}
+
+	p.partitions.Width = width - 2
+	p.partitions.Height = available * 3 / 5
+	p.configs.Width = width - 2
+	p.configs.Height = available - p.partitions.Height
+}
+
+// SetDetails shows a newly loaded topic
+func (p *topicDetailsPanes) SetDetails(details *kafka.TopicDetails) {
+	p.details = details
+	p.partitions.SetContent(partitionTable(details))
+	p.partitions.GotoTop()
+	p.refreshConfigs()
+}
+
+// ToggleDefaults switches between the non-default and all configs
+func (p *topicDetailsPanes) ToggleDefaults() {
+	p.showDefaults = !p.showDefaults
+	p.refreshConfigs()
+}
+
+// refreshConfigs renders the config pane
+func (p *topicDetailsPanes) refreshConfigs() {
+	p.configs.SetContent(configTable(p.details, p.showDefaults))
+	p.configs.GotoTop()
+}
+
+// SwitchFocus moves the focus to the other pane
+func (p *topicDetailsPanes) SwitchFocus() {
+	p.focus = 1 - p.focus
+}
+
+// View renders the summary and both panes
+func (p topicDetailsPanes) View() string {
+	if p.details == nil {
+		return "Loading topic details..."
+	}
+
+	partitionStyle, configStyle := focusedPaneStyle, paneStyle
+	if p.focus == 1 {
+		partitionStyle, configStyle = paneStyle, focusedPaneStyle
+	}
+
+	configTitle := "Non-default configs"
+	if p.showDefaults {
+		configTitle = "All configs"
+	}
+
+	return topicSummary(p.details) + "\n\n" +
+		"Partitions\n" + partitionStyle.Render(p.partitions.View()) + "\n" +
+		configTitle + "\n" + configStyle.Render(p.configs.View())
+}
+
+// topicSummary describes the topic and counts its unhealthy partitions
+func topicSummary(details *kafka.TopicDetails) string {
+	var underReplicated, leaderless int
+	var messages int64
+	for _, p := range details.Partitions {
+		if p.Leaderless() {
+			leaderless++
+		} else if p.UnderReplicated() {
+			underReplicated++
+		}
+		messages += p.Messages()
+	}
+
+	summary := fmt.Sprintf("Topic: %s  •  %d partitions  •  replication factor %d  •  %d messages",
+		details.Name, len(details.Partitions), details.ReplicationFactor, messages)
+	if underReplicated > 0 {
+		summary += "  •  " + underReplicatedStyle.Render(fmt.Sprintf("%d under-replicated", underReplicated))
+	}
+	if leaderless > 0 {
+		summary += "  •  " + leaderlessStyle.Render(fmt.Sprintf("%d without leader", leaderless))
+	}
+	return summary
+}
+
+// partitionTable renders one row per partition, coloring unhealthy ones
+func partitionTable(details *kafka.TopicDetails) string {
+	const format = "%-9s %-6s %-14s %-14s %-10s %12s %12s %10s  %s"
+
+	lines := []string{tableHeaderStyle.Render(fmt.Sprintf(format,
+		"Partition", "Leader", "Replicas", "ISR", "Offline", "Earliest", "Latest", "Messages", "Status"))}
+	for _, p := range details.Partitions {
+		leader, earliest, latest, messages := "none", "-", "-", "-"
+		if !p.Leaderless() {
+			leader = fmt.Sprint(p.Leader)
+			earliest = fmt.Sprint(p.Earliest)
+			latest = fmt.Sprint(p.Latest)
+			messages = fmt.Sprint(p.Messages())
+		}
+
+		status, style := "ok", lipgloss.NewStyle()
+		switch {
+		case p.Leaderless():
+			status, style = "no leader", leaderlessStyle
+		case p.UnderReplicated():
+			status, style = "under-replicated", underReplicatedStyle
+		}
+
+		lines = append(lines, style.Render(fmt.Sprintf(format,
+			fmt.Sprint(p.Partition), leader, brokerList(p.Replicas), brokerList(p.ISR), brokerList(p.Offline),
+			earliest, latest, messages, status)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// brokerList renders broker IDs as a comma-separated list
+func brokerList(ids []int) string {
+	if len(ids) == 0 {
+		return "-"
+	}
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = fmt.Sprint(id)
+	}
+	return strings.Join(parts, ",")
+}
+
+// configTable renders topic configs with the source of their value.
+// Defaults are left out unless showDefaults is set.
+func configTable(details *kafka.TopicDetails, showDefaults bool) string {
+	if details == nil {
+		return ""
+	}
+
+	width := 0
+	var entries []kafka.ConfigEntry
+	for _, entry := range details.Configs {
+		if entry.IsDefault() && !showDefaults {
+			continue
+		}
+		entries = append(entries, entry)
+		if len(entry.Name) > width {
+			width = len(entry.Name)
+		}
+	}
+	if len(entries) == 0 {
+		return dimStyle.Render("No topic-level config overrides (press 'a' to show all configs)")
+	}
+
+	lines := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		value := entry.Value
+		if entry.Sensitive {
+			value = "******"
+		}
+		line := fmt.Sprintf("%-*s  %-30s  %s", width, entry.Name, value, kafka.ConfigSourceName(entry.Source))
+		if entry.IsDefault() {
+			line = dimStyle.Render(line)
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
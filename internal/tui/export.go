@@ -0,0 +1,315 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/cfk-dev/cfk/internal/core"
+	"github.com/cfk-dev/cfk/internal/kafka"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Export scopes: the records on screen, or every record of the range they
+// come from, read again from the cluster
+const (
+	scopeShown = "shown"
+	scopeRange = "range"
+)
+
+// ExportForm writes records of the message browser or of search results to
+// a file and follows the progress of the export
+type ExportForm struct {
+	topic       string
+	shown       []kafka.Message    // the records on screen
+	request     core.SearchRequest // the range the records come from
+	rangeLabel  string             // describes the range
+	scope       formChoice
+	path        textinput.Model
+	format      formChoice
+	compression formChoice
+	focusIndex  int // 0 scope, 1 path, 2 format, 3 compression
+	run         *exportRun
+	progress    core.SearchProgress
+	result      *exportResult
+	message     string
+	width       int
+}
+
+// exportFields is the number of fields of the export form
+const exportFields = 4
+
+// NewExportForm creates an export form of the records shown, which come from
+// the range of a request. The range label describes that range.
+func NewExportForm(width int, topic string, shown []kafka.Message, request core.SearchRequest, rangeLabel string) ExportForm {
+	formats := make([]string, len(core.ExportFormats))
+	for i, format := range core.ExportFormats {
+		formats[i] = string(format)
+	}
+	compressions := make([]string, len(core.ExportCompressions))
+	for i, compression := range core.ExportCompressions {
+		compressions[i] = string(compression)
+	}
+
+	request.Limit = 0
+	f := ExportForm{
+		topic:       topic,
+		shown:       shown,
+		request:     request,
+		rangeLabel:  rangeLabel,
+		scope:       newFormChoice([]string{scopeShown, scopeRange}, scopeShown),
+		path:        newFormInput(""),
+		format:      newFormChoice(formats, formats[0]),
+		compression: newFormChoice(compressions, compressions[0]),
+		width:       width,
+	}
+	f.updatePlaceholder()
+	return f
+}
+
+// Init initializes the form
+func (f ExportForm) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+// SetSize resizes the form
+func (f *ExportForm) SetSize(width int) {
+	f.width = width
+}
+
+// Start follows a run of the form's request
+func (f *ExportForm) Start(run *exportRun) {
+	f.run = run
+	f.progress = core.SearchProgress{}
+	f.result = nil
+}
+
+// SetProgress shows the progress of the export
+func (f *ExportForm) SetProgress(progress core.SearchProgress) {
+	f.progress = progress
+}
+
+// SetResult shows the outcome of the export, which may have ended early
+func (f *ExportForm) SetResult(result exportResult) {
+	f.run = nil
+	f.result = &result
+	f.progress = result.Progress
+}
+
+// updatePlaceholder suggests a file name for the format and compression
+func (f *ExportForm) updatePlaceholder() {
+	f.path.Placeholder = core.ExportFileName(f.topic, core.ExportFormat(f.format.Value()),
+		core.ExportCompression(f.compression.Value()))
+}
+
+// choice returns the focused choice field, if any
+func (f *ExportForm) choice() *formChoice {
+	switch f.focusIndex {
+	case 0:
+		return &f.scope
+	case 2:
+		return &f.format
+	case 3:
+		return &f.compression
+	}
+	return nil
+}
+
+// Update handles form events. While an export is in progress the only keys
+// are esc and ctrl+x, which cancel it.
+func (f ExportForm) Update(msg tea.Msg) (ExportForm, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if f.run != nil {
+		if ok && (keyMsg.String() == "esc" || keyMsg.String() == "ctrl+x") {
+			f.run.cancel()
+		}
+		return f, nil
+	}
+	if !ok {
+		var cmd tea.Cmd
+		if f.focusIndex == 1 {
+			f.path, cmd = f.path.Update(msg)
+		}
+		return f, cmd
+	}
+
+	f.message = ""
+	switch keyMsg.String() {
+	case "esc":
+		return f, func() tea.Msg { return ExportFormCancelledMsg{} }
+	case "tab", "down", "shift+tab", "up":
+		if keyMsg.String() == "tab" || keyMsg.String() == "down" {
+			f.focusIndex = (f.focusIndex + 1) % exportFields
+		} else {
+			f.focusIndex = (f.focusIndex - 1 + exportFields) % exportFields
+		}
+		f.path.Blur()
+		if f.focusIndex == 1 {
+			return f, f.path.Focus()
+		}
+		return f, nil
+	case "enter":
+		req := f.exportRequest()
+		return f, func() tea.Msg { return req }
+	}
+
+	if choice := f.choice(); choice != nil {
+		switch keyMsg.String() {
+		case "left":
+			choice.Prev()
+		case " ", "right":
+			choice.Next()
+		}
+		f.updatePlaceholder()
+		return f, nil
+	}
+	var cmd tea.Cmd
+	f.path, cmd = f.path.Update(msg)
+	return f, cmd
+}
+
+// exportRequest builds the export request from the form inputs
+func (f ExportForm) exportRequest() ExportRequestedMsg {
+	req := ExportRequestedMsg{
+		Path:        strings.TrimSpace(f.path.Value()),
+		Topic:       f.topic,
+		Format:      core.ExportFormat(f.format.Value()),
+		Compression: core.ExportCompression(f.compression.Value()),
+	}
+	if req.Path == "" {
+		req.Path = f.path.Placeholder
+	}
+	if f.scope.Value() == scopeShown {
+		req.Messages = f.shown
+	} else {
+		req.Request = &f.request
+	}
+	return req
+}
+
+// View renders the form, with the progress or outcome of the export
+func (f ExportForm) View() string {
+	formStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("240")).
+		Padding(1, 2).
+		Width(f.width - 4)
+
+	titleStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("205")).
+		Bold(true).
+		MarginBottom(1)
+
+	labelStyle := lipgloss.NewStyle().Width(20)
+	view := titleStyle.Render("Export messages of "+f.topic) + "\n"
+	view += labelStyle.Render("Records:") + " " + f.scope.View(f.focusIndex == 0) + "\n"
+	if f.scope.Value() == scopeShown {
+		view += labelStyle.Render("") + " " + dimStyle.Render(fmt.Sprintf("the %d records on screen", len(f.shown))) + "\n\n"
+	} else {
+		view += labelStyle.Render("") + " " + dimStyle.Render(f.rangeLabel+", read again from the cluster") + "\n\n"
+	}
+	view += labelStyle.Render("File:") + " " + f.path.View() + "\n\n"
+	view += labelStyle.Render("Format:") + " " + f.format.View(f.focusIndex == 2) + "\n\n"
+	view += labelStyle.Render("Compression:") + " " + f.compression.View(f.focusIndex == 3) + "\n\n"
+	view += dimStyle.Render("JSON Lines and CSV keep non-UTF-8 bytes in base64; binary is a length-prefixed dump of every byte") + "\n\n"
+
+	if f.message != "" {
+		view += errorTextStyle.Render(f.message) + "\n\n"
+	}
+	switch {
+	case f.run != nil:
+		view += liveStyle.Render("Exporting") + "\n" + searchProgressBar(f.progress) + "\n\n"
+		view += "Press esc or ctrl+x to cancel"
+	case f.result == nil:
+		view += "Use tab/shift+tab to navigate, left/right to change options, enter to export, esc to cancel"
+	default:
+		view += exportResultView(f.result) + "\n\n"
+		view += "Press enter to export again, esc to go back"
+	}
+
+	return formStyle.Render(view)
+}
+
+// exportResultView describes how an export ended
+func exportResultView(r *exportResult) string {
+	written := fmt.Sprintf("%d records, %d bytes written to %s", r.Records, r.Bytes, r.Path)
+	switch {
+	case errors.Is(r.Err, context.Canceled):
+		return underReplicatedStyle.Render("Cancelled") + "  " + written
+	case r.Err != nil && r.Records > 0:
+		return errorTextStyle.Render(r.Err.Error()) + "\n" + written
+	case r.Err != nil:
+		return errorTextStyle.Render(r.Err.Error())
+	default:
+		return stableStyle.Render("Done") + "  " + written
+	}
+}
+
+// exportRun is an export running in the background; it ends with the range
+type exportRun = backgroundRun[core.SearchProgress, exportResult]
+
+// startExportRun starts writing records to a file
+func startExportRun(app *core.App, req ExportRequestedMsg) *exportRun {
+	return startRun(func(ctx context.Context, progress func(core.SearchProgress)) exportResult {
+		return exportFile(ctx, app, req, progress)
+	})
+}
+
+// exportFile writes the records of an export request to a new file. Files
+// that exist are not overwritten; the records written before a failure or
+// cancellation are kept.
+func exportFile(ctx context.Context, app *core.App, req ExportRequestedMsg, progress func(core.SearchProgress)) exportResult {
+	result := exportResult{Path: req.Path}
+	file, err := os.OpenFile(req.Path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	defer file.Close()
+
+	out, err := core.NewExportWriter(file, req.Topic, req.Format, req.Compression)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	if req.Request != nil {
+		result.Progress, result.Err = app.ExportMessages(ctx, *req.Request, out, progress)
+	} else {
+		result.Err = core.ExportPage(out, req.Messages)
+		result.Progress = core.SearchProgress{Scanned: int64(len(req.Messages)), Total: int64(len(req.Messages))}
+	}
+	if err := out.Close(); err != nil && result.Err == nil {
+		result.Err = err
+	}
+	result.Records = out.Records()
+	result.Progress.Matches = int(result.Records)
+	result.Bytes = out.Bytes()
+	return result
+}
+
+// ExportRequestedMsg is sent to start an export: of the messages, or of the
+// range of a request when it is set
+type ExportRequestedMsg struct {
+	Path        string
+	Topic       string
+	Format      core.ExportFormat
+	Compression core.ExportCompression
+	Messages    []kafka.Message
+	Request     *core.SearchRequest
+}
+
+// exportResult is the outcome of an export, cancelled or not
+type exportResult struct {
+	Path     string
+	Progress core.SearchProgress
+	Records  int64
+	Bytes    int64
+	Err      error
+}
+
+// ExportFormCancelledMsg is sent when the export form is closed
+type ExportFormCancelledMsg struct{}
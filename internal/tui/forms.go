@@ -3,17 +3,77 @@ package tui
 import (
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 
 	"github.com/cfk-dev/cfk/internal/config"
+	"github.com/cfk-dev/cfk/internal/kafka"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
 
+// formChoice is a form field that cycles through a fixed set of options
+type formChoice struct {
+	options  []string
+	selected int
+}
+
+// newFormChoice creates a choice field with the given option preselected
+func newFormChoice(options []string, value string) formChoice {
+	choice := formChoice{options: options}
+	for i, option := range options {
+		if strings.EqualFold(option, value) {
+			choice.selected = i
+			break
+		}
+	}
+	return choice
+}
+
+// newToggleChoice creates a yes/no choice field
+func newToggleChoice(enabled bool) formChoice {
+	if enabled {
+		return newFormChoice([]string{"no", "yes"}, "yes")
+	}
+	return newFormChoice([]string{"no", "yes"}, "no")
+}
+
+// Value returns the selected option
+func (c formChoice) Value() string {
+	return c.options[c.selected]
+}
+
+// Enabled reports whether a yes/no choice is set to yes
+func (c formChoice) Enabled() bool {
+	return c.Value() == "yes"
+}
+
+// Next selects the following option, wrapping around
+func (c *formChoice) Next() {
+	c.selected = (c.selected + 1) % len(c.options)
+}
+
+// Prev selects the preceding option, wrapping around
+func (c *formChoice) Prev() {
+	c.selected = (c.selected - 1 + len(c.options)) % len(c.options)
+}
+
+// View renders the choice, highlighting it when focused
+func (c formChoice) View(focused bool) string {
+	style := lipgloss.NewStyle().Foreground(lipgloss.Color("252"))
+	prompt := lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Render("  ")
+	if focused {
+		style = style.Foreground(lipgloss.Color("205")).Bold(true)
+		prompt = lipgloss.NewStyle().Foreground(lipgloss.Color("205")).Render("› ")
+	}
+	return prompt + style.Render("< "+c.Value()+" >")
+}
+
 // ClusterForm represents a form for adding/editing a cluster
 type ClusterForm struct {
 	inputs       []textinput.Model
+	choices      []formChoice // rendered after the inputs, share the focus index
 	focusIndex   int
 	submitButton string
 	cancelButton string
@@ -39,7 +99,7 @@ func NewClusterForm(width, height int, cluster *config.KafkaClusterConfig) Clust
 	}
 
 	// Create form inputs
-	inputs := make([]textinput.Model, 4)
+	inputs := make([]textinput.Model, 8)
 
 	// Name input
 	inputs[0] = textinput.New()
@@ -80,6 +140,54 @@ func NewClusterForm(width, height int, cluster *config.KafkaClusterConfig) Clust
 	inputs[3].TextStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("252"))
 	inputs[3].SetValue(cluster.Password)
 
+	// CA file input
+	inputs[4] = textinput.New()
+	inputs[4].Placeholder = "CA bundle path (optional)"
+	inputs[4].Width = 40
+	inputs[4].Prompt = "› "
+	inputs[4].PromptStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("205"))
+	inputs[4].TextStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("252"))
+	inputs[4].SetValue(cluster.SSLCAFile)
+
+	// Client certificate input
+	inputs[5] = textinput.New()
+	inputs[5].Placeholder = "Client certificate path (optional)"
+	inputs[5].Width = 40
+	inputs[5].Prompt = "› "
+	inputs[5].PromptStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("205"))
+	inputs[5].TextStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("252"))
+	inputs[5].SetValue(cluster.SSLCertFile)
+
+	// Client key input
+	inputs[6] = textinput.New()
+	inputs[6].Placeholder = "Client key path (optional)"
+	inputs[6].Width = 40
+	inputs[6].Prompt = "› "
+	inputs[6].PromptStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("205"))
+	inputs[6].TextStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("252"))
+	inputs[6].SetValue(cluster.SSLKeyFile)
+
+	// TLS server name input
+	inputs[7] = textinput.New()
+	inputs[7].Placeholder = "TLS server name override (optional)"
+	inputs[7].Width = 40
+	inputs[7].Prompt = "› "
+	inputs[7].PromptStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("205"))
+	inputs[7].TextStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("252"))
+	inputs[7].SetValue(cluster.SSLServerName)
+
+	// Toggles and the SASL mechanism picker
+	saslType := cluster.SASLType
+	if saslType == "" {
+		saslType = kafka.SASLTypePlain
+	}
+	choices := []formChoice{
+		newToggleChoice(cluster.SSL),                   // Enable SSL
+		newToggleChoice(cluster.SSLInsecureSkipVerify), // Skip TLS verification
+		newToggleChoice(cluster.SASL),                  // Enable SASL
+		newFormChoice(kafka.SASLTypes, saslType),       // SASL mechanism
+	}
+
 	submitText := "Add"
 	if isEdit {
 		submitText = "Update"
@@ -87,6 +195,7 @@ func NewClusterForm(width, height int, cluster *config.KafkaClusterConfig) Clust
 
 	return ClusterForm{
 		inputs:       inputs,
+		choices:      choices,
 		focusIndex:   0,
 		submitButton: submitText,
 		cancelButton: "Cancel",
@@ -102,6 +211,11 @@ func (f ClusterForm) Init() tea.Cmd {
 	return textinput.Blink
 }
 
+// fieldCount returns the number of focusable fields (inputs and choices)
+func (f ClusterForm) fieldCount() int {
+	return len(f.inputs) + len(f.choices)
+}
+
 // Update handles form events
 func (f ClusterForm) Update(msg tea.Msg) (ClusterForm, tea.Cmd) {
 	var cmds []tea.Cmd
@@ -109,13 +223,24 @@ func (f ClusterForm) Update(msg tea.Msg) (ClusterForm, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
 		switch msg.String() {
+		case " ", "left", "right":
+			// Cycle the focused choice
+			if f.focusIndex >= len(f.inputs) {
+				choice := &f.choices[f.focusIndex-len(f.inputs)]
+				if msg.String() == "left" {
+					choice.Prev()
+				} else {
+					choice.Next()
+				}
+				return f, nil
+			}
 		case "tab", "shift+tab", "up", "down":
 			// Cycle focus between inputs and buttons
 			if msg.String() == "up" || msg.String() == "shift+tab" {
 				// Move focus up
 				if f.focusIndex == -1 {
-					// If we're on buttons, move to last input
-					f.focusIndex = len(f.inputs) - 1
+					// If we're on buttons, move to last field
+					f.focusIndex = f.fieldCount() - 1
 					f.buttonFocus = -1
 				} else if f.focusIndex > 0 {
 					// Move to previous input
@@ -136,8 +261,8 @@ func (f ClusterForm) Update(msg tea.Msg) (ClusterForm, tea.Cmd) {
 						f.buttonFocus = -1
 						f.focusIndex = 0
 					}
-				} else if f.focusIndex < len(f.inputs)-1 {
-					// Move to next input
+				} else if f.focusIndex < f.fieldCount()-1 {
+					// Move to next field
 					f.focusIndex++
 				} else {
 					// Move to buttons
@@ -169,15 +294,21 @@ func (f ClusterForm) Update(msg tea.Msg) (ClusterForm, tea.Cmd) {
 							bootstrapServers[i] = strings.TrimSpace(server)
 						}
 
-						cluster := config.KafkaClusterConfig{
-							Name:      f.inputs[0].Value(),
-							Bootstrap: bootstrapServers,
-							Username:  f.inputs[2].Value(),
-							Password:  f.inputs[3].Value(),
-							SSL:       f.cluster.SSL,
-							SASL:      f.cluster.SASL,
-							SASLType:  f.cluster.SASLType,
-						}
+						// Start from the original config so settings that have
+						// no form field (e.g. OAuth providers) are preserved
+						cluster := *f.cluster
+						cluster.Name = f.inputs[0].Value()
+						cluster.Bootstrap = bootstrapServers
+						cluster.Username = f.inputs[2].Value()
+						cluster.Password = f.inputs[3].Value()
+						cluster.SSL = f.choices[0].Enabled()
+						cluster.SASL = f.choices[2].Enabled()
+						cluster.SASLType = f.choices[3].Value()
+						cluster.SSLCAFile = strings.TrimSpace(f.inputs[4].Value())
+						cluster.SSLCertFile = strings.TrimSpace(f.inputs[5].Value())
+						cluster.SSLKeyFile = strings.TrimSpace(f.inputs[6].Value())
+						cluster.SSLServerName = strings.TrimSpace(f.inputs[7].Value())
+						cluster.SSLInsecureSkipVerify = f.choices[1].Enabled()
 
 						if f.isEdit {
 							return ClusterUpdatedMsg{Cluster: cluster}
@@ -195,7 +326,7 @@ func (f ClusterForm) Update(msg tea.Msg) (ClusterForm, tea.Cmd) {
 	}
 
 	// Handle character input for the focused input
-	if f.focusIndex >= 0 {
+	if f.focusIndex >= 0 && f.focusIndex < len(f.inputs) {
 		var cmd tea.Cmd
 		f.inputs[f.focusIndex], cmd = f.inputs[f.focusIndex].Update(msg)
 		return f, cmd
@@ -236,12 +367,37 @@ func (f ClusterForm) View() string {
 			label = "Username (optional):"
 		case 3:
 			label = "Password (optional):"
+		case 4:
+			label = "CA File:"
+		case 5:
+			label = "Client Cert File:"
+		case 6:
+			label = "Client Key File:"
+		case 7:
+			label = "TLS Server Name:"
 		}
 
-		labelStyle := lipgloss.NewStyle().Width(20)
+		labelStyle := lipgloss.NewStyle().Width(22)
 		inputsView += labelStyle.Render(label) + " " + input.View() + "\n\n"
 	}
 
+	for i, choice := range f.choices {
+		var label string
+		switch i {
+		case 0:
+			label = "Enable SSL:"
+		case 1:
+			label = "Skip TLS Verify:"
+		case 2:
+			label = "Enable SASL:"
+		case 3:
+			label = "SASL Mechanism:"
+		}
+
+		labelStyle := lipgloss.NewStyle().Width(22)
+		inputsView += labelStyle.Render(label) + " " + choice.View(f.focusIndex == len(f.inputs)+i) + "\n\n"
+	}
+
 	// Render buttons
 	submitBgColor := "240"
 	if f.buttonFocus == 0 {
@@ -266,7 +422,7 @@ func (f ClusterForm) View() string {
 
 	buttonsView := submitButtonStyle.Render(f.submitButton) + " " + cancelButtonStyle.Render(f.cancelButton)
 
-	helpText := "\nUse tab/shift+tab to navigate, enter to submit"
+	helpText := "\nUse tab/shift+tab to navigate, space or left/right to change options, enter to submit"
 
 	return formStyle.Render(
 		titleStyle.Render(formTitle) + "\n" +
@@ -300,7 +456,26 @@ type TopicForm struct {
 	height       int
 	topicName    string
 	isEdit       bool
-	partitions   int // Store the original partitions value
+	partitions   int          // Store the original partitions value
+	choices      []formChoice // template choice, only when adding a topic
+	templates    []config.TopicTemplate
+	message      string // validation error shown above the buttons
+}
+
+// NewAddTopicForm creates a form for a new topic that can be prefilled from templates
+func NewAddTopicForm(width, height int, templates []config.TopicTemplate) TopicForm {
+	f := NewTopicForm(width, height, "", 0)
+	if len(templates) == 0 {
+		return f
+	}
+
+	options := []string{"none"}
+	for _, t := range templates {
+		options = append(options, t.Name)
+	}
+	f.choices = []formChoice{newFormChoice(options, "none")}
+	f.templates = templates
+	return f
 }
 
 // NewTopicForm creates a new topic form
@@ -312,8 +487,11 @@ func NewTopicForm(width, height int, topicName string, partitions int) TopicForm
 	isEdit := topicName != ""
 	fmt.Fprintf(file, "Creating TopicForm with topicName='%s', partitions=%d, isEdit=%v\n", topicName, partitions, isEdit)
 
-	// Create form inputs
+	// Create form inputs; the layout and configs can only be set on new topics
 	inputs := make([]textinput.Model, 2)
+	if !isEdit {
+		inputs = make([]textinput.Model, 5)
+	}
 
 	// Topic name input
 	inputs[0] = textinput.New()
@@ -330,7 +508,7 @@ func NewTopicForm(width, height int, topicName string, partitions int) TopicForm
 		// Make the topic name field appear read-only
 		inputs[0].PromptStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("240")) // Dimmed color
 		inputs[0].TextStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("240"))   // Dimmed color
-		inputs[0].Blur()                                                               // Remove focus indicator
+		inputs[0].Blur()                                                              // Remove focus indicator
 
 		// Add a note to the placeholder to indicate it's read-only
 		inputs[0].Placeholder = "[Read-only] Topic Name"
@@ -351,6 +529,28 @@ func NewTopicForm(width, height int, topicName string, partitions int) TopicForm
 		inputs[1].SetValue(fmt.Sprintf("%d", partitions))
 	} else {
 		inputs[1].SetValue("1") // Default to 1 partition
+
+		// Replication factor input
+		inputs[2] = textinput.New()
+		inputs[2].Placeholder = "Replication Factor"
+		inputs[2].Width = 10
+		inputs[2].SetValue("1")
+
+		// Replica assignment input
+		inputs[3] = textinput.New()
+		inputs[3].Placeholder = "e.g. 0:1,1:2,2:0 (optional)"
+		inputs[3].Width = 40
+
+		// Configs input
+		inputs[4] = textinput.New()
+		inputs[4].Placeholder = "e.g. retention.ms=86400000, cleanup.policy=compact (optional)"
+		inputs[4].Width = 60
+
+		for i := 2; i < len(inputs); i++ {
+			inputs[i].Prompt = "› "
+			inputs[i].PromptStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("205"))
+			inputs[i].TextStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("252"))
+		}
 	}
 
 	submitText := "Add"
@@ -428,14 +628,27 @@ func (f TopicForm) Update(msg tea.Msg) (TopicForm, tea.Cmd) {
 
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		f.message = ""
 		switch msg.String() {
+		case " ", "left", "right":
+			// Cycle the template and prefill the form with it
+			if f.focusIndex >= len(f.inputs) {
+				choice := &f.choices[f.focusIndex-len(f.inputs)]
+				if msg.String() == "left" {
+					choice.Prev()
+				} else {
+					choice.Next()
+				}
+				f.applyTemplate(choice.selected - 1)
+				return f, nil
+			}
 		case "tab", "shift+tab", "up", "down":
 			// Cycle focus between inputs and buttons
 			if msg.String() == "up" || msg.String() == "shift+tab" {
 				// Move focus up
 				if f.focusIndex == -1 {
-					// If we're on buttons, move to last input
-					f.focusIndex = len(f.inputs) - 1
+					// If we're on buttons, move to last field
+					f.focusIndex = f.fieldCount() - 1
 					f.buttonFocus = -1
 				} else if f.focusIndex > 0 {
 					// Move to previous input
@@ -463,8 +676,8 @@ func (f TopicForm) Update(msg tea.Msg) (TopicForm, tea.Cmd) {
 							f.focusIndex = 0
 						}
 					}
-				} else if f.focusIndex < len(f.inputs)-1 {
-					// Move to next input
+				} else if f.focusIndex < f.fieldCount()-1 {
+					// Move to next field
 					f.focusIndex++
 				} else {
 					// Move to buttons
@@ -487,7 +700,17 @@ func (f TopicForm) Update(msg tea.Msg) (TopicForm, tea.Cmd) {
 		case "enter":
 			if f.focusIndex == -1 {
 				// Button is focused
-				if f.buttonFocus == 0 {
+				if f.buttonFocus == 0 && f.topicName == "" {
+					// Check the new topic before submitting it
+					spec, err := f.topicSpec()
+					if err != nil {
+						f.message = err.Error()
+						return f, nil
+					}
+					return f, func() tea.Msg {
+						return TopicAddedMsg{Spec: spec}
+					}
+				} else if f.buttonFocus == 0 {
 					// Submit button
 					return f, func() tea.Msg {
 						// Parse partitions
@@ -499,24 +722,13 @@ func (f TopicForm) Update(msg tea.Msg) (TopicForm, tea.Cmd) {
 							}
 						}
 
-						// Check if we're editing based on stored topicName
+						// Update the topic being edited
 						fmt.Fprintf(file, "Submit button pressed, stored topicName='%s'\n", f.topicName)
-
-						if f.topicName != "" {
-							fmt.Fprintf(file, "Sending TopicUpdatedMsg for topic '%s' with partitions %d\n", f.topicName, partitions)
-							return TopicUpdatedMsg{
-								OldName:    f.topicName,
-								Name:       f.topicName, // Always use the stored topic name
-								Partitions: partitions,
-							}
-						} else {
-							// This is a new topic
-							fmt.Fprintf(file, "Sending TopicAddedMsg for topic '%s' with partitions %d\n", f.inputs[0].Value(), partitions)
-							return TopicAddedMsg{
-								Name:              f.inputs[0].Value(),
-								Partitions:        partitions,
-								ReplicationFactor: 1, // Default to 1 replica
-							}
+						fmt.Fprintf(file, "Sending TopicUpdatedMsg for topic '%s' with partitions %d\n", f.topicName, partitions)
+						return TopicUpdatedMsg{
+							OldName:    f.topicName,
+							Name:       f.topicName, // Always use the stored topic name
+							Partitions: partitions,
 						}
 					}
 				} else {
@@ -530,7 +742,7 @@ func (f TopicForm) Update(msg tea.Msg) (TopicForm, tea.Cmd) {
 	}
 
 	// Handle character input for the focused input
-	if f.focusIndex >= 0 {
+	if f.focusIndex >= 0 && f.focusIndex < len(f.inputs) {
 		// Skip updating the topic name field if we're editing
 		if f.topicName != "" && f.focusIndex == 0 {
 			// Do nothing - topic name is read-only when editing
@@ -551,6 +763,86 @@ func (f TopicForm) Update(msg tea.Msg) (TopicForm, tea.Cmd) {
 	return f, nil
 }
 
+// fieldCount returns the number of focusable fields before the buttons
+func (f TopicForm) fieldCount() int {
+	return len(f.inputs) + len(f.choices)
+}
+
+// applyTemplate prefills the layout and configs of the i-th template, or
+// restores the defaults if i is out of range
+func (f *TopicForm) applyTemplate(i int) {
+	if i < 0 || i >= len(f.templates) {
+		f.inputs[1].SetValue("1")
+		f.inputs[2].SetValue("1")
+		f.inputs[4].SetValue("")
+		return
+	}
+
+	t := f.templates[i]
+	f.inputs[1].SetValue(fmt.Sprint(t.Partitions))
+	f.inputs[2].SetValue(fmt.Sprint(t.ReplicationFactor))
+	f.inputs[3].SetValue("")
+	f.inputs[4].SetValue(formatConfigs(t.Configs))
+}
+
+// topicSpec builds the new topic from the form inputs and checks it
+func (f TopicForm) topicSpec() (kafka.TopicSpec, error) {
+	spec := kafka.TopicSpec{Name: strings.TrimSpace(f.inputs[0].Value())}
+
+	var err error
+	if spec.ReplicaAssignment, err = kafka.ParseReplicaAssignment(f.inputs[3].Value()); err != nil {
+		return spec, err
+	}
+	if len(spec.ReplicaAssignment) == 0 {
+		if spec.Partitions, err = strconv.Atoi(strings.TrimSpace(f.inputs[1].Value())); err != nil {
+			return spec, fmt.Errorf("invalid number of partitions")
+		}
+		if spec.ReplicationFactor, err = strconv.Atoi(strings.TrimSpace(f.inputs[2].Value())); err != nil {
+			return spec, fmt.Errorf("invalid replication factor")
+		}
+	}
+	if spec.Configs, err = parseConfigs(f.inputs[4].Value()); err != nil {
+		return spec, err
+	}
+
+	return spec, spec.Validate()
+}
+
+// formatConfigs renders configs as comma-separated name=value pairs
+func formatConfigs(configs map[string]string) string {
+	changes := kafka.SetConfigs(configs)
+	pairs := make([]string, len(changes))
+	for i, c := range changes {
+		pairs[i] = c.Name + "=" + c.Value
+	}
+	return strings.Join(pairs, ", ")
+}
+
+// parseConfigs parses comma-separated name=value pairs. A part without '='
+// continues the previous value, so list values like
+// cleanup.policy=compact,delete need no quoting.
+func parseConfigs(s string) (map[string]string, error) {
+	configs := make(map[string]string)
+	last := ""
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(part, "=")
+		if !ok {
+			if last == "" {
+				return nil, fmt.Errorf("config %q is not in name=value form", part)
+			}
+			configs[last] += "," + part
+			continue
+		}
+		last = strings.TrimSpace(name)
+		configs[last] = strings.TrimSpace(value)
+	}
+	return configs, nil
+}
+
 // View renders the form
 func (f TopicForm) View() string {
 	// Debug log to file
@@ -584,7 +876,7 @@ func (f TopicForm) View() string {
 		// Make sure the partitions field is focused and editable
 		f.inputs[1].PromptStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("205")) // Bright color
 		f.inputs[1].TextStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("252"))   // Normal text color
-		f.focusIndex = 1 // Set focus to partitions field
+		f.focusIndex = 1                                                                // Set focus to partitions field
 		f.inputs[1].Focus()
 		fmt.Fprintf(file, "Making partitions field editable and focused\n")
 
@@ -617,12 +909,27 @@ func (f TopicForm) View() string {
 			label = "Topic Name:"
 		case 1:
 			label = "Partitions:"
+		case 2:
+			label = "Replication Factor:"
+		case 3:
+			label = "Replica Assignment:"
+		case 4:
+			label = "Configs:"
 		}
 
 		labelStyle := lipgloss.NewStyle().Width(20)
 		inputsView += labelStyle.Render(label) + " " + input.View() + "\n\n"
 	}
 
+	for i, choice := range f.choices {
+		labelStyle := lipgloss.NewStyle().Width(20)
+		inputsView += labelStyle.Render("Template:") + " " + choice.View(f.focusIndex == len(f.inputs)+i) + "\n\n"
+	}
+
+	if f.message != "" {
+		inputsView += lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Render(f.message) + "\n\n"
+	}
+
 	// Render buttons
 	submitBgColor := "240"
 	if f.buttonFocus == 0 {
@@ -648,6 +955,9 @@ func (f TopicForm) View() string {
 	buttonsView := submitButtonStyle.Render(f.submitButton) + " " + cancelButtonStyle.Render(f.cancelButton)
 
 	helpText := "\nUse tab/shift+tab to navigate, enter to submit"
+	if len(f.choices) > 0 {
+		helpText = "\nUse tab/shift+tab to navigate, left/right to pick a template, enter to submit"
+	}
 
 	return formStyle.Render(
 		titleStyle.Render(formTitle) + "\n" +
@@ -659,9 +969,7 @@ func (f TopicForm) View() string {
 
 // TopicAddedMsg is sent when a new topic is added
 type TopicAddedMsg struct {
-	Name              string
-	Partitions        int
-	ReplicationFactor int
+	Spec kafka.TopicSpec
 }
 
 // TopicUpdatedMsg is sent when a topic is updated
@@ -672,4 +980,4 @@ type TopicUpdatedMsg struct {
 }
 
 // TopicFormCancelledMsg is sent when the topic form is cancelled
-type TopicFormCancelledMsg struct{}
\ No newline at end of file
+type TopicFormCancelledMsg struct{}
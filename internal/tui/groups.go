@@ -0,0 +1,411 @@
+package tui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cfk-dev/cfk/internal/kafka"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	selectedRowStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("205")).Bold(true)
+	stableStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("42"))
+)
+
+// groupList is a table of consumer groups with a cursor and a set of
+// selected groups for bulk actions
+type groupList struct {
+	groups   []kafka.GroupInfo
+	selected map[string]bool
+	cursor   int
+	offset   int // first visible row
+	width    int
+	height   int
+}
+
+// newGroupList creates an empty group list for a window size
+func newGroupList(width, height int) groupList {
+	l := groupList{selected: make(map[string]bool)}
+	l.SetSize(width, height)
+	return l
+}
+
+// SetSize fits the list into the window, leaving room for the header and help
+func (l *groupList) SetSize(width, height int) {
+	l.width = width
+	l.height = height - 8
+	if l.height < 3 {
+		l.height = 3
+	}
+	l.scroll()
+}
+
+// SetGroups shows newly loaded groups, keeping the cursor on the same group
+// and dropping selected groups that are gone
+func (l *groupList) SetGroups(groups []kafka.GroupInfo) {
+	current, ok := l.Selected()
+	l.groups = groups
+	l.cursor = 0
+	exists := make(map[string]bool, len(groups))
+	for i, g := range groups {
+		exists[g.GroupID] = true
+		if ok && g.GroupID == current.GroupID {
+			l.cursor = i
+		}
+	}
+	for id := range l.selected {
+		if !exists[id] {
+			delete(l.selected, id)
+		}
+	}
+	l.scroll()
+}
+
+// Select replaces the selected groups
+func (l *groupList) Select(groupIDs []string) {
+	l.selected = make(map[string]bool, len(groupIDs))
+	for _, id := range groupIDs {
+		l.selected[id] = true
+	}
+}
+
+// SelectedIDs returns the selected groups in list order, or the group under
+// the cursor if none are selected
+func (l groupList) SelectedIDs() []string {
+	var ids []string
+	for _, g := range l.groups {
+		if l.selected[g.GroupID] {
+			ids = append(ids, g.GroupID)
+		}
+	}
+	if len(ids) == 0 {
+		if g, ok := l.Selected(); ok {
+			ids = append(ids, g.GroupID)
+		}
+	}
+	return ids
+}
+
+// Selected returns the group under the cursor
+func (l groupList) Selected() (kafka.GroupInfo, bool) {
+	if l.cursor >= len(l.groups) {
+		return kafka.GroupInfo{}, false
+	}
+	return l.groups[l.cursor], true
+}
+
+// scroll keeps the cursor visible
+func (l *groupList) scroll() {
+	if l.cursor < l.offset {
+		l.offset = l.cursor
+	}
+	if l.cursor >= l.offset+l.height {
+		l.offset = l.cursor - l.height + 1
+	}
+}
+
+// Update moves the cursor
+func (l groupList) Update(msg tea.Msg) groupList {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok || len(l.groups) == 0 {
+		return l
+	}
+
+	switch keyMsg.String() {
+	case " ":
+		// Toggle the selection of the group under the cursor
+		id := l.groups[l.cursor].GroupID
+		if l.selected[id] {
+			delete(l.selected, id)
+		} else {
+			l.selected[id] = true
+		}
+	case "up", "k":
+		if l.cursor > 0 {
+			l.cursor--
+		}
+	case "down", "j":
+		if l.cursor < len(l.groups)-1 {
+			l.cursor++
+		}
+	case "pgup":
+		l.cursor -= l.height
+		if l.cursor < 0 {
+			l.cursor = 0
+		}
+	case "pgdown":
+		l.cursor += l.height
+		if l.cursor >= len(l.groups) {
+			l.cursor = len(l.groups) - 1
+		}
+	case "home":
+		l.cursor = 0
+	case "end":
+		l.cursor = len(l.groups) - 1
+	}
+	l.scroll()
+	return l
+}
+
+// View renders the visible groups, highlighting the one under the cursor
+// and marking the selected ones
+func (l groupList) View() string {
+	if len(l.groups) == 0 {
+		return dimStyle.Render("No consumer groups")
+	}
+
+	const format = "   %-40s %-20s %-12s %8s %12s"
+	lines := []string{tableHeaderStyle.Render(fmt.Sprintf(format, "Group", "State", "Protocol", "Members", "Lag"))}
+
+	end := l.offset + l.height
+	if end > len(l.groups) {
+		end = len(l.groups)
+	}
+	for i := l.offset; i < end; i++ {
+		g := l.groups[i]
+		lag := "-"
+		if g.OffsetsErr != nil {
+			lag = "?"
+		} else if len(g.Offsets) > 0 {
+			lag = fmt.Sprint(g.TotalLag())
+		}
+		line := fmt.Sprintf(format, g.GroupID, g.State, g.Protocol, fmt.Sprint(len(g.Members)), lag)
+		if l.selected[g.GroupID] {
+			line = line[:1] + "✓" + line[2:]
+		}
+		if i == l.cursor {
+			line = selectedRowStyle.Render("›" + line[1:])
+		} else {
+			line = groupStateStyle(g.State).Render(line)
+		}
+		lines = append(lines, line)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// groupStateStyle colors a group by its state: stable groups green, groups
+// in a rebalance orange and empty groups dimmed
+func groupStateStyle(state string) lipgloss.Style {
+	switch state {
+	case "Stable":
+		return stableStyle
+	case "PreparingRebalance", "CompletingRebalance":
+		return underReplicatedStyle
+	case "Empty":
+		return dimStyle
+	}
+	return lipgloss.NewStyle()
+}
+
+// groupDetailsPanes shows the members and committed offsets of a group in
+// two scrollable panes, one of which has the focus
+type groupDetailsPanes struct {
+	group   *kafka.GroupInfo
+	members viewport.Model
+	offsets viewport.Model
+	focus   int // 0 for members, 1 for offsets
+}
+
+// newGroupDetailsPanes creates the panes for a window size
+func newGroupDetailsPanes(width, height int) groupDetailsPanes {
+	p := groupDetailsPanes{
+		members: viewport.New(0, 0),
+		offsets: viewport.New(0, 0),
+	}
+	p.SetSize(width, height)
+	return p
+}
+
+// SetSize splits the window between the panes, giving most of it to the offsets
+func (p *groupDetailsPanes) SetSize(width, height int) {
+	// Leave room for the summary, help text, status line and pane borders
+	available := height - 12
+	if available < 6 {
+		available = 6
+	}
+
+	p.members.Width = width - 2
+	p.members.Height = available * 2 / 5
+	p.offsets.Width = width - 2
+	p.offsets.Height = available - p.members.Height
+}
+
+// SetGroup shows a newly loaded group
+func (p *groupDetailsPanes) SetGroup(group *kafka.GroupInfo) {
+	p.group = group
+	p.members.SetContent(memberTable(group))
+	p.members.GotoTop()
+	p.offsets.SetContent(groupOffsetTable(group))
+	p.offsets.GotoTop()
+}
+
+// SwitchFocus moves the focus to the other pane
+func (p *groupDetailsPanes) SwitchFocus() {
+	p.focus = 1 - p.focus
+}
+
+// Update scrolls the focused pane
+func (p groupDetailsPanes) Update(msg tea.Msg) (groupDetailsPanes, tea.Cmd) {
+	var cmd tea.Cmd
+	if p.focus == 0 {
+		p.members, cmd = p.members.Update(msg)
+	} else {
+		p.offsets, cmd = p.offsets.Update(msg)
+	}
+	return p, cmd
+}
+
+// View renders the summary and both panes
+func (p groupDetailsPanes) View() string {
+	if p.group == nil {
+		return "Loading consumer group..."
+	}
+
+	memberStyle, offsetStyle := focusedPaneStyle, paneStyle
+	if p.focus == 1 {
+		memberStyle, offsetStyle = paneStyle, focusedPaneStyle
+	}
+
+	return groupSummary(p.group) + "\n\n" +
+		"Members\n" + memberStyle.Render(p.members.View()) + "\n" +
+		"Committed offsets\n" + offsetStyle.Render(p.offsets.View())
+}
+
+// groupSummary describes the group and its total lag
+func groupSummary(g *kafka.GroupInfo) string {
+	protocol := g.ProtocolType
+	if g.Protocol != "" {
+		protocol += "/" + g.Protocol
+	}
+	return fmt.Sprintf("Group: %s  •  %s  •  %s  •  %d members  •  total lag %d",
+		g.GroupID, groupStateStyle(g.State).Render(g.State), protocol, len(g.Members), g.TotalLag())
+}
+
+// memberTable renders one row per member with its assigned partitions
+func memberTable(g *kafka.GroupInfo) string {
+	if len(g.Members) == 0 {
+		return dimStyle.Render("No active members")
+	}
+
+	const format = "%-40s %-24s %-18s %s"
+	lines := []string{tableHeaderStyle.Render(fmt.Sprintf(format, "Member", "Client ID", "Host", "Assigned partitions"))}
+	for _, m := range g.Members {
+		lines = append(lines, fmt.Sprintf(format, m.MemberID, m.ClientID, m.Host, assignmentList(m.Assignments)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// assignmentList renders assigned partitions as "topic: 0,1; other: 2"
+func assignmentList(assignments map[string][]int) string {
+	if len(assignments) == 0 {
+		return "-"
+	}
+
+	topics := make([]string, 0, len(assignments))
+	for topic := range assignments {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+
+	parts := make([]string, len(topics))
+	for i, topic := range topics {
+		parts[i] = topic + ": " + brokerList(assignments[topic])
+	}
+	return strings.Join(parts, "; ")
+}
+
+// groupOffsetTable renders the committed offset, log end and lag of every
+// partition the group has committed to, with the member consuming it
+func groupOffsetTable(g *kafka.GroupInfo) string {
+	if len(g.Offsets) == 0 {
+		return dimStyle.Render("No committed offsets")
+	}
+
+	const format = "%-30s %9s %12s %12s %10s  %s"
+	lines := []string{tableHeaderStyle.Render(fmt.Sprintf(format, "Topic", "Partition", "Committed", "Log end", "Lag", "Consumer"))}
+	for _, o := range g.Offsets {
+		consumer := "-"
+		if m, ok := g.Owner(o.Topic, o.Partition); ok {
+			consumer = m.ClientID
+		}
+		line := fmt.Sprintf(format, o.Topic, fmt.Sprint(o.Partition), fmt.Sprint(o.CommittedOffset),
+			fmt.Sprint(o.LogEndOffset), fmt.Sprint(o.Lag), consumer)
+		if o.Lag > 0 {
+			line = underReplicatedStyle.Render(line)
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// groupTopics returns the topics a group has committed offsets for
+func groupTopics(g *kafka.GroupInfo) []string {
+	var topics []string
+	for _, o := range g.Offsets {
+		if len(topics) == 0 || topics[len(topics)-1] != o.Topic {
+			topics = append(topics, o.Topic)
+		}
+	}
+	return topics
+}
+
+// deleteGroupsSummary lists the groups to delete, flagging the ones with
+// members that the brokers will refuse to delete
+func deleteGroupsSummary(groups []kafka.GroupInfo, groupIDs []string) string {
+	states := make(map[string]string, len(groups))
+	for _, g := range groups {
+		states[g.GroupID] = g.State
+	}
+
+	lines := make([]string, len(groupIDs))
+	for i, id := range groupIDs {
+		lines[i] = "  " + id
+		if state := states[id]; state != "Empty" {
+			lines[i] += underReplicatedStyle.Render(fmt.Sprintf("  (%s, will be refused)", state))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// deleteGroupsStatus summarizes a bulk delete, naming the first failure
+func deleteGroupsStatus(results map[string]error) string {
+	ids := make([]string, 0, len(results))
+	for id := range results {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var failed []error
+	for _, id := range ids {
+		if results[id] != nil {
+			failed = append(failed, results[id])
+		}
+	}
+	status := fmt.Sprintf("Deleted %d of %d groups", len(ids)-len(failed), len(ids))
+	if len(failed) > 0 {
+		status += fmt.Sprintf(", %d failed: %v", len(failed), failed[0])
+	}
+	return status
+}
+
+// GroupsDeleteConfirmedMsg is sent when deleting groups is confirmed
+type GroupsDeleteConfirmedMsg struct {
+	GroupIDs []string
+}
+
+// GroupOffsetsDeleteConfirmedMsg is sent when removing the offsets of a topic
+// from a group is confirmed
+type GroupOffsetsDeleteConfirmedMsg struct {
+	GroupID string
+	Topic   string
+}
+
+// GroupPatternSubmittedMsg is sent to select the Empty groups matching a pattern
+type GroupPatternSubmittedMsg struct {
+	Pattern string
+}
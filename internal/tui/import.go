@@ -0,0 +1,301 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cfk-dev/cfk/internal/core"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Import partition choices
+const (
+	partitionsByKey    = "by key"
+	partitionsOriginal = "original"
+)
+
+// ImportForm writes the records of an export file to a topic of one of the
+// clusters and follows the progress of the import
+type ImportForm struct {
+	cluster    string // the connected cluster
+	path       textinput.Model
+	target     formChoice // cluster
+	topic      textinput.Model
+	partitions formChoice
+	dedupe     formChoice
+	focusIndex int // 0 path, 1 cluster, 2 topic, 3 partitions, 4 dedupe
+	run        *importRun
+	progress   core.ImportProgress
+	result     *importResult
+	message    string
+	width      int
+}
+
+// importFields is the number of fields of the import form
+const importFields = 5
+
+// NewImportForm creates a form importing an export to a topic, by default of
+// the connected cluster
+func NewImportForm(width int, cluster string, clusters []string, topic string) ImportForm {
+	target := newFormInput("Topic to write to")
+	target.SetValue(topic)
+	f := ImportForm{
+		cluster:    cluster,
+		path:       newFormInput("Path of a JSON Lines or binary export, compressed or not"),
+		target:     newFormChoice(clusters, cluster),
+		topic:      target,
+		partitions: newFormChoice([]string{partitionsByKey, partitionsOriginal}, partitionsByKey),
+		dedupe:     newFormChoice([]string{"off", "on"}, "off"),
+		width:      width,
+	}
+	f.path.Focus()
+	return f
+}
+
+// Init initializes the form
+func (f ImportForm) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+// SetSize resizes the form
+func (f *ImportForm) SetSize(width int) {
+	f.width = width
+}
+
+// Start follows a run of the form's request
+func (f *ImportForm) Start(run *importRun) {
+	f.run = run
+	f.progress = core.ImportProgress{}
+	f.result = nil
+}
+
+// SetProgress shows the progress of the import
+func (f *ImportForm) SetProgress(progress core.ImportProgress) {
+	f.progress = progress
+}
+
+// SetResult shows the outcome of the import, which may have ended early
+func (f *ImportForm) SetResult(result importResult) {
+	f.run = nil
+	f.result = &result
+	f.progress = result.Progress
+}
+
+// input returns the focused text field, if any
+func (f *ImportForm) input() *textinput.Model {
+	switch f.focusIndex {
+	case 0:
+		return &f.path
+	case 2:
+		return &f.topic
+	}
+	return nil
+}
+
+// choice returns the focused choice field, if any
+func (f *ImportForm) choice() *formChoice {
+	switch f.focusIndex {
+	case 1:
+		return &f.target
+	case 3:
+		return &f.partitions
+	case 4:
+		return &f.dedupe
+	}
+	return nil
+}
+
+// Update handles form events. While an import is in progress the only keys
+// are esc and ctrl+x, which cancel it.
+func (f ImportForm) Update(msg tea.Msg) (ImportForm, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if f.run != nil {
+		if ok && (keyMsg.String() == "esc" || keyMsg.String() == "ctrl+x") {
+			f.run.cancel()
+		}
+		return f, nil
+	}
+	if !ok {
+		var cmd tea.Cmd
+		if input := f.input(); input != nil {
+			*input, cmd = input.Update(msg)
+		}
+		return f, cmd
+	}
+
+	f.message = ""
+	switch keyMsg.String() {
+	case "esc":
+		return f, func() tea.Msg { return ImportFormCancelledMsg{} }
+	case "tab", "down", "shift+tab", "up":
+		if keyMsg.String() == "tab" || keyMsg.String() == "down" {
+			f.focusIndex = (f.focusIndex + 1) % importFields
+		} else {
+			f.focusIndex = (f.focusIndex - 1 + importFields) % importFields
+		}
+		f.path.Blur()
+		f.topic.Blur()
+		if input := f.input(); input != nil {
+			return f, input.Focus()
+		}
+		return f, nil
+	case "enter":
+		req, err := f.request()
+		if err != nil {
+			f.message = err.Error()
+			return f, nil
+		}
+		return f, func() tea.Msg { return req }
+	}
+
+	if choice := f.choice(); choice != nil {
+		switch keyMsg.String() {
+		case "left":
+			choice.Prev()
+		case " ", "right":
+			choice.Next()
+		}
+		return f, nil
+	}
+	var cmd tea.Cmd
+	if input := f.input(); input != nil {
+		*input, cmd = input.Update(msg)
+	}
+	return f, cmd
+}
+
+// request builds the import request from the form inputs
+func (f ImportForm) request() (ImportRequestedMsg, error) {
+	req := ImportRequestedMsg{
+		Path: strings.TrimSpace(f.path.Value()),
+		Request: core.ImportRequest{
+			Topic:          strings.TrimSpace(f.topic.Value()),
+			KeepPartitions: f.partitions.Value() == partitionsOriginal,
+			Dedupe:         f.dedupe.Value() == "on",
+		},
+	}
+	if f.target.Value() != f.cluster {
+		req.Request.TargetCluster = f.target.Value()
+	}
+	if req.Path == "" {
+		return req, fmt.Errorf("the path of the file is required")
+	}
+	if req.Request.Topic == "" {
+		return req, fmt.Errorf("the target topic is required")
+	}
+	return req, nil
+}
+
+// View renders the form, with the progress or outcome of the import
+func (f ImportForm) View() string {
+	formStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("240")).
+		Padding(1, 2).
+		Width(f.width - 4)
+
+	titleStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("205")).
+		Bold(true).
+		MarginBottom(1)
+
+	labelStyle := lipgloss.NewStyle().Width(20)
+	view := titleStyle.Render("Import messages") + "\n"
+	view += labelStyle.Render("File:") + " " + f.path.View() + "\n\n"
+	view += labelStyle.Render("Target cluster:") + " " + f.target.View(f.focusIndex == 1) + "\n\n"
+	view += labelStyle.Render("Target topic:") + " " + f.topic.View() + "\n\n"
+	view += labelStyle.Render("Partitions:") + " " + f.partitions.View(f.focusIndex == 3) + "\n\n"
+	view += labelStyle.Render("Dedupe:") + " " + f.dedupe.View(f.focusIndex == 4) + "\n\n"
+	view += dimStyle.Render("Dedupe keeps a checkpoint of the import so that running it again on the same file, with dedupe on, skips the records already written") + "\n\n"
+
+	if f.message != "" {
+		view += errorTextStyle.Render(f.message) + "\n\n"
+	}
+	switch {
+	case f.run != nil:
+		view += liveStyle.Render("Importing") + "  " + importProgressSummary(f.progress) + "\n\n"
+		view += "Press esc or ctrl+x to cancel"
+	case f.result == nil:
+		view += "Use tab/shift+tab to navigate, left/right to change options, enter to import, esc to cancel"
+	default:
+		view += importResultView(f.result) + "\n\n"
+		view += "Press enter to import again, esc to go back"
+	}
+
+	return formStyle.Render(view)
+}
+
+// importProgressSummary counts the records of an import so far
+func importProgressSummary(p core.ImportProgress) string {
+	return fmt.Sprintf("read %d  •  written %d  •  skipped %d  •  %s",
+		p.Read, p.Written, p.Skipped, p.Elapsed.Round(time.Second))
+}
+
+// importResultView describes how an import ended
+func importResultView(r *importResult) string {
+	switch {
+	case errors.Is(r.Err, context.Canceled):
+		return underReplicatedStyle.Render("Cancelled") + "  " + importProgressSummary(r.Progress)
+	case r.Err != nil:
+		return errorTextStyle.Render(r.Err.Error()) + "\n" + importProgressSummary(r.Progress)
+	default:
+		return stableStyle.Render("Done") + "  " + importProgressSummary(r.Progress)
+	}
+}
+
+// importRun is an import running in the background; it ends with the file
+type importRun = backgroundRun[core.ImportProgress, importResult]
+
+// importResult is the outcome of an import, cancelled or not
+type importResult struct {
+	Progress core.ImportProgress
+	Err      error
+}
+
+// startImportRun starts writing the records of an export file
+func startImportRun(app *core.App, req ImportRequestedMsg) *importRun {
+	return startRun(func(ctx context.Context, progress func(core.ImportProgress)) importResult {
+		p, err := importFile(ctx, app, req, progress)
+		return importResult{Progress: p, Err: err}
+	})
+}
+
+// importFile writes the records of an export file to a topic
+func importFile(ctx context.Context, app *core.App, req ImportRequestedMsg, progress func(core.ImportProgress)) (core.ImportProgress, error) {
+	file, err := os.Open(req.Path)
+	if err != nil {
+		return core.ImportProgress{}, err
+	}
+	defer file.Close()
+	if req.Request.Dedupe && req.Request.ImportID == "" {
+		if req.Request.ImportID, err = core.ExportFileID(file); err != nil {
+			return core.ImportProgress{}, err
+		}
+		if _, err = file.Seek(0, io.SeekStart); err != nil {
+			return core.ImportProgress{}, err
+		}
+	}
+
+	input, err := core.NewExportReader(file)
+	if err != nil {
+		return core.ImportProgress{}, err
+	}
+	defer input.Close()
+	return app.ImportMessages(ctx, input, req.Request, progress)
+}
+
+// ImportRequestedMsg is sent to start importing an export file
+type ImportRequestedMsg struct {
+	Path    string
+	Request core.ImportRequest
+}
+
+// ImportFormCancelledMsg is sent when the import form is closed
+type ImportFormCancelledMsg struct{}
@@ -2,6 +2,7 @@ package tui
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/cfk-dev/cfk/internal/kafka"
 )
@@ -57,3 +58,19 @@ func NewClusterItem(clusterName string, bootstrapServers []string) Item {
 		itemDescription: description,
 	}
 }
+
+// NewSubjectItem creates a new item for a Schema Registry subject, naming the
+// topic it belongs to under the default topic name strategy
+func NewSubjectItem(subject string) Item {
+	description := "schema"
+	if topic, ok := strings.CutSuffix(subject, "-key"); ok {
+		description = "key schema of " + topic
+	} else if topic, ok := strings.CutSuffix(subject, "-value"); ok {
+		description = "value schema of " + topic
+	}
+
+	return Item{
+		itemTitle:       subject,
+		itemDescription: description,
+	}
+}
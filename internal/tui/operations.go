@@ -0,0 +1,156 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// operationTimeout bounds every Kafka operation started from the TUI
+const operationTimeout = 10 * time.Second
+
+// OperationCancelledMsg is sent when the user aborts an in-flight operation
+type OperationCancelledMsg struct {
+	Label string
+}
+
+// operations tracks the in-flight Kafka operation so it can be cancelled.
+// The model is copied on every update, so it holds a pointer to this.
+type operations struct {
+	mu     sync.Mutex
+	id     int
+	label  string
+	cancel context.CancelFunc
+}
+
+// begin starts a new operation, replacing (and cancelling) any previous one
+func (o *operations) begin(label string) (context.Context, int) {
+	ctx, cancel := context.WithTimeout(context.Background(), operationTimeout)
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.cancel != nil {
+		o.cancel()
+	}
+	o.id++
+	o.label = label
+	o.cancel = cancel
+	return ctx, o.id
+}
+
+// end releases an operation once its command has returned
+func (o *operations) end(id int) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.id == id && o.cancel != nil {
+		o.cancel()
+		o.cancel = nil
+		o.label = ""
+	}
+}
+
+// Cancel aborts the in-flight operation, reporting whether there was one
+func (o *operations) Cancel() bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.cancel == nil {
+		return false
+	}
+	o.cancel()
+	o.cancel = nil
+	o.label = ""
+	return true
+}
+
+// Running returns the label of the in-flight operation, if any
+func (o *operations) Running() string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.label
+}
+
+// run returns a command executing fn under a new cancellable operation.
+// If the user cancels it, an OperationCancelledMsg is sent instead of fn's result.
+func (o *operations) run(label string, fn func(ctx context.Context) tea.Msg) tea.Cmd {
+	ctx, id := o.begin(label)
+	return func() tea.Msg {
+		defer o.end(id)
+
+		msg := fn(ctx)
+		if errors.Is(ctx.Err(), context.Canceled) {
+			return OperationCancelledMsg{Label: label}
+		}
+		return msg
+	}
+}
+
+// backgroundRun is a long operation running in the background, reporting
+// progress P and ending with a result R. It is not bound by the operation
+// timeout; it ends by itself, or when cancelled.
+type backgroundRun[P, R any] struct {
+	cancel   context.CancelFunc
+	progress chan P // the latest progress not yet shown
+	done     chan R
+}
+
+// RunMsg is sent as a background run goes on, and once more when it ends,
+// cancelled or not
+type RunMsg[P, R any] struct {
+	Run      *backgroundRun[P, R]
+	Progress P
+	Result   R
+	Done     bool
+}
+
+// startRun runs fn in the background, passing it a function to report its
+// progress with
+func startRun[P, R any](fn func(ctx context.Context, progress func(P)) R) *backgroundRun[P, R] {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &backgroundRun[P, R]{
+		cancel:   cancel,
+		progress: make(chan P, 1),
+		done:     make(chan R, 1),
+	}
+	go func() {
+		defer cancel()
+		r.done <- fn(ctx, func(p P) {
+			// Replace progress that was not shown yet
+			select {
+			case <-r.progress:
+			default:
+			}
+			r.progress <- p
+		})
+	}()
+	return r
+}
+
+// wait returns a command waiting for the next progress or the end of the run
+func (r *backgroundRun[P, R]) wait() tea.Cmd {
+	return func() tea.Msg {
+		select {
+		case result := <-r.done:
+			return RunMsg[P, R]{Run: r, Result: result, Done: true}
+		case p := <-r.progress:
+			return RunMsg[P, R]{Run: r, Progress: p}
+		}
+	}
+}
+
+// followRun shows a message of the current run of a form, and waits for the
+// next one until the run ends. Messages of runs of a closed form, or of a
+// search that was replaced, are ignored.
+func followRun[P, R any](current *backgroundRun[P, R], msg RunMsg[P, R], setProgress func(P), setResult func(R)) tea.Cmd {
+	if msg.Run != current {
+		return nil
+	}
+	if msg.Done {
+		setResult(msg.Result)
+		return nil
+	}
+	setProgress(msg.Progress)
+	return current.wait()
+}
@@ -0,0 +1,583 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cfk-dev/cfk/internal/core"
+	"github.com/cfk-dev/cfk/internal/kafka"
+	"github.com/charmbracelet/bubbles/textarea"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// bulkErrorsShown is the number of failed lines listed after a bulk produce
+const bulkErrorsShown = 5
+
+// ProduceForm writes records to a topic one at a time. The form stays open
+// after each record so that more can be sent.
+type ProduceForm struct {
+	topic       string
+	key         textinput.Model
+	value       textarea.Model
+	headers     textinput.Model
+	partition   formChoice
+	serializer  formChoice
+	compression formChoice
+	acks        formChoice
+	focusIndex  int // 0 key, 1 value, 2 headers, 3 partition, 4 serializer, 5 compression, 6 acks
+	result      string
+	message     string
+	width       int
+}
+
+// produceFields is the number of fields of the produce form
+const produceFields = 7
+
+// newFormInput creates a text field of a form
+func newFormInput(placeholder string) textinput.Model {
+	input := textinput.New()
+	input.Placeholder = placeholder
+	input.Width = 40
+	input.Prompt = "› "
+	input.PromptStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("205"))
+	input.TextStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("252"))
+	return input
+}
+
+// serializerChoice creates a choice of the value serializers
+func serializerChoice() formChoice {
+	options := make([]string, len(core.Serializers))
+	for i, s := range core.Serializers {
+		options[i] = string(s)
+	}
+	return newFormChoice(options, options[0])
+}
+
+// NewProduceForm creates a produce form for a topic with a number of
+// partitions, or 0 if the partitions are not known yet
+func NewProduceForm(width, height int, topic string, partitions int) ProduceForm {
+	options := []string{"auto"}
+	for p := 0; p < partitions; p++ {
+		options = append(options, strconv.Itoa(p))
+	}
+
+	value := textarea.New()
+	value.CharLimit = 0
+	value.MaxHeight = 0
+	value.ShowLineNumbers = false
+	value.Prompt = "│ "
+	value.Placeholder = "Value, e.g. {\"id\": 1}"
+
+	f := ProduceForm{
+		topic:       topic,
+		key:         newFormInput("Key (empty for none)"),
+		value:       value,
+		headers:     newFormInput("e.g. source=cli, trace=abc"),
+		partition:   newFormChoice(options, "auto"),
+		serializer:  serializerChoice(),
+		compression: newFormChoice(kafka.CompressionCodecs, kafka.CompressionCodecs[0]),
+		acks:        newFormChoice(kafka.AcksLevels, kafka.AcksAll),
+	}
+	f.SetSize(width, height)
+	f.key.Focus()
+	return f
+}
+
+// Init initializes the form
+func (f ProduceForm) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+// SetSize fits the value editor into the form
+func (f *ProduceForm) SetSize(width, height int) {
+	f.width = width
+	f.value.SetWidth(width - 12)
+	h := height - 26
+	if h < 3 {
+		h = 3
+	}
+	f.value.SetHeight(h)
+}
+
+// SetResult shows where a record was written, or the error writing it
+func (f *ProduceForm) SetResult(msg *kafka.Message, err error) {
+	f.result = ""
+	f.message = ""
+	switch {
+	case err != nil:
+		f.message = err.Error()
+	case msg.Offset < 0:
+		f.result = fmt.Sprintf("Sent to %s/%d without waiting for an acknowledgement", msg.Topic, msg.Partition)
+	default:
+		f.result = fmt.Sprintf("Produced to %s/%d at offset %d", msg.Topic, msg.Partition, msg.Offset)
+	}
+}
+
+// choice returns the focused choice field, if any
+func (f *ProduceForm) choice() *formChoice {
+	switch f.focusIndex {
+	case 3:
+		return &f.partition
+	case 4:
+		return &f.serializer
+	case 5:
+		return &f.compression
+	case 6:
+		return &f.acks
+	}
+	return nil
+}
+
+// focus moves the cursor to the focused text field
+func (f *ProduceForm) focus() tea.Cmd {
+	f.key.Blur()
+	f.value.Blur()
+	f.headers.Blur()
+	switch f.focusIndex {
+	case 0:
+		return f.key.Focus()
+	case 1:
+		return f.value.Focus()
+	case 2:
+		return f.headers.Focus()
+	}
+	return nil
+}
+
+// Update handles form events
+func (f ProduceForm) Update(msg tea.Msg) (ProduceForm, tea.Cmd) {
+	if keyMsg, ok := msg.(tea.KeyMsg); ok {
+		switch keyMsg.String() {
+		case "esc":
+			return f, func() tea.Msg { return ProduceFormCancelledMsg{} }
+		case "tab":
+			f.focusIndex = (f.focusIndex + 1) % produceFields
+			return f, f.focus()
+		case "shift+tab":
+			f.focusIndex = (f.focusIndex - 1 + produceFields) % produceFields
+			return f, f.focus()
+		case "ctrl+s":
+			req, err := f.request()
+			if err != nil {
+				f.message = err.Error()
+				return f, nil
+			}
+			return f, func() tea.Msg { return ProduceRequestedMsg{Request: req} }
+		}
+		if choice := f.choice(); choice != nil {
+			switch keyMsg.String() {
+			case "left":
+				choice.Prev()
+			case " ", "right":
+				choice.Next()
+			}
+			return f, nil
+		}
+		f.message = ""
+	}
+
+	var cmd tea.Cmd
+	switch f.focusIndex {
+	case 0:
+		f.key, cmd = f.key.Update(msg)
+	case 1:
+		f.value, cmd = f.value.Update(msg)
+	case 2:
+		f.headers, cmd = f.headers.Update(msg)
+	}
+	return f, cmd
+}
+
+// request builds the produce request from the form inputs
+func (f ProduceForm) request() (core.ProduceRequest, error) {
+	headers, err := core.ParseHeaders(f.headers.Value())
+	if err != nil {
+		return core.ProduceRequest{}, err
+	}
+	req := core.ProduceRequest{
+		Topic:      f.topic,
+		Partition:  -1,
+		Key:        f.key.Value(),
+		Value:      f.value.Value(),
+		Headers:    headers,
+		Serializer: core.Serializer(f.serializer.Value()),
+		Options:    kafka.ProduceOptions{Acks: f.acks.Value(), Compression: f.compression.Value()},
+	}
+	if f.partition.Value() != "auto" {
+		req.Partition, _ = strconv.Atoi(f.partition.Value())
+	}
+	return req, nil
+}
+
+// View renders the form and the outcome of the last record
+func (f ProduceForm) View() string {
+	formStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("240")).
+		Padding(1, 2).
+		Width(f.width - 4)
+
+	titleStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("205")).
+		Bold(true).
+		MarginBottom(1)
+
+	labelStyle := lipgloss.NewStyle().Width(20)
+	view := titleStyle.Render("Produce to "+f.topic) + "\n"
+	view += labelStyle.Render("Key:") + " " + f.key.View() + "\n\n"
+	view += labelStyle.Render("Value:") + "\n" + f.value.View() + "\n\n"
+	view += labelStyle.Render("Headers:") + " " + f.headers.View() + "\n\n"
+	view += labelStyle.Render("Partition:") + " " + f.partition.View(f.focusIndex == 3) + "\n\n"
+	view += labelStyle.Render("Serializer:") + " " + f.serializer.View(f.focusIndex == 4) + "\n\n"
+	view += labelStyle.Render("Compression:") + " " + f.compression.View(f.focusIndex == 5) + "\n\n"
+	view += labelStyle.Render("Acks:") + " " + f.acks.View(f.focusIndex == 6) + "\n\n"
+	switch {
+	case f.message != "":
+		view += errorTextStyle.Render(f.message) + "\n\n"
+	case f.result != "":
+		view += stableStyle.Render(f.result) + "\n\n"
+	}
+	view += "Use tab/shift+tab to navigate, left/right to change options, ctrl+s to produce, esc to go back"
+
+	return formStyle.Render(view)
+}
+
+// BulkProduceForm writes the records of a file to a topic and follows the
+// progress of the run
+type BulkProduceForm struct {
+	topic       string
+	path        textinput.Model
+	format      formChoice
+	delimiter   textinput.Model
+	serializer  formChoice
+	compression formChoice
+	acks        formChoice
+	batchSize   textinput.Model
+	rate        textinput.Model
+	focusIndex  int // 0 path, 1 format, 2 delimiter, 3 serializer, 4 compression, 5 acks, 6 batch size, 7 rate
+	run         *bulkRun
+	progress    core.BulkProgress
+	result      *core.BulkResult
+	err         error
+	message     string
+	width       int
+}
+
+// bulkFields is the number of fields of the bulk produce form
+const bulkFields = 8
+
+// NewBulkProduceForm creates a bulk produce form for a topic
+func NewBulkProduceForm(width int, topic string) BulkProduceForm {
+	formats := make([]string, len(core.BulkFormats))
+	for i, format := range core.BulkFormats {
+		formats[i] = string(format)
+	}
+
+	f := BulkProduceForm{
+		topic:       topic,
+		path:        newFormInput("Path of a JSON Lines, CSV or delimited file"),
+		format:      newFormChoice(formats, formats[0]),
+		delimiter:   newFormInput(`\t (delimited files only)`),
+		serializer:  serializerChoice(),
+		compression: newFormChoice(kafka.CompressionCodecs, kafka.CompressionCodecs[0]),
+		acks:        newFormChoice(kafka.AcksLevels, kafka.AcksAll),
+		batchSize:   newFormInput(fmt.Sprintf("Records per request (default %d)", core.DefaultBatchSize)),
+		rate:        newFormInput("Records per second (default no limit)"),
+		width:       width,
+	}
+	f.path.Focus()
+	return f
+}
+
+// Init initializes the form
+func (f BulkProduceForm) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+// SetSize resizes the form
+func (f *BulkProduceForm) SetSize(width int) {
+	f.width = width
+}
+
+// Start follows a run of the form's request
+func (f *BulkProduceForm) Start(run *bulkRun) {
+	f.run = run
+	f.progress = core.BulkProgress{}
+	f.result = nil
+	f.err = nil
+}
+
+// Running reports whether a run is in progress
+func (f BulkProduceForm) Running() bool {
+	return f.run != nil
+}
+
+// SetProgress shows the progress of the run
+func (f *BulkProduceForm) SetProgress(progress core.BulkProgress) {
+	f.progress = progress
+}
+
+// SetResult shows the outcome of the run, which may have ended early
+func (f *BulkProduceForm) SetResult(result bulkResult) {
+	f.run = nil
+	f.result = result.Result
+	f.err = result.Err
+	if result.Result != nil {
+		f.progress = result.Result.BulkProgress
+	}
+}
+
+// input returns the focused text field, if any
+func (f *BulkProduceForm) input() *textinput.Model {
+	switch f.focusIndex {
+	case 0:
+		return &f.path
+	case 2:
+		return &f.delimiter
+	case 6:
+		return &f.batchSize
+	case 7:
+		return &f.rate
+	}
+	return nil
+}
+
+// choice returns the focused choice field, if any
+func (f *BulkProduceForm) choice() *formChoice {
+	switch f.focusIndex {
+	case 1:
+		return &f.format
+	case 3:
+		return &f.serializer
+	case 4:
+		return &f.compression
+	case 5:
+		return &f.acks
+	}
+	return nil
+}
+
+// Update handles form events. While a run is in progress the only keys are
+// esc and ctrl+x, which cancel it.
+func (f BulkProduceForm) Update(msg tea.Msg) (BulkProduceForm, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if f.run != nil {
+		if ok && (keyMsg.String() == "esc" || keyMsg.String() == "ctrl+x") {
+			f.run.cancel()
+		}
+		return f, nil
+	}
+	if !ok {
+		var cmd tea.Cmd
+		if input := f.input(); input != nil {
+			*input, cmd = input.Update(msg)
+		}
+		return f, cmd
+	}
+
+	f.message = ""
+	switch keyMsg.String() {
+	case "esc":
+		return f, func() tea.Msg { return BulkProduceFormCancelledMsg{} }
+	case "tab", "down", "shift+tab", "up":
+		if keyMsg.String() == "tab" || keyMsg.String() == "down" {
+			f.focusIndex = (f.focusIndex + 1) % bulkFields
+		} else {
+			f.focusIndex = (f.focusIndex - 1 + bulkFields) % bulkFields
+		}
+		for _, input := range []*textinput.Model{&f.path, &f.delimiter, &f.batchSize, &f.rate} {
+			input.Blur()
+		}
+		if input := f.input(); input != nil {
+			return f, input.Focus()
+		}
+		return f, nil
+	case "enter":
+		req, err := f.request()
+		if err != nil {
+			f.message = err.Error()
+			return f, nil
+		}
+		return f, func() tea.Msg { return req }
+	}
+
+	if choice := f.choice(); choice != nil {
+		switch keyMsg.String() {
+		case "left":
+			choice.Prev()
+		case " ", "right":
+			choice.Next()
+		}
+		return f, nil
+	}
+	var cmd tea.Cmd
+	if input := f.input(); input != nil {
+		*input, cmd = input.Update(msg)
+	}
+	return f, cmd
+}
+
+// request builds the bulk produce request from the form inputs
+func (f BulkProduceForm) request() (BulkProduceRequestedMsg, error) {
+	req := BulkProduceRequestedMsg{
+		Path:      strings.TrimSpace(f.path.Value()),
+		Format:    core.BulkFormat(f.format.Value()),
+		Delimiter: f.delimiter.Value(),
+		Options: core.BulkOptions{
+			Topic:      f.topic,
+			Serializer: core.Serializer(f.serializer.Value()),
+			Produce:    kafka.ProduceOptions{Acks: f.acks.Value(), Compression: f.compression.Value()},
+		},
+	}
+	if req.Path == "" {
+		return req, fmt.Errorf("the path of the file is required")
+	}
+	if value := strings.TrimSpace(f.batchSize.Value()); value != "" {
+		size, err := strconv.Atoi(value)
+		if err != nil || size <= 0 {
+			return req, fmt.Errorf("invalid batch size %q", value)
+		}
+		req.Options.BatchSize = size
+	}
+	if value := strings.TrimSpace(f.rate.Value()); value != "" {
+		rate, err := strconv.ParseFloat(value, 64)
+		if err != nil || rate <= 0 {
+			return req, fmt.Errorf("invalid rate %q", value)
+		}
+		req.Options.Rate = rate
+	}
+	return req, nil
+}
+
+// View renders the form, with the progress or outcome of the run
+func (f BulkProduceForm) View() string {
+	formStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("240")).
+		Padding(1, 2).
+		Width(f.width - 4)
+
+	titleStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("205")).
+		Bold(true).
+		MarginBottom(1)
+
+	labelStyle := lipgloss.NewStyle().Width(20)
+	view := titleStyle.Render("Bulk produce to "+f.topic) + "\n"
+	view += labelStyle.Render("File:") + " " + f.path.View() + "\n\n"
+	view += labelStyle.Render("Format:") + " " + f.format.View(f.focusIndex == 1) + "\n\n"
+	view += labelStyle.Render("Delimiter:") + " " + f.delimiter.View() + "\n\n"
+	view += labelStyle.Render("Serializer:") + " " + f.serializer.View(f.focusIndex == 3) + "\n\n"
+	view += labelStyle.Render("Compression:") + " " + f.compression.View(f.focusIndex == 4) + "\n\n"
+	view += labelStyle.Render("Acks:") + " " + f.acks.View(f.focusIndex == 5) + "\n\n"
+	view += labelStyle.Render("Batch size:") + " " + f.batchSize.View() + "\n\n"
+	view += labelStyle.Render("Rate:") + " " + f.rate.View() + "\n\n"
+
+	if f.message != "" {
+		view += errorTextStyle.Render(f.message) + "\n\n"
+	}
+	switch {
+	case f.run != nil:
+		view += liveStyle.Render("Producing") + "  " + bulkProgressSummary(f.progress) + "\n\n"
+		view += "Press esc or ctrl+x to cancel"
+	case f.result == nil && f.err == nil:
+		view += "Use tab/shift+tab to navigate, left/right to change options, enter to start, esc to cancel"
+	default:
+		view += bulkResultView(f.progress, f.result, f.err) + "\n"
+		view += "Press enter to run again, esc to go back"
+	}
+
+	return formStyle.Render(view)
+}
+
+// bulkProgressSummary counts the records of a bulk produce so far
+func bulkProgressSummary(p core.BulkProgress) string {
+	return fmt.Sprintf("read %d  •  sent %d  •  failed %d  •  %.0f records/s  •  %s",
+		p.Read, p.Sent, p.Failed, p.Rate(), p.Elapsed.Round(time.Second))
+}
+
+// bulkResultView describes how a bulk produce ended and its first failures
+func bulkResultView(p core.BulkProgress, result *core.BulkResult, err error) string {
+	var view string
+	switch {
+	case errors.Is(err, context.Canceled):
+		view = errorTextStyle.Render("Cancelled") + "  " + bulkProgressSummary(p) + "\n"
+	case err != nil:
+		view = errorTextStyle.Render(err.Error()) + "\n"
+		if result != nil {
+			view += bulkProgressSummary(p) + "\n"
+		}
+	case p.Failed > 0:
+		view = errorTextStyle.Render("Done with failures") + "  " + bulkProgressSummary(p) + "\n"
+	default:
+		view = stableStyle.Render("Done") + "  " + bulkProgressSummary(p) + "\n"
+	}
+	if result != nil {
+		for i, e := range result.Errors {
+			if i == bulkErrorsShown {
+				view += errorTextStyle.Render(fmt.Sprintf("  and %d more", p.Failed-bulkErrorsShown)) + "\n"
+				break
+			}
+			view += errorTextStyle.Render("  "+e.Error()) + "\n"
+		}
+	}
+	return view
+}
+
+// bulkRun is a bulk produce running in the background; it ends with the input
+type bulkRun = backgroundRun[core.BulkProgress, bulkResult]
+
+// bulkResult is the outcome of a bulk produce, cancelled or not
+type bulkResult struct {
+	Result *core.BulkResult
+	Err    error
+}
+
+// startBulkRun starts producing the records of a file
+func startBulkRun(app *core.App, req BulkProduceRequestedMsg) *bulkRun {
+	return startRun(func(ctx context.Context, progress func(core.BulkProgress)) bulkResult {
+		result, err := bulkProduceFile(ctx, app, req, progress)
+		return bulkResult{Result: result, Err: err}
+	})
+}
+
+// bulkProduceFile writes the records of a file to a topic
+func bulkProduceFile(ctx context.Context, app *core.App, req BulkProduceRequestedMsg, progress func(core.BulkProgress)) (*core.BulkResult, error) {
+	file, err := os.Open(req.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	input, err := core.NewBulkReader(file, req.Format, req.Delimiter)
+	if err != nil {
+		return nil, err
+	}
+	return app.BulkProduce(ctx, input, req.Options, progress)
+}
+
+// ProduceRequestedMsg is sent to write a record from the produce form
+type ProduceRequestedMsg struct {
+	Request core.ProduceRequest
+}
+
+// ProduceFormCancelledMsg is sent when the produce form is closed
+type ProduceFormCancelledMsg struct{}
+
+// BulkProduceRequestedMsg is sent to start producing the records of a file
+type BulkProduceRequestedMsg struct {
+	Path      string
+	Format    core.BulkFormat
+	Delimiter string
+	Options   core.BulkOptions
+}
+
+// BulkProduceFormCancelledMsg is sent when the bulk produce form is closed
+type BulkProduceFormCancelledMsg struct{}
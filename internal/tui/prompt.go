@@ -0,0 +1,141 @@
+package tui
+
+import (
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// prompt asks to confirm an action, optionally after picking one of a few
+// choices or typing a value. Submitting sends the message built by submit,
+// cancelling returns to the state the prompt was opened from.
+type prompt struct {
+	title  string
+	body   string
+	choice *formChoice
+	input  *textinput.Model
+	submit func(value string) tea.Msg
+	back   string
+	width  int
+}
+
+// newPrompt creates a yes/no prompt
+func newPrompt(width int, title, body, back string, submit func(value string) tea.Msg) prompt {
+	return prompt{title: title, body: body, back: back, submit: submit, width: width}
+}
+
+// WithChoice makes the prompt submit one of a few choices
+func (p prompt) WithChoice(choices []string) prompt {
+	choice := newFormChoice(choices, choices[0])
+	p.choice = &choice
+	return p
+}
+
+// WithDefault preselects one of the choices
+func (p prompt) WithDefault(value string) prompt {
+	if p.choice != nil {
+		choice := newFormChoice(p.choice.options, value)
+		p.choice = &choice
+	}
+	return p
+}
+
+// WithInput makes the prompt submit a typed value
+func (p prompt) WithInput(placeholder string) prompt {
+	input := textinput.New()
+	input.Placeholder = placeholder
+	input.Width = 40
+	input.Prompt = "› "
+	input.PromptStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("205"))
+	input.Focus()
+	p.input = &input
+	return p
+}
+
+// Init initializes the prompt
+func (p prompt) Init() tea.Cmd {
+	if p.input != nil {
+		return textinput.Blink
+	}
+	return nil
+}
+
+// Update handles prompt events
+func (p prompt) Update(msg tea.Msg) (prompt, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if ok {
+		key := keyMsg.String()
+		// y and n are only shortcuts while there is nothing to type
+		if p.input == nil && key == "y" {
+			key = "enter"
+		} else if p.input == nil && key == "n" {
+			key = "esc"
+		}
+
+		switch key {
+		case "enter":
+			value := ""
+			if p.choice != nil {
+				value = p.choice.Value()
+			} else if p.input != nil {
+				value = p.input.Value()
+			}
+			submit := p.submit
+			return p, func() tea.Msg { return submit(value) }
+		case "esc":
+			back := p.back
+			return p, func() tea.Msg { return PromptCancelledMsg{Back: back} }
+		case "left", "shift+tab":
+			if p.choice != nil {
+				p.choice.Prev()
+				return p, nil
+			}
+		case "right", "tab", " ":
+			if p.choice != nil {
+				p.choice.Next()
+				return p, nil
+			}
+		}
+	}
+
+	if p.input != nil {
+		input, cmd := p.input.Update(msg)
+		p.input = &input
+		return p, cmd
+	}
+	return p, nil
+}
+
+// View renders the prompt
+func (p prompt) View() string {
+	style := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("240")).
+		Padding(1, 2).
+		Width(p.width - 4)
+
+	titleStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("205")).
+		Bold(true).
+		MarginBottom(1)
+
+	view := titleStyle.Render(p.title) + "\n"
+	if p.body != "" {
+		view += p.body + "\n\n"
+	}
+	switch {
+	case p.choice != nil:
+		view += p.choice.View(true) + "\n\nUse left/right to choose, 'enter' to confirm, 'esc' to cancel"
+	case p.input != nil:
+		view += p.input.View() + "\n\nPress 'enter' to confirm, 'esc' to cancel"
+	default:
+		view += "Press 'y' to confirm, 'n' or 'esc' to cancel"
+	}
+
+	return style.Render(view)
+}
+
+// PromptCancelledMsg is sent when a prompt is cancelled
+type PromptCancelledMsg struct {
+	Back string // state to return to
+}
@@ -0,0 +1,369 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cfk-dev/cfk/internal/core"
+	"github.com/cfk-dev/cfk/internal/schemaregistry"
+	"github.com/charmbracelet/bubbles/textarea"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// versionsPaneWidth is the width of the version list of a subject
+const versionsPaneWidth = 28
+
+// subjectPanes shows the versions of a subject next to the schema of the
+// version under the cursor, or its diff against a version marked as base
+type subjectPanes struct {
+	details *core.SubjectDetails
+	cursor  int // index of the version under the cursor
+	base    int // index of the version diffed against, -1 for none
+	schema  viewport.Model
+	height  int
+}
+
+// newSubjectPanes creates the panes for a window size
+func newSubjectPanes(width, height int) subjectPanes {
+	p := subjectPanes{base: -1, schema: viewport.New(0, 0)}
+	p.SetSize(width, height)
+	return p
+}
+
+// SetSize gives the schema pane the width left by the version list
+func (p *subjectPanes) SetSize(width, height int) {
+	// Leave room for the summary, help text, status line and pane borders
+	p.height = height - 10
+	if p.height < 5 {
+		p.height = 5
+	}
+	p.schema.Width = width - versionsPaneWidth - 6
+	if p.schema.Width < 20 {
+		p.schema.Width = 20
+	}
+	p.schema.Height = p.height
+}
+
+// SetDetails shows a newly loaded subject, keeping the cursor and the base
+// on the same versions when they still exist
+func (p *subjectPanes) SetDetails(details *core.SubjectDetails) {
+	current, base := -1, -1
+	if p.details != nil && p.details.Subject == details.Subject {
+		if v, ok := p.Selected(); ok {
+			current = v.Version
+		}
+		if p.base >= 0 {
+			base = p.details.Versions[p.base].Version
+		}
+	}
+
+	p.details = details
+	p.cursor, p.base = len(details.Versions)-1, -1
+	for i, v := range details.Versions {
+		if v.Version == current {
+			p.cursor = i
+		}
+		if v.Version == base {
+			p.base = i
+		}
+	}
+	p.render()
+}
+
+// Selected returns the version under the cursor
+func (p subjectPanes) Selected() (*schemaregistry.Schema, bool) {
+	if p.details == nil || p.cursor < 0 || p.cursor >= len(p.details.Versions) {
+		return nil, false
+	}
+	return p.details.Versions[p.cursor], true
+}
+
+// Latest returns the newest live version
+func (p subjectPanes) Latest() (*schemaregistry.Schema, bool) {
+	if p.details == nil || len(p.details.Versions) == 0 {
+		return nil, false
+	}
+	return p.details.Versions[len(p.details.Versions)-1], true
+}
+
+// render shows the schema of the version under the cursor, or its diff
+// against the base version
+func (p *subjectPanes) render() {
+	selected, ok := p.Selected()
+	if !ok {
+		p.schema.SetContent(dimStyle.Render("No live versions"))
+		return
+	}
+
+	var lines []string
+	if p.base >= 0 && p.base != p.cursor {
+		base := p.details.Versions[p.base]
+		lines = append(lines, tableHeaderStyle.Render(fmt.Sprintf("Changes from version %d to version %d", base.Version, selected.Version)))
+		for _, l := range core.DiffSchemas(base, selected) {
+			switch l.Op {
+			case '-':
+				lines = append(lines, oldValueStyle.Render("- "+l.Text))
+			case '+':
+				lines = append(lines, newValueStyle.Render("+ "+l.Text))
+			default:
+				lines = append(lines, "  "+l.Text)
+			}
+		}
+	} else {
+		lines = append(lines, tableHeaderStyle.Render(fmt.Sprintf("Version %d  •  ID %d  •  %s", selected.Version, selected.ID, selected.SchemaType())))
+		for _, ref := range selected.References {
+			lines = append(lines, dimStyle.Render(fmt.Sprintf("References %s (subject %s version %d)", ref.Name, ref.Subject, ref.Version)))
+		}
+		lines = append(lines, core.FormatSchema(selected))
+	}
+	p.schema.SetContent(strings.Join(lines, "\n"))
+	p.schema.GotoTop()
+}
+
+// Update moves the cursor, marks the base version and scrolls the schema
+func (p subjectPanes) Update(msg tea.Msg) (subjectPanes, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok || p.details == nil || len(p.details.Versions) == 0 {
+		return p, nil
+	}
+
+	switch keyMsg.String() {
+	case "up", "k":
+		if p.cursor > 0 {
+			p.cursor--
+			p.render()
+		}
+		return p, nil
+	case "down", "j":
+		if p.cursor < len(p.details.Versions)-1 {
+			p.cursor++
+			p.render()
+		}
+		return p, nil
+	case " ":
+		// Mark the version under the cursor as the base of the diff
+		if p.base == p.cursor {
+			p.base = -1
+		} else {
+			p.base = p.cursor
+		}
+		p.render()
+		return p, nil
+	}
+
+	var cmd tea.Cmd
+	p.schema, cmd = p.schema.Update(msg)
+	return p, cmd
+}
+
+// View renders the summary, the version list and the schema
+func (p subjectPanes) View() string {
+	if p.details == nil {
+		return "Loading subject..."
+	}
+
+	var versions []string
+	for i, v := range p.details.Versions {
+		line := fmt.Sprintf("   v%-5d id %-6d %s", v.Version, v.ID, v.SchemaType())
+		if i == p.base {
+			line = line[:1] + "◆" + line[2:]
+		}
+		if i == p.cursor {
+			line = selectedRowStyle.Render("›" + line[1:])
+		}
+		versions = append(versions, line)
+	}
+	versionStyle := paneStyle.Copy().Width(versionsPaneWidth).Height(p.height)
+
+	return subjectSummary(p.details) + "\n\n" +
+		lipgloss.JoinHorizontal(lipgloss.Top,
+			versionStyle.Render(strings.Join(versions, "\n")),
+			focusedPaneStyle.Render(p.schema.View()))
+}
+
+// subjectSummary describes the subject and its compatibility level
+func subjectSummary(d *core.SubjectDetails) string {
+	level := d.Compatibility
+	if d.Inherited {
+		level += dimStyle.Render(" (global default)")
+	}
+	summary := fmt.Sprintf("Subject: %s  •  %d versions", d.Subject, len(d.Versions))
+	if len(d.Deleted) > 0 {
+		summary += fmt.Sprintf(" (%d soft-deleted)", len(d.Deleted))
+	}
+	return summary + "  •  compatibility: " + level
+}
+
+// deleteVersionBody explains the two ways of deleting a schema version
+func deleteVersionBody(subject string, version int) string {
+	return fmt.Sprintf("soft: version %d is hidden from %s but its schema ID stays readable, so existing records still decode.\n"+
+		"hard: version %d is removed for good and its schema ID is freed. Records written with it can no longer be decoded.", version, subject, version)
+}
+
+// SchemaTestForm tests a candidate schema against the compatibility level of
+// a subject without registering it
+type SchemaTestForm struct {
+	subject    string
+	schemaType formChoice
+	schema     textarea.Model
+	focusIndex int // 0 schema type, 1 schema text
+	result     *schemaregistry.CompatibilityResult
+	message    string
+	width      int
+	height     int
+}
+
+// NewSchemaTestForm creates a compatibility test form prefilled with the
+// latest version of a subject
+func NewSchemaTestForm(width, height int, subject string, latest *schemaregistry.Schema) SchemaTestForm {
+	schemaType := schemaregistry.TypeAvro
+	text := ""
+	if latest != nil {
+		schemaType = latest.SchemaType()
+		text = core.FormatSchema(latest)
+	}
+
+	schema := textarea.New()
+	schema.CharLimit = 0
+	schema.MaxHeight = 0
+	schema.ShowLineNumbers = false
+	schema.Prompt = "│ "
+	schema.SetValue(text)
+
+	f := SchemaTestForm{
+		subject:    subject,
+		schemaType: newFormChoice([]string{schemaregistry.TypeAvro, schemaregistry.TypeProtobuf, schemaregistry.TypeJSON}, schemaType),
+		schema:     schema,
+		focusIndex: 1,
+	}
+	f.SetSize(width, height)
+	f.schema.Focus()
+	return f
+}
+
+// Init initializes the form
+func (f SchemaTestForm) Init() tea.Cmd {
+	return textarea.Blink
+}
+
+// SetSize fits the schema editor into the form
+func (f *SchemaTestForm) SetSize(width, height int) {
+	f.width = width
+	f.height = height
+	f.schema.SetWidth(width - 12)
+	h := height - 18
+	if h < 5 {
+		h = 5
+	}
+	f.schema.SetHeight(h)
+}
+
+// SetResult shows the outcome of a test, or the error running it
+func (f *SchemaTestForm) SetResult(result *schemaregistry.CompatibilityResult, err error) {
+	f.result = nil
+	f.message = ""
+	if err != nil {
+		f.message = err.Error()
+		return
+	}
+	f.result = result
+}
+
+// Update handles form events
+func (f SchemaTestForm) Update(msg tea.Msg) (SchemaTestForm, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if ok {
+		switch keyMsg.String() {
+		case "esc":
+			return f, func() tea.Msg { return SchemaTestCancelledMsg{} }
+		case "tab", "shift+tab":
+			f.focusIndex = 1 - f.focusIndex
+			if f.focusIndex == 1 {
+				return f, f.schema.Focus()
+			}
+			f.schema.Blur()
+			return f, nil
+		case "ctrl+s":
+			subject, schemaType, text := f.subject, f.schemaType.Value(), f.schema.Value()
+			return f, func() tea.Msg {
+				return SchemaTestRequestedMsg{Subject: subject, SchemaType: schemaType, Schema: text}
+			}
+		}
+		if f.focusIndex == 0 {
+			switch keyMsg.String() {
+			case "left":
+				f.schemaType.Prev()
+			case " ", "right":
+				f.schemaType.Next()
+			}
+			return f, nil
+		}
+		// Editing the schema makes the last result stale
+		f.result = nil
+		f.message = ""
+	}
+
+	var cmd tea.Cmd
+	f.schema, cmd = f.schema.Update(msg)
+	return f, cmd
+}
+
+// View renders the form and the result of the last test
+func (f SchemaTestForm) View() string {
+	formStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("240")).
+		Padding(1, 2).
+		Width(f.width - 4)
+
+	titleStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("205")).
+		Bold(true).
+		MarginBottom(1)
+
+	labelStyle := lipgloss.NewStyle().Width(20)
+	view := titleStyle.Render("Test a schema against subject "+f.subject) + "\n"
+	view += labelStyle.Render("Schema type:") + " " + f.schemaType.View(f.focusIndex == 0) + "\n\n"
+	view += f.schema.View() + "\n\n"
+	switch {
+	case f.message != "":
+		view += errorTextStyle.Render(f.message) + "\n\n"
+	case f.result != nil && f.result.Compatible:
+		view += stableStyle.Render("Compatible: the schema can be registered as the next version") + "\n\n"
+	case f.result != nil:
+		view += errorTextStyle.Render("Incompatible") + "\n"
+		for _, m := range f.result.Messages {
+			view += errorTextStyle.Render("  "+m) + "\n"
+		}
+		view += "\n"
+	}
+	view += "Use tab to switch between the type and the schema, ctrl+s to test, esc to go back"
+
+	return formStyle.Render(view)
+}
+
+// SubjectCompatibilityConfirmedMsg is sent when a new compatibility level of
+// a subject is chosen
+type SubjectCompatibilityConfirmedMsg struct {
+	Subject string
+	Level   string
+}
+
+// SchemaVersionDeleteConfirmedMsg is sent when deleting a schema version is confirmed
+type SchemaVersionDeleteConfirmedMsg struct {
+	Subject   string
+	Version   int
+	Permanent bool
+}
+
+// SchemaTestRequestedMsg is sent to test a candidate schema against a subject
+type SchemaTestRequestedMsg struct {
+	Subject    string
+	SchemaType string
+	Schema     string
+}
+
+// SchemaTestCancelledMsg is sent when the schema test form is closed
+type SchemaTestCancelledMsg struct{}
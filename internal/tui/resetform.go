@@ -0,0 +1,413 @@
+package tui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cfk-dev/cfk/internal/core"
+	"github.com/cfk-dev/cfk/internal/kafka"
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Scopes of an offset reset
+const (
+	resetScopeAll        = "all topics"
+	resetScopeTopic      = "one topic"
+	resetScopePartitions = "partitions"
+)
+
+// Fields of the offset reset form, in focus order
+const (
+	resetFieldStrategy = iota
+	resetFieldScope
+	resetFieldTopic
+	resetFieldPartitions
+	resetFieldValue
+	resetFieldCount
+)
+
+// datetimeLayouts are the accepted formats of a datetime, in local time
+// unless the format has a zone
+var datetimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// OffsetResetForm is a wizard that resets the offsets of a consumer group.
+// Submitting it plans the reset and shows a dry run, which has to be
+// confirmed before anything is committed.
+type OffsetResetForm struct {
+	group      *kafka.GroupInfo
+	strategy   formChoice
+	scope      formChoice
+	topic      textinput.Model
+	partitions textinput.Model
+	value      textinput.Model
+	focusIndex int
+	planned    bool // showing the dry run
+	changes    []core.OffsetChange
+	dryRun     viewport.Model
+	message    string
+	width      int
+	height     int
+}
+
+// NewOffsetResetForm creates an offset reset wizard for a group
+func NewOffsetResetForm(width, height int, group *kafka.GroupInfo) OffsetResetForm {
+	strategies := make([]string, len(core.ResetStrategies))
+	for i, s := range core.ResetStrategies {
+		strategies[i] = string(s)
+	}
+
+	newInput := func(placeholder string, width int) textinput.Model {
+		input := textinput.New()
+		input.Placeholder = placeholder
+		input.Width = width
+		input.Prompt = "› "
+		input.PromptStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("205"))
+		input.TextStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("252"))
+		return input
+	}
+
+	f := OffsetResetForm{
+		group:      group,
+		strategy:   newFormChoice(strategies, string(core.ResetToEarliest)),
+		scope:      newFormChoice([]string{resetScopeAll, resetScopeTopic, resetScopePartitions}, resetScopeAll),
+		topic:      newInput("Topic", 40),
+		partitions: newInput("e.g. 0,1,4", 20),
+		value:      newInput("", 40),
+		dryRun:     viewport.New(0, 0),
+	}
+	if len(group.Offsets) > 0 {
+		f.topic.SetValue(group.Offsets[0].Topic)
+	}
+	f.SetSize(width, height)
+	f.updatePlaceholder()
+	return f
+}
+
+// Init initializes the form
+func (f OffsetResetForm) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+// SetSize resizes the form and its dry run table
+func (f *OffsetResetForm) SetSize(width, height int) {
+	f.width = width
+	f.height = height
+	f.dryRun.Width = width - 10
+	f.dryRun.Height = height - 16
+	if f.dryRun.Height < 5 {
+		f.dryRun.Height = 5
+	}
+}
+
+// SetPlan shows the dry run of a planned reset, or the error planning it
+func (f *OffsetResetForm) SetPlan(changes []core.OffsetChange, err error) {
+	if err != nil {
+		f.message = err.Error()
+		return
+	}
+	f.changes = changes
+	f.planned = true
+	f.dryRun.SetContent(dryRunTable(changes))
+	f.dryRun.GotoTop()
+}
+
+// visible reports whether a field applies to the chosen strategy and scope
+func (f OffsetResetForm) visible(field int) bool {
+	fromFile := f.strategy.Value() == string(core.ResetFromFile)
+	switch field {
+	case resetFieldScope:
+		return !fromFile
+	case resetFieldTopic:
+		return !fromFile && f.scope.Value() != resetScopeAll
+	case resetFieldPartitions:
+		return !fromFile && f.scope.Value() == resetScopePartitions
+	case resetFieldValue:
+		strategy := core.ResetStrategy(f.strategy.Value())
+		return strategy != core.ResetToEarliest && strategy != core.ResetToLatest
+	}
+	return true
+}
+
+// input returns the text input of a field, if it has one
+func (f *OffsetResetForm) input(field int) *textinput.Model {
+	switch field {
+	case resetFieldTopic:
+		return &f.topic
+	case resetFieldPartitions:
+		return &f.partitions
+	case resetFieldValue:
+		return &f.value
+	}
+	return nil
+}
+
+// focus moves the focus by delta to the next visible field
+func (f *OffsetResetForm) focus(delta int) tea.Cmd {
+	if input := f.input(f.focusIndex); input != nil {
+		input.Blur()
+	}
+	for {
+		f.focusIndex = (f.focusIndex + delta + resetFieldCount) % resetFieldCount
+		if f.visible(f.focusIndex) {
+			break
+		}
+	}
+	if input := f.input(f.focusIndex); input != nil {
+		return input.Focus()
+	}
+	return nil
+}
+
+// updatePlaceholder describes the value the chosen strategy needs
+func (f *OffsetResetForm) updatePlaceholder() {
+	switch core.ResetStrategy(f.strategy.Value()) {
+	case core.ResetToOffset:
+		f.value.Placeholder = "Offset"
+	case core.ResetToDatetime:
+		f.value.Placeholder = "e.g. 2024-05-01T12:00:00"
+	case core.ResetShiftBy:
+		f.value.Placeholder = "e.g. -100 or 50"
+	case core.ResetFromFile:
+		f.value.Placeholder = "CSV file of topic,partition,offset lines"
+	}
+}
+
+// Update handles form events
+func (f OffsetResetForm) Update(msg tea.Msg) (OffsetResetForm, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if f.planned {
+		if !ok {
+			return f, nil
+		}
+		switch keyMsg.String() {
+		case "enter", "y":
+			if f.group.State != "Empty" || len(f.changes) == 0 {
+				return f, nil
+			}
+			groupID, changes := f.group.GroupID, f.changes
+			return f, func() tea.Msg {
+				return OffsetResetConfirmedMsg{GroupID: groupID, Changes: changes}
+			}
+		case "esc", "n":
+			// Back to editing
+			f.planned = false
+			return f, nil
+		}
+		var cmd tea.Cmd
+		f.dryRun, cmd = f.dryRun.Update(msg)
+		return f, cmd
+	}
+
+	if !ok {
+		if input := f.input(f.focusIndex); input != nil {
+			var cmd tea.Cmd
+			*input, cmd = input.Update(msg)
+			return f, cmd
+		}
+		return f, nil
+	}
+
+	f.message = ""
+	switch keyMsg.String() {
+	case "esc":
+		return f, func() tea.Msg { return OffsetResetCancelledMsg{} }
+	case "tab", "down":
+		return f, f.focus(1)
+	case "shift+tab", "up":
+		return f, f.focus(-1)
+	case " ", "left", "right":
+		// Cycle the focused choice
+		var choice *formChoice
+		switch f.focusIndex {
+		case resetFieldStrategy:
+			choice = &f.strategy
+		case resetFieldScope:
+			choice = &f.scope
+		}
+		if choice != nil {
+			if keyMsg.String() == "left" {
+				choice.Prev()
+			} else {
+				choice.Next()
+			}
+			f.updatePlaceholder()
+			return f, nil
+		}
+	case "enter":
+		// Plan the reset for the dry run
+		reset, err := f.reset()
+		if err != nil {
+			f.message = err.Error()
+			return f, nil
+		}
+		groupID := f.group.GroupID
+		return f, func() tea.Msg {
+			return OffsetResetPlanMsg{GroupID: groupID, Reset: reset}
+		}
+	}
+
+	if input := f.input(f.focusIndex); input != nil {
+		var cmd tea.Cmd
+		*input, cmd = input.Update(msg)
+		return f, cmd
+	}
+	return f, nil
+}
+
+// reset builds the offset reset from the form inputs
+func (f OffsetResetForm) reset() (core.OffsetReset, error) {
+	reset := core.OffsetReset{Strategy: core.ResetStrategy(f.strategy.Value())}
+
+	if f.visible(resetFieldTopic) {
+		reset.Topic = strings.TrimSpace(f.topic.Value())
+		if reset.Topic == "" {
+			return reset, fmt.Errorf("choose a topic to reset")
+		}
+	}
+	if f.visible(resetFieldPartitions) {
+		for _, part := range strings.Split(f.partitions.Value(), ",") {
+			if part = strings.TrimSpace(part); part == "" {
+				continue
+			}
+			p, err := strconv.Atoi(part)
+			if err != nil || p < 0 {
+				return reset, fmt.Errorf("invalid partition %q", part)
+			}
+			reset.Partitions = append(reset.Partitions, p)
+		}
+		if len(reset.Partitions) == 0 {
+			return reset, fmt.Errorf("choose the partitions to reset")
+		}
+	}
+
+	value := strings.TrimSpace(f.value.Value())
+	var err error
+	switch reset.Strategy {
+	case core.ResetToOffset:
+		if reset.Offset, err = strconv.ParseInt(value, 10, 64); err != nil {
+			return reset, fmt.Errorf("invalid offset %q", value)
+		}
+	case core.ResetShiftBy:
+		if reset.Shift, err = strconv.ParseInt(value, 10, 64); err != nil {
+			return reset, fmt.Errorf("invalid shift %q", value)
+		}
+	case core.ResetToDatetime:
+		if reset.Datetime, err = parseDatetime(value); err != nil {
+			return reset, err
+		}
+	case core.ResetFromFile:
+		if reset.Offsets, err = core.ReadOffsetsFile(value); err != nil {
+			return reset, err
+		}
+	}
+
+	return reset, nil
+}
+
+// parseDatetime parses a datetime in one of datetimeLayouts
+func parseDatetime(s string) (time.Time, error) {
+	for _, layout := range datetimeLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid datetime %q, use e.g. 2024-05-01T12:00:00 or RFC 3339", s)
+}
+
+// View renders the form, or the dry run once the reset is planned
+func (f OffsetResetForm) View() string {
+	formStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("240")).
+		Padding(1, 2).
+		Width(f.width - 4)
+
+	titleStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("205")).
+		Bold(true).
+		MarginBottom(1)
+
+	if f.planned {
+		view := titleStyle.Render("Dry run: reset offsets of group "+f.group.GroupID) + "\n" + f.dryRun.View() + "\n\n"
+		switch {
+		case len(f.changes) == 0:
+			view += "Nothing to reset. Press 'esc' to go back"
+		case f.group.State != "Empty":
+			view += errorTextStyle.Render(fmt.Sprintf("Group is %s. Stop its consumers so that it is Empty before resetting offsets.", f.group.State)) +
+				"\n\nPress 'esc' to go back"
+		default:
+			view += fmt.Sprintf("Press 'enter' or 'y' to commit %d offsets, 'esc' or 'n' to go back", len(f.changes))
+		}
+		return formStyle.Render(view)
+	}
+
+	labelStyle := lipgloss.NewStyle().Width(20)
+	view := titleStyle.Render("Reset offsets of group "+f.group.GroupID) + "\n"
+	view += labelStyle.Render("Strategy:") + " " + f.strategy.View(f.focusIndex == resetFieldStrategy) + "\n\n"
+	if f.visible(resetFieldScope) {
+		view += labelStyle.Render("Scope:") + " " + f.scope.View(f.focusIndex == resetFieldScope) + "\n\n"
+	}
+	if f.visible(resetFieldTopic) {
+		view += labelStyle.Render("Topic:") + " " + f.topic.View() + "\n\n"
+	}
+	if f.visible(resetFieldPartitions) {
+		view += labelStyle.Render("Partitions:") + " " + f.partitions.View() + "\n\n"
+	}
+	if f.visible(resetFieldValue) {
+		view += labelStyle.Render("Value:") + " " + f.value.View() + "\n\n"
+	}
+	if f.group.State != "Empty" {
+		view += underReplicatedStyle.Render(fmt.Sprintf("Group is %s: the dry run works, but offsets can only be reset while it is Empty", f.group.State)) + "\n\n"
+	}
+	if f.message != "" {
+		view += errorTextStyle.Render(f.message) + "\n\n"
+	}
+	view += "Use tab/shift+tab to navigate, left/right to change options, enter for a dry run, esc to cancel"
+
+	return formStyle.Render(view)
+}
+
+// dryRunTable renders the current and new offset of every partition
+func dryRunTable(changes []core.OffsetChange) string {
+	const format = "%-30s %9s %12s %12s %10s"
+	lines := []string{tableHeaderStyle.Render(fmt.Sprintf(format, "Topic", "Partition", "Current", "New", "Change"))}
+	for _, c := range changes {
+		current, delta := "-", "-"
+		if c.Current >= 0 {
+			current = fmt.Sprint(c.Current)
+			delta = fmt.Sprintf("%+d", c.New-c.Current)
+		}
+		line := fmt.Sprintf(format, c.Topic, fmt.Sprint(c.Partition), current, fmt.Sprint(c.New), delta)
+		if c.Current == c.New {
+			line = dimStyle.Render(line)
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// OffsetResetPlanMsg is sent to plan an offset reset for the dry run
+type OffsetResetPlanMsg struct {
+	GroupID string
+	Reset   core.OffsetReset
+}
+
+// OffsetResetConfirmedMsg is sent when the dry run of a reset is confirmed
+type OffsetResetConfirmedMsg struct {
+	GroupID string
+	Changes []core.OffsetChange
+}
+
+// OffsetResetCancelledMsg is sent when the offset reset form is cancelled
+type OffsetResetCancelledMsg struct{}
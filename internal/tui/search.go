@@ -0,0 +1,386 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cfk-dev/cfk/internal/core"
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// progressBarWidth is the width of the search progress bar
+const progressBarWidth = 40
+
+// Search range kinds
+const (
+	rangeOffsets = "offsets"
+	rangeTime    = "time"
+)
+
+// SearchForm picks the partitions, range and filter of a message search
+type SearchForm struct {
+	topic      string
+	partition  formChoice
+	rangeKind  formChoice
+	from       textinput.Model
+	to         textinput.Model
+	filter     textinput.Model
+	limit      textinput.Model
+	focusIndex int // 0 partition, 1 range, 2 from, 3 to, 4 filter, 5 limit
+	message    string
+	width      int
+}
+
+// searchFields is the number of fields of the search form
+const searchFields = 6
+
+// NewSearchForm creates a search form for a topic with a number of
+// partitions, or 0 if the partitions are not known yet
+func NewSearchForm(width int, topic string, partitions int) SearchForm {
+	options := []string{"all"}
+	for p := 0; p < partitions; p++ {
+		options = append(options, strconv.Itoa(p))
+	}
+
+	filter := newFormInput(`e.g. $.customer.id == 42 AND header.source == "web"`)
+	filter.Width = 60
+	f := SearchForm{
+		topic:     topic,
+		partition: newFormChoice(options, "all"),
+		rangeKind: newFormChoice([]string{rangeOffsets, rangeTime}, rangeOffsets),
+		from:      newFormInput(""),
+		to:        newFormInput(""),
+		filter:    filter,
+		limit:     newFormInput(fmt.Sprintf("Matches to stop after (default %d)", core.DefaultSearchLimit)),
+		width:     width,
+	}
+	f.updatePlaceholders()
+	return f
+}
+
+// Init initializes the form
+func (f SearchForm) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+// SetSize resizes the form
+func (f *SearchForm) SetSize(width int) {
+	f.width = width
+}
+
+// updatePlaceholders describes the bounds the chosen range kind needs
+func (f *SearchForm) updatePlaceholders() {
+	if f.rangeKind.Value() == rangeTime {
+		f.from.Placeholder = "e.g. 2024-05-01T12:00:00 (default earliest)"
+		f.to.Placeholder = "Last record time (default latest)"
+	} else {
+		f.from.Placeholder = "First offset (default earliest)"
+		f.to.Placeholder = "Offset to stop before (default latest)"
+	}
+}
+
+// input returns the focused text field, if any
+func (f *SearchForm) input() *textinput.Model {
+	switch f.focusIndex {
+	case 2:
+		return &f.from
+	case 3:
+		return &f.to
+	case 4:
+		return &f.filter
+	case 5:
+		return &f.limit
+	}
+	return nil
+}
+
+// setFocus moves the focus to a field, or out of the form for -1
+func (f *SearchForm) setFocus(index int) tea.Cmd {
+	f.focusIndex = index
+	for _, input := range []*textinput.Model{&f.from, &f.to, &f.filter, &f.limit} {
+		input.Blur()
+	}
+	if input := f.input(); input != nil {
+		return input.Focus()
+	}
+	return nil
+}
+
+// Update handles form events
+func (f SearchForm) Update(msg tea.Msg) (SearchForm, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		var cmd tea.Cmd
+		if input := f.input(); input != nil {
+			*input, cmd = input.Update(msg)
+		}
+		return f, cmd
+	}
+
+	f.message = ""
+	switch keyMsg.String() {
+	case "esc":
+		return f, func() tea.Msg { return SearchFormCancelledMsg{} }
+	case "tab", "down", "shift+tab", "up":
+		if keyMsg.String() == "tab" || keyMsg.String() == "down" {
+			return f, f.setFocus((f.focusIndex + 1) % searchFields)
+		}
+		return f, f.setFocus((f.focusIndex - 1 + searchFields) % searchFields)
+	case "enter":
+		req, err := f.request()
+		if err != nil {
+			f.message = err.Error()
+			return f, nil
+		}
+		return f, func() tea.Msg { return SearchRequestedMsg{Request: req} }
+	}
+
+	if f.focusIndex < 2 {
+		choice := &f.partition
+		if f.focusIndex == 1 {
+			choice = &f.rangeKind
+		}
+		switch keyMsg.String() {
+		case "left":
+			choice.Prev()
+		case " ", "right":
+			choice.Next()
+		}
+		f.updatePlaceholders()
+		return f, nil
+	}
+	var cmd tea.Cmd
+	input := f.input()
+	*input, cmd = input.Update(msg)
+	return f, cmd
+}
+
+// request builds the search request from the form inputs
+func (f SearchForm) request() (core.SearchRequest, error) {
+	req := core.SearchRequest{Topic: f.topic, Partition: core.AllPartitions, ToOffset: -1}
+	if f.partition.Value() != "all" {
+		req.Partition, _ = strconv.Atoi(f.partition.Value())
+	}
+
+	from, to := strings.TrimSpace(f.from.Value()), strings.TrimSpace(f.to.Value())
+	var err error
+	if f.rangeKind.Value() == rangeTime {
+		if from != "" {
+			if req.FromTime, err = parseDatetime(from); err != nil {
+				return req, err
+			}
+		}
+		if to != "" {
+			if req.ToTime, err = parseDatetime(to); err != nil {
+				return req, err
+			}
+		}
+	} else {
+		if from != "" {
+			if req.FromOffset, err = strconv.ParseInt(from, 10, 64); err != nil || req.FromOffset < 0 {
+				return req, fmt.Errorf("invalid offset %q", from)
+			}
+		}
+		if to != "" {
+			if req.ToOffset, err = strconv.ParseInt(to, 10, 64); err != nil || req.ToOffset < req.FromOffset {
+				return req, fmt.Errorf("invalid end offset %q", to)
+			}
+		}
+	}
+
+	if text := strings.TrimSpace(f.filter.Value()); text != "" {
+		if req.Filter, err = core.ParseFilter(text); err != nil {
+			return req, fmt.Errorf("invalid filter: %w", err)
+		}
+	}
+	if value := strings.TrimSpace(f.limit.Value()); value != "" {
+		if req.Limit, err = strconv.Atoi(value); err != nil || req.Limit <= 0 {
+			return req, fmt.Errorf("invalid number of matches %q", value)
+		}
+	}
+	return req, nil
+}
+
+// View renders the form
+func (f SearchForm) View() string {
+	formStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("240")).
+		Padding(1, 2).
+		Width(f.width - 4)
+
+	titleStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("205")).
+		Bold(true).
+		MarginBottom(1)
+
+	labelStyle := lipgloss.NewStyle().Width(20)
+	view := titleStyle.Render("Search messages of "+f.topic) + "\n"
+	view += labelStyle.Render("Partition:") + " " + f.partition.View(f.focusIndex == 0) + "\n\n"
+	view += labelStyle.Render("Range:") + " " + f.rangeKind.View(f.focusIndex == 1) + "\n\n"
+	view += labelStyle.Render("From:") + " " + f.from.View() + "\n\n"
+	view += labelStyle.Render("To:") + " " + f.to.View() + "\n\n"
+	view += labelStyle.Render("Filter:") + " " + f.filter.View() + "\n\n"
+	view += labelStyle.Render("Limit:") + " " + f.limit.View() + "\n\n"
+	view += dimStyle.Render("Filters compare key, value, header.<name> or JSON paths like $.a.b[0] with ==, !=, <, >, contains, ~ (regex) or exists, combined with AND, OR, NOT and parentheses") + "\n\n"
+	if f.message != "" {
+		view += errorTextStyle.Render(f.message) + "\n\n"
+	}
+	view += "Use tab/shift+tab to navigate, left/right to change options, enter to search, esc to cancel"
+
+	return formStyle.Render(view)
+}
+
+// messageSearch runs a search in the background, showing its progress and
+// then the matching records
+type messageSearch struct {
+	req      core.SearchRequest
+	run      *searchRun
+	running  bool
+	progress core.SearchProgress
+	result   *core.SearchResult
+	err      error
+	format   recordFormat
+	view     viewport.Model
+}
+
+// startMessageSearch starts a search
+func startMessageSearch(width, height int, app *core.App, req core.SearchRequest, format recordFormat) *messageSearch {
+	s := &messageSearch{
+		req:     req,
+		running: true,
+		format:  format,
+		view:    viewport.New(0, 0),
+	}
+	s.view.Style = lipgloss.NewStyle().BorderStyle(lipgloss.RoundedBorder())
+	s.SetSize(width, height)
+	s.run = startRun(func(ctx context.Context, progress func(core.SearchProgress)) searchResult {
+		result, err := app.SearchMessages(ctx, req, progress)
+		return searchResult{Result: result, Err: err}
+	})
+	return s
+}
+
+// searchRun is a search running in the background; it ends with the range
+// or the limit of matches
+type searchRun = backgroundRun[core.SearchProgress, searchResult]
+
+// searchResult is the outcome of a search, stopped or not
+type searchResult struct {
+	Result *core.SearchResult
+	Err    error
+}
+
+// Stop cancels the search if it is still running
+func (s *messageSearch) Stop() {
+	s.run.cancel()
+}
+
+// SetSize fits the records into the window, leaving room for the summary and help
+func (s *messageSearch) SetSize(width, height int) {
+	s.view.Width = width - 4
+	s.view.Height = height - 9
+	if s.view.Height < 3 {
+		s.view.Height = 3
+	}
+	s.render()
+}
+
+// SetProgress shows the progress of the search
+func (s *messageSearch) SetProgress(progress core.SearchProgress) {
+	s.progress = progress
+}
+
+// SetResult shows the matching records, found before any error or cancellation
+func (s *messageSearch) SetResult(result searchResult) {
+	s.running = false
+	s.result = result.Result
+	s.err = result.Err
+	if result.Result != nil {
+		s.progress = result.Result.SearchProgress
+	}
+	s.render()
+	s.view.GotoTop()
+}
+
+// render shows the matching records
+func (s *messageSearch) render() {
+	if s.result != nil {
+		content, _ := messageLines(s.result.Messages, true, s.view.Width-2, s.format, -1)
+		s.view.SetContent(content)
+	}
+}
+
+// Update scrolls the records, or stops the running search
+func (s *messageSearch) Update(msg tea.Msg) tea.Cmd {
+	if keyMsg, ok := msg.(tea.KeyMsg); ok && s.running {
+		if keyMsg.String() == "esc" || keyMsg.String() == "ctrl+x" {
+			s.Stop()
+		}
+		return nil
+	}
+	if keyMsg, ok := msg.(tea.KeyMsg); ok && keyMsg.String() == "esc" {
+		return func() tea.Msg { return SearchClosedMsg{} }
+	}
+	var cmd tea.Cmd
+	s.view, cmd = s.view.Update(msg)
+	return cmd
+}
+
+// View renders the progress of the search, then its matches
+func (s *messageSearch) View() string {
+	summary := "Search of topic: " + s.req.Topic
+	if s.req.Filter != nil {
+		summary += "  •  " + s.req.Filter.String()
+	}
+	summary += "\n" + searchProgressBar(s.progress) + "\n"
+
+	if s.running {
+		return summary + "\nPress 'esc' or 'ctrl+x' to stop the search"
+	}
+	switch {
+	case errors.Is(s.err, context.Canceled):
+		summary += underReplicatedStyle.Render("Stopped") + "  "
+	case s.err != nil:
+		summary += errorTextStyle.Render(s.err.Error()) + "  "
+	case s.result.Limited:
+		summary += stableStyle.Render("Stopped at the limit") + "  "
+	default:
+		summary += stableStyle.Render("Done") + "  "
+	}
+	if s.result == nil {
+		return summary + "\n\nPress 'esc' to change the search, 'b' to go back to clusters, 'q' to quit"
+	}
+	summary += fmt.Sprintf("%d matches  •  %s", len(s.result.Messages), s.format)
+	return summary + "\n" + s.view.View() +
+		"\n\nPress 'k'/'v' to switch the key/value format, 'f' to show records in full, 'w' to export to a file, 'esc' to change the search, 'b' to go back to clusters, 'q' to quit"
+}
+
+// searchProgressBar renders the scanned share of the range with the counts
+func searchProgressBar(p core.SearchProgress) string {
+	filled := int(p.Fraction() * progressBarWidth)
+	if filled > progressBarWidth {
+		filled = progressBarWidth
+	}
+	bar := liveStyle.Render(strings.Repeat("█", filled)) + dimStyle.Render(strings.Repeat("░", progressBarWidth-filled))
+	return fmt.Sprintf("%s %3.0f%%  •  scanned %d/%d  •  %d matches  •  partitions %d/%d  •  %s",
+		bar, p.Fraction()*100, p.Scanned, p.Total, p.Matches, p.Done, p.Partitions, p.Elapsed.Round(100*time.Millisecond))
+}
+
+// SearchRequestedMsg is sent when the search form is submitted
+type SearchRequestedMsg struct {
+	Request core.SearchRequest
+}
+
+// SearchFormCancelledMsg is sent when the search form is cancelled
+type SearchFormCancelledMsg struct{}
+
+// SearchClosedMsg is sent to leave the results of a search
+type SearchClosedMsg struct{}
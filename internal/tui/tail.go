@@ -0,0 +1,156 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cfk-dev/cfk/internal/core"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Live tail timing
+const (
+	tailPollInterval = 500 * time.Millisecond // wait before polling an idle topic again
+	tailRateWindow   = 5 * time.Second        // window of the records/sec counter
+)
+
+var liveStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("42")).Bold(true)
+
+// messageTail streams the records appended to a topic into a bounded buffer.
+// Pausing stops polling; the tail cursors keep their place, so resuming
+// reads every record produced in the meantime.
+type messageTail struct {
+	tail    *core.Tail
+	ctx     context.Context
+	cancel  context.CancelFunc
+	ring    *core.MessageRing
+	rate    *core.RateMeter
+	limit   int // records read per partition and poll
+	format  recordFormat
+	paused  bool
+	polling bool
+	err     error
+	view    viewport.Model
+}
+
+// newMessageTail creates the view of a started tail
+func newMessageTail(width, height int, tail *core.Tail, bufferSize, limit int, format recordFormat) messageTail {
+	ctx, cancel := context.WithCancel(context.Background())
+	t := messageTail{
+		tail:   tail,
+		ctx:    ctx,
+		cancel: cancel,
+		ring:   core.NewMessageRing(bufferSize),
+		rate:   core.NewRateMeter(tailRateWindow),
+		limit:  limit,
+		format: format,
+		view:   viewport.New(0, 0),
+	}
+	t.view.Style = lipgloss.NewStyle().BorderStyle(lipgloss.RoundedBorder())
+	t.SetSize(width, height)
+	t.render()
+	return t
+}
+
+// SetSize fits the records into the window, leaving room for the summary and help
+func (t *messageTail) SetSize(width, height int) {
+	t.view.Width = width - 4
+	t.view.Height = height - 8
+	if t.view.Height < 3 {
+		t.view.Height = 3
+	}
+	if t.ring != nil {
+		t.render()
+	}
+}
+
+// Stop stops polling for good
+func (t *messageTail) Stop() {
+	if t.cancel != nil {
+		t.cancel()
+	}
+}
+
+// poll returns a command reading the next records, unless one is in flight
+// or the tail is paused
+func (t *messageTail) poll() tea.Cmd {
+	if t.paused || t.polling || t.ctx.Err() != nil {
+		return nil
+	}
+	t.polling = true
+	ctx, tail, limit := t.ctx, t.tail, t.limit
+	return func() tea.Msg { return TailPollCmd(ctx, tail, limit)() }
+}
+
+// Polled adds polled records and returns the command for the next poll
+func (t *messageTail) Polled(msg TailPolledMsg) tea.Cmd {
+	t.polling = false
+	if t.ctx.Err() != nil {
+		return nil
+	}
+
+	t.err = msg.Err
+	t.ring.Push(msg.Messages...)
+	t.rate.Add(time.Now(), len(msg.Messages))
+	if !t.paused {
+		t.render()
+	}
+
+	// Poll again right away while records keep coming
+	if msg.Err == nil && len(msg.Messages) > 0 {
+		return t.poll()
+	}
+	tail := t.tail
+	return tea.Tick(tailPollInterval, func(time.Time) tea.Msg { return tailTickMsg{Tail: tail} })
+}
+
+// TogglePause pauses or resumes the tail
+func (t *messageTail) TogglePause() tea.Cmd {
+	t.paused = !t.paused
+	if t.paused {
+		return nil
+	}
+	t.render()
+	return t.poll()
+}
+
+// render shows the buffered records, following the newest one
+func (t *messageTail) render() {
+	content, _ := messageLines(t.ring.Messages(), true, t.view.Width-2, t.format, -1)
+	t.view.SetContent(content)
+	t.view.GotoBottom()
+}
+
+// Update scrolls the records
+func (t messageTail) Update(msg tea.Msg) (messageTail, tea.Cmd) {
+	var cmd tea.Cmd
+	t.view, cmd = t.view.Update(msg)
+	return t, cmd
+}
+
+// View renders the tail state, the records and the help
+func (t messageTail) View() string {
+	state := liveStyle.Render("LIVE")
+	if t.paused {
+		state = underReplicatedStyle.Render("PAUSED")
+	}
+	summary := fmt.Sprintf("Tailing topic: %s  •  %s  •  %.1f records/s  •  buffer %d/%d  •  %s",
+		t.tail.Topic(), state, t.rate.Rate(time.Now()), t.ring.Len(), t.ring.Cap(), t.format)
+	if dropped := t.ring.Dropped(); dropped > 0 {
+		summary += fmt.Sprintf(" (%d oldest dropped)", dropped)
+	}
+	if t.err != nil {
+		summary += "\n" + errorTextStyle.Render(t.err.Error())
+	}
+
+	return summary + "\n" + t.view.View() +
+		"\n\nPress 'space' to pause or resume, 'k'/'v' to switch the key/value format, 'f' to show records in full, 'esc' to stop, 'b' to go back to clusters, 'q' to quit"
+}
+
+// tailTickMsg is sent when an idle tail is due to poll again
+type tailTickMsg struct {
+	Tail *core.Tail
+}
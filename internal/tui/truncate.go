@@ -0,0 +1,325 @@
+package tui
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cfk-dev/cfk/internal/core"
+	"github.com/cfk-dev/cfk/internal/kafka"
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Scopes of a truncation
+const (
+	truncateScopePartition = "partition"
+	truncateScopeAll       = "all partitions"
+)
+
+// Fields of the truncate form, in focus order
+const (
+	truncateFieldScope = iota
+	truncateFieldPartition
+	truncateFieldBefore
+	truncateFieldCount
+)
+
+// TruncateForm deletes the records of the browsed topic before the record
+// under the cursor: those of a partition before an offset, or those of every
+// partition before a time. Submitting it shows how many records each partition would
+// lose, which has to be confirmed before anything is deleted.
+type TruncateForm struct {
+	page       *core.MessagePage
+	from       kafka.Message // the first record to keep by default
+	scope      formChoice
+	partition  formChoice
+	before     textinput.Model
+	focusIndex int
+	planned    bool // showing the records to delete
+	plan       []core.RecordDeletion
+	summary    viewport.Model
+	message    string
+	width      int
+	height     int
+}
+
+// NewTruncateForm creates a form truncating the topic of a page up to a
+// record of the page, in the browsed partition if there is one
+func NewTruncateForm(width, height int, page *core.MessagePage, from kafka.Message) TruncateForm {
+	var partitions []int
+	for p := range page.Bounds {
+		partitions = append(partitions, p)
+	}
+	sort.Ints(partitions)
+	options := make([]string, len(partitions))
+	for i, p := range partitions {
+		options[i] = strconv.Itoa(p)
+	}
+
+	scope := truncateScopeAll
+	if page.Partition != core.AllPartitions {
+		scope = truncateScopePartition
+	}
+	f := TruncateForm{
+		page:      page,
+		from:      from,
+		scope:     newFormChoice([]string{truncateScopePartition, truncateScopeAll}, scope),
+		partition: newFormChoice(options, strconv.Itoa(from.Partition)),
+		before:    newFormInput(""),
+		summary:   viewport.New(0, 0),
+	}
+	f.SetSize(width, height)
+	f.updateBefore()
+	if f.visible(truncateFieldPartition) {
+		f.focusIndex = truncateFieldBefore
+		f.before.Focus()
+	}
+	return f
+}
+
+// Init initializes the form
+func (f TruncateForm) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+// SetSize resizes the form and its summary table
+func (f *TruncateForm) SetSize(width, height int) {
+	f.width = width
+	f.height = height
+	f.summary.Width = width - 10
+	f.summary.Height = height - 16
+	if f.summary.Height < 5 {
+		f.summary.Height = 5
+	}
+}
+
+// SetPlan shows the records a planned truncation deletes, or the error
+// planning it
+func (f *TruncateForm) SetPlan(plan []core.RecordDeletion, err error) {
+	if err != nil {
+		f.message = err.Error()
+		return
+	}
+	f.plan = plan
+	f.planned = true
+	f.summary.SetContent(truncateTable(plan))
+	f.summary.GotoTop()
+}
+
+// visible reports whether a field applies to the chosen scope
+func (f TruncateForm) visible(field int) bool {
+	if field == truncateFieldPartition {
+		return f.scope.Value() == truncateScopePartition
+	}
+	return true
+}
+
+// updateBefore fills in the position of the record to keep from: its offset
+// if it is in the chosen partition, or its time
+func (f *TruncateForm) updateBefore() {
+	f.before.SetValue("")
+	if f.scope.Value() == truncateScopePartition {
+		f.before.Placeholder = "Offset of the first record to keep"
+		if f.partition.Value() == strconv.Itoa(f.from.Partition) {
+			f.before.SetValue(strconv.FormatInt(f.from.Offset, 10))
+		}
+		return
+	}
+	f.before.Placeholder = "Time of the first records to keep, e.g. 2024-05-01T12:00:00"
+	f.before.SetValue(f.from.Timestamp.Local().Format(timestampLayout))
+}
+
+// focus moves the focus by delta to the next visible field
+func (f *TruncateForm) focus(delta int) tea.Cmd {
+	f.before.Blur()
+	for {
+		f.focusIndex = (f.focusIndex + delta + truncateFieldCount) % truncateFieldCount
+		if f.visible(f.focusIndex) {
+			break
+		}
+	}
+	if f.focusIndex == truncateFieldBefore {
+		return f.before.Focus()
+	}
+	return nil
+}
+
+// Update handles form events
+func (f TruncateForm) Update(msg tea.Msg) (TruncateForm, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if f.planned {
+		if !ok {
+			return f, nil
+		}
+		switch keyMsg.String() {
+		case "enter", "y":
+			if truncateTotal(f.plan) == 0 {
+				return f, nil
+			}
+			topic, partition, plan := f.page.Topic, f.page.Partition, f.plan
+			return f, func() tea.Msg {
+				return TruncateConfirmedMsg{Topic: topic, Partition: partition, Plan: plan}
+			}
+		case "esc", "n":
+			// Back to editing
+			f.planned = false
+			return f, nil
+		}
+		var cmd tea.Cmd
+		f.summary, cmd = f.summary.Update(msg)
+		return f, cmd
+	}
+
+	if !ok {
+		var cmd tea.Cmd
+		if f.focusIndex == truncateFieldBefore {
+			f.before, cmd = f.before.Update(msg)
+		}
+		return f, cmd
+	}
+
+	f.message = ""
+	switch keyMsg.String() {
+	case "esc":
+		return f, func() tea.Msg { return TruncateCancelledMsg{} }
+	case "tab", "down":
+		return f, f.focus(1)
+	case "shift+tab", "up":
+		return f, f.focus(-1)
+	case "enter":
+		// Plan the truncation for the summary
+		req, err := f.request()
+		if err != nil {
+			f.message = err.Error()
+			return f, nil
+		}
+		return f, func() tea.Msg { return TruncatePlanMsg{Request: req} }
+	}
+
+	if f.focusIndex != truncateFieldBefore {
+		choice := &f.scope
+		if f.focusIndex == truncateFieldPartition {
+			choice = &f.partition
+		}
+		switch keyMsg.String() {
+		case "left":
+			choice.Prev()
+		case " ", "right":
+			choice.Next()
+		default:
+			return f, nil
+		}
+		f.updateBefore()
+		return f, nil
+	}
+	var cmd tea.Cmd
+	f.before, cmd = f.before.Update(msg)
+	return f, cmd
+}
+
+// request builds the truncation from the form inputs
+func (f TruncateForm) request() (core.TruncateRequest, error) {
+	req := core.TruncateRequest{Topic: f.page.Topic, Partition: core.AllPartitions}
+	value := strings.TrimSpace(f.before.Value())
+	var err error
+	if f.scope.Value() == truncateScopeAll {
+		if req.Time, err = parseDatetime(value); err != nil {
+			return req, err
+		}
+		return req, nil
+	}
+
+	if req.Partition, err = strconv.Atoi(f.partition.Value()); err != nil {
+		return req, fmt.Errorf("choose a partition to truncate")
+	}
+	if req.Offset, err = strconv.ParseInt(value, 10, 64); err != nil || req.Offset < 0 {
+		return req, fmt.Errorf("invalid offset %q", value)
+	}
+	return req, nil
+}
+
+// View renders the form, or the records to delete once they are planned
+func (f TruncateForm) View() string {
+	formStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("240")).
+		Padding(1, 2).
+		Width(f.width - 4)
+
+	titleStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("205")).
+		Bold(true).
+		MarginBottom(1)
+
+	if f.planned {
+		view := titleStyle.Render("Delete records of topic "+f.page.Topic) + "\n" + f.summary.View() + "\n\n"
+		if total := truncateTotal(f.plan); total == 0 {
+			view += "Nothing to delete. Press 'esc' to go back"
+		} else {
+			view += errorTextStyle.Render("Deleted records cannot be recovered, and consumers behind them skip to the new start.") + "\n\n"
+			view += fmt.Sprintf("Press 'enter' or 'y' to delete %d records, 'esc' or 'n' to go back", total)
+		}
+		return formStyle.Render(view)
+	}
+
+	labelStyle := lipgloss.NewStyle().Width(20)
+	view := titleStyle.Render("Truncate topic "+f.page.Topic) + "\n"
+	view += labelStyle.Render("Scope:") + " " + f.scope.View(f.focusIndex == truncateFieldScope) + "\n\n"
+	if f.visible(truncateFieldPartition) {
+		view += labelStyle.Render("Partition:") + " " + f.partition.View(f.focusIndex == truncateFieldPartition) + "\n\n"
+		view += labelStyle.Render("Before offset:") + " " + f.before.View() + "\n\n"
+	} else {
+		view += labelStyle.Render("Before time:") + " " + f.before.View() + "\n\n"
+	}
+	view += dimStyle.Render("Records before the one under the cursor are deleted unless you change the position") + "\n\n"
+	if f.message != "" {
+		view += errorTextStyle.Render(f.message) + "\n\n"
+	}
+	view += "Use tab/shift+tab to navigate, left/right to change options, enter to see what would be deleted, esc to cancel"
+
+	return formStyle.Render(view)
+}
+
+// truncateTotal counts the records a truncation deletes
+func truncateTotal(plan []core.RecordDeletion) int64 {
+	var total int64
+	for _, d := range plan {
+		total += d.Records()
+	}
+	return total
+}
+
+// truncateTable renders the records each partition would lose
+func truncateTable(plan []core.RecordDeletion) string {
+	const format = "%9s %12s %12s %12s %12s"
+	lines := []string{tableHeaderStyle.Render(fmt.Sprintf(format, "Partition", "Earliest", "New earliest", "Latest", "Deleted"))}
+	for _, d := range plan {
+		line := fmt.Sprintf(format, fmt.Sprint(d.Partition), fmt.Sprint(d.Earliest), fmt.Sprint(d.Offset),
+			fmt.Sprint(d.Latest), fmt.Sprint(d.Records()))
+		if d.Records() == 0 {
+			line = dimStyle.Render(line)
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// TruncatePlanMsg is sent to plan a truncation for the summary
+type TruncatePlanMsg struct {
+	Request core.TruncateRequest
+}
+
+// TruncateConfirmedMsg is sent to delete the records of a planned truncation.
+// Partition is the browsed partition, to read again after the deletion.
+type TruncateConfirmedMsg struct {
+	Topic     string
+	Partition int
+	Plan      []core.RecordDeletion
+}
+
+// TruncateCancelledMsg is sent when the truncate form is closed
+type TruncateCancelledMsg struct{}
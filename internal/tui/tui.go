@@ -5,13 +5,13 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"time"
 
 	"github.com/cfk-dev/cfk/internal/config"
 	"github.com/cfk-dev/cfk/internal/core"
 	"github.com/cfk-dev/cfk/internal/kafka"
+	"github.com/cfk-dev/cfk/internal/schemaregistry"
+	"github.com/cfk-dev/cfk/internal/serde"
 	"github.com/charmbracelet/bubbles/list"
-	"github.com/charmbracelet/bubbles/table"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -19,21 +19,51 @@ import (
 
 // Model represents the TUI application model
 type Model struct {
-	config       *config.AppConfig
-	app          *core.App
-	kafkaClient  *kafka.Client
-	state        string
-	clusterList  list.Model
-	topicList    list.Model
-	topicTable   table.Model
-	viewport     viewport.Model
-	clusterForm  ClusterForm
-	topicForm    TopicForm
-	err          error
-	selectedItem string
+	config          *config.AppConfig
+	app             *core.App
+	state           string
+	clusterList     list.Model
+	topicList       list.Model
+	topicDetails    topicDetailsPanes
+	groupList       groupList
+	groupDetails    groupDetailsPanes
+	subjectList     list.Model
+	subjectPanes    subjectPanes
+	schemaTest      SchemaTestForm
+	viewport        viewport.Model
+	clusterForm     ClusterForm
+	topicForm       TopicForm
+	configForm      ConfigForm
+	resetForm       OffsetResetForm
+	prompt          prompt
+	browseForm      BrowseForm
+	produceForm     ProduceForm
+	bulkForm        BulkProduceForm
+	produceBack     string // state the produce forms were opened from
+	searchForm      SearchForm
+	search          *messageSearch
+	searchBack      string // state the search form was opened from
+	exportForm      ExportForm
+	exportBack      string // state the export form was opened from
+	copyForm        CopyForm
+	copyBack        string // state the copy form was opened from
+	importForm      ImportForm
+	importBack      string // state the import form was opened from
+	truncateForm    TruncateForm
+	messages        *core.MessagePage
+	cursor          int    // record of the page under the cursor
+	messagesBack    string // state the message browser or tail was opened from
+	format          recordFormat
+	tail            messageTail
+	serdes          *serde.Registry
+	err             error
+	selectedItem    string
 	selectedCluster string
-	width        int
-	height       int
+	selectedGroup   string
+	ops             *operations
+	status          string
+	width           int
+	height          int
 }
 
 // Initialize the TUI model
@@ -50,42 +80,51 @@ func NewModel(cfg *config.AppConfig, app *core.App) Model {
 	topicList := list.New([]list.Item{}, NewCustomDelegate(), 0, 0)
 	topicList.Title = "Topics"
 
-	// Create topic table
-	columns := []table.Column{
-		{Title: "Topic", Width: 30},
-		{Title: "Partitions", Width: 10},
-		{Title: "Replicas", Width: 10},
-	}
-	rows := []table.Row{}
-	topicTable := table.New(
-		table.WithColumns(columns),
-		table.WithRows(rows),
-		table.WithFocused(true),
-		table.WithHeight(10),
-	)
+	// Create the topic details panes
+	topicDetails := newTopicDetailsPanes(width, height)
+
+	// Create the consumer group views
+	groupList := newGroupList(width, height)
+	groupDetails := newGroupDetailsPanes(width, height)
+
+	// Create the schema registry views
+	subjectList := list.New([]list.Item{}, NewCustomDelegate(), 0, 0)
+	subjectList.Title = "Subjects"
+	subjectPanes := newSubjectPanes(width, height)
 
 	// Create viewport for message viewing
 	viewport := viewport.New(80, 20)
 	viewport.Style = lipgloss.NewStyle().BorderStyle(lipgloss.RoundedBorder())
 
+	// Decode records in the Confluent wire format with the registry of the
+	// connected cluster
+	serdes := serde.NewRegistry()
+	serdes.Register(schemaregistry.NewDeserializer(func() *schemaregistry.Client { return app.SchemaRegistry }))
+
 	// Create cluster form
 	clusterForm := NewClusterForm(width, height, nil)
 
 	// Create topic form
-	topicForm := NewTopicForm(width, height, "", 0)
+	topicForm := NewAddTopicForm(width, height, cfg.TopicTemplates)
 
 	return Model{
-		config:      cfg,
-		app:         app,
-		state:       "clusters",
-		clusterList: clusterList,
-		topicList:   topicList,
-		topicTable:  topicTable,
-		viewport:    viewport,
-		clusterForm: clusterForm,
-		topicForm:   topicForm,
-		width:       width,
-		height:      height,
+		config:       cfg,
+		app:          app,
+		state:        "clusters",
+		clusterList:  clusterList,
+		topicList:    topicList,
+		topicDetails: topicDetails,
+		groupList:    groupList,
+		groupDetails: groupDetails,
+		subjectList:  subjectList,
+		subjectPanes: subjectPanes,
+		viewport:     viewport,
+		clusterForm:  clusterForm,
+		topicForm:    topicForm,
+		serdes:       serdes,
+		ops:          &operations{},
+		width:        width,
+		height:       height,
 	}
 }
 
@@ -106,16 +145,41 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		// Update components with new size
 		m.clusterForm = NewClusterForm(m.width, m.height, nil)
-		m.topicForm = NewTopicForm(m.width, m.height, "", 0)
+		m.topicForm = NewAddTopicForm(m.width, m.height, m.config.TopicTemplates)
 
 		// Update list heights
 		h := m.height - 6 // Adjust for header and footer
 		m.clusterList.SetHeight(h)
 		m.topicList.SetHeight(h)
+		m.subjectList.SetHeight(h)
 
 		// Update viewport
 		m.viewport.Width = m.width - 4
 		m.viewport.Height = m.height - 8
+		m.topicDetails.SetSize(m.width, m.height)
+		m.configForm.SetSize(m.width, m.height)
+		m.resetForm.SetSize(m.width, m.height)
+		m.browseForm.SetSize(m.width)
+		if m.tail.tail != nil {
+			m.tail.SetSize(m.width, m.height)
+		}
+		if m.messages != nil {
+			m.showMessages()
+		}
+		m.groupList.SetSize(m.width, m.height)
+		m.groupDetails.SetSize(m.width, m.height)
+		m.subjectPanes.SetSize(m.width, m.height)
+		m.schemaTest.SetSize(m.width, m.height)
+		m.produceForm.SetSize(m.width, m.height)
+		m.bulkForm.SetSize(m.width)
+		m.searchForm.SetSize(m.width)
+		m.exportForm.SetSize(m.width)
+		m.copyForm.SetSize(m.width)
+		m.importForm.SetSize(m.width)
+		m.truncateForm.SetSize(m.width, m.height)
+		if m.search != nil {
+			m.search.SetSize(m.width, m.height)
+		}
 
 		return m, nil
 
@@ -125,11 +189,24 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		defer f.Close()
 		fmt.Fprintf(f, "Key pressed: '%s' in state: %s\n", msg.String(), m.state)
 
+		// Any key dismisses the last status message
+		m.status = ""
+
 		// Handle global key events
 		switch msg.String() {
+		case "ctrl+x":
+			// Abort the in-flight Kafka operation
+			if m.ops.Cancel() {
+				return m, nil
+			}
 		case "ctrl+c", "q":
 			if m.state != "add_cluster" && m.state != "edit_cluster" &&
-			   m.state != "add_topic" && m.state != "edit_topic" {
+				m.state != "add_topic" && m.state != "edit_topic" &&
+				m.state != "edit_config" && m.state != "reset_offsets" && m.state != "prompt" &&
+				m.state != "browse_form" && m.state != "schema_test" &&
+				m.state != "produce_form" && m.state != "bulk_produce" && m.state != "search_form" &&
+				m.state != "export_form" && m.state != "copy_form" && m.state != "import_form" &&
+				m.state != "truncate_form" {
 				return m, tea.Quit
 			}
 		case "enter":
@@ -139,13 +216,13 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.selectedItem = i.Title()
 					m.selectedCluster = i.Title()
 					// Connect to the selected cluster
-					return m, func() tea.Msg {
+					return m, m.ops.run("Connecting to "+m.selectedCluster, func(ctx context.Context) tea.Msg {
 						// Debug log to file
 						f, _ := os.OpenFile("/tmp/cfk_debug.log", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 						defer f.Close()
 						fmt.Fprintf(f, "Connecting to cluster: %s\n", m.selectedItem)
 
-						if err := m.app.ConnectToCluster(m.selectedItem); err != nil {
+						if err := m.app.ConnectToCluster(ctx, m.selectedItem); err != nil {
 							fmt.Fprintf(f, "Error connecting: %v\n", err)
 							return ErrorMsg{err}
 						}
@@ -158,33 +235,117 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 						fmt.Fprintf(f, "Updated title to: %s\n", m.topicList.Title)
 
 						// After connecting, update the topic list
-						return UpdateTopicListCmd(m.app)()
-					}
+						return UpdateTopicListCmd(ctx, m.app)()
+					})
 				}
 			} else if m.state == "topics" {
 				// When a topic is selected, show topic details
 				if i, ok := m.topicList.SelectedItem().(Item); ok {
 					m.selectedItem = i.Title()
 					// Get topic details
-					return m, func() tea.Msg {
-						ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-						defer cancel()
-
-						topicInfo, err := m.app.GetTopicInfo(ctx, m.selectedItem)
-						if err != nil {
-							return ErrorMsg{err}
-						}
-
-						// Update the topic table with the details
-						rows := []table.Row{
-							{m.selectedItem, fmt.Sprintf("%d", topicInfo.Partitions), "1"},
-						}
-						m.topicTable.SetRows(rows)
-
-						m.state = "topic_details"
-						return nil
-					}
+					return m, m.ops.run("Loading topic "+m.selectedItem, func(ctx context.Context) tea.Msg {
+						return LoadTopicDetailsCmd(ctx, m.app, m.selectedItem)()
+					})
 				}
+			} else if m.state == "groups" {
+				// When a group is selected, show its members and offsets
+				if group, ok := m.groupList.Selected(); ok {
+					groupID := group.GroupID
+					return m, m.ops.run("Loading group "+groupID, func(ctx context.Context) tea.Msg {
+						return LoadGroupDetailsCmd(ctx, m.app, groupID)()
+					})
+				}
+			} else if m.state == "subjects" {
+				// When a subject is selected, show its versions
+				if i, ok := m.subjectList.SelectedItem().(Item); ok {
+					subject := i.Title()
+					return m, m.ops.run("Loading subject "+subject, func(ctx context.Context) tea.Msg {
+						return LoadSubjectCmd(ctx, m.app, subject)()
+					})
+				}
+			}
+		case "tab":
+			// Move the focus between the partition and config panes
+			if m.state == "topic_details" {
+				m.topicDetails.SwitchFocus()
+				return m, nil
+			} else if m.state == "group_details" {
+				m.groupDetails.SwitchFocus()
+				return m, nil
+			}
+		case "r":
+			// Reload the topic details
+			if m.state == "topic_details" {
+				topicName := m.selectedItem
+				return m, m.ops.run("Loading topic "+topicName, func(ctx context.Context) tea.Msg {
+					return LoadTopicDetailsCmd(ctx, m.app, topicName)()
+				})
+			} else if m.state == "groups" {
+				return m, m.ops.run("Loading consumer groups", func(ctx context.Context) tea.Msg {
+					return LoadGroupsCmd(ctx, m.app)()
+				})
+			} else if m.state == "group_details" {
+				groupID := m.selectedGroup
+				return m, m.ops.run("Loading group "+groupID, func(ctx context.Context) tea.Msg {
+					return LoadGroupDetailsCmd(ctx, m.app, groupID)()
+				})
+			} else if m.state == "subjects" {
+				return m, m.ops.run("Loading subjects", func(ctx context.Context) tea.Msg {
+					return LoadSubjectsCmd(ctx, m.app)()
+				})
+			} else if m.state == "subject" && m.subjectPanes.details != nil {
+				subject := m.subjectPanes.details.Subject
+				return m, m.ops.run("Loading subject "+subject, func(ctx context.Context) tea.Msg {
+					return LoadSubjectCmd(ctx, m.app, subject)()
+				})
+			}
+		case "o":
+			// Reset the offsets of the group
+			if m.state == "group_details" && m.groupDetails.group != nil {
+				m.resetForm = NewOffsetResetForm(m.width, m.height, m.groupDetails.group)
+				m.state = "reset_offsets"
+				return m, m.resetForm.Init()
+			}
+		case "g":
+			// Browse the consumer groups of the cluster
+			if m.state == "topics" {
+				return m, m.ops.run("Loading consumer groups", func(ctx context.Context) tea.Msg {
+					return LoadGroupsCmd(ctx, m.app)()
+				})
+			}
+		case "s":
+			// Browse the subjects of the schema registry
+			if m.state == "topics" {
+				if m.app.SchemaRegistry == nil {
+					m.status = "No schema registry configured for this cluster"
+					return m, nil
+				}
+				return m, m.ops.run("Loading subjects", func(ctx context.Context) tea.Msg {
+					return LoadSubjectsCmd(ctx, m.app)()
+				})
+			}
+		case "c":
+			// Change the compatibility level of the subject
+			if m.state == "subject" && m.subjectPanes.details != nil {
+				details := m.subjectPanes.details
+				m.prompt = newPrompt(m.width, "Compatibility level of subject "+details.Subject,
+					"New versions of the subject are checked against this level before they are registered.",
+					"subject", func(level string) tea.Msg {
+						return SubjectCompatibilityConfirmedMsg{Subject: details.Subject, Level: level}
+					}).WithChoice(schemaregistry.CompatibilityLevels).WithDefault(details.Compatibility)
+				m.state = "prompt"
+				return m, m.prompt.Init()
+			}
+			// Copy records of the topic to a topic of one of the clusters
+			if topicName, partitions := m.selectedTopic(); topicName != "" {
+				clusters := make([]string, len(m.config.Clusters))
+				for i, cluster := range m.config.Clusters {
+					clusters[i] = cluster.Name
+				}
+				m.copyForm = NewCopyForm(m.width, m.selectedCluster, clusters, topicName, partitions)
+				m.copyBack = m.state
+				m.state = "copy_form"
+				return m, m.copyForm.Init()
 			}
 		case "backspace", "esc":
 			// Debug log to file
@@ -202,8 +363,23 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.state = "topics"
 				return m, nil
 			} else if m.state == "messages" {
-				fmt.Fprintf(f, "Changing state from messages to topic_details\n")
-				m.state = "topic_details"
+				m.state = m.messagesBack
+				return m, nil
+			} else if m.state == "tail" {
+				m.tail.Stop()
+				m.state = m.messagesBack
+				return m, nil
+			} else if m.state == "groups" {
+				m.state = "topics"
+				return m, nil
+			} else if m.state == "group_details" {
+				m.state = "groups"
+				return m, nil
+			} else if m.state == "subjects" {
+				m.state = "topics"
+				return m, nil
+			} else if m.state == "subject" {
+				m.state = "subjects"
 				return m, nil
 			}
 		case "b":
@@ -213,7 +389,18 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			fmt.Fprintf(f, "Processing 'b' key in state: %s\n", m.state)
 
 			// Go directly back to clusters view from any view
-			if m.state == "topics" || m.state == "topic_details" || m.state == "messages" {
+			if m.state == "tail" {
+				m.tail.Stop()
+				m.state = "clusters"
+				return m, nil
+			}
+			if m.state == "search" {
+				m.search.Stop()
+				m.state = "clusters"
+				return m, nil
+			}
+			if m.state == "topics" || m.state == "topic_details" || m.state == "messages" ||
+				m.state == "groups" || m.state == "group_details" || m.state == "subjects" || m.state == "subject" {
 				fmt.Fprintf(f, "Changing state to clusters from %s\n", m.state)
 				m.state = "clusters"
 				return m, nil
@@ -224,6 +411,10 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.state = "add_cluster"
 				m.clusterForm = NewClusterForm(m.width, m.height, nil)
 				return m, m.clusterForm.Init()
+			} else if m.state == "topic_details" {
+				// Show or hide configs left at their default
+				m.topicDetails.ToggleDefaults()
+				return m, nil
 			}
 		case "n":
 			// Add a new topic
@@ -232,10 +423,16 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			defer f.Close()
 			fmt.Fprintf(f, "'n' key pressed in state: %s\n", m.state)
 
-			if m.state == "topics" {
+			if m.state == "messages" && m.messages != nil {
+				// Read the next page of records
+				page := m.messages
+				return m, m.ops.run("Reading messages of "+page.Topic, func(ctx context.Context) tea.Msg {
+					return NextMessagesCmd(ctx, m.app, page, m.pageSize())()
+				})
+			} else if m.state == "topics" {
 				fmt.Fprintf(f, "Creating new topic form\n")
 				m.state = "add_topic"
-				m.topicForm = NewTopicForm(m.width, m.height, "", 0)
+				m.topicForm = NewAddTopicForm(m.width, m.height, m.config.TopicTemplates)
 				cmd := m.topicForm.Init()
 				fmt.Fprintf(f, "Topic form initialized\n")
 				return m, cmd
@@ -256,18 +453,222 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			} else if m.state == "topics" {
 				if i, ok := m.topicList.SelectedItem().(Item); ok {
 					topicName := i.Title()
-					return m, func() tea.Msg {
-						ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-						defer cancel()
-
+					return m, m.ops.run("Deleting topic "+topicName, func(ctx context.Context) tea.Msg {
 						if err := m.app.DeleteTopic(ctx, topicName); err != nil {
 							return ErrorMsg{err}
 						}
 
 						// Update the topic list
-						return UpdateTopicListCmd(m.app)()
+						return UpdateTopicListCmd(ctx, m.app)()
+					})
+				}
+			} else if m.state == "groups" {
+				// Delete the selected groups after a confirmation
+				groupIDs := m.groupList.SelectedIDs()
+				if len(groupIDs) > 0 {
+					m.prompt = newPrompt(m.width, fmt.Sprintf("Delete %d consumer groups and their committed offsets?", len(groupIDs)),
+						deleteGroupsSummary(m.groupList.groups, groupIDs), "groups", func(string) tea.Msg {
+							return GroupsDeleteConfirmedMsg{GroupIDs: groupIDs}
+						})
+					m.state = "prompt"
+					return m, m.prompt.Init()
+				}
+			} else if m.state == "subject" {
+				// Soft- or hard-delete the version under the cursor after a confirmation
+				if version, ok := m.subjectPanes.Selected(); ok {
+					subject, v := m.subjectPanes.details.Subject, version.Version
+					m.prompt = newPrompt(m.width, fmt.Sprintf("Delete version %d of subject %s?", v, subject),
+						deleteVersionBody(subject, v), "subject", func(mode string) tea.Msg {
+							return SchemaVersionDeleteConfirmedMsg{Subject: subject, Version: v, Permanent: mode == "hard"}
+						}).WithChoice([]string{"soft", "hard"})
+					m.state = "prompt"
+					return m, m.prompt.Init()
+				}
+			}
+		case "m":
+			// Browse the messages of the topic
+			if m.state == "topics" {
+				if i, ok := m.topicList.SelectedItem().(Item); ok {
+					partitions := 0
+					if info, ok := i.Data.(*kafka.TopicInfo); ok && info != nil {
+						partitions = info.Partitions
+					}
+					m.browseForm = NewBrowseForm(m.width, i.Title(), partitions, m.pageSize())
+					m.messagesBack = m.state
+					m.state = "browse_form"
+					return m, m.browseForm.Init()
+				}
+			} else if m.state == "topic_details" && m.topicDetails.details != nil {
+				details := m.topicDetails.details
+				m.browseForm = NewBrowseForm(m.width, details.Name, len(details.Partitions), m.pageSize())
+				m.messagesBack = m.state
+				m.state = "browse_form"
+				return m, m.browseForm.Init()
+			}
+		case "t":
+			// Follow the records appended to the topic
+			topicName := ""
+			if m.state == "topics" {
+				if i, ok := m.topicList.SelectedItem().(Item); ok {
+					topicName = i.Title()
+				}
+			} else if m.state == "topic_details" && m.topicDetails.details != nil {
+				topicName = m.topicDetails.details.Name
+			}
+			if m.state == "subject" && m.subjectPanes.details != nil {
+				// Test a candidate schema against the subject
+				latest, _ := m.subjectPanes.Latest()
+				m.schemaTest = NewSchemaTestForm(m.width, m.height, m.subjectPanes.details.Subject, latest)
+				m.state = "schema_test"
+				return m, m.schemaTest.Init()
+			}
+			if topicName != "" {
+				m.messagesBack = m.state
+				return m, m.ops.run("Starting tail of "+topicName, func(ctx context.Context) tea.Msg {
+					return StartTailCmd(ctx, m.app, topicName)()
+				})
+			}
+		case " ":
+			// Pause or resume the tail
+			if m.state == "tail" {
+				return m, m.tail.TogglePause()
+			}
+		case "k", "v", "f":
+			// Switch the key or value format, or show records in full
+			var format *recordFormat
+			if m.state == "messages" && m.messages != nil {
+				format = &m.format
+			} else if m.state == "tail" {
+				format = &m.tail.format
+			} else if m.state == "search" && m.search.result != nil {
+				format = &m.search.format
+			}
+			if format != nil {
+				switch msg.String() {
+				case "k":
+					format.NextKey()
+				case "v":
+					format.NextValue()
+				default:
+					format.ToggleFull()
+				}
+				if m.state == "tail" {
+					m.tail.render()
+				} else if m.state == "search" {
+					m.search.render()
+				} else {
+					m.showMessages()
+				}
+				return m, nil
+			}
+			// Search the messages of the topic, keeping the last search of the same topic
+			if msg.String() == "f" {
+				if topicName, partitions := m.selectedTopic(); topicName != "" {
+					if m.searchForm.topic != topicName {
+						m.searchForm = NewSearchForm(m.width, topicName, partitions)
 					}
+					m.searchBack = m.state
+					m.state = "search_form"
+					return m, m.searchForm.Init()
+				}
+			}
+		case "p":
+			// Read the previous page of records
+			if m.state == "messages" && m.messages != nil {
+				if !m.messages.HasPrev() {
+					m.status = "At the start of the topic"
+					return m, nil
 				}
+				page := m.messages
+				return m, m.ops.run("Reading messages of "+page.Topic, func(ctx context.Context) tea.Msg {
+					return PrevMessagesCmd(ctx, m.app, page, m.pageSize())()
+				})
+			}
+			// Write records to the topic
+			if topicName, partitions := m.selectedTopic(); topicName != "" {
+				m.produceForm = NewProduceForm(m.width, m.height, topicName, partitions)
+				m.produceBack = m.state
+				m.state = "produce_form"
+				return m, m.produceForm.Init()
+			}
+		case "P":
+			// Write the records of a file to the topic
+			if topicName, _ := m.selectedTopic(); topicName != "" {
+				m.bulkForm = NewBulkProduceForm(m.width, topicName)
+				m.produceBack = m.state
+				m.state = "bulk_produce"
+				return m, m.bulkForm.Init()
+			}
+		case "w":
+			// Write the records on screen, or their whole range, to a file
+			if m.state == "messages" && m.messages != nil {
+				page := m.messages
+				start := make(map[int]int64, len(page.Start))
+				for partition, offset := range page.Start {
+					start[partition] = offset
+				}
+				req := core.SearchRequest{Topic: page.Topic, Partition: page.Partition, Offsets: start, ToOffset: -1}
+				label := "from the page to the end of the topic"
+				if page.Partition != core.AllPartitions {
+					label = fmt.Sprintf("from the page to the end of partition %d", page.Partition)
+				}
+				m.exportForm = NewExportForm(m.width, page.Topic, page.Messages, req, label)
+				m.exportBack = m.state
+				m.state = "export_form"
+				return m, m.exportForm.Init()
+			} else if m.state == "search" && !m.search.running && m.search.result != nil {
+				m.exportForm = NewExportForm(m.width, m.search.req.Topic, m.search.result.Messages, m.search.req,
+					"every match in the range of the search, without its limit")
+				m.exportBack = m.state
+				m.state = "export_form"
+				return m, m.exportForm.Init()
+			}
+		case "i":
+			// Write the records of an export file to the topic
+			if topicName, _ := m.selectedTopic(); topicName != "" {
+				clusters := make([]string, len(m.config.Clusters))
+				for i, cluster := range m.config.Clusters {
+					clusters[i] = cluster.Name
+				}
+				m.importForm = NewImportForm(m.width, m.selectedCluster, clusters, topicName)
+				m.importBack = m.state
+				m.state = "import_form"
+				return m, m.importForm.Init()
+			}
+		case "/":
+			// Select the Empty groups matching a pattern
+			if m.state == "groups" {
+				m.prompt = newPrompt(m.width, "Select the Empty groups matching", "Use * for any characters and ? for one character", "groups",
+					func(pattern string) tea.Msg { return GroupPatternSubmittedMsg{Pattern: pattern} }).WithInput("e.g. test-*")
+				m.state = "prompt"
+				return m, m.prompt.Init()
+			}
+		case "x":
+			// Delete the records of the browsed topic up to the record under the cursor
+			if m.state == "messages" && m.messages != nil {
+				if m.cursor >= len(m.messages.Messages) {
+					m.status = "No record to delete up to"
+					return m, nil
+				}
+				m.truncateForm = NewTruncateForm(m.width, m.height, m.messages, m.messages.Messages[m.cursor])
+				m.state = "truncate_form"
+				return m, m.truncateForm.Init()
+			}
+			// Remove the offsets of one topic from the group
+			if m.state == "group_details" && m.groupDetails.group != nil {
+				group := m.groupDetails.group
+				topics := groupTopics(group)
+				if len(topics) == 0 {
+					m.status = "Group " + group.GroupID + " has no committed offsets"
+					return m, nil
+				}
+				m.prompt = newPrompt(m.width, "Remove the committed offsets of group "+group.GroupID+" for topic",
+					"The group keeps its offsets on other topics. Brokers refuse while members of the group consume the topic.",
+					"group_details", func(topic string) tea.Msg {
+						return GroupOffsetsDeleteConfirmedMsg{GroupID: group.GroupID, Topic: topic}
+					}).WithChoice(topics)
+				m.state = "prompt"
+				return m, m.prompt.Init()
 			}
 		case "e":
 			// Debug log to file
@@ -316,45 +717,25 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					fmt.Fprintf(f, "Setting state to edit_topic\n")
 					m.state = "edit_topic"
 
-					// Get topic info for editing
-					return m, func() tea.Msg {
-						f, _ := os.OpenFile("/tmp/cfk_debug.log", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-						defer f.Close()
-						fmt.Fprintf(f, "Getting topic info for %s\n", topicName)
-
-						ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-						defer cancel()
+					// Show the form right away and fill in the partition count once loaded
+					m.topicForm = NewTopicForm(m.width, m.height, topicName, 0)
 
+					// Get topic info for editing
+					return m, m.ops.run("Loading topic "+topicName, func(ctx context.Context) tea.Msg {
 						topicInfo, err := m.app.GetTopicInfo(ctx, topicName)
 						if err != nil {
-							fmt.Fprintf(f, "Error getting topic info: %v\n", err)
 							return ErrorMsg{err}
 						}
-
-						fmt.Fprintf(f, "Got topic info, partitions: %d\n", topicInfo.Partitions)
-						// Create the form
-						m.topicForm = NewTopicForm(m.width, m.height, topicName, topicInfo.Partitions)
-						fmt.Fprintf(f, "Created topic form\n")
-
-						// Double-check that the state is still edit_topic
-						if m.state != "edit_topic" {
-							fmt.Fprintf(f, "WARNING: State changed from edit_topic to %s, forcing back to edit_topic\n", m.state)
-							m.state = "edit_topic"
-						}
-
-						// Make sure the topic name is set in the form
-						m.topicForm.topicName = topicName
-						m.topicForm.isEdit = true
-						fmt.Fprintf(f, "Explicitly set topicName='%s' and isEdit=true\n", topicName)
-
-						// Initialize the form
-						cmd := m.topicForm.Init()()
-						fmt.Fprintf(f, "Initialized topic form\n")
-						return cmd
-					}
+						return TopicEditLoadedMsg{Info: topicInfo}
+					})
 				} else {
 					fmt.Fprintf(f, "Could not get selected topic item\n")
 				}
+			} else if m.state == "topic_details" && m.topicDetails.details != nil {
+				// Edit the topic configs
+				m.configForm = NewConfigForm(m.width, m.height, m.topicDetails.details)
+				m.state = "edit_config"
+				return m, m.configForm.Init()
 			} else {
 				fmt.Fprintf(f, "Unhandled state for 'e' key: %s\n", m.state)
 			}
@@ -365,20 +746,8 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		defer f.Close()
 		fmt.Fprintf(f, "ItemsUpdatedMsg received with state: %s, items count: %d\n", m.state, len(msg.Items))
 
-		// Check if these are topics (they'll have names like _schemas or __consumer_offsets)
-		isTopic := false
-		if len(msg.Items) > 0 {
-			if i, ok := msg.Items[0].(Item); ok {
-				title := i.Title()
-				if title == "_schemas" || title == "__consumer_offsets" {
-					isTopic = true
-					fmt.Fprintf(f, "Detected topic items based on names\n")
-				}
-			}
-		}
-
 		// Update the list with the new items
-		if m.state == "topics" || (isTopic && m.state != "edit_topic" && m.state != "add_topic") {
+		if msg.Topics {
 			fmt.Fprintf(f, "Setting items for topic list\n")
 			// Only set the state if we're not in a form
 			if m.state != "edit_topic" && m.state != "add_topic" {
@@ -386,13 +755,27 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			m.topicList.Title = "Topics in " + m.selectedCluster // Ensure title is set
 			m.topicList.SetItems(msg.Items)
-		} else if m.state == "clusters" {
+		} else {
 			fmt.Fprintf(f, "Setting items for cluster list\n")
 			m.clusterList.SetItems(msg.Items)
-		} else {
-			fmt.Fprintf(f, "Unknown state: %s\n", m.state)
 		}
 		return m, nil
+	case TopicDetailsLoadedMsg:
+		// Show the partitions and configs of the topic
+		m.topicDetails.SetDetails(msg.Details)
+		m.state = "topic_details"
+		return m, nil
+	case GroupsLoadedMsg:
+		// Show the consumer groups
+		m.groupList.SetGroups(msg.Groups)
+		m.state = "groups"
+		return m, nil
+	case GroupDetailsLoadedMsg:
+		// Show the members and offsets of the group
+		m.selectedGroup = msg.Group.GroupID
+		m.groupDetails.SetGroup(msg.Group)
+		m.state = "group_details"
+		return m, nil
 	case ErrorMsg:
 		// Handle errors
 		m.err = msg.err
@@ -421,39 +804,320 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		// Return to clusters view
 		m.state = "clusters"
 		return m, nil
+	case TopicEditLoadedMsg:
+		// Fill in the edit form once the topic details arrive
+		if m.state == "edit_topic" && m.topicForm.topicName == msg.Info.Name {
+			m.topicForm = NewTopicForm(m.width, m.height, msg.Info.Name, msg.Info.Partitions)
+			return m, m.topicForm.Init()
+		}
+		return m, nil
 	case TopicAddedMsg:
-		// Add the new topic
-		return m, func() tea.Msg {
-			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-			defer cancel()
-
-			if err := m.app.CreateTopic(ctx, msg.Name, msg.Partitions, msg.ReplicationFactor); err != nil {
+		// Return to topics view and add the new topic
+		m.state = "topics"
+		return m, m.ops.run("Creating topic "+msg.Spec.Name, func(ctx context.Context) tea.Msg {
+			if err := m.app.CreateTopicFromSpec(ctx, msg.Spec); err != nil {
 				return ErrorMsg{err}
 			}
 
-			// Return to topics view and update the list
-			m.state = "topics"
-			return UpdateTopicListCmd(m.app)()
-		}
+			// Update the list
+			return UpdateTopicListCmd(ctx, m.app)()
+		})
 	case TopicUpdatedMsg:
-		// Update the topic
-		return m, func() tea.Msg {
-			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-			defer cancel()
-
+		// Return to topics view and update the topic
+		m.state = "topics"
+		return m, m.ops.run("Updating topic "+msg.OldName, func(ctx context.Context) tea.Msg {
 			// Currently we can only update partitions
 			if err := m.app.UpdateTopicPartitions(ctx, msg.OldName, msg.Partitions); err != nil {
 				return ErrorMsg{err}
 			}
 
-			// Return to topics view and update the list
-			m.state = "topics"
-			return UpdateTopicListCmd(m.app)()
-		}
+			// Update the list
+			return UpdateTopicListCmd(ctx, m.app)()
+		})
+	case OperationCancelledMsg:
+		// Stay on the current view
+		m.status = "Cancelled: " + msg.Label
+		return m, nil
 	case TopicFormCancelledMsg:
 		// Return to topics view
 		m.state = "topics"
 		return m, nil
+	case ConfigChangesSubmittedMsg:
+		// Return to the topic details and apply the changes
+		m.state = "topic_details"
+		return m, m.ops.run("Updating configs of "+msg.Topic, func(ctx context.Context) tea.Msg {
+			return AlterTopicConfigCmd(ctx, m.app, msg.Topic, msg.Changes)()
+		})
+	case TopicConfigAlteredMsg:
+		// Show the topic with its new configs
+		m.topicDetails.SetDetails(msg.Details)
+		m.status = fmt.Sprintf("Updated %d configs of topic %s", msg.Changed, msg.Details.Name)
+		return m, nil
+	case ConfigFormCancelledMsg:
+		// Return to the topic details
+		m.state = "topic_details"
+		return m, nil
+	case OffsetResetPlanMsg:
+		// Work out the dry run of the reset
+		return m, m.ops.run("Planning offset reset of "+msg.GroupID, func(ctx context.Context) tea.Msg {
+			return PlanOffsetResetCmd(ctx, m.app, msg.GroupID, msg.Reset)()
+		})
+	case OffsetResetPlannedMsg:
+		// Show the dry run
+		m.resetForm.SetPlan(msg.Changes, msg.Err)
+		return m, nil
+	case OffsetResetConfirmedMsg:
+		// Return to the group details and commit the new offsets
+		m.state = "group_details"
+		return m, m.ops.run("Resetting offsets of "+msg.GroupID, func(ctx context.Context) tea.Msg {
+			return ApplyOffsetResetCmd(ctx, m.app, msg.GroupID, msg.Changes)()
+		})
+	case OffsetsResetMsg:
+		// Show the group with its new offsets
+		m.groupDetails.SetGroup(msg.Group)
+		m.status = fmt.Sprintf("Reset %d offsets of group %s", msg.Reset, msg.Group.GroupID)
+		return m, nil
+	case OffsetResetCancelledMsg:
+		// Return to the group details
+		m.state = "group_details"
+		return m, nil
+	case GroupPatternSubmittedMsg:
+		// Select the matching groups
+		m.state = "groups"
+		groupIDs, err := core.MatchEmptyGroups(m.groupList.groups, msg.Pattern)
+		if err != nil {
+			m.status = err.Error()
+			return m, nil
+		}
+		m.groupList.Select(groupIDs)
+		m.status = fmt.Sprintf("Selected %d Empty groups matching %s", len(groupIDs), msg.Pattern)
+		return m, nil
+	case GroupsDeleteConfirmedMsg:
+		// Return to the groups and delete them
+		m.state = "groups"
+		return m, m.ops.run(fmt.Sprintf("Deleting %d groups", len(msg.GroupIDs)), func(ctx context.Context) tea.Msg {
+			return DeleteGroupsCmd(ctx, m.app, msg.GroupIDs)()
+		})
+	case GroupsDeletedMsg:
+		// Show the remaining groups and what could not be deleted
+		m.groupList.SetGroups(msg.Groups)
+		m.groupList.Select(nil)
+		m.status = deleteGroupsStatus(msg.Results)
+		return m, nil
+	case GroupOffsetsDeleteConfirmedMsg:
+		// Return to the group details and remove the offsets
+		m.state = "group_details"
+		return m, m.ops.run("Removing offsets of "+msg.GroupID, func(ctx context.Context) tea.Msg {
+			return DeleteGroupOffsetsCmd(ctx, m.app, msg.GroupID, msg.Topic)()
+		})
+	case GroupOffsetsDeletedMsg:
+		// Show the group without the offsets of the topic
+		m.groupDetails.SetGroup(msg.Group)
+		m.status = fmt.Sprintf("Removed the offsets of group %s for topic %s", msg.Group.GroupID, msg.Topic)
+		return m, nil
+	case BrowseRequestedMsg:
+		// Read the first page of records
+		return m, m.ops.run("Reading messages of "+msg.Request.Topic, func(ctx context.Context) tea.Msg {
+			return BrowseMessagesCmd(ctx, m.app, msg.Request, m.pageSize())()
+		})
+	case MessagesLoadedMsg:
+		// Keep the current page when paging runs out of records
+		if m.state == "messages" && len(msg.Page.Messages) == 0 {
+			m.status = "No more records"
+			return m, nil
+		}
+		// Decode the records of a newly browsed topic as its serde rule says
+		if m.state != "messages" {
+			m.format = newRecordFormat(m.serdes, m.config.Serdes, msg.Page.Topic)
+		}
+		m.messages = msg.Page
+		m.cursor = 0
+		m.viewport.GotoTop()
+		m.showMessages()
+		m.state = "messages"
+		return m, nil
+	case TailStartedMsg:
+		// Start polling, replacing any previous tail
+		m.tail.Stop()
+		m.tail = newMessageTail(m.width, m.height, msg.Tail, m.tailBufferSize(), m.pageSize(),
+			newRecordFormat(m.serdes, m.config.Serdes, msg.Tail.Topic()))
+		m.state = "tail"
+		return m, m.tail.poll()
+	case TailPolledMsg:
+		// Ignore polls of a stopped tail
+		if msg.Tail != m.tail.tail {
+			return m, nil
+		}
+		return m, m.tail.Polled(msg)
+	case tailTickMsg:
+		if msg.Tail != m.tail.tail {
+			return m, nil
+		}
+		return m, m.tail.poll()
+	case BrowseFormCancelledMsg:
+		// Return to where the browser was opened
+		m.state = m.messagesBack
+		return m, nil
+	case SubjectsLoadedMsg:
+		// Show the subjects of the registry
+		m.subjectList.Title = "Subjects in " + m.app.SchemaRegistry.URL
+		m.subjectList.SetItems(msg.Items)
+		m.state = "subjects"
+		return m, nil
+	case SubjectLoadedMsg:
+		// Show the versions of the subject
+		m.subjectPanes.SetDetails(msg.Details)
+		m.state = "subject"
+		return m, nil
+	case SubjectCompatibilityConfirmedMsg:
+		// Return to the subject and change its level
+		m.state = "subject"
+		return m, m.ops.run("Setting compatibility of "+msg.Subject, func(ctx context.Context) tea.Msg {
+			return SetSubjectCompatibilityCmd(ctx, m.app, msg.Subject, msg.Level)()
+		})
+	case SubjectCompatibilitySetMsg:
+		// Show the subject with its new level
+		m.subjectPanes.SetDetails(msg.Details)
+		m.status = fmt.Sprintf("Set compatibility of subject %s to %s", msg.Details.Subject, msg.Details.Compatibility)
+		return m, nil
+	case SchemaVersionDeleteConfirmedMsg:
+		// Return to the subject and delete the version
+		m.state = "subject"
+		return m, m.ops.run(fmt.Sprintf("Deleting version %d of %s", msg.Version, msg.Subject), func(ctx context.Context) tea.Msg {
+			return DeleteSchemaVersionCmd(ctx, m.app, msg.Subject, msg.Version, msg.Permanent)()
+		})
+	case SchemaVersionDeletedMsg:
+		// Show the remaining versions, or the subjects once none are left
+		how := "Soft-deleted"
+		if msg.Permanent {
+			how = "Permanently deleted"
+		}
+		m.status = fmt.Sprintf("%s version %d of subject %s", how, msg.Version, msg.Subject)
+		if msg.Details == nil {
+			m.subjectList.SetItems(msg.Subjects)
+			m.state = "subjects"
+			return m, nil
+		}
+		m.subjectPanes.SetDetails(msg.Details)
+		return m, nil
+	case SchemaTestRequestedMsg:
+		// Test the schema, keeping the form open for the result
+		return m, m.ops.run("Testing schema against "+msg.Subject, func(ctx context.Context) tea.Msg {
+			return TestSchemaCompatibilityCmd(ctx, m.app, msg.Subject, msg.SchemaType, msg.Schema)()
+		})
+	case SchemaCompatibilityTestedMsg:
+		m.schemaTest.SetResult(msg.Result, msg.Err)
+		return m, nil
+	case SchemaTestCancelledMsg:
+		// Return to the subject
+		m.state = "subject"
+		return m, nil
+	case ProduceRequestedMsg:
+		// Write the record, keeping the form open for the result
+		return m, m.ops.run("Producing to "+msg.Request.Topic, func(ctx context.Context) tea.Msg {
+			return ProduceMessageCmd(ctx, m.app, msg.Request)()
+		})
+	case MessageProducedMsg:
+		m.produceForm.SetResult(msg.Message, msg.Err)
+		return m, nil
+	case ProduceFormCancelledMsg:
+		// Return to where the form was opened
+		m.state = m.produceBack
+		return m, nil
+	case BulkProduceRequestedMsg:
+		// Start the run and follow its progress in the form
+		run := startBulkRun(m.app, msg)
+		m.bulkForm.Start(run)
+		return m, run.wait()
+	case RunMsg[core.BulkProgress, bulkResult]:
+		return m, followRun(m.bulkForm.run, msg, m.bulkForm.SetProgress, m.bulkForm.SetResult)
+	case BulkProduceFormCancelledMsg:
+		// Return to where the form was opened
+		m.state = m.produceBack
+		return m, nil
+	case SearchRequestedMsg:
+		// Start the search and follow its progress
+		if m.search != nil {
+			m.search.Stop()
+		}
+		m.search = startMessageSearch(m.width, m.height, m.app, msg.Request,
+			newRecordFormat(m.serdes, m.config.Serdes, msg.Request.Topic))
+		m.state = "search"
+		return m, m.search.run.wait()
+	case RunMsg[core.SearchProgress, searchResult]:
+		return m, followRun(m.search.run, msg, m.search.SetProgress, m.search.SetResult)
+	case SearchFormCancelledMsg:
+		// Return to where the form was opened
+		m.state = m.searchBack
+		return m, nil
+	case ExportRequestedMsg:
+		// Start the export and follow its progress in the form
+		run := startExportRun(m.app, msg)
+		m.exportForm.Start(run)
+		return m, run.wait()
+	case RunMsg[core.SearchProgress, exportResult]:
+		return m, followRun(m.exportForm.run, msg, m.exportForm.SetProgress, m.exportForm.SetResult)
+	case ExportFormCancelledMsg:
+		// Return to where the form was opened
+		m.state = m.exportBack
+		return m, nil
+	case CopyRequestedMsg:
+		// Start the copy and follow its progress in the form
+		run := startCopyRun(m.app, msg.Request)
+		m.copyForm.Start(run)
+		return m, run.wait()
+	case RunMsg[core.SearchProgress, copyResult]:
+		return m, followRun(m.copyForm.run, msg, m.copyForm.SetProgress, m.copyForm.SetResult)
+	case CopyFormCancelledMsg:
+		// Return to where the form was opened
+		m.state = m.copyBack
+		return m, nil
+	case ImportRequestedMsg:
+		// Start the import and follow its progress in the form
+		run := startImportRun(m.app, msg)
+		m.importForm.Start(run)
+		return m, run.wait()
+	case RunMsg[core.ImportProgress, importResult]:
+		return m, followRun(m.importForm.run, msg, m.importForm.SetProgress, m.importForm.SetResult)
+	case ImportFormCancelledMsg:
+		// Return to where the form was opened
+		m.state = m.importBack
+		return m, nil
+	case TruncatePlanMsg:
+		// Work out the records the truncation deletes
+		return m, m.ops.run("Planning truncation of "+msg.Request.Topic, func(ctx context.Context) tea.Msg {
+			return PlanTruncateCmd(ctx, m.app, msg.Request)()
+		})
+	case TruncatePlannedMsg:
+		// Show the records to delete
+		m.truncateForm.SetPlan(msg.Plan, msg.Err)
+		return m, nil
+	case TruncateConfirmedMsg:
+		// Return to the message browser and delete the records
+		m.state = "messages"
+		return m, m.ops.run("Deleting records of "+msg.Topic, func(ctx context.Context) tea.Msg {
+			return ApplyTruncateCmd(ctx, m.app, msg.Topic, msg.Partition, msg.Plan, m.pageSize())()
+		})
+	case RecordsDeletedMsg:
+		// Show the new start of the topic, even if nothing is left
+		m.messages = msg.Page
+		m.cursor = 0
+		m.viewport.GotoTop()
+		m.showMessages()
+		m.status = fmt.Sprintf("Deleted %d records of topic %s", msg.Deleted, msg.Page.Topic)
+		return m, nil
+	case TruncateCancelledMsg:
+		// Return to the message browser
+		m.state = "messages"
+		return m, nil
+	case SearchClosedMsg:
+		// Return to the form to change the search
+		m.state = "search_form"
+		return m, m.searchForm.Init()
+	case PromptCancelledMsg:
+		// Return to where the prompt was opened
+		m.state = msg.Back
+		return m, nil
 	}
 
 	// Update components based on current state
@@ -462,11 +1126,48 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.topicList, cmd = m.topicList.Update(msg)
 		return m, cmd
 	case "topic_details":
-		m.topicTable, cmd = m.topicTable.Update(msg)
+		// Scroll the focused pane
+		if m.topicDetails.focus == 0 {
+			m.topicDetails.partitions, cmd = m.topicDetails.partitions.Update(msg)
+		} else {
+			m.topicDetails.configs, cmd = m.topicDetails.configs.Update(msg)
+		}
 		return m, cmd
 	case "messages":
+		// Move the cursor over the records, and scroll with the other keys
+		if keyMsg, ok := msg.(tea.KeyMsg); ok && m.messages != nil {
+			switch keyMsg.String() {
+			case "up":
+				if m.cursor > 0 {
+					m.cursor--
+					m.showMessages()
+				}
+				return m, nil
+			case "down":
+				if m.cursor < len(m.messages.Messages)-1 {
+					m.cursor++
+					m.showMessages()
+				}
+				return m, nil
+			}
+		}
 		m.viewport, cmd = m.viewport.Update(msg)
 		return m, cmd
+	case "groups":
+		m.groupList = m.groupList.Update(msg)
+		return m, nil
+	case "group_details":
+		m.groupDetails, cmd = m.groupDetails.Update(msg)
+		return m, cmd
+	case "subjects":
+		m.subjectList, cmd = m.subjectList.Update(msg)
+		return m, cmd
+	case "subject":
+		m.subjectPanes, cmd = m.subjectPanes.Update(msg)
+		return m, cmd
+	case "schema_test":
+		m.schemaTest, cmd = m.schemaTest.Update(msg)
+		return m, cmd
 	case "add_cluster", "edit_cluster":
 		// Update the cluster form
 		newForm, cmd := m.clusterForm.Update(msg)
@@ -478,38 +1179,6 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		defer f.Close()
 		fmt.Fprintf(f, "Updating topic form with message type: %T\n", msg)
 
-		// Ensure the form state is correct based on the application state
-		if m.state == "edit_topic" {
-			// If we're in edit_topic state, make sure we have the correct topic info
-			if i, ok := m.topicList.SelectedItem().(Item); ok {
-				topicName := i.Title()
-				fmt.Fprintf(f, "Getting current topic info for '%s' before update\n", topicName)
-
-				// Get the latest topic info
-				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-				defer cancel()
-
-				topicInfo, err := m.app.GetTopicInfo(ctx, topicName)
-				if err == nil {
-					// Update the form with the correct topic info
-					fmt.Fprintf(f, "Setting form with topic '%s' and partitions %d\n", topicName, topicInfo.Partitions)
-					m.topicForm.topicName = topicName
-					m.topicForm.isEdit = true
-					m.topicForm.partitions = topicInfo.Partitions
-
-					// Set the input values
-					m.topicForm.inputs[0].SetValue(topicName)
-					m.topicForm.inputs[1].SetValue(fmt.Sprintf("%d", topicInfo.Partitions))
-				} else {
-					fmt.Fprintf(f, "Error getting topic info: %v\n", err)
-					// Still set the topic name at minimum
-					m.topicForm.topicName = topicName
-					m.topicForm.isEdit = true
-					m.topicForm.inputs[0].SetValue(topicName)
-				}
-			}
-		}
-
 		// Update the topic form
 		newForm, cmd := m.topicForm.Update(msg)
 
@@ -522,6 +1191,44 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		m.topicForm = newForm
 		return m, cmd
+	case "edit_config":
+		m.configForm, cmd = m.configForm.Update(msg)
+		return m, cmd
+	case "reset_offsets":
+		m.resetForm, cmd = m.resetForm.Update(msg)
+		return m, cmd
+	case "prompt":
+		m.prompt, cmd = m.prompt.Update(msg)
+		return m, cmd
+	case "browse_form":
+		m.browseForm, cmd = m.browseForm.Update(msg)
+		return m, cmd
+	case "produce_form":
+		m.produceForm, cmd = m.produceForm.Update(msg)
+		return m, cmd
+	case "bulk_produce":
+		m.bulkForm, cmd = m.bulkForm.Update(msg)
+		return m, cmd
+	case "search_form":
+		m.searchForm, cmd = m.searchForm.Update(msg)
+		return m, cmd
+	case "search":
+		return m, m.search.Update(msg)
+	case "export_form":
+		m.exportForm, cmd = m.exportForm.Update(msg)
+		return m, cmd
+	case "copy_form":
+		m.copyForm, cmd = m.copyForm.Update(msg)
+		return m, cmd
+	case "import_form":
+		m.importForm, cmd = m.importForm.Update(msg)
+		return m, cmd
+	case "truncate_form":
+		m.truncateForm, cmd = m.truncateForm.Update(msg)
+		return m, cmd
+	case "tail":
+		m.tail, cmd = m.tail.Update(msg)
+		return m, cmd
 	default: // clusters
 		m.clusterList, cmd = m.clusterList.Update(msg)
 		return m, cmd
@@ -530,6 +1237,22 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 // View renders the TUI
 func (m Model) View() string {
+	return m.stateView() + m.statusLine()
+}
+
+// statusLine renders the in-flight operation or the last status message
+func (m Model) statusLine() string {
+	if running := m.ops.Running(); running != "" {
+		return "\n\n" + running + "... (press 'ctrl+x' to cancel)"
+	}
+	if m.status != "" {
+		return "\n\n" + m.status
+	}
+	return ""
+}
+
+// stateView renders the view for the current state
+func (m Model) stateView() string {
 	// Debug log to file
 	f, _ := os.OpenFile("/tmp/cfk_debug.log", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	defer f.Close()
@@ -539,55 +1262,67 @@ func (m Model) View() string {
 		return fmt.Sprintf("Error: %v\n\nPress any key to exit.", m.err)
 	}
 
-
-
 	fmt.Fprintf(f, "View switch statement with state: %s\n", m.state)
 	switch m.state {
 	case "topics":
-		helpText := "\nPress 'n' to add new topic, 'e' to edit, 'd' to delete, 'enter' to view details, 'b' or 'esc' to go back to clusters, 'q' to quit"
+		helpText := "\nPress 'n' to add new topic, 'e' to edit, 'd' to delete, 'enter' to view details, 'm' to browse messages, 't' to tail, 'f' to search, 'p' to produce, 'P' to produce from a file, 'c' to copy to another topic, 'i' to import an export, 'g' for consumer groups, 's' for the schema registry, 'b' or 'esc' to go back to clusters, 'q' to quit"
 		return fmt.Sprintf("Connected to cluster: %s\n\n%s\n%s",
 			m.selectedCluster, m.topicList.View(), helpText)
 	case "topic_details":
-		return m.topicTable.View() + "\n\nPress 'esc' to go back to topics, 'b' to go back to clusters, 'q' to quit"
+		return m.topicDetails.View() +
+			"\n\nPress 'tab' to switch panes, 'a' to toggle default configs, 'e' to edit configs, 'm' to browse messages, 't' to tail, 'f' to search, 'p' to produce, 'P' to produce from a file, 'c' to copy to another topic, 'i' to import an export, 'r' to refresh, 'esc' to go back to topics, 'b' to go back to clusters, 'q' to quit"
 	case "messages":
-		return m.viewport.View() + "\n\nPress 'esc' to go back, 'q' to quit"
+		if m.messages == nil {
+			return m.viewport.View()
+		}
+		return messageSummary(m.messages) + "  •  " + m.format.String() + "\n" + m.viewport.View() +
+			"\n\nPress 'n' for the next page, 'p' for the previous page, 'k'/'v' to switch the key/value format, 'f' to show records in full, 'w' to export to a file, 'up'/'down' to move the cursor, 'x' to delete the records before the cursor, 'esc' to go back, 'b' to go back to clusters, 'q' to quit"
+	case "browse_form":
+		return m.browseForm.View()
+	case "produce_form":
+		return m.produceForm.View()
+	case "bulk_produce":
+		return m.bulkForm.View()
+	case "search_form":
+		return m.searchForm.View()
+	case "search":
+		return m.search.View()
+	case "export_form":
+		return m.exportForm.View()
+	case "copy_form":
+		return m.copyForm.View()
+	case "import_form":
+		return m.importForm.View()
+	case "truncate_form":
+		return m.truncateForm.View()
+	case "tail":
+		return m.tail.View()
+	case "groups":
+		return fmt.Sprintf("Consumer groups in %s\n\n%s\n\n%s", m.selectedCluster, m.groupList.View(),
+			"Press 'enter' to view a group, 'space' to select, '/' to select Empty groups by pattern, 'd' to delete, 'r' to refresh, 'esc' to go back to topics, 'b' to go back to clusters, 'q' to quit")
+	case "group_details":
+		return m.groupDetails.View() +
+			"\n\nPress 'tab' to switch panes, 'o' to reset offsets, 'x' to remove a topic's offsets, 'r' to refresh, 'esc' to go back to groups, 'b' to go back to clusters, 'q' to quit"
+	case "subjects":
+		return m.subjectList.View() +
+			"\n\nPress 'enter' to view a subject, 'r' to refresh, 'esc' to go back to topics, 'b' to go back to clusters, 'q' to quit"
+	case "subject":
+		return m.subjectPanes.View() +
+			"\n\nPress up/down to pick a version, 'space' to diff against it, 'c' to change compatibility, 't' to test a schema, 'd' to delete the version, 'r' to refresh, 'esc' to go back to subjects, 'b' to go back to clusters, 'q' to quit"
+	case "schema_test":
+		return m.schemaTest.View()
 	case "add_cluster", "edit_cluster":
 		return m.clusterForm.View()
 	case "add_topic", "edit_topic":
 		fmt.Fprintf(f, "Rendering %s form\n", m.state)
-		// Force the form to show the correct view based on the state
-		if m.state == "edit_topic" {
-			// If we're in edit_topic state, make sure we have the correct topic info
-			if i, ok := m.topicList.SelectedItem().(Item); ok {
-				topicName := i.Title()
-				fmt.Fprintf(f, "Getting current topic info for '%s' before rendering\n", topicName)
-
-				// Get the latest topic info
-				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-				defer cancel()
-
-				topicInfo, err := m.app.GetTopicInfo(ctx, topicName)
-				if err == nil {
-					// Update the form with the correct topic info
-					fmt.Fprintf(f, "Setting form with topic '%s' and partitions %d for rendering\n", topicName, topicInfo.Partitions)
-					m.topicForm.topicName = topicName
-					m.topicForm.isEdit = true
-					m.topicForm.partitions = topicInfo.Partitions
-
-					// Set the input values
-					m.topicForm.inputs[0].SetValue(topicName)
-					m.topicForm.inputs[1].SetValue(fmt.Sprintf("%d", topicInfo.Partitions))
-				} else {
-					fmt.Fprintf(f, "Error getting topic info for rendering: %v\n", err)
-					// Still set the topic name at minimum
-					m.topicForm.topicName = topicName
-					m.topicForm.isEdit = true
-					m.topicForm.inputs[0].SetValue(topicName)
-				}
-			}
-		}
 		// Return the form view
 		return m.topicForm.View()
+	case "edit_config":
+		return m.configForm.View()
+	case "reset_offsets":
+		return m.resetForm.View()
+	case "prompt":
+		return m.prompt.View()
 	default: // clusters
 		helpText := "\nPress 'a' to add, 'e' to edit, 'd' to delete, 'enter' to connect, 'q' to quit"
 		if m.clusterList.Items() == nil || len(m.clusterList.Items()) == 0 {
@@ -597,9 +1332,62 @@ func (m Model) View() string {
 	}
 }
 
+// selectedTopic returns the topic of the topic list or details with its
+// number of partitions, or 0 if not known
+func (m Model) selectedTopic() (string, int) {
+	if m.state == "topics" {
+		if i, ok := m.topicList.SelectedItem().(Item); ok {
+			partitions := 0
+			if info, ok := i.Data.(*kafka.TopicInfo); ok && info != nil {
+				partitions = info.Partitions
+			}
+			return i.Title(), partitions
+		}
+	} else if m.state == "topic_details" && m.topicDetails.details != nil {
+		return m.topicDetails.details.Name, len(m.topicDetails.details.Partitions)
+	}
+	return "", 0
+}
+
+// pageSize returns the number of records per page of the message browser
+func (m Model) pageSize() int {
+	if m.config.UI.MaxMessagesShown > 0 {
+		return m.config.UI.MaxMessagesShown
+	}
+	return config.DefaultConfig().UI.MaxMessagesShown
+}
+
+// showMessages renders the browsed page, scrolled to keep the record under
+// the cursor in view
+func (m *Model) showMessages() {
+	content, starts := messageTable(m.messages, m.viewport.Width-2, m.format, m.cursor)
+	m.viewport.SetContent(content)
+	if m.cursor == 0 || m.cursor >= len(starts) {
+		m.viewport.GotoTop()
+		return
+	}
+	// Records in full start at the top, lines of the table at the bottom
+	if line := starts[m.cursor]; line < m.viewport.YOffset || (m.format.full && line >= m.viewport.YOffset+m.viewport.Height) {
+		m.viewport.SetYOffset(line)
+	} else if line >= m.viewport.YOffset+m.viewport.Height {
+		m.viewport.SetYOffset(line - m.viewport.Height + 1)
+	}
+}
+
+// tailBufferSize returns the number of records the live tail keeps
+func (m Model) tailBufferSize() int {
+	if m.config.UI.TailBufferSize > 0 {
+		return m.config.UI.TailBufferSize
+	}
+	return config.DefaultConfig().UI.TailBufferSize
+}
+
 // Start starts the TUI application
 func Start(cfg *config.AppConfig, app *core.App) error {
 	model := NewModel(cfg, app)
+	if err := model.serdes.Validate(cfg.Serdes); err != nil {
+		return fmt.Errorf("invalid serdes config: %w", err)
+	}
 
 	// Initialize the cluster list
 	initialCmd := UpdateClusterListCmd(cfg.Clusters)